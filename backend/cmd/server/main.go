@@ -2,35 +2,70 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/api"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logbuffer"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/storage"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/webhook"
 	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 func main() {
+	// Keep recent log lines in memory for GET /api/v1/admin/logs.
+	log.SetOutput(io.MultiWriter(log.Writer(), logbuffer.Default))
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	proxy.SetMaxResponseSize(cfg.Upstream.MaxResponseSize)
+	proxy.SetMetadataTimeout(cfg.Upstream.MetadataTimeout)
+	proxy.SetVersionsCacheTTL(cfg.Upstream.VersionsCacheTTL)
 
 	db, err := initDatabase(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	var settings models.Settings
+	if err := db.First(&settings).Error; err == nil {
+		proxy.SetUpstreamRateLimit(settings.UpstreamRateLimit, settings.UpstreamRateBurst)
+	}
+
 	jwtManager := auth.NewJWTManager(cfg.Auth.SecretKey, 24*time.Hour)
+	jwtManager.SetRefreshDuration(cfg.Auth.RefreshTokenTTL)
+
+	var downloadSigner *auth.DownloadSigner
+	if cfg.Auth.SignedDownloads {
+		downloadSigner = auth.NewDownloadSigner(cfg.Auth.SecretKey, cfg.Auth.DownloadTokenTTL)
+		log.Printf("Signed provider downloads enabled (token TTL %s)", cfg.Auth.DownloadTokenTTL)
+	}
 
 	storagePath := cfg.Storage.Path
 	if storagePath == "" {
@@ -40,47 +75,85 @@ func main() {
 		log.Fatalf("Failed to create storage directory: %v", err)
 	}
 	storagePath, _ = filepath.Abs(storagePath)
+	cfg.Storage.Path = storagePath
 	log.Printf("Storage path: %s", storagePath)
 
-	syncScheduler := scheduler.New(db, storagePath)
-	if err := syncScheduler.Start(); err != nil {
+	store, err := openStorage(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	proxy.UseStorage(store)
+	// Module archives stay under the storage path with either backend.
+	go removeStaleTempFiles(storagePath)
+
+	if cfg.Storage.BackfillMetadata {
+		go func() {
+			api.BackfillPlatformMetadata(db, storagePath)
+			api.BackfillProviderProtocols(db, storagePath)
+		}()
+	}
+
+	syncScheduler := scheduler.New(db, storagePath, scheduler.RetryPolicy{
+		Retries:           cfg.Scheduler.Retries,
+		Backoff:           cfg.Scheduler.RetryBackoff,
+		FailureRetryDelay: cfg.Scheduler.FailureRetryDelay,
+	})
+	if cfg.Server.Frozen {
+		log.Println("Registry is frozen by configuration; scheduled syncs are disabled")
+	} else if err := syncScheduler.Start(); err != nil {
 		log.Printf("Warning: Failed to start scheduler: %v", err)
 	}
 
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
-		log.Println("Shutting down...")
-		syncScheduler.Stop()
-		os.Exit(0)
-	}()
+	var downloads *api.DownloadCounter
+	if cfg.Server.DownloadFlushInterval > 0 {
+		downloads = api.NewDownloadCounter(db, cfg.Server.DownloadFlushInterval)
+		downloads.Start()
+	}
 
-	router := api.SetupRouter(db, jwtManager, cfg.Auth.Enabled, storagePath)
+	router := api.SetupRouter(db, jwtManager, downloadSigner, downloads, syncScheduler, cfg)
 
 	addr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
-	log.Printf("Starting server on %s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadHeaderTimeout: 30 * time.Second,
 	}
-}
+	go func() {
+		log.Printf("Starting server on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
 
-func initDatabase(cfg *config.Config) (*gorm.DB, error) {
-	dbPath := cfg.Database.URL
-	if dbPath == "" {
-		dbPath = "/data/registry.db"
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
 
-	if len(dbPath) > 7 && dbPath[:7] == "sqlite:" {
-		dbPath = dbPath[7:]
+	// Let in-flight downloads and mirror streams finish before the scheduler
+	// and download counter, which they may still use, are stopped.
+	log.Printf("Shutting down, waiting up to %s for active requests", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Closing remaining connections: %v", err)
+		_ = srv.Close()
+	}
+	syncScheduler.Stop()
+	if downloads != nil {
+		downloads.Stop()
 	}
+	// Deliveries still retrying are dropped once the shutdown timeout is up.
+	webhook.Default.Wait(shutdownCtx)
+	log.Println("Server stopped")
+}
 
-	dbDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0750); err != nil { // #nosec G301 - database directory needs group access
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
+func initDatabase(cfg *config.Config) (*gorm.DB, error) {
+	dialector, target, err := openDialector(cfg.Database)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -93,36 +166,199 @@ func initDatabase(cfg *config.Config) (*gorm.DB, error) {
 		&models.MirrorConfig{},
 		&models.Settings{},
 		&models.SyncSchedule{},
+		&models.SyncRun{},
+		&models.ProviderClientVersion{},
+		&models.NamespaceOwner{},
+		&models.SigningKey{},
+		&models.ProviderSigningKey{},
+		&models.UpstreamChecksum{},
+		&models.RefreshToken{},
+		&models.Webhook{},
 	); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	// Create default admin user if no users exist
+	// Users without an API token used to store '', which the unique index
+	// allows only once; NULL marks "no token" now.
+	db.Model(&models.User{}).Where("api_token = ''").Update("api_token", nil)
+
+	if err := bootstrapAdminUsers(db, cfg.Server.Mode); err != nil {
+		return nil, err
+	}
+	flagInsecureAdmins(db)
+
+	log.Printf("Database initialized: %s", target)
+	return db, nil
+}
+
+// openDialector returns the GORM dialector for the database URL and a
+// description of the target that is safe to log. The directory of a SQLite
+// database file is created if missing.
+func openDialector(dbCfg config.DatabaseConfig) (gorm.Dialector, string, error) {
+	switch dbCfg.Driver() {
+	case config.DriverPostgres:
+		return postgres.Open(dbCfg.URL), dbCfg.RedactedURL(), nil
+	case config.DriverSQLite:
+		dbPath := dbCfg.SQLitePath()
+		if dbPath == "" {
+			dbPath = "/data/registry.db"
+		}
+		dbDir := filepath.Dir(dbPath)
+		if err := os.MkdirAll(dbDir, 0750); err != nil { // #nosec G301 - database directory needs group access
+			return nil, "", fmt.Errorf("failed to create database directory: %w", err)
+		}
+		return sqlite.Open(dbPath), dbPath, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported database url %q", dbCfg.RedactedURL())
+	}
+}
+
+// openStorage returns the backend provider files are kept in, selected by
+// storage.type. The local backend uses the storage path itself.
+func openStorage(storageCfg config.StorageConfig) (storage.Storage, error) {
+	if storageCfg.Type == "s3" {
+		s3 := storageCfg.S3
+		log.Printf("Storage backend: s3 bucket %s at %s", s3.Bucket, s3.Endpoint)
+		return storage.OpenS3(s3)
+	}
+	return storage.NewLocalStorage(storageCfg.Path)
+}
+
+// staleTempAge is how old a partial file in the storage path must be before
+// the startup sweep removes it, so that writes still in progress in another
+// replica sharing the volume are left alone.
+const staleTempAge = time.Hour
+
+// removeStaleTempFiles removes partial files left under the storage path by
+// downloads and uploads that were interrupted by a crash or a kill.
+func removeStaleTempFiles(storagePath string) {
+	local, err := storage.NewLocalStorage(storagePath)
+	if err != nil {
+		return
+	}
+	removed, size, err := local.RemoveStaleTempFiles(staleTempAge)
+	if err != nil {
+		log.Printf("Warning: Failed to remove stale temporary files: %v", err)
+	}
+	if removed > 0 {
+		log.Printf("Removed %d stale temporary files (%d bytes)", removed, size)
+	}
+}
+
+// adminSeed describes an admin account created on first boot.
+type adminSeed struct {
+	Username string
+	Email    string
+	Password string
+}
+
+// parseAdminUsers parses a comma-separated list of user:email:password entries.
+// The password is everything after the second colon, so it may itself contain colons.
+func parseAdminUsers(value string) ([]adminSeed, error) {
+	var seeds []adminSeed
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid admin user entry %q: expected user:email:password", parts[0])
+		}
+		seeds = append(seeds, adminSeed{Username: parts[0], Email: parts[1], Password: parts[2]})
+	}
+	return seeds, nil
+}
+
+// insecureAdminPassword is the password earlier releases gave the bootstrap
+// admin when ADMIN_PASSWORD was unset.
+const insecureAdminPassword = "admin123"
+
+// generateAdminPassword returns a random password for the bootstrap admin.
+func generateAdminPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// bootstrapAdminUsers creates the initial admin accounts if no users exist.
+// Accounts come from ADMIN_USERS when set, otherwise a single "admin" user is
+// created with the password from ADMIN_PASSWORD. Without ADMIN_PASSWORD the
+// password is generated, logged once and must be changed at first login. The
+// insecure default password is refused in release mode.
+func bootstrapAdminUsers(db *gorm.DB, mode string) error {
 	var userCount int64
 	db.Model(&models.User{}).Count(&userCount)
-	if userCount == 0 {
+	if userCount > 0 {
+		return nil
+	}
+
+	var seeds []adminSeed
+	if adminUsers := os.Getenv("ADMIN_USERS"); adminUsers != "" {
+		parsed, err := parseAdminUsers(adminUsers)
+		if err != nil {
+			log.Printf("Warning: ignoring ADMIN_USERS: %v", err)
+		} else {
+			seeds = parsed
+		}
+	}
+	generated := false
+	if len(seeds) == 0 {
 		adminPassword := os.Getenv("ADMIN_PASSWORD")
 		if adminPassword == "" {
-			adminPassword = "admin123"
+			var err error
+			if adminPassword, err = generateAdminPassword(); err != nil {
+				return fmt.Errorf("failed to generate admin password: %w", err)
+			}
+			generated = true
 		}
-		hashedPassword, err := auth.HashPassword(adminPassword)
+		seeds = []adminSeed{{Username: "admin", Email: "admin@localhost", Password: adminPassword}}
+	}
+	for _, seed := range seeds {
+		if seed.Password == insecureAdminPassword && mode == "release" {
+			return fmt.Errorf("admin user %s has the insecure default password; choose another before running in release mode", logsafe.Clean(seed.Username))
+		}
+	}
+
+	for _, seed := range seeds {
+		hashedPassword, err := auth.HashPassword(seed.Password)
 		if err != nil {
-			log.Printf("Warning: failed to hash admin password: %v", err)
-		} else {
-			adminUser := models.User{
-				Username: "admin",
-				Email:    "admin@localhost",
-				Password: hashedPassword,
-				Role:     "admin",
-			}
-			if err := db.Create(&adminUser).Error; err != nil {
-				log.Printf("Warning: failed to create admin user: %v", err)
-			} else {
-				log.Printf("Default admin user created (username: admin)")
-			}
+			log.Printf("Warning: failed to hash password for admin user %s: %v", logsafe.Clean(seed.Username), err)
+			continue
+		}
+		adminUser := models.User{
+			Username:           seed.Username,
+			Email:              seed.Email,
+			Password:           hashedPassword,
+			Role:               "admin",
+			MustChangePassword: generated || seed.Password == insecureAdminPassword,
+		}
+		if err := db.Create(&adminUser).Error; err != nil {
+			log.Printf("Warning: failed to create admin user %s: %v", logsafe.Clean(seed.Username), err)
+			continue
+		}
+		if generated {
+			log.Printf("Admin user created (username: %s, password: %s); the password is not shown again and must be changed at first login",
+				logsafe.Clean(seed.Username), seed.Password)
+			continue
 		}
+		log.Printf("Admin user created (username: %s)", logsafe.Clean(seed.Username))
 	}
+	return nil
+}
 
-	log.Printf("Database initialized: %s", dbPath)
-	return db, nil
+// flagInsecureAdmins requires admins that still have the insecure default
+// password, such as those bootstrapped by earlier releases, to change it.
+func flagInsecureAdmins(db *gorm.DB) {
+	var admins []models.User
+	db.Where("role = ? AND must_change_password = ?", "admin", false).Find(&admins)
+	for _, admin := range admins {
+		if !auth.CheckPassword(insecureAdminPassword, admin.Password) {
+			continue
+		}
+		db.Model(&models.User{}).Where("id = ?", admin.ID).Update("must_change_password", true)
+		log.Printf("Warning: admin user %s has the insecure default password and must change it at next login", logsafe.Clean(admin.Username))
+	}
 }
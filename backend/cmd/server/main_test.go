@@ -1,9 +1,16 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
 	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestInitDatabase(t *testing.T) {
@@ -22,3 +29,128 @@ func TestInitDatabase(t *testing.T) {
 		t.Fatal("Database instance is nil")
 	}
 }
+
+func TestOpenDialector(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	dialector, target, err := openDialector(config.DatabaseConfig{URL: "sqlite:" + filepath.Join(dir, "registry.db")})
+	if err != nil {
+		t.Fatalf("sqlite: %v", err)
+	}
+	if dialector.Name() != "sqlite" {
+		t.Errorf("sqlite: dialector = %q", dialector.Name())
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("sqlite: database directory not created: %v", err)
+	}
+	if target != filepath.Join(dir, "registry.db") {
+		t.Errorf("sqlite: target = %q", target)
+	}
+
+	// Opening a Postgres dialector does not connect, and must not touch the filesystem.
+	dialector, target, err = openDialector(config.DatabaseConfig{URL: "postgres://registry:secret@db/registry"})
+	if err != nil {
+		t.Fatalf("postgres: %v", err)
+	}
+	if dialector.Name() != "postgres" {
+		t.Errorf("postgres: dialector = %q", dialector.Name())
+	}
+	if strings.Contains(target, "secret") {
+		t.Errorf("postgres: target %q leaks the password", target)
+	}
+
+	if _, _, err := openDialector(config.DatabaseConfig{URL: "mysql://db/registry"}); err == nil {
+		t.Error("mysql: expected error")
+	}
+}
+
+func TestParseAdminUsers(t *testing.T) {
+	t.Run("multiple entries", func(t *testing.T) {
+		seeds, err := parseAdminUsers("alice:alice@example.com:secret, bob:bob@example.com:pa:ss")
+		if err != nil {
+			t.Fatalf("parseAdminUsers() error = %v", err)
+		}
+		if len(seeds) != 2 {
+			t.Fatalf("len(seeds) = %d, want 2", len(seeds))
+		}
+		if seeds[0].Username != "alice" || seeds[0].Email != "alice@example.com" || seeds[0].Password != "secret" {
+			t.Errorf("seeds[0] = %+v", seeds[0])
+		}
+		if seeds[1].Password != "pa:ss" {
+			t.Errorf("seeds[1].Password = %q, want %q", seeds[1].Password, "pa:ss")
+		}
+	})
+
+	t.Run("empty entries skipped", func(t *testing.T) {
+		seeds, err := parseAdminUsers("alice:alice@example.com:secret,,")
+		if err != nil {
+			t.Fatalf("parseAdminUsers() error = %v", err)
+		}
+		if len(seeds) != 1 {
+			t.Errorf("len(seeds) = %d, want 1", len(seeds))
+		}
+	})
+
+	t.Run("malformed entry", func(t *testing.T) {
+		if _, err := parseAdminUsers("alice:alice@example.com"); err == nil {
+			t.Error("parseAdminUsers() expected error for missing password")
+		}
+	})
+}
+
+func TestBootstrapAdminUsers(t *testing.T) {
+	newDB := func(t *testing.T) *gorm.DB {
+		t.Helper()
+		db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "registry.db")), &gorm.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AutoMigrate(&models.User{}); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	t.Setenv("ADMIN_USERS", "")
+
+	t.Run("generated password", func(t *testing.T) {
+		t.Setenv("ADMIN_PASSWORD", "")
+		db := newDB(t)
+		if err := bootstrapAdminUsers(db, "release"); err != nil {
+			t.Fatal(err)
+		}
+		var admin models.User
+		if err := db.Where("username = ?", "admin").First(&admin).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !admin.MustChangePassword || auth.CheckPassword(insecureAdminPassword, admin.Password) {
+			t.Errorf("admin = %+v, want a generated password that must be changed", admin)
+		}
+	})
+
+	t.Run("insecure default refused in release mode", func(t *testing.T) {
+		t.Setenv("ADMIN_PASSWORD", insecureAdminPassword)
+		db := newDB(t)
+		if err := bootstrapAdminUsers(db, "release"); err == nil {
+			t.Error("expected error in release mode")
+		}
+		if err := bootstrapAdminUsers(db, "debug"); err != nil {
+			t.Fatal(err)
+		}
+		var admin models.User
+		db.First(&admin)
+		if !admin.MustChangePassword {
+			t.Error("debug mode: insecure default password must be changed")
+		}
+	})
+
+	t.Run("existing insecure admin flagged", func(t *testing.T) {
+		db := newDB(t)
+		hash, _ := auth.HashPassword(insecureAdminPassword)
+		db.Create(&models.User{Username: "admin", Email: "admin@localhost", Password: hash, Role: "admin"})
+		flagInsecureAdmins(db)
+		var admin models.User
+		db.First(&admin)
+		if !admin.MustChangePassword {
+			t.Error("existing admin with the insecure default password not flagged")
+		}
+	})
+}
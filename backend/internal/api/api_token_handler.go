@@ -0,0 +1,93 @@
+// Package api provides HTTP handlers for managing API tokens.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiTokenResolver looks API tokens up by their stored hash, for AuthMiddleware.
+func apiTokenResolver(db *gorm.DB) auth.APITokenResolver {
+	return func(token string) (*auth.Claims, error) {
+		var user models.User
+		if err := db.Where("api_token = ?", auth.HashAPIToken(token)).First(&user).Error; err != nil {
+			return nil, auth.ErrInvalidAPIToken
+		}
+		return &auth.Claims{UserID: user.ID, Username: user.Username, Role: user.Role, Type: auth.TokenTypeAccess}, nil
+	}
+}
+
+// CreateAPIToken issues a new API token for the current user, replacing any
+// previous one. The token is only returned here; the registry keeps its hash.
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	h.rotateAPIToken(c, userID)
+}
+
+// RevokeAPIToken removes the current user's API token.
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	h.revokeAPIToken(c, userID)
+}
+
+// CreateUserAPIToken issues a new API token for the user in the id path
+// parameter (admin only).
+func (h *AuthHandler) CreateUserAPIToken(c *gin.Context) {
+	h.rotateAPIToken(c, c.Param("id"))
+}
+
+// RevokeUserAPIToken removes the API token of the user in the id path
+// parameter (admin only).
+func (h *AuthHandler) RevokeUserAPIToken(c *gin.Context) {
+	h.revokeAPIToken(c, c.Param("id"))
+}
+
+func (h *AuthHandler) rotateAPIToken(c *gin.Context, userID interface{}) {
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, msg := h.issueAPIToken(&user)
+	if msg != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_token": token,
+		"username":  user.Username,
+		"message":   "Store this token now; it cannot be shown again",
+	})
+}
+
+// issueAPIToken gives user a new API token, replacing any previous one, and
+// returns it, or the error message to answer with.
+func (h *AuthHandler) issueAPIToken(user *models.User) (string, string) {
+	token, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		return "", "Failed to generate API token"
+	}
+	if err := h.db.Model(user).Update("api_token", hash).Error; err != nil {
+		return "", "Failed to save API token"
+	}
+	return token, ""
+}
+
+func (h *AuthHandler) revokeAPIToken(c *gin.Context, userID interface{}) {
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := h.db.Model(&user).Update("api_token", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
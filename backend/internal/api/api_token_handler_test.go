@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestAPITokens(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Username: "admin", Email: "admin@example.com", Password: "x", Role: "admin"}
+	ci := models.User{Username: "ci", Email: "ci@example.com", Password: "x", Role: "user"}
+	// Both start without a token, which the unique index must allow.
+	for _, u := range []*models.User{&admin, &ci} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("create %s: %v", u.Username, err)
+		}
+	}
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+	ciJWT, _ := jwtManager.Generate(ci.ID, ci.Username, ci.Role)
+	adminJWT, _ := jwtManager.Generate(admin.ID, admin.Username, admin.Role)
+
+	do := func(method, path string, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	issue := func(path, jwt string) string {
+		t.Helper()
+		w := do(http.MethodPost, path, "Authorization", "Bearer "+jwt)
+		var resp struct {
+			APIToken string `json:"api_token"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusCreated || !strings.HasPrefix(resp.APIToken, auth.APITokenPrefix) {
+			t.Fatalf("POST %s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		return resp.APIToken
+	}
+	whoAmI := func(header, value string) (int, string) {
+		w := do(http.MethodGet, "/api/v1/auth/me", header, value)
+		var resp struct {
+			User models.User `json:"user"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.User.Username
+	}
+
+	token := issue("/api/v1/auth/api-token", ciJWT)
+	var stored models.User
+	db.First(&stored, ci.ID)
+	if stored.APIToken != auth.HashAPIToken(token) {
+		t.Errorf("stored token = %q, want its hash", stored.APIToken)
+	}
+
+	for _, header := range []string{"Authorization", "X-API-Key"} {
+		value := token
+		if header == "Authorization" {
+			value = "Bearer " + token
+		}
+		if code, user := whoAmI(header, value); code != http.StatusOK || user != "ci" {
+			t.Errorf("%s: status = %d, user = %q; want 200 ci", header, code, user)
+		}
+	}
+	if code, _ := whoAmI("X-API-Key", ciJWT); code != http.StatusUnauthorized {
+		t.Errorf("JWT in X-API-Key: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	rotated := issue("/api/v1/auth/api-token", ciJWT)
+	if code, _ := whoAmI("X-API-Key", token); code != http.StatusUnauthorized {
+		t.Errorf("token after rotation: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code, _ := whoAmI("X-API-Key", rotated); code != http.StatusOK {
+		t.Errorf("rotated token: status = %d, want %d", code, http.StatusOK)
+	}
+
+	if w := do(http.MethodDelete, "/api/v1/auth/api-token", "X-API-Key", rotated); w.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if code, _ := whoAmI("X-API-Key", rotated); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	// Admins manage other users' tokens; users cannot.
+	userPath := "/api/v1/admin/users/" + strconv.FormatUint(uint64(ci.ID), 10) + "/api-token"
+	if w := do(http.MethodPost, userPath, "Authorization", "Bearer "+ciJWT); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin issuing: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	issued := issue(userPath, adminJWT)
+	if code, user := whoAmI("X-API-Key", issued); code != http.StatusOK || user != "ci" {
+		t.Errorf("admin-issued token: status = %d, user = %q", code, user)
+	}
+	if w := do(http.MethodDelete, userPath, "Authorization", "Bearer "+adminJWT); w.Code != http.StatusOK {
+		t.Errorf("admin revoke: status = %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/admin/users/999/api-token", "Authorization", "Bearer "+adminJWT); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -2,7 +2,9 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
@@ -32,16 +34,22 @@ type LoginRequest struct {
 
 // LoginResponse represents login response body.
 type LoginResponse struct {
-	Token     string      `json:"token"`
-	User      models.User `json:"user"`
-	ExpiresIn int         `json:"expires_in"` // seconds
+	Token            string      `json:"token"`
+	RefreshToken     string      `json:"refresh_token"`
+	User             models.User `json:"user"`
+	ExpiresIn        int         `json:"expires_in"`         // seconds
+	RefreshExpiresIn int         `json:"refresh_expires_in"` // seconds
 }
 
-// RegisterRequest represents registration request body.
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required,min=3,max=50"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+// RefreshRequest represents the body of refresh and revoke requests.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ChangePasswordRequest represents the body of a password change.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
 // Login handles user login.
@@ -63,79 +71,100 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.jwtManager.Generate(user.ID, user.Username, user.Role)
+	resp, err := h.issueTokens(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{
-		Token:     token,
-		User:      user,
-		ExpiresIn: 86400, // 24 hours
-	})
+	c.JSON(http.StatusOK, resp)
 }
 
-// Register handles user registration.
-func (h *AuthHandler) Register(c *gin.Context) {
-	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
-		return
+// issueTokens creates an access token and a recorded refresh token for user.
+// Expired refresh token records are cleared on the way.
+func (h *AuthHandler) issueTokens(user models.User) (LoginResponse, error) {
+	token, err := h.jwtManager.Generate(user.ID, user.Username, user.Role)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	refreshToken, claims, err := h.jwtManager.GenerateRefreshToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		return LoginResponse{}, err
 	}
 
-	// Check if username already exists
-	var existingUser models.User
-	if err := h.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
-		return
+	h.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{})
+	record := models.RefreshToken{JTI: claims.ID, UserID: user.ID, ExpiresAt: claims.ExpiresAt.Time}
+	if err := h.db.Create(&record).Error; err != nil {
+		return LoginResponse{}, err
 	}
 
-	// Check if email already exists
-	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+	return LoginResponse{
+		Token:            token,
+		RefreshToken:     refreshToken,
+		User:             user,
+		ExpiresIn:        int(h.jwtManager.TokenDuration().Seconds()),
+		RefreshExpiresIn: int(h.jwtManager.RefreshDuration().Seconds()),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token carrying the user's
+// current role. The refresh token itself is not rotated and stays valid until
+// it expires or is revoked.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: refresh_token is required"})
 		return
 	}
 
-	// Hash password
-	hashedPassword, err := auth.HashPassword(req.Password)
+	token, claims, err := h.jwtManager.RefreshAccessToken(req.RefreshToken, func(claims *auth.Claims) (*auth.Claims, bool) {
+		var record models.RefreshToken
+		if err := h.db.Where("jti = ? AND user_id = ?", claims.ID, claims.UserID).First(&record).Error; err != nil || record.RevokedAt != nil {
+			return nil, false
+		}
+		// Deleted users keep no access, even with an unexpired refresh token.
+		var user models.User
+		if err := h.db.First(&user, claims.UserID).Error; err != nil {
+			return nil, false
+		}
+		return &auth.Claims{UserID: user.ID, Username: user.Username, Role: user.Role}, true
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if this is the first user (make them admin)
-	var userCount int64
-	h.db.Model(&models.User{}).Count(&userCount)
-	role := "user"
-	if userCount == 0 {
-		role = "admin"
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_in": int(h.jwtManager.TokenDuration().Seconds()),
+		"username":   claims.Username,
+	})
+}
 
-	user := models.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: hashedPassword,
-		Role:     role,
+// Revoke invalidates a refresh token, typically on logout. Access tokens already
+// issued from it stay valid until they expire.
+func (h *AuthHandler) Revoke(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: refresh_token is required"})
+		return
 	}
 
-	if err := h.db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	claims, err := h.jwtManager.VerifyRefreshToken(req.RefreshToken)
+	if errors.Is(err, auth.ErrExpiredToken) {
+		// Nothing left to revoke.
+		c.JSON(http.StatusOK, gin.H{"message": "Refresh token revoked"})
 		return
 	}
-
-	// Generate token for auto-login
-	token, err := h.jwtManager.Generate(user.ID, user.Username, user.Role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, LoginResponse{
-		Token:     token,
-		User:      user,
-		ExpiresIn: 86400,
-	})
+	h.db.Model(&models.RefreshToken{}).
+		Where("jti = ? AND revoked_at IS NULL", claims.ID).
+		Update("revoked_at", time.Now())
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh token revoked"})
 }
 
 // GetCurrentUser returns the current authenticated user.
@@ -155,6 +184,52 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+// ChangePassword sets a new password for the current user after checking the
+// current one, and clears a pending MustChangePassword. The user's refresh
+// tokens are deleted with it, so sessions started with the old password end.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, c.MustGet("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !auth.CheckPassword(req.CurrentPassword, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password":             hashedPassword,
+			"must_change_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RefreshToken{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
 // CheckAuthStatus checks if authentication is required.
 func (h *AuthHandler) CheckAuthStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestAuthHandler_RefreshTokens(t *testing.T) {
+	db := newTestDB(t)
+	hash, _ := auth.HashPassword("ci-password")
+	user := models.User{Username: "ci", Email: "ci@example.com", Password: hash, Role: "user"}
+	db.Create(&user)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	me := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	w := post("/api/v1/auth/login", LoginRequest{Username: "ci", Password: "ci-password"})
+	var login LoginResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &login)
+	if w.Code != http.StatusOK || login.RefreshToken == "" || login.ExpiresIn != 3600 || login.RefreshExpiresIn <= login.ExpiresIn {
+		t.Fatalf("login: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.Model(&models.RefreshToken{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("recorded refresh tokens = %d, want 1", count)
+	}
+
+	if code := me(login.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("refresh token as access token: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	w = post("/api/v1/auth/refresh", RefreshRequest{RefreshToken: login.RefreshToken})
+	var refreshed struct {
+		Token string `json:"token"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if w.Code != http.StatusOK || refreshed.Token == "" {
+		t.Fatalf("refresh: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if code := me(refreshed.Token); code != http.StatusOK {
+		t.Errorf("refreshed access token: status = %d, want %d", code, http.StatusOK)
+	}
+
+	if w := post("/api/v1/auth/refresh", RefreshRequest{RefreshToken: login.Token}); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with access token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	if w := post("/api/v1/auth/revoke", RefreshRequest{RefreshToken: login.RefreshToken}); w.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := post("/api/v1/auth/refresh", RefreshRequest{RefreshToken: login.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after revoke: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// Refreshed access tokens carry the role the user has now.
+	w = post("/api/v1/auth/login", LoginRequest{Username: "ci", Password: "ci-password"})
+	_ = json.Unmarshal(w.Body.Bytes(), &login)
+	db.Model(&user).Update("role", "admin")
+	w = post("/api/v1/auth/refresh", RefreshRequest{RefreshToken: login.RefreshToken})
+	_ = json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if claims, err := jwtManager.Verify(refreshed.Token); err != nil || claims.Role != "admin" {
+		t.Errorf("refresh after role change: claims = %+v, %v; want role admin", claims, err)
+	}
+
+	// A refresh token for a deleted user is useless even if never revoked.
+	w = post("/api/v1/auth/login", LoginRequest{Username: "ci", Password: "ci-password"})
+	_ = json.Unmarshal(w.Body.Bytes(), &login)
+	db.Delete(&user)
+	if w := post("/api/v1/auth/refresh", RefreshRequest{RefreshToken: login.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh for deleted user: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAuthHandler_MustChangePassword(t *testing.T) {
+	db := newTestDB(t)
+	hash, _ := auth.HashPassword("generated")
+	user := models.User{Username: "admin", Email: "admin@localhost", Password: hash, Role: "admin", MustChangePassword: true}
+	db.Create(&user)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+	token, _ := jwtManager.Generate(user.ID, user.Username, user.Role)
+
+	serve := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodGet, "/api/v1/mirror/coverage?platforms=linux/amd64", nil); w.Code != http.StatusForbidden {
+		t.Errorf("before change: status = %d, want 403", w.Code)
+	}
+	if w := serve(http.MethodGet, "/api/v1/auth/me", nil); w.Code != http.StatusOK {
+		t.Errorf("me: status = %d, want 200", w.Code)
+	}
+	if w := serve(http.MethodPost, "/api/v1/auth/change-password", ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong current password: status = %d, want 401", w.Code)
+	}
+	if w := serve(http.MethodPost, "/api/v1/auth/change-password", ChangePasswordRequest{CurrentPassword: "generated", NewPassword: "generated"}); w.Code != http.StatusBadRequest {
+		t.Errorf("unchanged password: status = %d, want 400", w.Code)
+	}
+	db.Create(&models.RefreshToken{JTI: "old-session", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	if w := serve(http.MethodPost, "/api/v1/auth/change-password", ChangePasswordRequest{CurrentPassword: "generated", NewPassword: "new-password"}); w.Code != http.StatusOK {
+		t.Fatalf("change: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var sessions int64
+	db.Model(&models.RefreshToken{}).Where("user_id = ?", user.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("refresh tokens after change = %d, want 0", sessions)
+	}
+
+	db.First(&user, user.ID)
+	if user.MustChangePassword || !auth.CheckPassword("new-password", user.Password) {
+		t.Errorf("user = %+v, want new password and flag cleared", user)
+	}
+	if w := serve(http.MethodGet, "/api/v1/mirror/coverage?platforms=linux/amd64", nil); w.Code != http.StatusOK {
+		t.Errorf("after change: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,152 @@
+// Package api provides the startup backfill of legacy provider metadata.
+package api
+
+import (
+	"context"
+	"log"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+	"gorm.io/gorm"
+)
+
+// backfillProgressInterval is how many rows are processed between progress log lines.
+const backfillProgressInterval = 100
+
+// BackfillResult summarizes a BackfillPlatformMetadata or
+// BackfillProviderProtocols run.
+type BackfillResult struct {
+	Checked int // Rows examined
+	Updated int // Rows whose values were filled in or corrected
+	Missing int // Rows skipped because their file, or their upstream version, is gone
+	Failed  int // Rows that could not be checked or saved
+}
+
+// BackfillPlatformMetadata fills in the SHA256Sum and FileSize of provider platform
+// rows written by older releases, hashing the stored file. Rows that already have
+// both values are not touched, so running it again is a no-op.
+func BackfillPlatformMetadata(db *gorm.DB, storagePath string) BackfillResult {
+	var platforms []models.ProviderPlatform
+	if err := db.Where("sha256_sum = '' OR sha256_sum IS NULL OR file_size = 0 OR file_size IS NULL").
+		Find(&platforms).Error; err != nil {
+		log.Printf("Platform metadata backfill: failed to load platforms: %v", err)
+		return BackfillResult{}
+	}
+
+	result := BackfillResult{Checked: len(platforms)}
+	if result.Checked == 0 {
+		return result
+	}
+	log.Printf("Platform metadata backfill: %d platforms need checksum or size", result.Checked)
+
+	hasher := proxy.NewProxyService(storagePath, "")
+	for i, p := range platforms {
+		if i > 0 && i%backfillProgressInterval == 0 {
+			log.Printf("Platform metadata backfill: processed %d/%d", i, result.Checked)
+		}
+
+		if !hasher.FileExists(p.FilePath) {
+			log.Printf("Platform metadata backfill: skipping platform %d, file %s is missing", p.ID, logsafe.Clean(p.FilePath))
+			result.Missing++
+			continue
+		}
+
+		updates := map[string]interface{}{}
+		if p.SHA256Sum == "" {
+			sum, err := hasher.CalculateFileSHA256(p.FilePath)
+			if err != nil {
+				log.Printf("Platform metadata backfill: failed to hash platform %d: %v", p.ID, err)
+				result.Failed++
+				continue
+			}
+			updates["sha256_sum"] = sum
+		}
+		if p.FileSize == 0 {
+			size, _ := hasher.FileSize(p.FilePath)
+			updates["file_size"] = size
+		}
+
+		if err := db.Model(&models.ProviderPlatform{}).Where("id = ?", p.ID).UpdateColumns(updates).Error; err != nil {
+			log.Printf("Platform metadata backfill: failed to save platform %d: %v", p.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Updated++
+	}
+
+	log.Printf("Platform metadata backfill: done, %d updated, %d missing files, %d failed",
+		result.Updated, result.Missing, result.Failed)
+	return result
+}
+
+// BackfillProviderProtocols replaces the plugin protocols of mirrored provider
+// versions with the ones their upstream lists. Older releases recorded a fixed
+// value regardless of upstream, advertising protocol-6-only providers as 5.0.
+// Each provider's upstream is queried once; rows that already match are not
+// touched, so running it again makes no changes.
+func BackfillProviderProtocols(db *gorm.DB, storagePath string) BackfillResult {
+	var providers []models.Provider
+	if err := db.Where("source_type = ?", models.SourceMirror).
+		Order("namespace, name").Find(&providers).Error; err != nil {
+		log.Printf("Provider protocols backfill: failed to load providers: %v", err)
+		return BackfillResult{}
+	}
+
+	result := BackfillResult{Checked: len(providers)}
+	if result.Checked == 0 {
+		return result
+	}
+	log.Printf("Provider protocols backfill: checking %d mirrored versions against upstream", result.Checked)
+
+	var settings models.Settings
+	hasSettings := db.First(&settings).Error == nil
+	for start := 0; start < len(providers); {
+		namespace, name := providers[start].Namespace, providers[start].Name
+		end := start
+		for end < len(providers) && providers[end].Namespace == namespace && providers[end].Name == name {
+			end++
+		}
+		versions := providers[start:end]
+		start = end
+
+		ps := proxy.NewProxyService(storagePath, scheduler.UpstreamFor(db, namespace, name))
+		if hasSettings {
+			applyProxySettings(ps, settings)
+		}
+		upstream, err := ps.GetProviderVersions(context.Background(), namespace, name)
+		if err != nil {
+			log.Printf("Provider protocols backfill: failed to get versions of %s/%s: %s",
+				logsafe.Clean(namespace), logsafe.Clean(name), logsafe.CleanErr(err))
+			result.Failed += len(versions)
+			continue
+		}
+		listed := make(map[string][]string, len(upstream.Versions))
+		for _, v := range upstream.Versions {
+			listed[v.Version] = v.Protocols
+		}
+
+		for _, p := range versions {
+			protocols, ok := listed[p.Version]
+			if !ok || len(protocols) == 0 {
+				result.Missing++
+				continue
+			}
+			encoded := proxy.EncodeProtocols(protocols)
+			if encoded == p.Protocols {
+				continue
+			}
+			if err := db.Model(&models.Provider{}).Where("id = ?", p.ID).UpdateColumn("protocols", encoded).Error; err != nil {
+				log.Printf("Provider protocols backfill: failed to save provider %d: %v", p.ID, err)
+				result.Failed++
+				continue
+			}
+			result.Updated++
+		}
+	}
+
+	log.Printf("Provider protocols backfill: done, %d updated, %d not listed upstream, %d failed",
+		result.Updated, result.Missing, result.Failed)
+	return result
+}
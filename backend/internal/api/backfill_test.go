@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestBackfillPlatformMetadata(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	filePath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	if err := os.WriteFile(filePath, []byte("provider-binary"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	legacy := models.ProviderPlatform{ProviderID: 1, OS: "linux", Arch: "amd64", Filename: filepath.Base(filePath), FilePath: filePath}
+	missing := models.ProviderPlatform{ProviderID: 1, OS: "darwin", Arch: "arm64", Filename: "gone.zip", FilePath: filepath.Join(storagePath, "gone.zip")}
+	complete := models.ProviderPlatform{ProviderID: 1, OS: "windows", Arch: "amd64", Filename: "w.zip", FilePath: "/w.zip", SHA256Sum: "keep", FileSize: 7}
+	db.Create(&legacy)
+	db.Create(&missing)
+	db.Create(&complete)
+
+	result := BackfillPlatformMetadata(db, storagePath)
+	if result.Checked != 2 || result.Updated != 1 || result.Missing != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v, want 2 checked, 1 updated, 1 missing", result)
+	}
+
+	var got models.ProviderPlatform
+	db.First(&got, legacy.ID)
+	// sha256 of "provider-binary"
+	const wantSum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9"
+	if got.SHA256Sum != wantSum || got.FileSize != int64(len("provider-binary")) {
+		t.Errorf("backfilled platform = %q/%d, want %q/%d", got.SHA256Sum, got.FileSize, wantSum, len("provider-binary"))
+	}
+
+	if again := BackfillPlatformMetadata(db, storagePath); again.Updated != 0 {
+		t.Errorf("second run updated %d platforms, want 0", again.Updated)
+	}
+}
+
+func TestBackfillProviderProtocols(t *testing.T) {
+	requests := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/providers/hashicorp/awscc/versions" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0","protocols":["6.0"]},{"version":"0.9.0","protocols":["5.0","6.0"]}]}`))
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{AllowOnlineSearch: true, DefaultUpstreamURL: upstream.URL})
+	legacy := models.Provider{Namespace: "hashicorp", Name: "awscc", Version: "1.0.0", SourceType: models.SourceMirror, Protocols: `["5.0"]`}
+	current := models.Provider{Namespace: "hashicorp", Name: "awscc", Version: "0.9.0", SourceType: models.SourceMirror, Protocols: `["5.0","6.0"]`}
+	yanked := models.Provider{Namespace: "hashicorp", Name: "awscc", Version: "0.1.0", SourceType: models.SourceMirror, Protocols: `["5.0"]`}
+	uploaded := models.Provider{Namespace: "internal", Name: "tools", Version: "1.0.0", SourceType: models.SourceUpload, Protocols: `["5.0"]`}
+	for _, p := range []*models.Provider{&legacy, &current, &yanked, &uploaded} {
+		db.Create(p)
+	}
+
+	result := BackfillProviderProtocols(db, t.TempDir())
+	if result.Checked != 3 || result.Updated != 1 || result.Missing != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v, want 3 checked, 1 updated, 1 missing", result)
+	}
+	if requests != 1 {
+		t.Errorf("upstream queried %d times, want once per provider", requests)
+	}
+
+	var got models.Provider
+	db.First(&got, legacy.ID)
+	if got.Protocols != `["6.0"]` {
+		t.Errorf("backfilled protocols = %s, want [\"6.0\"]", got.Protocols)
+	}
+	var kept models.Provider
+	db.First(&kept, yanked.ID)
+	if kept.Protocols != `["5.0"]` {
+		t.Errorf("protocols of a version upstream no longer lists = %s, want them kept", kept.Protocols)
+	}
+
+	if again := BackfillProviderProtocols(db, t.TempDir()); again.Updated != 0 {
+		t.Errorf("second run updated %d providers, want 0", again.Updated)
+	}
+}
@@ -0,0 +1,250 @@
+// Package api provides HTTP handlers for mirroring providers in batches.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxBatchMirrorItems bounds the providers one MirrorBatch request may name.
+	maxBatchMirrorItems = 50
+	// defaultBatchMirrorWorkers is how many providers of a batch are mirrored at once.
+	defaultBatchMirrorWorkers = 4
+)
+
+// BatchMirrorItem is one provider of a MirrorBatch request. Version defaults to
+// the latest upstream version, OS and Arch to the configured default platform.
+// Items with a higher Priority are started first; equal priorities keep the
+// order of the request.
+type BatchMirrorItem struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Arch      string `json:"arch,omitempty"`
+	Priority  int    `json:"priority,omitempty"`
+}
+
+// BatchMirrorResult is the outcome of mirroring one BatchMirrorItem. Version is
+// the resolved version; Platforms counts the platforms mirrored. A provider
+// mirrored for only some of its platforms is "partial", with the others in
+// FailedPlatforms.
+type BatchMirrorResult struct {
+	Namespace       string           `json:"namespace"`
+	Name            string           `json:"name"`
+	Version         string           `json:"version,omitempty"`
+	Status          string           `json:"status"` // "mirrored", "partial" or "failed"
+	Platforms       int              `json:"platforms"`
+	FailedPlatforms []FailedPlatform `json:"failed_platforms,omitempty"`
+	Error           string           `json:"error,omitempty"`
+}
+
+// setOutcome records the outcome of mirroring a provider in r and reports
+// whether any platform was mirrored.
+func (r *BatchMirrorResult) setOutcome(platforms int, failed []FailedPlatform, err error) bool {
+	r.FailedPlatforms = failed
+	switch {
+	case err != nil:
+		r.Status = "failed"
+		r.Error = err.Error()
+		return false
+	case len(failed) > 0:
+		r.Status = "partial"
+	default:
+		r.Status = "mirrored"
+	}
+	r.Platforms = platforms
+	return true
+}
+
+// BatchMirrorProgress is a progress update of a streamed MirrorBatch. The
+// embedded MirrorProgress describes the current item, except that Percent is
+// the progress of the whole batch. Type is "item" once an item has finished,
+// with its Result, and "complete" at the end, with every result.
+type BatchMirrorProgress struct {
+	MirrorProgress
+	Item     int                 `json:"item"`  // Current item's index in priority order (1-based)
+	Items    int                 `json:"items"` // Items in the batch
+	Provider string              `json:"provider,omitempty"`
+	Result   *BatchMirrorResult  `json:"result,omitempty"`
+	Results  []BatchMirrorResult `json:"results,omitempty"`
+}
+
+// MirrorBatch mirrors a list of providers, each the way MirrorProvider does, a
+// few at a time and higher priorities first, and reports the outcome of every
+// one in the order they finished; a failed item does not stop the rest. The
+// body is a JSON array of BatchMirrorItem. With stream=true, progress is sent
+// as server-sent BatchMirrorProgress events instead of a single response at
+// the end.
+func (h *MirrorHandler) MirrorBatch(c *gin.Context) {
+	var items []BatchMirrorItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one provider is required"})
+		return
+	}
+	if len(items) > maxBatchMirrorItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d providers may be mirrored at once", maxBatchMirrorItems)})
+		return
+	}
+	defaultOS, defaultArch := defaultPlatform(h.db)
+	for i := range items {
+		item := &items[i]
+		if msg := validateProviderParams(item.Namespace, item.Name, item.Version); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("item %d: %s", i+1, msg)})
+			return
+		}
+		if item.OS == "" {
+			item.OS = defaultOS
+		}
+		if item.Arch == "" {
+			item.Arch = defaultArch
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Priority > items[j].Priority })
+
+	stream := c.Query("stream") == "true"
+	sendProgress := func(BatchMirrorProgress) {}
+	if stream {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		sendProgress = func(p BatchMirrorProgress) {
+			data, _ := json.Marshal(p)
+			c.SSEvent("message", string(data))
+			c.Writer.Flush()
+		}
+	}
+
+	// mu serializes progress events and guards the results and counters below.
+	var mu sync.Mutex
+	results := make([]BatchMirrorResult, 0, len(items))
+	mirrored := 0
+	done := make([]float64, len(items)) // Finished fraction of each item
+	batchPercent := func() float64 {
+		var sum float64
+		for _, d := range done {
+			sum += d
+		}
+		return sum / float64(len(items)) * 100
+	}
+
+	// The queue holds the items in priority order, so the workers take up
+	// higher priorities first.
+	ctx := c.Request.Context()
+	queue := make(chan int, len(items))
+	for i := range items {
+		queue <- i
+	}
+	close(queue)
+	var wg sync.WaitGroup
+	for range min(h.batchWorkers, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				item := items[i]
+				provider := item.Namespace + "/" + item.Name
+				itemProgress := func(p MirrorProgress) {
+					mu.Lock()
+					defer mu.Unlock()
+					done[i] = p.Percent / 100
+					p.Percent = batchPercent()
+					sendProgress(BatchMirrorProgress{MirrorProgress: p, Item: i + 1, Items: len(items), Provider: provider})
+				}
+
+				result := BatchMirrorResult{Namespace: item.Namespace, Name: item.Name, Version: item.Version}
+				version, platforms, failed, err := h.mirrorBatchItem(c, item, itemProgress)
+				if version != "" {
+					result.Version = version
+				}
+
+				mu.Lock()
+				if result.setOutcome(platforms, failed, err) {
+					mirrored++
+				}
+				results = append(results, result)
+				done[i] = 1
+				sendProgress(BatchMirrorProgress{
+					MirrorProgress: MirrorProgress{
+						Type: "item", Percent: batchPercent(),
+						Message: fmt.Sprintf("%s: %s", provider, result.Status),
+					},
+					Item: i + 1, Items: len(items), Provider: provider, Result: &result,
+				})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// The client went away; providers finished before that stay cached.
+		return
+	}
+
+	message := fmt.Sprintf("Mirrored %d of %d providers", mirrored, len(items))
+	if stream {
+		sendProgress(BatchMirrorProgress{
+			MirrorProgress: MirrorProgress{Type: "complete", Percent: 100, Message: message},
+			Item:           len(items), Items: len(items), Results: results,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  message,
+		"mirrored": mirrored,
+		"failed":   len(items) - mirrored,
+		"results":  results,
+	})
+}
+
+// mirrorBatchItem mirrors one provider of a batch and returns the resolved
+// version, how many platforms were mirrored and the platforms that failed.
+func (h *MirrorHandler) mirrorBatchItem(c *gin.Context, item BatchMirrorItem, sendProgress func(MirrorProgress)) (string, int, []FailedPlatform, error) {
+	ctx := c.Request.Context()
+	if h.namespaceOwnership && !canWriteNamespace(h.db, c, item.Namespace) {
+		return "", 0, nil, errors.New(namespaceForbidden(item.Namespace))
+	}
+
+	proxyService := h.getProxyService(item.Namespace, item.Name, "")
+	sendProgress(MirrorProgress{Type: "progress", Message: "Fetching version information..."})
+	platforms, version, published, err := h.fetchPlatformsToMirror(ctx, proxyService, item.Namespace, item.Name, item.Version, item.OS, item.Arch, nil)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	platforms = filterLockedPlatforms(platforms, lockedPlatforms(h.db, item.Namespace, item.Name, version))
+	if len(platforms) == 0 {
+		return version, 0, nil, errors.New(immutableConflict(item.Namespace, item.Name, version, "all requested platforms"))
+	}
+
+	mirroredPlatforms, _, failed, lastError := h.downloadPlatformsWithProgress(ctx, proxyService, item.Namespace, item.Name, version, platforms, sendProgress)
+	if errors.Is(lastError, proxy.ErrSignatureInvalid) {
+		return version, 0, failed, lastError
+	}
+	if err := ctx.Err(); err != nil {
+		return version, 0, failed, err
+	}
+	if len(mirroredPlatforms) == 0 {
+		return version, 0, failed, fmt.Errorf("failed to mirror any platform: %v", lastError)
+	}
+	if err := h.saveMirroredProvider(ctx, proxyService, item.Namespace, item.Name, version, published, mirroredPlatforms); err != nil {
+		return version, 0, failed, err
+	}
+	notifyMirrorCompleted(h.db, item.Namespace, item.Name, version, mirroredPlatforms, failed)
+	return version, len(mirroredPlatforms), failed, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMirrorBatch(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/batch", h.MirrorBatch)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	// The upstream knows hashicorp/aws only, so the second item fails without
+	// stopping the batch.
+	const body = `[{"namespace":"hashicorp","name":"aws","os":"linux","arch":"amd64"},{"namespace":"hashicorp","name":"google"}]`
+	w := post("/mirror/batch", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Mirrored int                 `json:"mirrored"`
+		Failed   int                 `json:"failed"`
+		Results  []BatchMirrorResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Mirrored != 1 || resp.Failed != 1 || len(resp.Results) != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	// Results come in the order the items finished.
+	sort.Slice(resp.Results, func(i, j int) bool { return resp.Results[i].Name < resp.Results[j].Name })
+	if r := resp.Results[0]; r.Status != "mirrored" || r.Version != "5.0.0" || r.Platforms != 1 {
+		t.Errorf("results[0] = %+v", r)
+	}
+	if r := resp.Results[1]; r.Status != "failed" || r.Error == "" {
+		t.Errorf("results[1] = %+v", r)
+	}
+	var platform models.ProviderPlatform
+	if err := db.First(&platform).Error; err != nil || platform.OS != "linux" {
+		t.Errorf("platform = %+v, %v", platform, err)
+	}
+
+	// Streamed, each item reports its result and the last event carries them all.
+	db.Where("1 = 1").Delete(&models.ProviderPlatform{})
+	db.Unscoped().Where("1 = 1").Delete(&models.Provider{})
+	w = post("/mirror/batch?stream=true", body)
+	var events []BatchMirrorProgress
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			var e BatchMirrorProgress
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				t.Fatalf("event %q: %v", data, err)
+			}
+			events = append(events, e)
+		}
+	}
+	var items []string
+	for _, e := range events {
+		if e.Type == "item" {
+			items = append(items, e.Provider+" "+e.Result.Status)
+		}
+		if e.Percent < 0 || e.Percent > 100 {
+			t.Errorf("event %+v: percent out of range", e)
+		}
+	}
+	sort.Strings(items)
+	if strings.Join(items, ", ") != "hashicorp/aws mirrored, hashicorp/google failed" {
+		t.Errorf("item events = %v", items)
+	}
+	if last := events[len(events)-1]; last.Type != "complete" || len(last.Results) != 2 {
+		t.Errorf("last event = %+v, want complete with both results", last)
+	}
+
+	// With one worker the items run in priority order; equal priorities keep
+	// the order of the request.
+	h.batchWorkers = 1
+	w = post("/mirror/batch", `[{"namespace":"hashicorp","name":"google"},{"namespace":"hashicorp","name":"random","priority":5},`+
+		`{"namespace":"hashicorp","name":"null","priority":-1},{"namespace":"hashicorp","name":"aws","priority":5}]`)
+	resp.Results = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, r := range resp.Results {
+		order = append(order, r.Name)
+	}
+	if strings.Join(order, ",") != "random,aws,google,null" {
+		t.Errorf("mirror order = %v, want random,aws,google,null", order)
+	}
+
+	for _, body := range []string{`[]`, `{"namespace":"hashicorp"}`, `[{"namespace":"Hashi Corp","name":"aws"}]`} {
+		if w := post("/mirror/batch", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+}
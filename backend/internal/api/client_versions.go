@@ -0,0 +1,173 @@
+// Package api provides HTTP handlers for Terraform client version statistics.
+package api
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxClientVersionLength bounds the stored version string so a misbehaving
+// client cannot inflate the table with arbitrary header values.
+const maxClientVersionLength = 64
+
+// terraformClientVersion returns the Terraform version of the requesting client,
+// or "" if the request did not come from Terraform. The X-Terraform-Version header
+// sent by the registry client takes precedence over the User-Agent, which is the
+// only hint available on network mirror requests.
+func terraformClientVersion(c *gin.Context) string {
+	version := c.GetHeader("X-Terraform-Version")
+	if version == "" {
+		ua := c.GetHeader("User-Agent")
+		if rest, ok := strings.CutPrefix(ua, "Terraform/"); ok {
+			version, _, _ = strings.Cut(rest, " ")
+		}
+	}
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if len(version) > maxClientVersionLength {
+		return ""
+	}
+	if _, ok := semver.Parse(version); !ok {
+		return ""
+	}
+	return version
+}
+
+// clientVersionQueueSize bounds how many client version updates wait for the
+// recorder's worker.
+const clientVersionQueueSize = 256
+
+// clientVersionUpdate is one request to count for a Terraform client version.
+type clientVersionUpdate struct {
+	namespace, name, version string
+}
+
+// clientVersionRecorder writes client version counts on a single background
+// worker so the writes never delay a response. Updates arriving while its
+// queue is full are dropped; the counts are statistics, not an audit log.
+type clientVersionRecorder struct {
+	db      *gorm.DB
+	updates chan clientVersionUpdate
+}
+
+// newClientVersionRecorder creates a clientVersionRecorder and starts its worker.
+func newClientVersionRecorder(db *gorm.DB) *clientVersionRecorder {
+	r := &clientVersionRecorder{db: db, updates: make(chan clientVersionUpdate, clientVersionQueueSize)}
+	go r.run()
+	return r
+}
+
+// run records queued updates one at a time.
+func (r *clientVersionRecorder) run() {
+	for u := range r.updates {
+		recordClientVersion(r.db, u.namespace, u.name, u.version)
+	}
+}
+
+// record queues an update, dropping it if the queue is full.
+func (r *clientVersionRecorder) record(namespace, name, version string) {
+	select {
+	case r.updates <- clientVersionUpdate{namespace: namespace, name: name, version: version}:
+	default:
+	}
+}
+
+// recordClientVersions returns middleware that counts successful requests per
+// Terraform client version for the provider in the route, handing the write to
+// recorder.
+func recordClientVersions(recorder *clientVersionRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Status() != http.StatusOK {
+			return
+		}
+		namespace, name := c.Param("namespace"), c.Param("name")
+		if validateProviderParams(namespace, name, "") != "" {
+			return
+		}
+		version := terraformClientVersion(c)
+		if version == "" {
+			return
+		}
+		recorder.record(namespace, name, version)
+	}
+}
+
+// recordClientVersion increments the download count of a provider for a Terraform version.
+func recordClientVersion(db *gorm.DB, namespace, name, version string) {
+	now := time.Now()
+	row := models.ProviderClientVersion{
+		Namespace:        namespace,
+		Name:             name,
+		TerraformVersion: version,
+		Downloads:        1,
+		LastSeenAt:       now,
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "namespace"}, {Name: "name"}, {Name: "terraform_version"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"downloads":    gorm.Expr("downloads + 1"),
+			"last_seen_at": now,
+		}),
+	}).Create(&row).Error
+	if err != nil {
+		log.Printf("Failed to record client version for %s/%s: %v",
+			logsafe.Clean(namespace), logsafe.Clean(name), err)
+	}
+}
+
+// ClientVersionCount is one bucket of the client version histogram.
+type ClientVersionCount struct {
+	TerraformVersion string    `json:"terraform_version"`
+	Downloads        int64     `json:"downloads"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+}
+
+// GetProviderClients returns how many downloads of a provider each Terraform
+// version made, newest version first.
+func (h *MirrorHandler) GetProviderClients(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if msg := validateProviderParams(namespace, name, ""); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var rows []models.ProviderClientVersion
+	if err := h.db.Where("namespace = ? AND name = ?", namespace, name).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load client versions"})
+		return
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return semver.Compare(rows[i].TerraformVersion, rows[j].TerraformVersion) > 0
+	})
+
+	clients := make([]ClientVersionCount, 0, len(rows))
+	var total int64
+	for _, r := range rows {
+		clients = append(clients, ClientVersionCount{
+			TerraformVersion: r.TerraformVersion,
+			Downloads:        r.Downloads,
+			LastSeenAt:       r.LastSeenAt,
+		})
+		total += r.Downloads
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"name":      name,
+		"total":     total,
+		"clients":   clients,
+	})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTerraformClientVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		userAgent string
+		want      string
+	}{
+		{"header", "1.6.2", "", "1.6.2"},
+		{"header wins over user agent", "1.6.2", "Terraform/1.5.0", "1.6.2"},
+		{"user agent", "", "Terraform/1.5.7 (+https://www.terraform.io)", "1.5.7"},
+		{"prerelease", "1.7.0-beta1", "", "1.7.0-beta1"},
+		{"not terraform", "", "curl/8.4.0", ""},
+		{"garbage header", "not-a-version", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("X-Terraform-Version", tt.header)
+			}
+			if tt.userAgent != "" {
+				c.Request.Header.Set("User-Agent", tt.userAgent)
+			}
+
+			if got := terraformClientVersion(c); got != tt.want {
+				t.Errorf("terraformClientVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetProviderClients(t *testing.T) {
+	db := newTestDB(t)
+	recordClientVersion(db, "hashicorp", "aws", "1.5.7")
+	recordClientVersion(db, "hashicorp", "aws", "1.10.0")
+	recordClientVersion(db, "hashicorp", "aws", "1.5.7")
+	recordClientVersion(db, "hashicorp", "null", "1.5.7")
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.GET("/mirror/providers/:namespace/:name/clients", h.GetProviderClients)
+
+	req := httptest.NewRequest(http.MethodGet, "/mirror/providers/hashicorp/aws/clients", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Total   int64                `json:"total"`
+		Clients []ClientVersionCount `json:"clients"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal error = %v", err)
+	}
+	if resp.Total != 3 {
+		t.Errorf("total = %d, want 3", resp.Total)
+	}
+	if len(resp.Clients) != 2 {
+		t.Fatalf("len(clients) = %d, want 2", len(resp.Clients))
+	}
+	if resp.Clients[0].TerraformVersion != "1.10.0" || resp.Clients[0].Downloads != 1 {
+		t.Errorf("clients[0] = %+v, want 1.10.0 with 1 download", resp.Clients[0])
+	}
+	if resp.Clients[1].TerraformVersion != "1.5.7" || resp.Clients[1].Downloads != 2 {
+		t.Errorf("clients[1] = %+v, want 1.5.7 with 2 downloads", resp.Clients[1])
+	}
+}
+
+func TestClientVersionRecorder(t *testing.T) {
+	db := newTestDB(t)
+
+	// Without a worker draining it, the queue holds one update and drops the rest.
+	stalled := &clientVersionRecorder{db: db, updates: make(chan clientVersionUpdate, 1)}
+	stalled.record("hashicorp", "aws", "1.5.7")
+	stalled.record("hashicorp", "aws", "1.6.0")
+	if len(stalled.updates) != 1 {
+		t.Errorf("queued updates = %d, want 1", len(stalled.updates))
+	}
+
+	recorder := newClientVersionRecorder(db)
+	recorder.record("hashicorp", "aws", "1.5.7")
+	recorder.record("hashicorp", "aws", "1.5.7")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var row models.ProviderClientVersion
+		if db.Where("namespace = ? AND name = ? AND terraform_version = ?", "hashicorp", "aws", "1.5.7").First(&row).Error == nil && row.Downloads == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("downloads = %d, want 2", row.Downloads)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
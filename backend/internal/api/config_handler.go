@@ -0,0 +1,26 @@
+// Package api provides the HTTP handler for viewing the effective configuration.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler serves the configuration the server was started with.
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+// NewConfigHandler creates a new ConfigHandler instance.
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{cfg: cfg}
+}
+
+// GetConfig returns the effective configuration after defaults, the config file
+// and environment variables were merged, with secrets redacted. Upstream proxy
+// credentials are runtime settings and are not part of it.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cfg.Redacted())
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	db := newTestDB(t)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	cfg := testConfig(t)
+	cfg.Database.URL = "postgres://registry:db-password@db/registry"
+	cfg.Storage.S3.AccessKey = "s3-access-key"
+	cfg.Storage.S3.SecretKey = "s3-secret-key"
+	router := SetupRouter(db, jwtManager, nil, nil, nil, cfg)
+
+	get := func(role string) *httptest.ResponseRecorder {
+		token, err := jwtManager.Generate(1, "someone", role)
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/config", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("user"); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w := get("admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, secret := range []string{"test-secret", "db-password", "s3-access-key", "s3-secret-key"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("response leaks %q: %s", secret, body)
+		}
+	}
+	if !strings.Contains(body, cfg.Storage.Path) {
+		t.Errorf("response missing storage path: %s", body)
+	}
+}
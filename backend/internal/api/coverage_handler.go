@@ -0,0 +1,125 @@
+// Package api provides HTTP handlers for platform coverage reports.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCoveragePlatforms bounds the number of platforms one coverage query may check.
+const maxCoveragePlatforms = 32
+
+// ProviderCoverageGap describes a provider version missing some requested platforms.
+type ProviderCoverageGap struct {
+	ID         uint     `json:"id"`
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	SourceType string   `json:"source_type"`
+	Missing    []string `json:"missing"`
+}
+
+// parsePlatformList parses a comma-separated list of os/arch pairs, dropping duplicates.
+func parsePlatformList(value string) ([]string, error) {
+	var platforms []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		osType, arch, ok := strings.Cut(entry, "/")
+		safeOS, safeArch := validatePlatform(osType, arch)
+		if !ok || safeOS == "invalid" || safeArch == "invalid" {
+			return nil, fmt.Errorf("invalid platform %q: must be os/arch", entry)
+		}
+		if !seen[entry] {
+			seen[entry] = true
+			platforms = append(platforms, entry)
+		}
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("platforms is required, e.g. linux/amd64,darwin/arm64")
+	}
+	if len(platforms) > maxCoveragePlatforms {
+		return nil, fmt.Errorf("at most %d platforms may be requested", maxCoveragePlatforms)
+	}
+	return platforms, nil
+}
+
+// findCoverageGaps returns provider versions lacking any of platforms, ordered by
+// namespace, name and newest version first. sourceType filters providers when set.
+func (h *MirrorHandler) findCoverageGaps(platforms []string, sourceType string) ([]ProviderCoverageGap, error) {
+	query := h.db.Preload("Platforms")
+	if sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	var providers []models.Provider
+	if err := query.Find(&providers).Error; err != nil {
+		return nil, err
+	}
+
+	gaps := make([]ProviderCoverageGap, 0)
+	for _, p := range providers {
+		have := make(map[string]bool, len(p.Platforms))
+		for _, plat := range p.Platforms {
+			have[plat.OS+"/"+plat.Arch] = true
+		}
+		var missing []string
+		for _, want := range platforms {
+			if !have[want] {
+				missing = append(missing, want)
+			}
+		}
+		if len(missing) > 0 {
+			gaps = append(gaps, ProviderCoverageGap{
+				ID:         p.ID,
+				Namespace:  p.Namespace,
+				Name:       p.Name,
+				Version:    p.Version,
+				SourceType: string(p.SourceType),
+				Missing:    missing,
+			})
+		}
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Namespace != gaps[j].Namespace {
+			return gaps[i].Namespace < gaps[j].Namespace
+		}
+		if gaps[i].Name != gaps[j].Name {
+			return gaps[i].Name < gaps[j].Name
+		}
+		return semver.Compare(gaps[i].Version, gaps[j].Version) > 0
+	})
+	return gaps, nil
+}
+
+// GetPlatformCoverage lists provider versions missing any of the requested platforms.
+// Query: platforms (required, e.g. "linux/amd64,darwin/arm64"), source_type (optional).
+// Gaps can be filled with POST /api/v1/mirror/:namespace/:name?version=&os=&arch=.
+func (h *MirrorHandler) GetPlatformCoverage(c *gin.Context) {
+	platforms, err := parsePlatformList(c.Query("platforms"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	gaps, err := h.findCoverageGaps(platforms, c.Query("source_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load providers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"platforms": platforms,
+		"providers": gaps,
+		"total":     len(gaps),
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestParsePlatformList(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"two platforms", "linux/amd64,darwin/arm64", []string{"linux/amd64", "darwin/arm64"}, false},
+		{"whitespace and duplicates", " linux/amd64 , linux/amd64,", []string{"linux/amd64"}, false},
+		{"empty", "", nil, true},
+		{"missing arch", "linux", nil, true},
+		{"empty arch", "linux/", nil, true},
+		{"invalid characters", "linux/amd 64", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePlatformList(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlatformList(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePlatformList(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCoverageGaps(t *testing.T) {
+	db := newTestDB(t)
+	create := func(version string, source models.SourceType, platforms ...[2]string) {
+		p := models.Provider{Namespace: "hashicorp", Name: "aws", Version: version, SourceType: source}
+		db.Create(&p)
+		for _, plat := range platforms {
+			db.Create(&models.ProviderPlatform{ProviderID: p.ID, OS: plat[0], Arch: plat[1], Filename: "f", FilePath: "p", SHA256Sum: "s"})
+		}
+	}
+	create("5.9.0", models.SourceMirror, [2]string{"linux", "amd64"})
+	create("5.10.0", models.SourceMirror)
+	create("5.8.0", models.SourceMirror, [2]string{"linux", "amd64"}, [2]string{"darwin", "arm64"})
+	create("1.0.0", models.SourceUpload)
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	gaps, err := h.findCoverageGaps([]string{"linux/amd64", "darwin/arm64"}, string(models.SourceMirror))
+	if err != nil {
+		t.Fatalf("findCoverageGaps error = %v", err)
+	}
+
+	if len(gaps) != 2 {
+		t.Fatalf("len(gaps) = %d, want 2", len(gaps))
+	}
+	if gaps[0].Version != "5.10.0" || !reflect.DeepEqual(gaps[0].Missing, []string{"linux/amd64", "darwin/arm64"}) {
+		t.Errorf("gaps[0] = %+v, want 5.10.0 missing both platforms", gaps[0])
+	}
+	if gaps[1].Version != "5.9.0" || !reflect.DeepEqual(gaps[1].Missing, []string{"darwin/arm64"}) {
+		t.Errorf("gaps[1] = %+v, want 5.9.0 missing darwin/arm64", gaps[1])
+	}
+}
@@ -0,0 +1,132 @@
+// Package api provides batching of provider download counts and bytes served.
+package api
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"gorm.io/gorm"
+)
+
+// DownloadCounter collects provider download counts and bytes served in memory
+// and writes them in one transaction per interval, so concurrent downloads of
+// the same provider do not each take the SQLite write lock. Counts read back
+// from the database may lag by up to one interval.
+type DownloadCounter struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[uint]downloadTally
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// downloadTally is what a provider accumulated since the last flush.
+type downloadTally struct {
+	downloads int64
+	fills     int64
+	bytes     int64
+}
+
+// NewDownloadCounter creates a DownloadCounter that flushes every interval once
+// started.
+func NewDownloadCounter(db *gorm.DB, interval time.Duration) *DownloadCounter {
+	return &DownloadCounter{
+		db:       db,
+		interval: interval,
+		pending:  make(map[uint]downloadTally),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins flushing in the background until Stop is called.
+func (d *DownloadCounter) Start() {
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.logFlush()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background flushing and writes the remaining counts. It must be
+// called on shutdown, or up to one interval of downloads is lost.
+func (d *DownloadCounter) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+	d.logFlush()
+}
+
+// Increment records one download of a provider that served bytes. fill marks
+// a download that first filled the cache from upstream.
+func (d *DownloadCounter) Increment(providerID uint, bytes int64, fill bool) {
+	d.mu.Lock()
+	t := d.pending[providerID]
+	t.downloads++
+	if fill {
+		t.fills++
+	}
+	t.bytes += bytes
+	d.pending[providerID] = t
+	d.mu.Unlock()
+}
+
+// Flush writes all pending counts. On failure they are kept for the next flush.
+func (d *DownloadCounter) Flush() error {
+	d.mu.Lock()
+	batch := d.pending
+	d.pending = make(map[uint]downloadTally, len(batch))
+	d.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		for id, t := range batch {
+			if err := tx.Model(&models.Provider{ID: id}).Updates(map[string]interface{}{
+				"downloads":    gorm.Expr("downloads + ?", t.downloads),
+				"cache_fills":  gorm.Expr("cache_fills + ?", t.fills),
+				"bytes_served": gorm.Expr("bytes_served + ?", t.bytes),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		d.mu.Lock()
+		for id, t := range batch {
+			p := d.pending[id]
+			p.downloads += t.downloads
+			p.fills += t.fills
+			p.bytes += t.bytes
+			d.pending[id] = p
+		}
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// logFlush flushes and logs a failure; the counts are retried on the next flush.
+func (d *DownloadCounter) logFlush() {
+	if err := d.Flush(); err != nil {
+		log.Printf("Failed to flush download counts: %s", logsafe.CleanErr(err))
+	}
+}
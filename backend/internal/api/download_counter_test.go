@@ -0,0 +1,93 @@
+package api
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestDownloadCounter(t *testing.T) {
+	db := newTestDB(t)
+	aws := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", Downloads: 3}
+	google := models.Provider{Namespace: "hashicorp", Name: "google", Version: "5.0.0"}
+	db.Create(&aws)
+	db.Create(&google)
+
+	downloads := func(p models.Provider) int64 {
+		var got models.Provider
+		db.First(&got, p.ID)
+		return got.Downloads
+	}
+
+	counter := NewDownloadCounter(db, time.Hour)
+	counter.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Increment(aws.ID, 100, false)
+		}()
+	}
+	wg.Wait()
+	counter.Increment(google.ID, 10, false)
+
+	if got := downloads(aws); got != 3 {
+		t.Errorf("before flush: downloads = %d, want 3", got)
+	}
+	if err := counter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := downloads(aws); got != 53 {
+		t.Errorf("after flush: aws downloads = %d, want 53", got)
+	}
+	if got := downloads(google); got != 1 {
+		t.Errorf("after flush: google downloads = %d, want 1", got)
+	}
+	var got models.Provider
+	db.First(&got, aws.ID)
+	if got.BytesServed != 5000 {
+		t.Errorf("after flush: aws bytes served = %d, want 5000", got.BytesServed)
+	}
+
+	// Stop writes what was counted since the last flush.
+	counter.Increment(google.ID, 10, true)
+	counter.Stop()
+	if got := downloads(google); got != 2 {
+		t.Errorf("after stop: google downloads = %d, want 2", got)
+	}
+	var filled models.Provider
+	db.First(&filled, google.ID)
+	if filled.CacheFills != 1 {
+		t.Errorf("after stop: google cache fills = %d, want 1", filled.CacheFills)
+	}
+}
+
+func TestDownloadCounter_ColumnsAddedToExistingRows(t *testing.T) {
+	db := newTestDB(t)
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	// As for a provider stored before the columns existed.
+	for _, column := range []string{"CacheFills", "BytesServed"} {
+		if err := db.Migrator().DropColumn(&models.Provider{}, column); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AutoMigrate(&models.Provider{}); err != nil {
+		t.Fatal(err)
+	}
+
+	counter := NewDownloadCounter(db, time.Hour)
+	counter.Increment(provider.ID, 100, true)
+	if err := counter.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	var got models.Provider
+	db.First(&got, provider.ID)
+	if got.Downloads != 1 || got.CacheFills != 1 || got.BytesServed != 100 {
+		t.Errorf("downloads = %d, cache fills = %d, bytes served = %d; want 1, 1, 100", got.Downloads, got.CacheFills, got.BytesServed)
+	}
+}
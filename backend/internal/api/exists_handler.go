@@ -0,0 +1,110 @@
+// Package api provides HTTP handlers for checking whether providers are cached.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// HeadProviderBinary answers a HEAD request for a provider binary with its
+// headers when the binary is cached here and 404 otherwise. Unlike
+// DownloadProvider it never reads from upstream and counts nothing, so that
+// CI can check cheaply that a provider is mirrored.
+func (h *MirrorHandler) HeadProviderBinary(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	osType := c.Param("os")
+	arch := c.Param("arch")
+
+	filePath, filename := "", ""
+	var platform models.ProviderPlatform
+	if err := h.db.Joins("JOIN providers ON providers.id = provider_platforms.provider_id AND providers.deleted_at IS NULL").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND provider_platforms.os = ? AND provider_platforms.arch = ?",
+			namespace, name, version, osType, arch).
+		First(&platform).Error; err == nil && h.proxyService.FileExists(platform.FilePath) {
+		filePath, filename = platform.FilePath, platform.Filename
+	} else if path, cached := h.proxyService.GetCachedFilePath(namespace, name, version, osType, arch); cached {
+		filePath = path
+	}
+	if filePath == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	size, err := h.proxyService.FileSize(filePath)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if filename == "" {
+		filename = filepath.Base(filePath)
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Status(http.StatusOK)
+}
+
+// GetProviderVersionExists reports whether a provider version is cached here
+// and which of its platforms, as os_arch, have their binary in storage. The
+// optional platforms query, a comma-separated os_arch list, names platforms
+// that must all be present for exists to be true; those that are not are
+// listed in missing. Nothing is read from upstream.
+func (h *MirrorHandler) GetProviderVersionExists(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	if msg := validateProviderParams(namespace, name, version); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	var required []string
+	if list := c.Query("platforms"); list != "" {
+		for _, p := range strings.Split(list, ",") {
+			p = strings.TrimSpace(p)
+			osType, arch, ok := strings.Cut(p, "_")
+			if !ok || !validIdentifierStrict.MatchString(osType) || !validIdentifierStrict.MatchString(arch) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid platform %q: must be os_arch", p)})
+				return
+			}
+			required = append(required, p)
+		}
+	}
+
+	cached, err := h.versionPlatforms(namespace, name, version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	platforms := make([]string, 0, len(cached))
+	present := make(map[string]bool, len(cached))
+	for _, p := range cached {
+		if h.proxyService.FileExists(p.FilePath) {
+			key := p.OS + "_" + p.Arch
+			platforms = append(platforms, key)
+			present[key] = true
+		}
+	}
+	missing := make([]string, 0)
+	for _, p := range required {
+		if !present[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"name":      name,
+		"version":   version,
+		"exists":    len(platforms) > 0 && len(missing) == 0,
+		"platforms": platforms,
+		"missing":   missing,
+	})
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHeadProviderBinaryAndExists(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upstream request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	zipPath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	if err := os.WriteFile(zipPath, []byte("provider-binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+		Filename: filepath.Base(zipPath), FilePath: zipPath, SHA256Sum: "aaaa"})
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "darwin", Arch: "arm64",
+		Filename: "missing.zip", FilePath: filepath.Join(storagePath, "missing.zip"), SHA256Sum: "bbbb"})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.HEAD("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.HeadProviderBinary)
+	router.GET("/exists/:namespace/:name/:version", h.GetProviderVersionExists)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := serve(http.MethodHead, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "15" || w.Body.Len() != 0 {
+		t.Errorf("cached: status = %d, headers = %v, body = %q", w.Code, w.Header(), w.Body.String())
+	}
+	for _, path := range []string{
+		"/v1/providers/hashicorp/aws/5.0.0/download/darwin/arm64/binary",
+		"/v1/providers/hashicorp/aws/6.0.0/download/linux/amd64/binary",
+	} {
+		if w := serve(http.MethodHead, path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, w.Code)
+		}
+	}
+	var counted models.Provider
+	db.First(&counted, provider.ID)
+	if counted.Downloads != 0 {
+		t.Errorf("downloads = %d, want HEAD not to count", counted.Downloads)
+	}
+
+	exists := func(path string) (bool, string, string) {
+		t.Helper()
+		w := serve(http.MethodGet, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Exists    bool     `json:"exists"`
+			Platforms []string `json:"platforms"`
+			Missing   []string `json:"missing"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Exists, strings.Join(resp.Platforms, ","), strings.Join(resp.Missing, ",")
+	}
+	if ok, platforms, _ := exists("/exists/hashicorp/aws/5.0.0"); !ok || platforms != "linux_amd64" {
+		t.Errorf("exists = %v, platforms = %q", ok, platforms)
+	}
+	if ok, _, missing := exists("/exists/hashicorp/aws/5.0.0?platforms=linux_amd64,darwin_arm64"); ok || missing != "darwin_arm64" {
+		t.Errorf("with platforms: exists = %v, missing = %q", ok, missing)
+	}
+	if ok, platforms, _ := exists("/exists/hashicorp/aws/6.0.0"); ok || platforms != "" {
+		t.Errorf("uncached version: exists = %v, platforms = %q", ok, platforms)
+	}
+	for _, path := range []string{"/exists/hashicorp/aws/latest", "/exists/hashicorp/aws/5.0.0?platforms=linux"} {
+		if w := serve(http.MethodGet, path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, w.Code)
+		}
+	}
+}
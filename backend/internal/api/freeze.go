@@ -0,0 +1,73 @@
+// Package api provides the read-only maintenance mode middleware.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// freezeExemptRoutes are mutating routes that stay available while frozen.
+var freezeExemptRoutes = map[string]bool{
+	"/api/v1/settings/freeze":      true,
+	"/api/v1/auth/change-password": true, // a required password change must not wait for maintenance
+	"/api/v1/mirror/verify-lock":   true, // read-only despite POST
+	"/api/v1/mirror/verify":        true, // read-only despite POST
+}
+
+// freezeOverrideParam lets a request to one of freezeOverrideRoutes through while
+// frozen. It must be set explicitly, so a stray client cannot write during maintenance.
+const freezeOverrideParam = "during_freeze"
+
+// freezeOverrideRoutes are mutating routes that may run while frozen when the
+// request passes freezeOverrideParam=true.
+var freezeOverrideRoutes = map[string]bool{
+	// Storage migrations are meant to run while the registry is frozen.
+	"/api/v1/admin/migrate-storage": true,
+}
+
+// freezeWriteRoutes are GET routes that nevertheless write to the registry.
+var freezeWriteRoutes = map[string]bool{
+	"/api/v1/mirror/:namespace/:name/stream": true,
+}
+
+// isFrozen reports whether the registry is in read-only mode, either because
+// forced is set by configuration or because an admin froze it in settings.
+func isFrozen(db *gorm.DB, forced bool) bool {
+	if forced {
+		return true
+	}
+	var settings models.Settings
+	if err := db.First(&settings).Error; err != nil {
+		return false
+	}
+	return settings.Frozen
+}
+
+// freezeMiddleware rejects mutating requests with 503 while the registry is frozen.
+// Reads, including the Terraform protocol routes, are unaffected.
+func freezeMiddleware(db *gorm.DB, forced bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		mutating := freezeWriteRoutes[route]
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			mutating = true
+		}
+
+		exempt := freezeExemptRoutes[route] ||
+			(freezeOverrideRoutes[route] && c.Query(freezeOverrideParam) == "true")
+		if mutating && !exempt && isFrozen(db, forced) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Registry is frozen for maintenance; write operations are temporarily disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestFreezeMode(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	token, _ := jwtManager.Generate(1, "admin", "admin")
+
+	do := func(t *testing.T, router http.Handler, method, path, body string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("frozen by settings", func(t *testing.T) {
+		db := newTestDB(t)
+		db.Create(&models.Settings{Frozen: true})
+		router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+
+		if code := do(t, router, http.MethodPut, "/api/v1/settings", `{"registry_name":"x"}`); code != http.StatusServiceUnavailable {
+			t.Errorf("write while frozen: status code = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if code := do(t, router, http.MethodGet, "/api/v1/mirror/hashicorp/aws/stream", ""); code != http.StatusServiceUnavailable {
+			t.Errorf("mirror stream while frozen: status code = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if code := do(t, router, http.MethodGet, "/api/v1/providers", ""); code != http.StatusOK {
+			t.Errorf("read while frozen: status code = %d, want %d", code, http.StatusOK)
+		}
+		if code := do(t, router, http.MethodPost, "/api/v1/admin/migrate-storage", `{}`); code != http.StatusServiceUnavailable {
+			t.Errorf("storage migration while frozen: status code = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+		if code := do(t, router, http.MethodPost, "/api/v1/admin/migrate-storage?during_freeze=true", `{}`); code != http.StatusBadRequest {
+			t.Errorf("storage migration with override while frozen: status code = %d, want %d", code, http.StatusBadRequest)
+		}
+		if code := do(t, router, http.MethodPut, "/api/v1/settings/freeze", `{"frozen":false}`); code != http.StatusOK {
+			t.Fatalf("unfreeze: status code = %d, want %d", code, http.StatusOK)
+		}
+		if code := do(t, router, http.MethodPut, "/api/v1/settings", `{"registry_name":"x"}`); code != http.StatusOK {
+			t.Errorf("write after unfreeze: status code = %d, want %d", code, http.StatusOK)
+		}
+	})
+
+	t.Run("frozen by configuration", func(t *testing.T) {
+		db := newTestDB(t)
+		cfg := testConfig(t)
+		cfg.Server.Frozen = true
+		router := SetupRouter(db, jwtManager, nil, nil, nil, cfg)
+
+		if code := do(t, router, http.MethodPut, "/api/v1/settings/freeze", `{"frozen":false}`); code != http.StatusConflict {
+			t.Errorf("unfreeze forced freeze: status code = %d, want %d", code, http.StatusConflict)
+		}
+		if code := do(t, router, http.MethodPut, "/api/v1/settings", `{"registry_name":"x"}`); code != http.StatusServiceUnavailable {
+			t.Errorf("write while frozen: status code = %d, want %d", code, http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -3,21 +3,38 @@ package api
 
 import (
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// Version is the server version reported by /api/v1/version, set at build time via -ldflags.
+var Version = "dev"
+
 // Handler contains dependencies for HTTP handlers.
 type Handler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	instanceID string
+	// loginEnabled advertises the login.v1 service, set when auth is enabled.
+	loginEnabled bool
 }
 
 // NewHandler creates a new Handler instance.
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{db: db}
+func NewHandler(db *gorm.DB, instanceID string) *Handler {
+	return &Handler{db: db, instanceID: instanceID}
+}
+
+// registryName returns the configured registry display name, or "" if unset.
+func (h *Handler) registryName() string {
+	var settings models.Settings
+	if err := h.db.First(&settings).Error; err != nil {
+		return ""
+	}
+	return settings.RegistryName
 }
 
 // ProviderSummary represents a provider with aggregated version info.
@@ -31,56 +48,75 @@ type ProviderSummary struct {
 	Published     string `json:"published"`
 }
 
-// ListProviders returns a list of unique providers (grouped by namespace/name).
+// ListProviders returns a list of unique providers (grouped by namespace/name),
+// newest first unless sort selects downloads, name, updated or versions.
 func (h *Handler) ListProviders(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := pageParams(c, defaultListPageSize, maxListPageSize)
+	order, err := providerSortOrder(c, "MAX(created_at) DESC")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Query to get unique providers grouped by namespace/name
 	var results []struct {
-		Namespace     string
-		Name          string
-		Description   string
-		LatestVersion string
-		VersionCount  int64
-		Downloads     int64
-		Published     string
-	}
-
-	baseQuery := h.db.Model(&models.Provider{})
-
-	if namespace := c.Query("namespace"); namespace != "" {
-		baseQuery = baseQuery.Where("namespace = ?", namespace)
+		Namespace    string
+		Name         string
+		Description  string
+		VersionCount int64
+		Downloads    int64
+		Published    string
 	}
 
-	if name := c.Query("name"); name != "" {
-		baseQuery = baseQuery.Where("name LIKE ?", "%"+name+"%")
+	namespace, name := c.Query("namespace"), c.Query("name")
+	filtered := func() *gorm.DB {
+		query := h.db.Model(&models.Provider{})
+		if namespace != "" {
+			query = query.Where("namespace = ?", namespace)
+		}
+		if name != "" {
+			query = query.Where("name LIKE ?", "%"+name+"%")
+		}
+		return query
 	}
 
-	// Count unique providers
+	// Count unique providers matching the filters
 	var total int64
-	h.db.Model(&models.Provider{}).Select("COUNT(DISTINCT namespace || '/' || name)").Scan(&total)
+	if err := filtered().Select("COUNT(DISTINCT namespace || '/' || name)").Scan(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pagination := newPagination(page, limit, total)
 
 	// Get aggregated provider info
-	if err := baseQuery.Select(`
+	if err := filtered().Select(`
 		namespace,
 		name,
 		MAX(description) as description,
-		MAX(version) as latest_version,
 		COUNT(DISTINCT version) as version_count,
 		SUM(downloads) as downloads,
 		MAX(published) as published
 	`).
 		Group("namespace, name").
-		Order("MAX(created_at) DESC").
-		Offset(offset).
+		Order(order).
+		Offset(pagination.offset()).
 		Limit(limit).
 		Scan(&results).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The latest version is picked by semver precedence; MAX(version) compares strings.
+	keys := make([]string, len(results))
+	for i, r := range results {
+		keys[i] = r.Namespace + "/" + r.Name
+	}
+	latest, err := latestVersions(h.db.Model(&models.Provider{}), keys)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Convert to ProviderSummary
 	providers := make([]ProviderSummary, len(results))
 	for i, r := range results {
@@ -88,29 +124,55 @@ func (h *Handler) ListProviders(c *gin.Context) {
 			Namespace:     r.Namespace,
 			Name:          r.Name,
 			Description:   r.Description,
-			LatestVersion: r.LatestVersion,
+			LatestVersion: latest[keys[i]],
 			VersionCount:  int(r.VersionCount),
 			Downloads:     int(r.Downloads),
 			Published:     r.Published,
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"providers": providers,
-		"page":      page,
-		"limit":     limit,
-		"total":     total,
-	})
+	c.JSON(http.StatusOK, paginated(gin.H{"providers": providers}, pagination))
+}
+
+// RegistryMediaType is the Accept value that selects the registry shape of a
+// provider version instead of the admin model; see GetProvider.
+const RegistryMediaType = "application/vnd.terraform.registry+json"
+
+// RegistryProviderVersion is the registry shape of a provider version, modelled
+// on the public registry's provider version document.
+type RegistryProviderVersion struct {
+	ID          string             `json:"id"` // namespace/name/version
+	Namespace   string             `json:"namespace"`
+	Name        string             `json:"name"`
+	Version     string             `json:"version"`
+	Description string             `json:"description"`
+	Source      string             `json:"source"`
+	Tier        string             `json:"tier"`
+	LogoURL     string             `json:"logo_url"`
+	PublishedAt time.Time          `json:"published_at"`
+	Downloads   int64              `json:"downloads"`
+	Protocols   []string           `json:"protocols"`
+	Platforms   []RegistryPlatform `json:"platforms"`
+	Versions    []string           `json:"versions"` // Every version of the provider available here, newest first
 }
 
-// GetProvider returns a specific provider.
+// RegistryPlatform is an OS/arch pair in a RegistryProviderVersion.
+type RegistryPlatform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// GetProvider returns a specific provider version. By default the response is
+// the admin model; a request whose Accept header names RegistryMediaType gets
+// a RegistryProviderVersion with that content type instead.
 func (h *Handler) GetProvider(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 	version := c.Param("version")
+	c.Header("Vary", "Accept")
 
 	var provider models.Provider
-	if err := h.db.Where("namespace = ? AND name = ? AND version = ?",
+	if err := h.db.Preload("Platforms").Where("namespace = ? AND name = ? AND version = ?",
 		namespace, name, version).First(&provider).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
@@ -120,7 +182,35 @@ func (h *Handler) GetProvider(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, provider)
+	if c.NegotiateFormat(gin.MIMEJSON, RegistryMediaType) != RegistryMediaType {
+		c.JSON(http.StatusOK, provider)
+		return
+	}
+
+	doc := RegistryProviderVersion{
+		ID:          namespace + "/" + name + "/" + version,
+		Namespace:   provider.Namespace,
+		Name:        provider.Name,
+		Version:     provider.Version,
+		Description: provider.Description,
+		Source:      provider.SourceURL,
+		Tier:        provider.Tier,
+		LogoURL:     provider.LogoURL,
+		PublishedAt: provider.Published,
+		Downloads:   provider.Downloads,
+		Protocols:   proxy.DecodeProtocols(provider.Protocols),
+		Platforms:   make([]RegistryPlatform, 0, len(provider.Platforms)),
+	}
+	for _, p := range provider.Platforms {
+		doc.Platforms = append(doc.Platforms, RegistryPlatform{OS: p.OS, Arch: p.Arch})
+	}
+	h.db.Model(&models.Provider{}).Where("namespace = ? AND name = ?", namespace, name).
+		Pluck("version", &doc.Versions)
+	semver.SortDescending(doc.Versions)
+
+	// c.JSON keeps a Content-Type that is already set.
+	c.Header("Content-Type", RegistryMediaType)
+	c.JSON(http.StatusOK, doc)
 }
 
 // CreateProvider creates a new provider.
@@ -153,24 +243,21 @@ func (h *Handler) ListModules(c *gin.Context) {
 		query = query.Where("name LIKE ?", "%"+name+"%")
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := pageParams(c, defaultListPageSize, maxListPageSize)
 
 	var total int64
-	query.Count(&total)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pagination := newPagination(page, limit, total)
 
-	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&modules).Error; err != nil {
+	if err := query.Offset(pagination.offset()).Limit(limit).Order("created_at DESC").Find(&modules).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"modules": modules,
-		"page":    page,
-		"limit":   limit,
-		"total":   total,
-	})
+	c.JSON(http.StatusOK, paginated(gin.H{"modules": modules}, pagination))
 }
 
 // GetModule returns a specific module.
@@ -194,22 +281,37 @@ func (h *Handler) GetModule(c *gin.Context) {
 	c.JSON(http.StatusOK, module)
 }
 
-// SearchProviders searches for providers.
-func (h *Handler) SearchProviders(c *gin.Context) {
-	q := c.Query("q")
-	if q == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'q' is required"})
-		return
+// Discovery serves the Terraform remote service discovery document.
+// registry_name and instance_id are non-standard fields; Terraform ignores them.
+// login.v1 is listed when auth is enabled, so that terraform login and tofu
+// login can obtain a token through OAuthToken.
+func (h *Handler) Discovery(c *gin.Context) {
+	host, scheme := getHostAndScheme(c)
+	doc := gin.H{
+		"providers.v1":  "/v1/providers/",
+		"modules.v1":    "/v1/modules/",
+		"metadata.v1":   scheme + "://" + host + "/",
+		"registry_name": h.registryName(),
+		"instance_id":   h.instanceID,
 	}
-
-	var providers []models.Provider
-	if err := h.db.Where("name LIKE ? OR description LIKE ?",
-		"%"+q+"%", "%"+q+"%").Limit(20).Find(&providers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if h.loginEnabled {
+		doc["login.v1"] = gin.H{
+			"client":      oauthClientID,
+			"grant_types": []string{"password"},
+			"token":       oauthTokenPath,
+		}
 	}
+	c.JSON(http.StatusOK, doc)
+}
 
-	c.JSON(http.StatusOK, gin.H{"providers": providers})
+// GetVersion returns the server version and the identity of this registry instance.
+func (h *Handler) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"service":       "vc-terraform-registry",
+		"version":       Version,
+		"registry_name": h.registryName(),
+		"instance_id":   h.instanceID,
+	})
 }
 
 // HealthCheck returns the health status of the service.
@@ -0,0 +1,65 @@
+// Package api provides provider version immutability enforcement.
+package api
+
+import (
+	"fmt"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"gorm.io/gorm"
+)
+
+// immutableVersionsEnabled reports whether published provider versions are immutable.
+func immutableVersionsEnabled(db *gorm.DB) bool {
+	var settings models.Settings
+	if err := db.First(&settings).Error; err != nil {
+		return false
+	}
+	return settings.ImmutableVersions
+}
+
+// lockedPlatforms returns the os/arch pairs of a provider version that may not be
+// modified. It is empty unless immutable versions are enabled, in which case every
+// platform already stored for the version is locked; new platforms can still be added.
+func lockedPlatforms(db *gorm.DB, namespace, name, version string) map[string]bool {
+	locked := make(map[string]bool)
+	if !immutableVersionsEnabled(db) {
+		return locked
+	}
+	var platforms []models.ProviderPlatform
+	db.Joins("JOIN providers ON providers.id = provider_platforms.provider_id AND providers.deleted_at IS NULL").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ?", namespace, name, version).
+		Find(&platforms)
+	for _, p := range platforms {
+		locked[p.OS+"/"+p.Arch] = true
+	}
+	return locked
+}
+
+// immutableConflict returns the error message for a rejected change to a published platform.
+func immutableConflict(namespace, name, version, platform string) string {
+	return fmt.Sprintf("%s/%s %s is already published for %s and versions are immutable", namespace, name, version, platform)
+}
+
+// filterLockedPlatforms drops platforms that are locked from a mirror request.
+func filterLockedPlatforms(platforms []platformInfo, locked map[string]bool) []platformInfo {
+	if len(locked) == 0 {
+		return platforms
+	}
+	filtered := make([]platformInfo, 0, len(platforms))
+	for _, p := range platforms {
+		if !locked[p.OS+"/"+p.Arch] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// allPlatformsLocked reports whether every platform of an import manifest is locked.
+func allPlatformsLocked(platforms []PlatformManifest, locked map[string]bool) bool {
+	for _, p := range platforms {
+		if !locked[p.OS+"/"+p.Arch] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadProvider_ImmutableVersions(t *testing.T) {
+	db := newTestDB(t)
+	provider := models.Provider{Namespace: "acme", Name: "widget", Version: "1.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: "f.zip", FilePath: "/f.zip", SHA256Sum: "x"})
+	settings := models.Settings{}
+	db.Create(&settings)
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.POST("/upload", h.UploadProvider)
+
+	upload := func(osType, arch string) int {
+		form := url.Values{
+			"namespace": {"acme"},
+			"name":      {"widget"},
+			"version":   {"1.0.0"},
+			"os":        {osType},
+			"arch":      {arch},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Requests that pass the immutability check fail on the missing file.
+	if code := upload("linux", "amd64"); code != http.StatusBadRequest {
+		t.Errorf("mutable overwrite: status code = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	db.Model(&settings).Update("immutable_versions", true)
+	if code := upload("linux", "amd64"); code != http.StatusConflict {
+		t.Errorf("immutable overwrite: status code = %d, want %d", code, http.StatusConflict)
+	}
+	if code := upload("darwin", "arm64"); code != http.StatusBadRequest {
+		t.Errorf("immutable new platform: status code = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestFilterLockedPlatforms(t *testing.T) {
+	platforms := []platformInfo{{"linux", "amd64"}, {"darwin", "arm64"}}
+
+	if got := filterLockedPlatforms(platforms, map[string]bool{}); len(got) != 2 {
+		t.Errorf("no locks: got %v, want all platforms", got)
+	}
+	got := filterLockedPlatforms(platforms, map[string]bool{"linux/amd64": true})
+	if len(got) != 1 || got[0].OS != "darwin" {
+		t.Errorf("got %v, want only darwin/arm64", got)
+	}
+}
@@ -0,0 +1,171 @@
+// Package api provides HTTP handlers for verifying cached provider files.
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrityIssue describes a cached platform whose file is missing or no longer
+// matches its stored checksum.
+type IntegrityIssue struct {
+	PlatformID uint   `json:"platform_id"`
+	ProviderID uint   `json:"provider_id"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	FilePath   string `json:"file_path"`
+	Expected   string `json:"expected_sha256"`
+	Actual     string `json:"actual_sha256,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// VerifyIntegrity re-hashes every cached platform file and compares it with the
+// SHA256Sum recorded when it was stored. Platforms without a file or a recorded
+// checksum cannot be verified and are only counted as skipped.
+// Query: provider_id limits the scan to one provider version.
+func (h *MirrorHandler) VerifyIntegrity(c *gin.Context) {
+	query := h.db.Order("id")
+	if value := c.Query("provider_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "provider_id must be a positive integer"})
+			return
+		}
+		if err := h.db.First(&models.Provider{}, id).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+			return
+		}
+		query = query.Where("provider_id = ?", id)
+	}
+
+	var platforms []models.ProviderPlatform
+	if err := query.Find(&platforms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	providers := make(map[uint]models.Provider)
+	issue := func(p models.ProviderPlatform) IntegrityIssue {
+		provider, ok := providers[p.ProviderID]
+		if !ok {
+			h.db.Unscoped().First(&provider, p.ProviderID)
+			providers[p.ProviderID] = provider
+		}
+		return IntegrityIssue{
+			PlatformID: p.ID,
+			ProviderID: p.ProviderID,
+			Namespace:  provider.Namespace,
+			Name:       provider.Name,
+			Version:    provider.Version,
+			OS:         p.OS,
+			Arch:       p.Arch,
+			FilePath:   p.FilePath,
+			Expected:   p.SHA256Sum,
+		}
+	}
+
+	mismatched := make([]IntegrityIssue, 0)
+	missing := make([]IntegrityIssue, 0)
+	verified, skipped := 0, 0
+	for _, p := range platforms {
+		if p.FilePath == "" || p.SHA256Sum == "" {
+			skipped++
+			continue
+		}
+		if !h.proxyService.FileExists(p.FilePath) {
+			missing = append(missing, issue(p))
+			continue
+		}
+		sum, err := h.proxyService.CalculateFileSHA256(p.FilePath)
+		if err != nil {
+			entry := issue(p)
+			entry.Error = err.Error()
+			mismatched = append(mismatched, entry)
+			continue
+		}
+		if sum != p.SHA256Sum {
+			entry := issue(p)
+			entry.Actual = sum
+			mismatched = append(mismatched, entry)
+			continue
+		}
+		verified++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checked":    len(platforms),
+		"verified":   verified,
+		"skipped":    skipped,
+		"mismatched": mismatched,
+		"missing":    missing,
+		"healthy":    len(mismatched) == 0 && len(missing) == 0,
+	})
+}
+
+// PlatformFileStatus is a cached platform together with the state of its file
+// in storage.
+type PlatformFileStatus struct {
+	models.ProviderPlatform
+	FileExists bool `json:"file_exists"`
+	// DiskFileSize is the size of the stored file, 0 when it is missing.
+	DiskFileSize int64 `json:"disk_file_size"`
+	// SizeMismatch is set when the file exists but its size differs from FileSize.
+	SizeMismatch bool `json:"size_mismatch"`
+}
+
+// ListPlatformFiles lists the cached platforms of a provider version with
+// whether each file is still in storage and its actual size. Unlike
+// VerifyIntegrity it does not hash the files, so it is cheap to call while
+// triaging downloads that fail.
+func (h *MirrorHandler) ListPlatformFiles(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var provider models.Provider
+	if err := h.db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).First(&provider).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider version not found"})
+		return
+	}
+	var platforms []models.ProviderPlatform
+	if err := h.db.Where("provider_id = ?", provider.ID).Order("os, arch").Find(&platforms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statuses := make([]PlatformFileStatus, 0, len(platforms))
+	missing, mismatched := 0, 0
+	for _, p := range platforms {
+		status := PlatformFileStatus{ProviderPlatform: p}
+		if h.proxyService.FileExists(p.FilePath) {
+			status.FileExists = true
+			if size, err := h.proxyService.FileSize(p.FilePath); err == nil {
+				status.DiskFileSize = size
+			}
+			status.SizeMismatch = status.DiskFileSize != p.FileSize
+		}
+		if !status.FileExists {
+			missing++
+		} else if status.SizeMismatch {
+			mismatched++
+		}
+		statuses = append(statuses, status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider_id":     provider.ID,
+		"platforms":       statuses,
+		"missing":         missing,
+		"size_mismatched": mismatched,
+	})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(storagePath, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// sha256 of "provider-binary"
+	const goodSum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9"
+
+	aws := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	google := models.Provider{Namespace: "hashicorp", Name: "google", Version: "5.0.0"}
+	db.Create(&aws)
+	db.Create(&google)
+	db.Create(&models.ProviderPlatform{ProviderID: aws.ID, OS: "linux", Arch: "amd64", Filename: "good.zip", FilePath: write("good.zip", "provider-binary"), SHA256Sum: goodSum})
+	corrupt := models.ProviderPlatform{ProviderID: aws.ID, OS: "darwin", Arch: "arm64", Filename: "bad.zip", FilePath: write("bad.zip", "truncated"), SHA256Sum: goodSum}
+	db.Create(&corrupt)
+	gone := models.ProviderPlatform{ProviderID: google.ID, OS: "linux", Arch: "amd64", Filename: "gone.zip", FilePath: filepath.Join(storagePath, "gone.zip"), SHA256Sum: goodSum}
+	db.Create(&gone)
+	db.Create(&models.ProviderPlatform{ProviderID: google.ID, OS: "windows", Arch: "amd64", Filename: "legacy.zip", FilePath: write("legacy.zip", "x")})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	router := gin.New()
+	router.POST("/verify", h.VerifyIntegrity)
+
+	type report struct {
+		Checked    int              `json:"checked"`
+		Verified   int              `json:"verified"`
+		Skipped    int              `json:"skipped"`
+		Mismatched []IntegrityIssue `json:"mismatched"`
+		Missing    []IntegrityIssue `json:"missing"`
+		Healthy    bool             `json:"healthy"`
+	}
+	scan := func(query string) (int, report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/verify"+query, nil))
+		var r report
+		_ = json.Unmarshal(w.Body.Bytes(), &r)
+		return w.Code, r
+	}
+
+	code, r := scan("")
+	if code != http.StatusOK || r.Checked != 4 || r.Verified != 1 || r.Skipped != 1 || r.Healthy {
+		t.Fatalf("full scan: status = %d, report = %+v", code, r)
+	}
+	if len(r.Mismatched) != 1 || r.Mismatched[0].PlatformID != corrupt.ID || r.Mismatched[0].Actual == "" || r.Mismatched[0].Name != "aws" {
+		t.Errorf("mismatched = %+v, want platform %d", r.Mismatched, corrupt.ID)
+	}
+	if len(r.Missing) != 1 || r.Missing[0].PlatformID != gone.ID || r.Missing[0].Version != "5.0.0" {
+		t.Errorf("missing = %+v, want platform %d", r.Missing, gone.ID)
+	}
+
+	code, r = scan("?provider_id=" + strconv.FormatUint(uint64(google.ID), 10))
+	if code != http.StatusOK || r.Checked != 2 || len(r.Mismatched) != 0 || len(r.Missing) != 1 {
+		t.Errorf("scoped scan: status = %d, report = %+v", code, r)
+	}
+
+	if code, _ := scan("?provider_id=abc"); code != http.StatusBadRequest {
+		t.Errorf("invalid provider_id: status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := scan("?provider_id=999"); code != http.StatusNotFound {
+		t.Errorf("unknown provider_id: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestListPlatformFiles(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(storagePath, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	aws := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&aws)
+	db.Create(&models.ProviderPlatform{ProviderID: aws.ID, OS: "linux", Arch: "amd64", Filename: "good.zip", FilePath: write("good.zip", "provider-binary"), FileSize: 15})
+	db.Create(&models.ProviderPlatform{ProviderID: aws.ID, OS: "darwin", Arch: "arm64", Filename: "short.zip", FilePath: write("short.zip", "trunc"), FileSize: 15})
+	db.Create(&models.ProviderPlatform{ProviderID: aws.ID, OS: "windows", Arch: "amd64", Filename: "gone.zip", FilePath: filepath.Join(storagePath, "gone.zip"), FileSize: 15})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	router := gin.New()
+	router.GET("/mirror/providers/:namespace/:name/:version/platforms", h.ListPlatformFiles)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/providers/hashicorp/aws/5.0.0/platforms", nil))
+	var resp struct {
+		Platforms      []PlatformFileStatus `json:"platforms"`
+		Missing        int                  `json:"missing"`
+		SizeMismatched int                  `json:"size_mismatched"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(resp.Platforms) != 3 || resp.Missing != 1 || resp.SizeMismatched != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	byOS := make(map[string]PlatformFileStatus)
+	for _, p := range resp.Platforms {
+		byOS[p.OS] = p
+	}
+	if p := byOS["linux"]; !p.FileExists || p.DiskFileSize != 15 || p.SizeMismatch {
+		t.Errorf("linux = %+v, want an intact file", p)
+	}
+	if p := byOS["darwin"]; !p.FileExists || p.DiskFileSize != 5 || !p.SizeMismatch || p.FileSize != 15 {
+		t.Errorf("darwin = %+v, want a size mismatch", p)
+	}
+	if p := byOS["windows"]; p.FileExists || p.DiskFileSize != 0 {
+		t.Errorf("windows = %+v, want a missing file", p)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/providers/hashicorp/aws/6.0.0/platforms", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown version: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
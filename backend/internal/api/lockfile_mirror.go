@@ -0,0 +1,198 @@
+// Package api provides HTTP handlers for mirroring providers from lock files.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// maxLockFileSize bounds the lock files MirrorFromLockFile accepts.
+const maxLockFileSize = 1 << 20
+
+// lockFile is the part of a .terraform.lock.hcl file MirrorFromLockFile reads.
+type lockFile struct {
+	Providers []lockFileProvider `hcl:"provider,block"`
+	Remain    hcl.Body           `hcl:",remain"`
+}
+
+// lockFileProvider is a provider block of a lock file.
+type lockFileProvider struct {
+	Source  string   `hcl:"source,label"`
+	Version string   `hcl:"version"`
+	Hashes  []string `hcl:"hashes,optional"`
+	Remain  hcl.Body `hcl:",remain"`
+}
+
+// parseLockFile parses a .terraform.lock.hcl file.
+func parseLockFile(data []byte) (*lockFile, error) {
+	file, diags := hclparse.NewParser().ParseHCL(data, ".terraform.lock.hcl")
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var lock lockFile
+	if diags := gohcl.DecodeBody(file.Body, nil, &lock); diags.HasErrors() {
+		return nil, diags
+	}
+	return &lock, nil
+}
+
+// parseLockFileSource splits a lock file provider source, [host/]namespace/name,
+// into its namespace and name.
+func parseLockFileSource(source string) (string, string, error) {
+	parts := strings.Split(source, "/")
+	if len(parts) == 3 && parts[0] != "" {
+		parts = parts[1:]
+	}
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid provider source %q: must be [hostname/]namespace/name", source)
+	}
+	if msg := validateProviderParams(parts[0], parts[1], ""); msg != "" {
+		return "", "", fmt.Errorf("invalid provider source %q: %s", source, msg)
+	}
+	return parts[0], parts[1], nil
+}
+
+// MirrorFromLockFile mirrors the providers of a .terraform.lock.hcl file, each
+// at its locked version and for exactly the platforms whose zh: hash the file
+// lists, so that terraform init with that lock file can run against this
+// mirror alone. The lock file is the "file" field of a multipart form or, for
+// any other content type, the request body. Providers are read from the
+// upstream configured for them whatever host their source names. The response
+// reports the outcome of every provider as MirrorBatch does.
+func (h *MirrorHandler) MirrorFromLockFile(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLockFileSize+multipartOverhead)
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No lock file uploaded"})
+			return
+		}
+		defer func() { _ = file.Close() }()
+		reader = file
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, maxLockFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read lock file"})
+		return
+	}
+	if len(data) > maxLockFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("lock file exceeds %d bytes", maxLockFileSize)})
+		return
+	}
+
+	lock, err := parseLockFile(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid lock file: %v", err)})
+		return
+	}
+	if len(lock.Providers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lock file has no provider blocks"})
+		return
+	}
+	if len(lock.Providers) > maxBatchMirrorItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d providers may be mirrored at once", maxBatchMirrorItems)})
+		return
+	}
+	items := make([]BatchMirrorItem, len(lock.Providers))
+	for i, p := range lock.Providers {
+		namespace, name, err := parseLockFileSource(p.Source)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if msg := validateProviderParams(namespace, name, p.Version); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("provider %q: %s", p.Source, msg)})
+			return
+		}
+		items[i] = BatchMirrorItem{Namespace: namespace, Name: name, Version: p.Version}
+	}
+
+	results := make([]BatchMirrorResult, 0, len(items))
+	mirrored := 0
+	for i, item := range items {
+		result := BatchMirrorResult{Namespace: item.Namespace, Name: item.Name, Version: item.Version}
+		platforms, failed, err := h.mirrorLockedProvider(c, item, lock.Providers[i].Hashes)
+		if result.setOutcome(platforms, failed, err) {
+			mirrored++
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  fmt.Sprintf("Mirrored %d of %d providers", mirrored, len(items)),
+		"mirrored": mirrored,
+		"failed":   len(items) - mirrored,
+		"results":  results,
+	})
+}
+
+// mirrorLockedProvider mirrors the platforms of a provider version whose
+// upstream archive checksum is among the zh: hashes of its lock file entry,
+// and returns how many were newly mirrored and the platforms that failed.
+func (h *MirrorHandler) mirrorLockedProvider(c *gin.Context, item BatchMirrorItem, hashes []string) (int, []FailedPlatform, error) {
+	ctx := c.Request.Context()
+	if h.namespaceOwnership && !canWriteNamespace(h.db, c, item.Namespace) {
+		return 0, nil, errors.New(namespaceForbidden(item.Namespace))
+	}
+	pinned := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if sum, ok := strings.CutPrefix(hash, "zh:"); ok {
+			pinned[strings.ToLower(sum)] = true
+		}
+	}
+	if len(pinned) == 0 {
+		return 0, nil, errors.New("the lock file lists no zh: hashes to select platforms by")
+	}
+
+	proxyService := h.getProxyService(item.Namespace, item.Name, "")
+	available, _, published, err := h.fetchPlatformsToMirror(ctx, proxyService, item.Namespace, item.Name, item.Version, "all", "all", nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	sums := upstreamChecksums(ctx, h.db, proxyService, item.Namespace, item.Name, item.Version,
+		proxy.Platform{OS: available[0].OS, Arch: available[0].Arch})
+	if sums == nil {
+		return 0, nil, errors.New("failed to get the upstream checksums")
+	}
+	var platforms []platformInfo
+	for _, p := range available {
+		if pinned[sums[p.OS+"_"+p.Arch]] {
+			platforms = append(platforms, p)
+		}
+	}
+	if len(platforms) == 0 {
+		return 0, nil, errors.New("no upstream platform matches the zh: hashes of the lock file")
+	}
+
+	// Published platforms of an immutable version are already what the lock
+	// file pins, so only the others are mirrored.
+	platforms = filterLockedPlatforms(platforms, lockedPlatforms(h.db, item.Namespace, item.Name, item.Version))
+	if len(platforms) == 0 {
+		return 0, nil, nil
+	}
+	mirroredPlatforms, failed, lastError := h.downloadPlatforms(ctx, proxyService, item.Namespace, item.Name, item.Version, platforms)
+	if errors.Is(lastError, proxy.ErrSignatureInvalid) {
+		return 0, failed, lastError
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, failed, err
+	}
+	if len(mirroredPlatforms) == 0 {
+		return 0, failed, fmt.Errorf("failed to mirror any platform: %v", lastError)
+	}
+	if err := h.saveMirroredProvider(ctx, proxyService, item.Namespace, item.Name, item.Version, published, mirroredPlatforms); err != nil {
+		return 0, failed, err
+	}
+	notifyMirrorCompleted(h.db, item.Namespace, item.Name, item.Version, mirroredPlatforms, failed)
+	return len(mirroredPlatforms), failed, nil
+}
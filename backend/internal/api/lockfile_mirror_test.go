@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+// newLockFileUpstream serves hashicorp/aws 5.0.0 for linux_amd64 and
+// darwin_arm64, with a SHA256SUMS file, and returns the checksum of each
+// platform's archive.
+func newLockFileUpstream(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+	sums := map[string]string{}
+	for _, platform := range []string{"linux_amd64", "darwin_arm64"} {
+		sum := sha256.Sum256([]byte("binary-" + platform))
+		sums[platform] = hex.EncodeToString(sum[:])
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"},{"os":"darwin","arch":"arm64"}]}]}`))
+		case strings.HasPrefix(path, "/v1/providers/hashicorp/aws/5.0.0/download/"):
+			osType, arch, _ := strings.Cut(strings.TrimPrefix(path, "/v1/providers/hashicorp/aws/5.0.0/download/"), "/")
+			platform := osType + "_" + arch
+			_, _ = fmt.Fprintf(w, `{"protocols":["5.0"],"os":%q,"arch":%q,"filename":"terraform-provider-aws_5.0.0_%s.zip","download_url":"%s/binary/%s","shasums_url":"%s/SHA256SUMS","shasum":%q}`,
+				osType, arch, platform, server.URL, platform, server.URL, sums[platform])
+		case path == "/SHA256SUMS":
+			for platform, sum := range sums {
+				_, _ = fmt.Fprintf(w, "%s  terraform-provider-aws_5.0.0_%s.zip\n", sum, platform)
+			}
+		case strings.HasPrefix(path, "/binary/"):
+			_, _ = w.Write([]byte("binary-" + strings.TrimPrefix(path, "/binary/")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, sums
+}
+
+func TestMirrorFromLockFile(t *testing.T) {
+	upstream, sums := newLockFileUpstream(t)
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/from-lockfile", h.MirrorFromLockFile)
+
+	// Only linux_amd64 is pinned by a zh: hash; the h1: hash names no platform.
+	lockFile := `# This file is maintained automatically by "terraform init".
+
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.0.0"
+  constraints = "~> 5.0"
+  hashes = [
+    "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+    "zh:` + sums["linux_amd64"] + `",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes  = ["zh:` + strings.Repeat("0", 64) + `"]
+}
+`
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("file", ".terraform.lock.hcl")
+	_, _ = part.Write([]byte(lockFile))
+	_ = mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/mirror/from-lockfile", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Mirrored int                 `json:"mirrored"`
+		Failed   int                 `json:"failed"`
+		Results  []BatchMirrorResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Mirrored != 1 || resp.Failed != 1 || len(resp.Results) != 2 {
+		t.Fatalf("response = %s", w.Body.String())
+	}
+	if r := resp.Results[0]; r.Status != "mirrored" || r.Platforms != 1 {
+		t.Errorf("results[0] = %+v", r)
+	}
+	if r := resp.Results[1]; r.Name != "random" || r.Status != "failed" {
+		t.Errorf("results[1] = %+v", r)
+	}
+	var platforms []models.ProviderPlatform
+	db.Find(&platforms)
+	if len(platforms) != 1 || platforms[0].OS != "linux" || platforms[0].SHA256Sum != sums["linux_amd64"] {
+		t.Errorf("platforms = %+v, want linux_amd64 only", platforms)
+	}
+
+	for _, body := range []string{
+		`provider "registry.terraform.io/hashicorp/aws" {`,
+		`provider "registry.terraform.io/HashiCorp/aws" { version = "5.0.0" }`,
+		`provider "a/b/c/d" { version = "5.0.0" }`,
+		`provider "hashicorp/aws" { version = "latest" }`,
+		`# no providers`,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/from-lockfile", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", body, w.Code)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package api provides HTTP handlers for viewing recent server logs.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logbuffer"
+	"github.com/gin-gonic/gin"
+)
+
+// logsHeartbeat is how often an idle log stream sends a comment to keep proxies
+// from closing the connection.
+const logsHeartbeat = 30 * time.Second
+
+// LogsHandler serves the in-memory log buffer to admins.
+type LogsHandler struct {
+	buffer *logbuffer.Buffer
+}
+
+// NewLogsHandler creates a new LogsHandler instance.
+func NewLogsHandler(buffer *logbuffer.Buffer) *LogsHandler {
+	return &LogsHandler{buffer: buffer}
+}
+
+// parseLogsSince parses the since query parameter, which is either the ID of the
+// last event the client has seen or an RFC 3339 timestamp.
+func parseLogsSince(value string) (uint64, time.Time, error) {
+	if value == "" {
+		return 0, time.Time{}, nil
+	}
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return id, time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return 0, t, nil
+	}
+	return 0, time.Time{}, fmt.Errorf("since must be an event ID or an RFC 3339 timestamp")
+}
+
+// ListLogs returns buffered log events, oldest first. Query: since (optional).
+func (h *LogsHandler) ListLogs(c *gin.Context) {
+	afterID, notBefore, err := parseLogsSince(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events := h.buffer.Since(afterID, notBefore)
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": len(events)})
+}
+
+// StreamLogs tails the log buffer over SSE. Buffered events matching since are
+// sent first, then new events as they are logged, until the client disconnects.
+func (h *LogsHandler) StreamLogs(c *gin.Context) {
+	afterID, notBefore, err := parseLogsSince(c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Subscribe before reading the backlog so nothing logged in between is lost.
+	events, cancel := h.buffer.Subscribe()
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	send := func(e logbuffer.Event) {
+		data, _ := json.Marshal(e)
+		c.SSEvent("message", string(data))
+	}
+
+	for _, e := range h.buffer.Since(afterID, notBefore) {
+		send(e)
+		afterID = e.ID
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(logsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e := <-events:
+			if e.ID > afterID {
+				send(e)
+			}
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logbuffer"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogsHandler_ListLogs(t *testing.T) {
+	buffer := logbuffer.New(10)
+	_, _ = buffer.Write([]byte("first\nsecond password=hunter2\n"))
+
+	router := gin.New()
+	router.GET("/admin/logs", NewLogsHandler(buffer).ListLogs)
+
+	get := func(query string) (int, []logbuffer.Event) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/logs"+query, nil))
+		var body struct {
+			Events []logbuffer.Event `json:"events"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Events
+	}
+
+	code, events := get("")
+	if code != http.StatusOK || len(events) != 2 {
+		t.Fatalf("status = %d, events = %d; want 200 with 2 events", code, len(events))
+	}
+	if events[1].Message != "second password=[REDACTED]" {
+		t.Errorf("Message = %q, want secret redacted", events[1].Message)
+	}
+
+	if _, events := get("?since=" + strconv.FormatUint(events[0].ID, 10)); len(events) != 1 {
+		t.Errorf("since ID: got %d events, want 1", len(events))
+	}
+	if _, events := get("?since=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); len(events) != 0 {
+		t.Errorf("since future time: got %d events, want 0", len(events))
+	}
+	if code, _ := get("?since=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("invalid since: status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestLogsHandler_StreamLogs(t *testing.T) {
+	buffer := logbuffer.New(10)
+	_, _ = buffer.Write([]byte("backlog\n"))
+
+	router := gin.New()
+	router.GET("/admin/logs/stream", NewLogsHandler(buffer).StreamLogs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/admin/logs/stream", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	// Give the handler time to subscribe, then log a live event and disconnect.
+	time.Sleep(50 * time.Millisecond)
+	_, _ = buffer.Write([]byte("live\n"))
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := w.Body.String()
+	if !strings.Contains(body, `"message":"backlog"`) || !strings.Contains(body, `"message":"live"`) {
+		t.Errorf("stream body missing events: %s", body)
+	}
+}
@@ -0,0 +1,92 @@
+// Package api provides HTTP handlers for per-provider upstream registries.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// MirrorConfigRequest is the body of PutMirrorConfig.
+type MirrorConfigRequest struct {
+	UpstreamURL string `json:"upstream_url" binding:"required"`
+	AutoSync    bool   `json:"auto_sync"`
+}
+
+// validateMirrorConfigParams checks the namespace and name of a MirrorConfig,
+// where the name may also be scheduler.AnyName.
+func validateMirrorConfigParams(namespace, name string) string {
+	if name == scheduler.AnyName {
+		name = "any"
+	}
+	return validateProviderParams(namespace, name, "")
+}
+
+// ListMirrorConfigs lists the upstream registries configured per provider or
+// namespace.
+func (h *MirrorHandler) ListMirrorConfigs(c *gin.Context) {
+	var configs []models.MirrorConfig
+	if err := h.db.Order("namespace, name").Find(&configs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load mirror configs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"configs": configs})
+}
+
+// PutMirrorConfig sets the upstream registry a provider, or with name "*" every
+// provider of a namespace, is mirrored from.
+func (h *MirrorHandler) PutMirrorConfig(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if msg := validateMirrorConfigParams(namespace, name); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	var req MirrorConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: upstream_url is required"})
+		return
+	}
+	upstream, err := normalizeRegistryURL(req.UpstreamURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var config models.MirrorConfig
+	h.db.Where("namespace = ? AND name = ?", namespace, name).First(&config)
+	config.Namespace = namespace
+	config.Name = name
+	config.UpstreamURL = upstream
+	config.AutoSync = req.AutoSync
+	if err := h.db.Save(&config).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mirror config"})
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// DeleteMirrorConfig removes a provider's or namespace's upstream registry, so
+// it is mirrored from the default upstream again.
+func (h *MirrorHandler) DeleteMirrorConfig(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if msg := validateMirrorConfigParams(namespace, name); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	// Unscoped so that a later PutMirrorConfig does not collide with the
+	// soft-deleted row on the unique index.
+	result := h.db.Unscoped().Where("namespace = ? AND name = ?", namespace, name).Delete(&models.MirrorConfig{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete mirror config"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mirror config not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Mirror config deleted"})
+}
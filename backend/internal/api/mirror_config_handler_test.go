@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+)
+
+func TestUpstreamFor(t *testing.T) {
+	db := newTestDB(t)
+	if got := scheduler.UpstreamFor(db, "hashicorp", "aws"); got != "" {
+		t.Errorf("without config = %q, want default", got)
+	}
+	db.Create(&models.Settings{DefaultUpstreamURL: "https://default.example.com"})
+	db.Create(&models.MirrorConfig{Namespace: "hashicorp", Name: scheduler.AnyName, UpstreamURL: "https://namespace.example.com"})
+	db.Create(&models.MirrorConfig{Namespace: "hashicorp", Name: "aws", UpstreamURL: "https://aws.example.com"})
+
+	tests := []struct{ namespace, name, want string }{
+		{"hashicorp", "aws", "https://aws.example.com"},
+		{"hashicorp", "google", "https://namespace.example.com"},
+		{"acme", "widget", "https://default.example.com"},
+	}
+	for _, tt := range tests {
+		if got := scheduler.UpstreamFor(db, tt.namespace, tt.name); got != tt.want {
+			t.Errorf("UpstreamFor(%s/%s) = %q, want %q", tt.namespace, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMirrorConfigs(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+	adminJWT, _ := jwtManager.Generate(1, "admin", "admin")
+	userJWT, _ := jwtManager.Generate(2, "user", "user")
+
+	do := func(method, path, jwt, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+jwt)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, "/api/v1/mirror/configs/hashicorp/*", userJWT, `{"upstream_url":"`+upstream.URL+`"}`); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	for _, body := range []string{`{}`, `{"upstream_url":"ftp://example.com"}`} {
+		if w := do(http.MethodPut, "/api/v1/mirror/configs/hashicorp/aws", adminJWT, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status = %d, want %d", body, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	if w := do(http.MethodPut, "/api/v1/mirror/configs/hashicorp/*", adminJWT, `{"upstream_url":"`+upstream.URL+`/"}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	// Updating an entry must not trip the unique index.
+	if w := do(http.MethodPut, "/api/v1/mirror/configs/hashicorp/*", adminJWT, `{"upstream_url":"`+upstream.URL+`","auto_sync":true}`); w.Code != http.StatusOK {
+		t.Fatalf("second PUT: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var config models.MirrorConfig
+	db.Where("namespace = ? AND name = ?", "hashicorp", scheduler.AnyName).First(&config)
+	if config.UpstreamURL != upstream.URL || !config.AutoSync {
+		t.Errorf("stored config = %+v", config)
+	}
+	if w := do(http.MethodGet, "/api/v1/mirror/configs", adminJWT, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), upstream.URL) {
+		t.Errorf("GET: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// Mirroring hashicorp/aws now reads from the configured upstream rather
+	// than registry.terraform.io.
+	if w := do(http.MethodPost, "/api/v1/mirror/hashicorp/aws?version=5.0.0", adminJWT, ""); w.Code != http.StatusOK {
+		t.Fatalf("mirror: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodDelete, "/api/v1/mirror/configs/hashicorp/*", adminJWT, ""); w.Code != http.StatusOK {
+		t.Errorf("DELETE: status = %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/v1/mirror/configs/hashicorp/*", adminJWT, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(http.MethodPut, "/api/v1/mirror/configs/hashicorp/*", adminJWT, `{"upstream_url":"`+upstream.URL+`"}`); w.Code != http.StatusOK {
+		t.Errorf("PUT after DELETE: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPut, "/api/v1/settings", adminJWT, `{"default_upstream_url":"https://example.com/v1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("default upstream with path: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := do(http.MethodPut, "/api/v1/settings", adminJWT, `{"default_upstream_url":"Registry.Example.com"}`); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), `"default_upstream_url":"https://registry.example.com"`) {
+		t.Errorf("default upstream: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
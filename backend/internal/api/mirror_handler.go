@@ -3,34 +3,59 @@ package api
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/retention"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // MirrorHandler handles provider mirroring operations.
 type MirrorHandler struct {
-	db           *gorm.DB
-	proxyService *proxy.ProxyService
-	storagePath  string
+	db                 *gorm.DB
+	proxyService       *proxy.ProxyService
+	storagePath        string
+	downloadSigner     *auth.DownloadSigner
+	namespaceOwnership bool
+	// downloads batches download counts when set; nil writes each one immediately.
+	downloads *DownloadCounter
+	// upstreamStable caches upstream version lists for GetUpdatesAvailable.
+	upstreamStable upstreamStableCache
+	// batchWorkers is how many providers of a MirrorBatch are mirrored at once.
+	batchWorkers int
 }
 
 // NewMirrorHandler creates a new MirrorHandler instance.
-func NewMirrorHandler(db *gorm.DB, storagePath string) *MirrorHandler {
+// downloadSigner may be nil, in which case download URLs carry no token.
+// When namespaceOwnership is set, non-admin users may only publish into
+// namespaces they own.
+func NewMirrorHandler(db *gorm.DB, storagePath string, downloadSigner *auth.DownloadSigner, namespaceOwnership bool) *MirrorHandler {
 	h := &MirrorHandler{
-		db:           db,
-		proxyService: proxy.NewProxyService(storagePath, ""),
-		storagePath:  storagePath,
+		db:                 db,
+		proxyService:       proxy.NewProxyService(storagePath, ""),
+		storagePath:        storagePath,
+		downloadSigner:     downloadSigner,
+		namespaceOwnership: namespaceOwnership,
+		batchWorkers:       defaultBatchMirrorWorkers,
 	}
 	h.refreshProxySettings()
 	return h
@@ -40,20 +65,29 @@ func NewMirrorHandler(db *gorm.DB, storagePath string) *MirrorHandler {
 func (h *MirrorHandler) refreshProxySettings() {
 	var settings models.Settings
 	if err := h.db.First(&settings).Error; err == nil {
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
 	}
 }
 
+// applyProxySettings configures ps with the proxy in settings.
+func applyProxySettings(ps *proxy.ProxyService, settings models.Settings) {
+	ps.SetAuthenticatedProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType, settings.ProxyUsername, settings.ProxyPassword)
+}
+
 // platformInfo holds OS and Arch for a platform.
 type platformInfo struct {
 	OS   string
 	Arch string
 }
 
-// getPlatformsForVersion extracts matching platforms from version info.
-func getPlatformsForVersion(versions *proxy.VersionsResponse, version, osType, arch string) ([]platformInfo, string) {
-	if version == "" && len(versions.Versions) > 0 {
-		version = versions.Versions[0].Version
+// getPlatformsForVersion extracts matching platforms from version info. Without a
+// version the newest one by semver is taken, skipping prereleases unless set.
+func getPlatformsForVersion(versions *proxy.VersionsResponse, version, osType, arch string, prereleases bool) ([]platformInfo, string) {
+	if version == "" {
+		if newest := proxy.NewestVersions(versions.Versions, 1, prereleases); len(newest) > 0 {
+			version = newest[0]
+		}
 	}
 
 	var platforms []platformInfo
@@ -71,13 +105,18 @@ func getPlatformsForVersion(versions *proxy.VersionsResponse, version, osType, a
 }
 
 // getOrCreateProvider retrieves or creates a provider in the database.
-func (h *MirrorHandler) getOrCreateProvider(namespace, name, version, protocols string) (*models.Provider, error) {
+// published is the upstream publish time; when zero, new rows use the current time
+// and existing rows keep theirs.
+func (h *MirrorHandler) getOrCreateProvider(namespace, name, version, protocols string, published time.Time) (*models.Provider, error) {
 	var provider models.Provider
 	result := h.db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).First(&provider)
 
 	if result.Error == gorm.ErrRecordNotFound {
 		if protocols == "" {
-			protocols = `["5.0"]`
+			protocols = proxy.EncodeProtocols(nil)
+		}
+		if published.IsZero() {
+			published = time.Now()
 		}
 		provider = models.Provider{
 			Namespace:  namespace,
@@ -85,21 +124,70 @@ func (h *MirrorHandler) getOrCreateProvider(namespace, name, version, protocols
 			Version:    version,
 			SourceType: models.SourceMirror,
 			SourceURL:  "https://registry.terraform.io",
-			Published:  time.Now(),
+			Published:  published,
 			Protocols:  protocols,
 		}
 		if err := h.db.Create(&provider).Error; err != nil {
 			return nil, err
 		}
+	} else {
+		if !published.IsZero() && !provider.Published.Equal(published) {
+			h.db.Model(&provider).UpdateColumn("published", published)
+		}
+		if protocols != "" && provider.Protocols != protocols {
+			h.db.Model(&provider).UpdateColumn("protocols", protocols)
+		}
 	}
 	return &provider, nil
 }
 
+// parsePublishedAfter parses a published_after filter given as an RFC 3339
+// timestamp or a YYYY-MM-DD date. An empty value means no filter.
+func parsePublishedAfter(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid published_after %q: use YYYY-MM-DD or an RFC 3339 timestamp", value)
+}
+
+// resolvePublishDate returns when version was published upstream, or the zero time if
+// that is unknown. With publishedAfter set, an older version or an unknown date is an error.
+func resolvePublishDate(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version string, publishedAfter *time.Time) (time.Time, error) {
+	dates, err := proxyService.GetProviderPublishDates(ctx, namespace, name)
+	if err != nil {
+		if publishedAfter != nil {
+			return time.Time{}, fmt.Errorf("failed to get publish dates: %v", err)
+		}
+		return time.Time{}, nil
+	}
+
+	published := dates[version]
+	if publishedAfter != nil {
+		if published.IsZero() {
+			return time.Time{}, fmt.Errorf("publish date of version %s is unknown", version)
+		}
+		if published.Before(*publishedAfter) {
+			return time.Time{}, fmt.Errorf("version %s was published %s, before published_after %s",
+				version, published.Format(time.DateOnly), publishedAfter.Format(time.RFC3339))
+		}
+	}
+	return published, nil
+}
+
 // savePlatformEntry creates or updates a platform entry in the database.
+// Existing entries are left untouched while versions are immutable.
 func (h *MirrorHandler) savePlatformEntry(providerID uint, plat models.ProviderPlatform) {
 	var existingPlatform models.ProviderPlatform
 	if err := h.db.Where("provider_id = ? AND os = ? AND arch = ?",
 		providerID, plat.OS, plat.Arch).First(&existingPlatform).Error; err == nil {
+		if immutableVersionsEnabled(h.db) {
+			return
+		}
 		existingPlatform.FilePath = plat.FilePath
 		existingPlatform.Filename = plat.Filename
 		existingPlatform.SHA256Sum = plat.SHA256Sum
@@ -127,6 +215,15 @@ func (h *MirrorHandler) UploadProvider(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeNamespace(c, namespace) {
+		return
+	}
+
+	if platform := osType + "/" + arch; lockedPlatforms(h.db, namespace, name, version)[platform] {
+		c.JSON(http.StatusConflict, gin.H{"error": immutableConflict(namespace, name, version, platform)})
+		return
+	}
+
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
@@ -156,7 +253,7 @@ func (h *MirrorHandler) UploadProvider(c *gin.Context) {
 			Description: description,
 			SourceType:  models.SourceUpload,
 			Published:   time.Now(),
-			Protocols:   `["5.0"]`,
+			Protocols:   proxy.EncodeProtocols(nil),
 		}
 		if err := h.db.Create(&provider).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -208,6 +305,25 @@ type MirrorProgress struct {
 	ETASeconds     float64 `json:"eta_seconds"`      // Estimated time remaining
 	Message        string  `json:"message"`          // Status message
 	Error          string  `json:"error,omitempty"`  // Error message if any
+	// Set on the final event when some platforms failed to mirror
+	Partial         bool             `json:"partial,omitempty"`
+	FailedPlatforms []FailedPlatform `json:"failed_platforms,omitempty"`
+}
+
+// FailedPlatform is a platform that failed to mirror, with the reason.
+type FailedPlatform struct {
+	OS    string `json:"os"`
+	Arch  string `json:"arch"`
+	Error string `json:"error"`
+}
+
+// failedPlatformNames lists failed platforms as os/arch for messages.
+func failedPlatformNames(failed []FailedPlatform) string {
+	names := make([]string, len(failed))
+	for i, f := range failed {
+		names[i] = f.OS + "/" + f.Arch
+	}
+	return strings.Join(names, ", ")
 }
 
 // MirrorProviderWithProgress mirrors a provider with SSE progress updates.
@@ -215,9 +331,11 @@ func (h *MirrorHandler) MirrorProviderWithProgress(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 	version := c.Query("version")
-	osType := c.DefaultQuery("os", "all")
-	arch := c.DefaultQuery("arch", "all")
+	defaultOS, defaultArch := defaultPlatform(h.db)
+	osType := c.DefaultQuery("os", defaultOS)
+	arch := c.DefaultQuery("arch", defaultArch)
 	proxyURL := c.Query("proxy_url")
+	publishedAfter, publishedAfterErr := parsePublishedAfter(c.Query("published_after"))
 
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
@@ -235,121 +353,282 @@ func (h *MirrorHandler) MirrorProviderWithProgress(c *gin.Context) {
 		sendProgress(MirrorProgress{Type: "error", Error: "namespace and name are required"})
 		return
 	}
+	if publishedAfterErr != nil {
+		sendProgress(MirrorProgress{Type: "error", Error: publishedAfterErr.Error()})
+		return
+	}
+
+	if h.namespaceOwnership && !canWriteNamespace(h.db, c, namespace) {
+		sendProgress(MirrorProgress{Type: "error", Error: namespaceForbidden(namespace)})
+		return
+	}
 
 	// Create proxy service
-	proxyService := h.getProxyService(proxyURL)
+	proxyService := h.getProxyService(namespace, name, proxyURL)
 	if proxyURL != "" {
 		sendProgress(MirrorProgress{Type: "progress", Message: fmt.Sprintf("Using proxy: %s", proxyURL)})
 	}
 
 	// Get platforms to mirror
 	sendProgress(MirrorProgress{Type: "progress", Message: "Fetching version information..."})
-	platforms, resolvedVersion, err := h.fetchPlatformsToMirror(proxyService, namespace, name, version, osType, arch)
+	platforms, resolvedVersion, published, err := h.fetchPlatformsToMirror(c.Request.Context(), proxyService, namespace, name, version, osType, arch, publishedAfter)
 	if err != nil {
 		sendProgress(MirrorProgress{Type: "error", Error: err.Error()})
 		return
 	}
 	version = resolvedVersion
 
+	platforms = filterLockedPlatforms(platforms, lockedPlatforms(h.db, namespace, name, version))
+	if len(platforms) == 0 {
+		sendProgress(MirrorProgress{Type: "error", Error: immutableConflict(namespace, name, version, "all requested platforms")})
+		return
+	}
+
 	total := len(platforms)
 	sendProgress(MirrorProgress{Type: "progress", Total: total, Message: fmt.Sprintf("Found %d platforms to mirror", total)})
 
 	// Download all platforms with progress
-	mirroredPlatforms, totalBytes, lastError := h.downloadPlatformsWithProgress(proxyService, namespace, name, version, platforms, sendProgress)
+	mirroredPlatforms, totalBytes, failed, lastError := h.downloadPlatformsWithProgress(c.Request.Context(), proxyService, namespace, name, version, platforms, sendProgress)
+	if errors.Is(lastError, proxy.ErrSignatureInvalid) {
+		sendProgress(MirrorProgress{Type: "error", Error: lastError.Error()})
+		return
+	}
+	if c.Request.Context().Err() != nil {
+		// The client went away; platforms finished before that stay cached.
+		return
+	}
 
 	if len(mirroredPlatforms) == 0 {
-		sendProgress(MirrorProgress{Type: "error", Error: fmt.Sprintf("Failed to mirror any platform: %v", lastError)})
+		sendProgress(MirrorProgress{
+			Type: "error", Total: total, Error: fmt.Sprintf("Failed to mirror any platform: %v", lastError),
+			FailedPlatforms: failed,
+		})
 		return
 	}
 
 	// Save to database
-	if err := h.saveMirroredProvider(proxyService, namespace, name, version, mirroredPlatforms); err != nil {
+	if err := h.saveMirroredProvider(c.Request.Context(), proxyService, namespace, name, version, published, mirroredPlatforms); err != nil {
 		sendProgress(MirrorProgress{Type: "error", Error: err.Error()})
 		return
 	}
+	notifyMirrorCompleted(h.db, namespace, name, version, mirroredPlatforms, failed)
 
+	message := fmt.Sprintf("Successfully mirrored %d platforms (%.2f MB total)", len(mirroredPlatforms), float64(totalBytes)/1024/1024)
+	if len(failed) > 0 {
+		message = fmt.Sprintf("Mirrored %d of %d platforms (%.2f MB total); failed: %s",
+			len(mirroredPlatforms), total, float64(totalBytes)/1024/1024, failedPlatformNames(failed))
+	}
 	sendProgress(MirrorProgress{
-		Type:    "complete",
-		Current: total,
-		Total:   total,
-		Percent: 100,
-		Message: fmt.Sprintf("Successfully mirrored %d platforms (%.2f MB total)", len(mirroredPlatforms), float64(totalBytes)/1024/1024),
+		Type:            "complete",
+		Current:         total,
+		Total:           total,
+		Percent:         100,
+		Message:         message,
+		Partial:         len(failed) > 0,
+		FailedPlatforms: failed,
 	})
 }
 
-// getProxyService returns the appropriate proxy service based on proxyURL.
-func (h *MirrorHandler) getProxyService(proxyURL string) *proxy.ProxyService {
-	if proxyURL != "" {
-		return proxy.NewProxyServiceWithProxy(h.storagePath, "", proxyURL, "http")
+// getProxyService returns the proxy service to mirror namespace/name with. It
+// reads from the upstream scheduler.UpstreamFor resolves, through proxyURL
+// when set and the configured proxy otherwise. Either way it follows the
+// Settings.VerifySignatures switch.
+func (h *MirrorHandler) getProxyService(namespace, name, proxyURL string) *proxy.ProxyService {
+	upstream := scheduler.UpstreamFor(h.db, namespace, name)
+	ps := h.proxyService
+	switch {
+	case proxyURL != "":
+		ps = proxy.NewProxyServiceWithProxy(h.storagePath, upstream, proxyURL, "http")
+	case upstream != "" && upstream != ps.UpstreamURL():
+		ps = proxy.NewProxyService(h.storagePath, upstream)
+		var settings models.Settings
+		if h.db.First(&settings).Error == nil {
+			applyProxySettings(ps, settings)
+		}
+	}
+	ps.SetVerifySignatures(verifySignaturesEnabled(h.db))
+	return ps
+}
+
+// verifySignaturesEnabled reports whether mirrored binaries must carry a valid
+// upstream GPG signature.
+func verifySignaturesEnabled(db *gorm.DB) bool {
+	var settings models.Settings
+	return db.First(&settings).Error == nil && settings.VerifySignatures
+}
+
+// defaultMirrorConcurrency and maxMirrorConcurrency bound Settings.MirrorConcurrency.
+const (
+	defaultMirrorConcurrency = 4
+	maxMirrorConcurrency     = 16
+)
+
+// mirrorConcurrency returns how many platforms are downloaded at once when
+// mirroring a provider.
+func mirrorConcurrency(db *gorm.DB) int {
+	var settings models.Settings
+	if err := db.First(&settings).Error; err != nil || settings.MirrorConcurrency < 1 {
+		return defaultMirrorConcurrency
+	}
+	return min(settings.MirrorConcurrency, maxMirrorConcurrency)
+}
+
+// defaultPlatform returns the OS and architecture mirrored, or synced by a new
+// schedule, when a request names none. Either may be "all".
+func defaultPlatform(db *gorm.DB) (string, string) {
+	osType, arch := "all", "all"
+	var settings models.Settings
+	if db.First(&settings).Error == nil {
+		if settings.DefaultOS != "" {
+			osType = settings.DefaultOS
+		}
+		if settings.DefaultArch != "" {
+			arch = settings.DefaultArch
+		}
 	}
-	return h.proxyService
+	return osType, arch
 }
 
-// fetchPlatformsToMirror fetches version info and returns platforms to download.
-func (h *MirrorHandler) fetchPlatformsToMirror(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string) ([]platformInfo, string, error) {
-	versions, err := proxyService.GetProviderVersions(namespace, name)
+// upstreamPublishDate returns when version was published upstream, or the current
+// time if the upstream does not report it.
+func upstreamPublishDate(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version string) time.Time {
+	if published, err := resolvePublishDate(ctx, proxyService, namespace, name, version, nil); err == nil && !published.IsZero() {
+		return published
+	}
+	return time.Now()
+}
+
+// fetchPlatformsToMirror fetches version info and returns platforms to download,
+// the resolved version and its upstream publish time (zero if unknown). A version
+// published before publishedAfter is rejected.
+func (h *MirrorHandler) fetchPlatformsToMirror(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version, osType, arch string, publishedAfter *time.Time) ([]platformInfo, string, time.Time, error) {
+	versions, err := proxyService.GetProviderVersions(ctx, namespace, name)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get versions: %v", err)
+		return nil, "", time.Time{}, fmt.Errorf("failed to get versions: %v", err)
 	}
 	if len(versions.Versions) == 0 {
-		return nil, "", fmt.Errorf("no versions available")
+		return nil, "", time.Time{}, fmt.Errorf("no versions available")
 	}
 
-	platforms, resolvedVersion := getPlatformsForVersion(versions, version, osType, arch)
+	var settings models.Settings
+	prereleases := h.db.First(&settings).Error == nil && settings.IncludePrereleases
+	platforms, resolvedVersion := getPlatformsForVersion(versions, version, osType, arch, prereleases)
 	if len(platforms) == 0 {
-		return nil, "", fmt.Errorf("no matching platforms found")
+		return nil, "", time.Time{}, fmt.Errorf("no matching platforms found")
 	}
-	return platforms, resolvedVersion, nil
+
+	published, err := resolvePublishDate(ctx, proxyService, namespace, name, resolvedVersion, publishedAfter)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	return platforms, resolvedVersion, published, nil
 }
 
-// downloadPlatformsWithProgress downloads platforms and sends progress updates.
-func (h *MirrorHandler) downloadPlatformsWithProgress(proxyService *proxy.ProxyService, namespace, name, version string, platforms []platformInfo, sendProgress func(MirrorProgress)) ([]models.ProviderPlatform, int64, error) {
-	var mirroredPlatforms []models.ProviderPlatform
-	var lastError error
-	var totalBytes int64
+// downloadPlatformsWithProgress downloads platforms, up to the
+// Settings.MirrorConcurrency at a time, and sends progress updates. Events are
+// sent one at a time; Current counts the platforms finished so far, and the
+// speed and ETA reflect the combined throughput. Downloaded and failed
+// platforms are returned in the order given, with the last failure's error.
+func (h *MirrorHandler) downloadPlatformsWithProgress(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version string, platforms []platformInfo, sendProgress func(MirrorProgress)) ([]models.ProviderPlatform, int64, []FailedPlatform, error) {
+	type outcome struct {
+		filePath  string
+		sha256sum string
+		fileSize  int64
+		err       error
+	}
+	outcomes := make([]outcome, len(platforms))
 	total := len(platforms)
 	startTime := time.Now()
 
-	for i, plat := range platforms {
-		platformStr := fmt.Sprintf("%s/%s", plat.OS, plat.Arch)
-		sendProgress(MirrorProgress{
-			Type: "progress", Current: i + 1, Total: total, Platform: platformStr,
-			Percent: float64(i) / float64(total) * 100, Message: fmt.Sprintf("Downloading %s...", platformStr),
-		})
+	// mu serializes progress events and guards the counters below.
+	var mu sync.Mutex
+	var completed int
+	var totalBytes int64
+	var signatureErr error
 
-		platStart := time.Now()
-		filePath, sha256sum, err := proxyService.DownloadAndCacheProvider(namespace, name, version, plat.OS, plat.Arch)
-		if err != nil {
-			lastError = err
-			sendProgress(MirrorProgress{Type: "progress", Current: i + 1, Total: total, Platform: platformStr, Message: fmt.Sprintf("Failed: %v", err)})
-			continue
-		}
+	sem := make(chan struct{}, mirrorConcurrency(h.db))
+	var wg sync.WaitGroup
+	for i, plat := range platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			platformStr := fmt.Sprintf("%s/%s", plat.OS, plat.Arch)
+			mu.Lock()
+			if signatureErr != nil {
+				mu.Unlock()
+				return
+			}
+			sendProgress(MirrorProgress{
+				Type: "progress", Current: completed, Total: total, Platform: platformStr,
+				Percent: float64(completed) / float64(total) * 100, Message: fmt.Sprintf("Downloading %s...", platformStr),
+			})
+			mu.Unlock()
 
-		fileSize := getFileSize(filePath)
-		totalBytes += fileSize
-		platSpeed := int64(float64(fileSize) / time.Since(platStart).Seconds())
-		etaSeconds := calculateETA(totalBytes, i+1, total, time.Since(startTime).Seconds())
+			filePath, sha256sum, err := proxyService.DownloadAndCacheProvider(ctx, namespace, name, version, plat.OS, plat.Arch)
+			var fileSize int64
+			if err == nil {
+				fileSize, _ = proxyService.FileSize(filePath)
+			}
+			outcomes[i] = outcome{filePath: filePath, sha256sum: sha256sum, fileSize: fileSize, err: err}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, proxy.ErrSignatureInvalid) {
+				// Every platform shares one SHA256SUMS file, so the rest would fail too.
+				if signatureErr == nil {
+					signatureErr = err
+				}
+				return
+			}
+			if signatureErr != nil {
+				return
+			}
+			completed++
+			if err != nil {
+				sendProgress(MirrorProgress{
+					Type: "progress", Current: completed, Total: total, Platform: platformStr,
+					Percent: float64(completed) / float64(total) * 100, Message: fmt.Sprintf("Failed: %v", err),
+				})
+				return
+			}
+			totalBytes += fileSize
+			elapsed := time.Since(startTime).Seconds()
+			var speed int64
+			if elapsed > 0 {
+				speed = int64(float64(totalBytes) / elapsed)
+			}
+			sendProgress(MirrorProgress{
+				Type: "progress", Current: completed, Total: total, Platform: platformStr,
+				Percent: float64(completed) / float64(total) * 100, BytesPerSecond: speed,
+				ETASeconds: calculateETA(totalBytes, completed, total, elapsed),
+				Message:    fmt.Sprintf("Downloaded %s (%.2f MB)", platformStr, float64(fileSize)/1024/1024),
+			})
+		}()
+	}
+	wg.Wait()
 
-		sendProgress(MirrorProgress{
-			Type: "progress", Current: i + 1, Total: total, Platform: platformStr,
-			Percent: float64(i+1) / float64(total) * 100, BytesPerSecond: platSpeed, ETASeconds: etaSeconds,
-			Message: fmt.Sprintf("Downloaded %s (%.2f MB)", platformStr, float64(fileSize)/1024/1024),
-		})
+	if signatureErr != nil {
+		return nil, 0, nil, signatureErr
+	}
 
+	var mirroredPlatforms []models.ProviderPlatform
+	var failed []FailedPlatform
+	var lastError error
+	for i, plat := range platforms {
+		if outcomes[i].err != nil {
+			lastError = outcomes[i].err
+			failed = append(failed, FailedPlatform{OS: plat.OS, Arch: plat.Arch, Error: lastError.Error()})
+			continue
+		}
 		mirroredPlatforms = append(mirroredPlatforms, models.ProviderPlatform{
-			OS: plat.OS, Arch: plat.Arch, Filename: filepath.Base(filePath),
-			FilePath: filePath, SHA256Sum: sha256sum, FileSize: fileSize,
+			OS: plat.OS, Arch: plat.Arch, Filename: filepath.Base(outcomes[i].filePath),
+			FilePath: outcomes[i].filePath, SHA256Sum: outcomes[i].sha256sum, FileSize: outcomes[i].fileSize,
 		})
 	}
-	return mirroredPlatforms, totalBytes, lastError
-}
-
-// getFileSize returns the size of a file, or 0 if it cannot be determined.
-func getFileSize(filePath string) int64 {
-	if info, err := os.Stat(filePath); err == nil {
-		return info.Size()
-	}
-	return 0
+	return mirroredPlatforms, totalBytes, failed, lastError
 }
 
 // calculateETA estimates remaining time based on progress.
@@ -364,22 +643,30 @@ func calculateETA(totalBytes int64, completed, total int, elapsed float64) float
 }
 
 // saveMirroredProvider saves the provider and platforms to the database.
-func (h *MirrorHandler) saveMirroredProvider(proxyService *proxy.ProxyService, namespace, name, version string, platforms []models.ProviderPlatform) error {
-	protocols := `["5.0"]`
+func (h *MirrorHandler) saveMirroredProvider(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version string, published time.Time, platforms []models.ProviderPlatform) error {
+	// An empty value keeps the protocols an existing row already has.
+	protocols := ""
+	var signingKeys proxy.SigningKeys
 	if len(platforms) > 0 {
-		if info, err := proxyService.GetProviderDownloadInfo(namespace, name, version, platforms[0].OS, platforms[0].Arch); err == nil && len(info.Protocols) > 0 {
-			protocols = fmt.Sprintf(`["%s"]`, info.Protocols[0])
+		if info, err := proxyService.GetProviderDownloadInfo(ctx, namespace, name, version, platforms[0].OS, platforms[0].Arch); err == nil {
+			if len(info.Protocols) > 0 {
+				protocols = proxy.EncodeProtocols(info.Protocols)
+			}
+			signingKeys = info.SigningKeys
 		}
 	}
 
-	provider, err := h.getOrCreateProvider(namespace, name, version, protocols)
+	provider, err := h.getOrCreateProvider(namespace, name, version, protocols, published)
 	if err != nil {
 		return err
 	}
+	scheduler.RecordSigningKeys(h.db, provider.ID, signingKeys)
 
 	for _, plat := range platforms {
 		h.savePlatformEntry(provider.ID, plat)
 	}
+	scheduler.RecordUpstreamDetails(ctx, h.db, proxyService, namespace, name)
+	proxy.InvalidateProviderVersions(namespace, name)
 	return nil
 }
 
@@ -388,65 +675,88 @@ func (h *MirrorHandler) MirrorProvider(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 	version := c.Query("version")
-	osType := c.DefaultQuery("os", "all")
-	arch := c.DefaultQuery("arch", "all")
+	defaultOS, defaultArch := defaultPlatform(h.db)
+	osType := c.DefaultQuery("os", defaultOS)
+	arch := c.DefaultQuery("arch", defaultArch)
 
 	if namespace == "" || name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace and name are required"})
 		return
 	}
 
+	publishedAfter, err := parsePublishedAfter(c.Query("published_after"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.authorizeNamespace(c, namespace) {
+		return
+	}
+
 	// Fetch platforms to mirror
-	platforms, resolvedVersion, err := h.fetchPlatformsToMirror(h.proxyService, namespace, name, version, osType, arch)
+	proxyService := h.getProxyService(namespace, name, "")
+	platforms, resolvedVersion, published, err := h.fetchPlatformsToMirror(c.Request.Context(), proxyService, namespace, name, version, osType, arch, publishedAfter)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 	version = resolvedVersion
 
+	// Published platforms are skipped; only new ones are added to an immutable version.
+	platforms = filterLockedPlatforms(platforms, lockedPlatforms(h.db, namespace, name, version))
+	if len(platforms) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": immutableConflict(namespace, name, version, "all requested platforms")})
+		return
+	}
+
 	// Download all platforms
-	mirroredPlatforms, lastError := h.downloadPlatforms(h.proxyService, namespace, name, version, platforms)
+	mirroredPlatforms, failed, lastError := h.downloadPlatforms(c.Request.Context(), proxyService, namespace, name, version, platforms)
+	if failed == nil {
+		failed = []FailedPlatform{}
+	}
 
 	if len(mirroredPlatforms) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to mirror any platform: %v", lastError)})
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":            fmt.Sprintf("Failed to mirror any platform: %v", lastError),
+			"failed_platforms": failed,
+		})
 		return
 	}
 
 	// Save to database
-	if err := h.saveMirroredProvider(h.proxyService, namespace, name, version, mirroredPlatforms); err != nil {
+	if err := h.saveMirroredProvider(c.Request.Context(), proxyService, namespace, name, version, published, mirroredPlatforms); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	notifyMirrorCompleted(h.db, namespace, name, version, mirroredPlatforms, failed)
 
 	// Load saved provider for response
 	var provider models.Provider
 	h.db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).Preload("Platforms").First(&provider)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":   fmt.Sprintf("Provider mirrored successfully (%d platforms)", len(mirroredPlatforms)),
-		"provider":  provider,
-		"platforms": provider.Platforms,
+	// Some platforms failing is a partial success, answered with 207 so that
+	// scripts can tell it from a complete mirror.
+	status := http.StatusOK
+	message := fmt.Sprintf("Provider mirrored successfully (%d platforms)", len(mirroredPlatforms))
+	if len(failed) > 0 {
+		status = http.StatusMultiStatus
+		message = fmt.Sprintf("Provider partially mirrored (%d of %d platforms); failed: %s",
+			len(mirroredPlatforms), len(platforms), failedPlatformNames(failed))
+	}
+	c.JSON(status, gin.H{
+		"message":          message,
+		"provider":         provider,
+		"platforms":        provider.Platforms,
+		"partial":          len(failed) > 0,
+		"failed_platforms": failed,
 	})
 }
 
 // downloadPlatforms downloads all specified platforms without progress updates.
-func (h *MirrorHandler) downloadPlatforms(proxyService *proxy.ProxyService, namespace, name, version string, platforms []platformInfo) ([]models.ProviderPlatform, error) {
-	var mirroredPlatforms []models.ProviderPlatform
-	var lastError error
-
-	for _, plat := range platforms {
-		filePath, sha256sum, err := proxyService.DownloadAndCacheProvider(namespace, name, version, plat.OS, plat.Arch)
-		if err != nil {
-			lastError = err
-			continue
-		}
-
-		mirroredPlatforms = append(mirroredPlatforms, models.ProviderPlatform{
-			OS: plat.OS, Arch: plat.Arch, Filename: filepath.Base(filePath),
-			FilePath: filePath, SHA256Sum: sha256sum, FileSize: getFileSize(filePath),
-		})
-	}
-	return mirroredPlatforms, lastError
+func (h *MirrorHandler) downloadPlatforms(ctx context.Context, proxyService *proxy.ProxyService, namespace, name, version string, platforms []platformInfo) ([]models.ProviderPlatform, []FailedPlatform, error) {
+	mirroredPlatforms, _, failed, err := h.downloadPlatformsWithProgress(ctx, proxyService, namespace, name, version, platforms, func(MirrorProgress) {})
+	return mirroredPlatforms, failed, err
 }
 
 // ListUpstreamVersions lists available versions from upstream.
@@ -464,10 +774,10 @@ func (h *MirrorHandler) ListUpstreamVersions(c *gin.Context) {
 			return
 		}
 		// Update proxy settings before making upstream request
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
 	}
 
-	versions, err := h.proxyService.GetProviderVersions(namespace, name)
+	versions, err := h.getProxyService(namespace, name, "").GetProviderVersions(c.Request.Context(), namespace, name)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": fmt.Sprintf("Failed to get versions: %v", err),
@@ -480,7 +790,8 @@ func (h *MirrorHandler) ListUpstreamVersions(c *gin.Context) {
 
 // DownloadProvider handles provider binary downloads.
 // If the provider is not cached locally, it will download from upstream,
-// cache it, and serve it to the client.
+// cache it, and serve it to the client. With online search disabled and
+// redirect_uncached set, uncached binaries are redirected to upstream instead.
 func (h *MirrorHandler) DownloadProvider(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
@@ -494,16 +805,21 @@ func (h *MirrorHandler) DownloadProvider(c *gin.Context) {
 	if err := h.db.First(&settings).Error; err == nil {
 		allowOnline = settings.AllowOnlineSearch
 		// Update proxy settings before making upstream request
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
 	}
 
-	// Find the provider and platform
+	// Find the provider and platform. Serving a cached binary must never depend on
+	// upstream availability, so every local option is tried before going online.
 	var provider models.Provider
 	if err := h.db.Where("namespace = ? AND name = ? AND version = ?",
 		namespace, name, version).First(&provider).Error; err != nil {
+		if h.serveFromDiskCache(c, namespace, name, version, osType, arch) {
+			return
+		}
 		// Provider not found locally, try to download from upstream
 		if !allowOnline {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+			h.redirectOrNotFound(c, settings.RedirectUncached, "Provider not found", namespace, name, version, osType, arch)
 			return
 		}
 		h.downloadAndCacheFromUpstream(c, namespace, name, version, osType, arch)
@@ -512,45 +828,197 @@ func (h *MirrorHandler) DownloadProvider(c *gin.Context) {
 
 	var platform models.ProviderPlatform
 	if err := h.db.Where("provider_id = ? AND os = ? AND arch = ?",
-		provider.ID, osType, arch).First(&platform).Error; err != nil {
+		provider.ID, osType, arch).First(&platform).Error; err != nil || !h.proxyService.FileExists(platform.FilePath) {
+		if h.serveFromDiskCache(c, namespace, name, version, osType, arch) {
+			return
+		}
 		// Platform not found locally, try to download from upstream
 		if !allowOnline {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Platform not found"})
+			h.redirectOrNotFound(c, settings.RedirectUncached, "Platform not found", namespace, name, version, osType, arch)
 			return
 		}
 		h.downloadAndCacheFromUpstream(c, namespace, name, version, osType, arch)
 		return
 	}
 
-	// Increment download counter
-	h.db.Model(&provider).Update("downloads", gorm.Expr("downloads + 1"))
+	h.touchPlatform(platform.ID)
+	h.revalidateIfStale(settings, provider)
+
+	// Serve the file, then count it if the client received all of it
+	h.serveFile(c, platform.FilePath, platform.Filename)
+	h.countDownload(c, provider.ID, platform.FileSize, false)
+}
+
+// redirectOrNotFound answers a download for a binary that is not cached while
+// read-through caching is off. In redirect mode the client is sent to the upstream
+// download URL with a 307 and nothing is stored; otherwise it gets a 404.
+func (h *MirrorHandler) redirectOrNotFound(c *gin.Context, redirect bool, notFound, namespace, name, version, osType, arch string) {
+	if !redirect {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	info, err := h.getProxyService(namespace, name, "").GetProviderDownloadInfo(c.Request.Context(), namespace, name, version, osType, arch)
+	if err != nil || info.DownloadURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found upstream"})
+		return
+	}
+	c.Redirect(http.StatusTemporaryRedirect, info.DownloadURL)
+}
+
+// lastDownloadedThrottle bounds how often LastDownloadedAt is rewritten for a platform,
+// so a burst of downloads costs one write instead of one per request.
+const lastDownloadedThrottle = time.Minute
+
+// downloadCountedKey marks a request whose download has been counted.
+const downloadCountedKey = "download_counted"
+
+// countDownload records a download of a provider whose binary of size bytes
+// was just served from the cache, batched when a DownloadCounter is
+// configured. Only a GET that sent the whole binary counts, or the range
+// request that reached its end, and a request counts at most once; aborted
+// transfers, HEAD and conditional requests and earlier ranges do not. fill
+// marks a download that first filled the cache from upstream.
+func (h *MirrorHandler) countDownload(c *gin.Context, providerID uint, size int64, fill bool) {
+	if c.GetBool(downloadCountedKey) || !completedDownload(c, size) {
+		return
+	}
+	c.Set(downloadCountedKey, true)
+
+	written := int64(c.Writer.Size())
+	if h.downloads != nil {
+		h.downloads.Increment(providerID, written, fill)
+		return
+	}
+	fills := 0
+	if fill {
+		fills = 1
+	}
+	h.db.Model(&models.Provider{ID: providerID}).Updates(map[string]interface{}{
+		"downloads":    gorm.Expr("downloads + 1"),
+		"cache_fills":  gorm.Expr("cache_fills + ?", fills),
+		"bytes_served": gorm.Expr("bytes_served + ?", written),
+	})
+}
+
+// completedDownload reports whether the response to c delivered the end of a
+// binary of size bytes: all of it with a 200, or a single range ending at its
+// last byte with a 206. A size of zero, left by rows recorded without one,
+// accepts any complete 200.
+func completedDownload(c *gin.Context, size int64) bool {
+	if c.Request.Method != http.MethodGet {
+		return false
+	}
+	written := int64(c.Writer.Size())
+	switch c.Writer.Status() {
+	case http.StatusOK:
+		return written > 0 && (size <= 0 || written == size)
+	case http.StatusPartialContent:
+		var first, last, total int64
+		if _, err := fmt.Sscanf(c.Writer.Header().Get("Content-Range"), "bytes %d-%d/%d", &first, &last, &total); err != nil {
+			return false
+		}
+		return last == total-1 && written == last-first+1
+	}
+	return false
+}
+
+// touchPlatform records that a platform binary was just served.
+func (h *MirrorHandler) touchPlatform(platformID uint) {
+	now := time.Now()
+	h.db.Model(&models.ProviderPlatform{}).
+		Where("id = ? AND (last_downloaded_at IS NULL OR last_downloaded_at < ?)", platformID, now.Add(-lastDownloadedThrottle)).
+		UpdateColumn("last_downloaded_at", now)
+}
+
+// serveFile answers with a stored provider package as an attachment named
+// filename, honoring Range and conditional requests so interrupted downloads
+// can resume. An empty filename falls back to the stored file's name.
+func (h *MirrorHandler) serveFile(c *gin.Context, filePath, filename string) {
+	f, err := h.proxyService.OpenFile(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider file not found"})
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	if filename == "" {
+		filename = filepath.Base(filePath)
+	}
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		size, _ := h.proxyService.FileSize(filePath)
+		c.DataFromReader(http.StatusOK, size, "application/zip", f, nil)
+		return
+	}
+	var modTime time.Time
+	if local, ok := f.(*os.File); ok {
+		if info, err := local.Stat(); err == nil {
+			modTime = info.ModTime()
+		}
+	}
+	http.ServeContent(c.Writer, c.Request, filename, modTime, rs)
+}
+
+// serveFromDiskCache serves a binary that is present in storage but missing from the
+// database, for example one written by the background cache in GetProviderDownloadInfo.
+// The file is registered so later requests take the normal database path. It makes no
+// upstream calls and returns false if nothing is cached on disk.
+func (h *MirrorHandler) serveFromDiskCache(c *gin.Context, namespace, name, version, osType, arch string) bool {
+	filePath, cached := h.proxyService.GetCachedFilePath(namespace, name, version, osType, arch)
+	if !cached {
+		return false
+	}
+
+	sha256sum, err := h.proxyService.CalculateFileSHA256(filePath)
+	if err != nil {
+		return false
+	}
+
+	provider, err := h.getOrCreateProvider(namespace, name, version, "", time.Time{})
+	if err != nil {
+		return false
+	}
 
-	// Serve the file
-	c.File(platform.FilePath)
+	platform := models.ProviderPlatform{
+		OS:        osType,
+		Arch:      arch,
+		Filename:  filepath.Base(filePath),
+		FilePath:  filePath,
+		SHA256Sum: sha256sum,
+	}
+	platform.FileSize, _ = h.proxyService.FileSize(filePath)
+	h.savePlatformEntry(provider.ID, platform)
+
+	var saved models.ProviderPlatform
+	if err := h.db.Where("provider_id = ? AND os = ? AND arch = ?", provider.ID, osType, arch).First(&saved).Error; err == nil {
+		h.touchPlatform(saved.ID)
+	}
+	h.serveFile(c, filePath, platform.Filename)
+	h.countDownload(c, provider.ID, platform.FileSize, false)
+	return true
 }
 
 // downloadAndCacheFromUpstream downloads a provider from upstream, caches it, and serves it.
 func (h *MirrorHandler) downloadAndCacheFromUpstream(c *gin.Context, namespace, name, version, osType, arch string) {
 	// Get download info from upstream
-	downloadInfo, err := h.proxyService.GetProviderDownloadInfo(namespace, name, version, osType, arch)
+	proxyService := h.getProxyService(namespace, name, "")
+	downloadInfo, err := proxyService.GetProviderDownloadInfo(c.Request.Context(), namespace, name, version, osType, arch)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found upstream: " + err.Error()})
 		return
 	}
 
 	// Download and cache the provider
-	filePath, sha256sum, err := h.proxyService.DownloadAndCacheProvider(namespace, name, version, osType, arch)
+	filePath, sha256sum, err := proxyService.DownloadAndCacheProvider(c.Request.Context(), namespace, name, version, osType, arch)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download provider: " + err.Error()})
 		return
 	}
 
 	// Get file info for size
-	fileInfo, _ := os.Stat(filePath)
-	var fileSize int64
-	if fileInfo != nil {
-		fileSize = fileInfo.Size()
-	}
+	fileSize, _ := proxyService.FileSize(filePath)
 
 	// Create or get provider record
 	var provider models.Provider
@@ -558,16 +1026,18 @@ func (h *MirrorHandler) downloadAndCacheFromUpstream(c *gin.Context, namespace,
 		namespace, name, version).First(&provider).Error; err != nil {
 		// Create new provider
 		provider = models.Provider{
-			Namespace:   namespace,
-			Name:        name,
-			Version:     version,
-			Description: "Auto-cached from upstream",
-			SourceType:  models.SourceMirror,
-			SourceURL:   "https://registry.terraform.io",
-			Protocols:   `["5.0", "6.0"]`,
-			Published:   time.Now(),
+			Namespace:  namespace,
+			Name:       name,
+			Version:    version,
+			SourceType: models.SourceMirror,
+			SourceURL:  "https://registry.terraform.io",
+			Protocols:  proxy.EncodeProtocols(downloadInfo.Protocols),
+			Published:  upstreamPublishDate(c.Request.Context(), h.proxyService, namespace, name, version),
+		}
+		if h.db.Create(&provider).Error == nil {
+			scheduler.RecordUpstreamDetails(c.Request.Context(), h.db, h.proxyService, namespace, name)
+			scheduler.RecordSigningKeys(h.db, provider.ID, downloadInfo.SigningKeys)
 		}
-		h.db.Create(&provider)
 	}
 
 	// Create platform record if not exists
@@ -586,11 +1056,11 @@ func (h *MirrorHandler) downloadAndCacheFromUpstream(c *gin.Context, namespace,
 		h.db.Create(&platform)
 	}
 
-	// Increment download counter
-	h.db.Model(&provider).Update("downloads", gorm.Expr("downloads + 1"))
+	h.touchPlatform(platform.ID)
 
-	// Serve the file
-	c.File(filePath)
+	// Serve the file, then count it as the download that filled the cache
+	h.serveFile(c, filePath, platform.Filename)
+	h.countDownload(c, provider.ID, fileSize, true)
 }
 
 // GetProviderDownloadInfo returns download info following Terraform protocol.
@@ -607,7 +1077,8 @@ func (h *MirrorHandler) GetProviderDownloadInfo(c *gin.Context) {
 	if err := h.db.First(&settings).Error; err == nil {
 		allowOnline = settings.AllowOnlineSearch
 		// Update proxy settings before making upstream request
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
 	}
 
 	// Check if we have it locally
@@ -623,6 +1094,17 @@ func (h *MirrorHandler) GetProviderDownloadInfo(c *gin.Context) {
 		}
 	}
 
+	// Optionally fall back to a cached version that satisfies the client's constraint.
+	if !hasLocal && settings.VersionFallback {
+		if constraint := c.GetHeader(versionConstraintHeader); constraint != "" {
+			if p, plat, ok := h.findFallbackPlatform(namespace, name, version, osType, arch, constraint); ok {
+				provider, platform, hasLocal = p, plat, true
+				version = provider.Version
+				c.Header(resolvedVersionHeader, version)
+			}
+		}
+	}
+
 	// Get the host from request
 	host := c.Request.Host
 	scheme := "http"
@@ -635,46 +1117,71 @@ func (h *MirrorHandler) GetProviderDownloadInfo(c *gin.Context) {
 
 	if hasLocal {
 		// Return local download info
-		downloadURL := fmt.Sprintf("%s://%s/v1/providers/%s/%s/%s/download/%s/%s/binary",
-			scheme, host, namespace, name, version, osType, arch)
+		downloadURL := providerBinaryURL(scheme, host, namespace, name, version, osType, arch, h.downloadSigner)
+
+		// The checksum files are only linked when mirroring stored them; not
+		// every provider publishes a signature.
+		shasumsURL, signatureURL := "", ""
+		if sumsPath, sigPath, err := h.proxyService.ChecksumFilePaths(namespace, name, version); err == nil &&
+			h.proxyService.FileExists(sumsPath) {
+			shasumsURL = scheme + "://" + host + "/v1/providers/" + namespace + "/" + name + "/" + version + "/sha256sums"
+			if h.proxyService.FileExists(sigPath) {
+				signatureURL = shasumsURL + ".sig"
+			}
+		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"protocols":             []string{"5.0"},
+			"protocols":             proxy.DecodeProtocols(provider.Protocols),
 			"os":                    osType,
 			"arch":                  arch,
 			"filename":              platform.Filename,
 			"download_url":          downloadURL,
 			"shasum":                platform.SHA256Sum,
-			"shasums_url":           "",
-			"shasums_signature_url": "",
-			"signing_keys": gin.H{
-				"gpg_public_keys": []gin.H{},
-			},
+			"shasums_url":           shasumsURL,
+			"shasums_signature_url": signatureURL,
+			"signing_keys":          providerSigningKeys(h.db, provider.ID, namespace),
 		})
 		return
 	}
 
-	// Only fetch from upstream if online search is allowed
-	if !allowOnline {
+	// Only fetch from upstream if online search is allowed, or in redirect mode,
+	// where the client downloads straight from upstream and nothing is cached.
+	redirect := !allowOnline && settings.RedirectUncached
+	if !allowOnline && !redirect {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 		return
 	}
 
 	// Try to get from upstream and cache
-	info, err := h.proxyService.GetProviderDownloadInfo(namespace, name, version, osType, arch)
+	proxyService := h.getProxyService(namespace, name, "")
+	info, err := proxyService.GetProviderDownloadInfo(c.Request.Context(), namespace, name, version, osType, arch)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 		return
 	}
 
+	if redirect {
+		c.JSON(http.StatusOK, gin.H{
+			"protocols":             info.Protocols,
+			"os":                    osType,
+			"arch":                  arch,
+			"filename":              info.Filename,
+			"download_url":          info.DownloadURL,
+			"shasum":                info.SHA256Sum,
+			"shasums_url":           info.SHA256SumsURL,
+			"shasums_signature_url": info.SHA256SumsSignature,
+			"signing_keys":          info.SigningKeys,
+		})
+		return
+	}
+
 	// Start background caching
 	go func() {
-		_, _, _ = h.proxyService.DownloadAndCacheProvider(namespace, name, version, osType, arch) // #nosec G104 - async cache
+		_, _, _ = proxyService.DownloadAndCacheProvider(context.Background(), namespace, name, version, osType, arch) // #nosec G104 - async cache
 	}()
 
 	// Return upstream info but with our download URL
-	downloadURL := fmt.Sprintf("%s://%s/v1/providers/%s/%s/%s/download/%s/%s/binary",
-		scheme, host, namespace, name, version, osType, arch)
+	downloadURL := providerBinaryURL(scheme, host, namespace, name, version, osType, arch, h.downloadSigner)
 
 	c.JSON(http.StatusOK, gin.H{
 		"protocols":             info.Protocols,
@@ -689,29 +1196,133 @@ func (h *MirrorHandler) GetProviderDownloadInfo(c *gin.Context) {
 	})
 }
 
-// GetProviderVersions returns available versions following Terraform protocol.
-func (h *MirrorHandler) GetProviderVersions(c *gin.Context) {
+// GetProviderChecksums serves the SHA256SUMS file stored for a cached provider
+// version, or its detached signature on the sha256sums.sig path.
+func (h *MirrorHandler) GetProviderChecksums(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
+	version := c.Param("version")
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
 
-	// Get local versions
-	var providers []models.Provider
-	h.db.Where("namespace = ? AND name = ?", namespace, name).
-		Order("version DESC").Find(&providers)
+	sumsPath, sigPath, err := h.proxyService.ChecksumFilePaths(namespace, name, version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filePath, contentType := sumsPath, "text/plain; charset=utf-8"
+	if strings.HasSuffix(c.FullPath(), ".sig") {
+		filePath, contentType = sigPath, "application/octet-stream"
+	}
 
-	// Build versions response
-	versions := make([]gin.H, 0)
-	versionMap := make(map[string]bool)
+	f, err := h.proxyService.OpenFile(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checksums not found"})
+		return
+	}
+	defer func() { _ = f.Close() }()
+	size, err := h.proxyService.FileSize(filePath)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checksums not found"})
+		return
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, f, nil)
+}
 
-	for _, p := range providers {
-		if versionMap[p.Version] {
+// versionConstraintHeader carries the Terraform version constraint a client will
+// accept in place of the exact version it asked for; see findFallbackPlatform.
+const versionConstraintHeader = "X-Provider-Version-Constraint"
+
+// resolvedVersionHeader reports the version actually served after a fallback.
+const resolvedVersionHeader = "X-Provider-Resolved-Version"
+
+// findFallbackPlatform picks the cached version of a provider nearest to version that
+// satisfies constraint and has the requested platform: the lowest newer version if
+// there is one, otherwise the highest older one.
+func (h *MirrorHandler) findFallbackPlatform(namespace, name, version, osType, arch, constraint string) (models.Provider, models.ProviderPlatform, bool) {
+	constraints, err := semver.ParseConstraints(constraint)
+	if err != nil {
+		return models.Provider{}, models.ProviderPlatform{}, false
+	}
+	requested, ok := semver.Parse(version)
+	if !ok {
+		return models.Provider{}, models.ProviderPlatform{}, false
+	}
+
+	var providers []models.Provider
+	h.db.Where("namespace = ? AND name = ?", namespace, name).
+		Preload("Platforms", "os = ? AND arch = ?", osType, arch).
+		Find(&providers)
+
+	var best *models.Provider
+	var bestVersion semver.Version
+	for i := range providers {
+		v, ok := semver.Parse(providers[i].Version)
+		if !ok || len(providers[i].Platforms) == 0 || !constraints.Check(v) {
 			continue
 		}
-		versionMap[p.Version] = true
+		if best == nil || nearer(v, bestVersion, requested) {
+			best, bestVersion = &providers[i], v
+		}
+	}
+	if best == nil {
+		return models.Provider{}, models.ProviderPlatform{}, false
+	}
+	return *best, best.Platforms[0], true
+}
 
-		// Get platforms for this version
-		var platforms []models.ProviderPlatform
-		h.db.Where("provider_id = ?", p.ID).Find(&platforms)
+// nearer reports whether a is a better fallback for requested than b.
+// Newer versions are preferred over older ones, and among each the closest wins.
+func nearer(a, b, requested semver.Version) bool {
+	aNewer, bNewer := a.Compare(requested) > 0, b.Compare(requested) > 0
+	if aNewer != bNewer {
+		return aNewer
+	}
+	if aNewer {
+		return a.Compare(b) < 0
+	}
+	return a.Compare(b) > 0
+}
+
+// GetProviderVersions returns available versions following Terraform protocol.
+// Local versions are always listed. Without any, upstream versions are listed
+// instead, leaving out prereleases unless enabled; see includePrereleases.
+func (h *MirrorHandler) GetProviderVersions(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	// Check if online search is allowed
+	var settings models.Settings
+	allowOnline := true
+	if err := h.db.First(&settings).Error; err == nil {
+		allowOnline = settings.AllowOnlineSearch
+	}
+	prereleases, err := includePrereleases(c, settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get local versions, newest first by semver precedence
+	var providers []models.Provider
+	h.db.Where("namespace = ? AND name = ?", namespace, name).Find(&providers)
+	sortProvidersByVersion(providers)
+
+	// Build versions response
+	versions := make([]gin.H, 0)
+	versionMap := make(map[string]bool)
+
+	for _, p := range providers {
+		if versionMap[p.Version] {
+			continue
+		}
+		versionMap[p.Version] = true
+
+		// Get platforms for this version
+		var platforms []models.ProviderPlatform
+		h.db.Where("provider_id = ?", p.ID).Find(&platforms)
 
 		platformList := make([]gin.H, 0)
 		for _, pl := range platforms {
@@ -723,34 +1334,31 @@ func (h *MirrorHandler) GetProviderVersions(c *gin.Context) {
 
 		versions = append(versions, gin.H{
 			"version":   p.Version,
-			"protocols": []string{"5.0"},
+			"protocols": proxy.DecodeProtocols(p.Protocols),
 			"platforms": platformList,
 		})
 	}
 
 	// If no local versions, try upstream (if allowed)
 	if len(versions) == 0 {
-		// Check if online search is allowed and update proxy settings
-		var settings models.Settings
-		allowOnline := true
-		if err := h.db.First(&settings).Error; err == nil {
-			allowOnline = settings.AllowOnlineSearch
-			// Update proxy settings before making upstream request
-			h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
-		}
-
 		if !allowOnline {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 			return
 		}
 
-		upstreamVersions, err := h.proxyService.GetProviderVersions(namespace, name)
+		// Update proxy settings before making upstream request
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
+		upstreamVersions, err := h.getProxyService(namespace, name, "").GetProviderVersions(c.Request.Context(), namespace, name)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 			return
 		}
 
 		for _, v := range upstreamVersions.Versions {
+			if !prereleases && semver.IsPrerelease(v.Version) {
+				continue
+			}
 			platformList := make([]gin.H, 0)
 			for _, p := range v.Platforms {
 				platformList = append(platformList, gin.H{
@@ -786,10 +1394,16 @@ type MirroredProviderSummary struct {
 	PlatformCount int    `json:"platform_count"`
 }
 
+// ListMirroredProviders returns the cached providers grouped by namespace/name,
+// most recently updated first unless sort selects another order; see
+// providerSortOrder.
 func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset := (page - 1) * limit
+	page, limit := pageParams(c, defaultListPageSize, maxListPageSize)
+	order, err := providerSortOrder(c, "MAX(updated_at) DESC")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Query to get unique providers grouped by namespace/name
 	var results []struct {
@@ -797,7 +1411,6 @@ func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
 		Namespace     string
 		Name          string
 		Description   string
-		LatestVersion string
 		VersionCount  int64
 		Downloads     int64
 		SourceType    string
@@ -805,23 +1418,29 @@ func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
 		PlatformCount int64
 	}
 
-	baseQuery := h.db.Model(&models.Provider{})
-
-	if sourceType := c.Query("source_type"); sourceType != "" {
-		baseQuery = baseQuery.Where("source_type = ?", sourceType)
+	sourceType := c.Query("source_type")
+	filtered := func() *gorm.DB {
+		query := h.db.Model(&models.Provider{})
+		if sourceType != "" {
+			query = query.Where("source_type = ?", sourceType)
+		}
+		return query
 	}
 
-	// Count unique providers
+	// Count unique providers matching the filter
 	var total int64
-	h.db.Model(&models.Provider{}).Select("COUNT(DISTINCT namespace || '/' || name)").Scan(&total)
+	if err := filtered().Select("COUNT(DISTINCT namespace || '/' || name)").Scan(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	pagination := newPagination(page, limit, total)
 
 	// Get aggregated provider info with unique platform count
-	if err := baseQuery.Select(`
+	if err := filtered().Select(`
 		MAX(providers.id) as id,
 		namespace,
 		name,
 		MAX(description) as description,
-		MAX(version) as latest_version,
 		COUNT(DISTINCT version) as version_count,
 		SUM(downloads) as downloads,
 		MAX(source_type) as source_type,
@@ -831,14 +1450,25 @@ func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
 		) as platform_count
 	`).
 		Group("namespace, name").
-		Order("MAX(updated_at) DESC").
-		Offset(offset).
+		Order(order).
+		Offset(pagination.offset()).
 		Limit(limit).
 		Scan(&results).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// The latest version is picked by semver precedence; MAX(version) compares strings.
+	keys := make([]string, len(results))
+	for i, r := range results {
+		keys[i] = r.Namespace + "/" + r.Name
+	}
+	latest, err := latestVersions(filtered(), keys)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Convert to MirroredProviderSummary
 	providers := make([]MirroredProviderSummary, len(results))
 	for i, r := range results {
@@ -847,7 +1477,7 @@ func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
 			Namespace:     r.Namespace,
 			Name:          r.Name,
 			Description:   r.Description,
-			LatestVersion: r.LatestVersion,
+			LatestVersion: latest[keys[i]],
 			VersionCount:  int(r.VersionCount),
 			Downloads:     int(r.Downloads),
 			SourceType:    r.SourceType,
@@ -856,38 +1486,191 @@ func (h *MirrorHandler) ListMirroredProviders(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"providers": providers,
-		"page":      page,
-		"limit":     limit,
-		"total":     total,
-	})
+	c.JSON(http.StatusOK, paginated(gin.H{"providers": providers}, pagination))
 }
 
-// GetProviderVersionsDetail returns all versions of a specific provider with platform details.
+// Page sizes for GetProviderVersionsDetail.
+const (
+	defaultVersionsPageSize = 50
+	maxVersionsPageSize     = 200
+)
+
+// ProviderVersionSummary is a provider version with the number of cached platforms.
+// Platforms is only filled in when a single version is requested.
+type ProviderVersionSummary struct {
+	models.Provider
+	PlatformCount int64 `json:"platform_count"`
+}
+
+// GetProviderVersionsDetail returns the versions of a specific provider, a page at a
+// time, with platform counts. Query: page, limit (default 50, max 200). With
+// version set, only that version is returned, including its full platform list.
 func (h *MirrorHandler) GetProviderVersionsDetail(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 
-	var providers []models.Provider
-	if err := h.db.Preload("Platforms").
-		Where("namespace = ? AND name = ?", namespace, name).
-		Order("version DESC").
-		Find(&providers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if version := c.Query("version"); version != "" {
+		var provider models.Provider
+		if err := h.db.Preload("Platforms").
+			Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).
+			First(&provider).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Provider version not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"namespace": namespace,
+			"name":      name,
+			"versions":  []ProviderVersionSummary{{Provider: provider, PlatformCount: int64(len(provider.Platforms))}},
+			"total":     1,
+		})
 		return
 	}
 
-	if len(providers) == 0 {
+	page, limit := pageParams(c, defaultVersionsPageSize, maxVersionsPageSize)
+
+	query := h.db.Model(&models.Provider{}).Where("namespace = ? AND name = ?", namespace, name)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if total == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// Page in semver order: load every version's id, sort, then fetch the page.
+	var all []models.Provider
+	if err := h.db.Select("id, version").Where("namespace = ? AND name = ?", namespace, name).
+		Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sortProvidersByVersion(all)
+	start := min((page-1)*limit, len(all))
+	pageIDs := make([]uint, 0, limit)
+	for _, p := range all[start:min(start+limit, len(all))] {
+		pageIDs = append(pageIDs, p.ID)
+	}
+
+	var providers []models.Provider
+	if len(pageIDs) > 0 {
+		if err := h.db.Where("id IN ?", pageIDs).Find(&providers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	sortProvidersByVersion(providers)
+
+	// One grouped query for the counts of the whole page.
+	ids := make([]uint, len(providers))
+	for i, p := range providers {
+		ids[i] = p.ID
+	}
+	var counts []struct {
+		ProviderID uint
+		Count      int64
+	}
+	if len(ids) > 0 {
+		h.db.Model(&models.ProviderPlatform{}).
+			Select("provider_id, COUNT(*) as count").
+			Where("provider_id IN ?", ids).
+			Group("provider_id").
+			Scan(&counts)
+	}
+	countByID := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		countByID[row.ProviderID] = row.Count
+	}
+
+	versions := make([]ProviderVersionSummary, len(providers))
+	for i, p := range providers {
+		versions[i] = ProviderVersionSummary{Provider: p, PlatformCount: countByID[p.ID]}
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{
 		"namespace": namespace,
 		"name":      name,
-		"versions":  providers,
-		"total":     len(providers),
+		"versions":  versions,
+	}, newPagination(page, limit, total)))
+}
+
+// GetLatestProviderVersion returns the newest cached version of a provider by
+// semver precedence, with its full platform list. Prereleases are skipped
+// unless include_prerelease=true.
+func (h *MirrorHandler) GetLatestProviderVersion(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if errMsg := validateProviderParams(namespace, name, ""); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	includePrerelease := c.Query("include_prerelease") == "true"
+
+	var all []models.Provider
+	if err := h.db.Select("id, version").Where("namespace = ? AND name = ?", namespace, name).
+		Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var latest *models.Provider
+	for i, p := range all {
+		if !includePrerelease && semver.IsPrerelease(p.Version) {
+			continue
+		}
+		if latest == nil || semver.Compare(p.Version, latest.Version) > 0 {
+			latest = &all[i]
+		}
+	}
+	if latest == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+		return
+	}
+
+	var provider models.Provider
+	if err := h.db.Preload("Platforms").First(&provider, latest.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ProviderVersionSummary{Provider: provider, PlatformCount: int64(len(provider.Platforms))})
+}
+
+// GetPlatformChecksum returns the stored checksum and size of a single platform binary.
+// The response is built from the database row only, so no file is read.
+func (h *MirrorHandler) GetPlatformChecksum(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	osType := c.Param("os")
+	arch := c.Param("arch")
+
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var platform models.ProviderPlatform
+	if err := h.db.Joins("JOIN providers ON providers.id = provider_platforms.provider_id").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND providers.deleted_at IS NULL",
+			namespace, name, version).
+		Where("provider_platforms.os = ? AND provider_platforms.arch = ?", osType, arch).
+		First(&platform).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Platform not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sha256":   platform.SHA256Sum,
+		"size":     platform.FileSize,
+		"filename": platform.Filename,
 	})
 }
 
@@ -901,24 +1684,100 @@ func (h *MirrorHandler) DeleteProvider(c *gin.Context) {
 		return
 	}
 
-	// Delete associated platforms and files
-	var platforms []models.ProviderPlatform
-	h.db.Where("provider_id = ?", provider.ID).Find(&platforms)
+	freed := retention.DeleteVersion(h.db, h.proxyService, &provider)
+	notifyProviderDeleted(h.db, provider, freed)
 
-	for _, p := range platforms {
-		if p.FilePath != "" {
-			_ = os.Remove(p.FilePath) // #nosec G104 - best effort cleanup
-		}
+	c.JSON(http.StatusOK, gin.H{"message": "Provider deleted successfully"})
+}
+
+// DeleteProviderVersion deletes one version of a provider by namespace, name
+// and version, together with its platforms and their files, and removes the
+// version's directories left empty in local storage.
+func (h *MirrorHandler) DeleteProviderVersion(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if !h.authorizeNamespace(c, namespace) {
+		return
 	}
 
-	h.db.Where("provider_id = ?", provider.ID).Delete(&models.ProviderPlatform{})
-	h.db.Delete(&provider)
+	var provider models.Provider
+	if err := h.db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).
+		First(&provider).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider version not found"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Provider deleted successfully"})
+	freed := retention.DeleteVersion(h.db, h.proxyService, &provider)
+	h.proxyService.RemoveEmptyVersionDirs(namespace, name, version)
+	notifyProviderDeleted(h.db, provider, freed)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Provider version deleted successfully",
+		"id":          provider.ID,
+		"version":     provider.Version,
+		"freed_bytes": freed,
+	})
 }
 
+// DeleteProviderPlatform deletes one platform of a provider version and its
+// file. The version and its other platforms are kept.
+func (h *MirrorHandler) DeleteProviderPlatform(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	osType := c.Param("os")
+	arch := c.Param("arch")
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	if !validIdentifierStrict.MatchString(osType) || !validIdentifierStrict.MatchString(arch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid os or arch"})
+		return
+	}
+	if !h.authorizeNamespace(c, namespace) {
+		return
+	}
+
+	var platform models.ProviderPlatform
+	if err := h.db.Joins("JOIN providers ON providers.id = provider_platforms.provider_id AND providers.deleted_at IS NULL").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND provider_platforms.os = ? AND provider_platforms.arch = ?",
+			namespace, name, version, osType, arch).
+		First(&platform).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider platform not found"})
+		return
+	}
+
+	if err := h.db.Delete(&platform).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete platform"})
+		return
+	}
+	if platform.FilePath != "" {
+		_ = h.proxyService.RemoveFile(platform.FilePath) // #nosec G104 - best effort cleanup
+	}
+	h.proxyService.RemoveEmptyVersionDirs(namespace, name, version)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Provider platform deleted successfully",
+		"os":          platform.OS,
+		"arch":        platform.Arch,
+		"freed_bytes": platform.FileSize,
+	})
+}
+
+// exportEpoch is the modification time stamped on every export archive entry.
+// A fixed value keeps exports of the same provider byte-for-byte identical.
+var exportEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // ExportProvider exports a provider as a downloadable package.
-// The package includes all platform binaries and a manifest file.
+// The package includes all platform binaries and a manifest file. It is
+// streamed to the client as it is written, so exports of any size need no
+// temporary space.
 func (h *MirrorHandler) ExportProvider(c *gin.Context) {
 	id := c.Param("id")
 
@@ -933,20 +1792,43 @@ func (h *MirrorHandler) ExportProvider(c *gin.Context) {
 		return
 	}
 
-	// Create a temporary zip file
-	zipFileName := fmt.Sprintf("terraform-provider-%s_%s_%s.zip",
-		provider.Name, provider.Version, provider.Namespace)
-	tempFile, err := os.CreateTemp("", "provider-export-*.zip")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create temp file"})
+	// Nothing can be taken back once the first entry is sent, so platforms
+	// whose binary is missing are dropped before the response starts.
+	available := make([]models.ProviderPlatform, 0, len(provider.Platforms))
+	for _, platform := range provider.Platforms {
+		if h.proxyService.FileExists(platform.FilePath) {
+			available = append(available, platform)
+		}
+	}
+	if len(available) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid platform binaries found"})
 		return
 	}
-	defer func() { _ = os.Remove(tempFile.Name()) }()
-	defer func() { _ = tempFile.Close() }()
+	provider.Platforms = available
+
+	zipFileName := fmt.Sprintf("terraform-provider-%s_%s_%s.zip",
+		provider.Name, provider.Version, provider.Namespace)
+
+	// Set headers for download
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", zipFileName))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Status(http.StatusOK)
 
-	zipWriter := zip.NewWriter(tempFile)
+	if _, err := writeExportArchive(c.Writer, h.proxyService, &provider); err != nil {
+		// The archive is left without its central directory, which clients
+		// reject as a corrupt zip.
+		log.Printf("Export of provider %d failed mid-stream: %s", provider.ID, logsafe.CleanErr(err))
+	}
+}
 
-	// Create manifest
+// writeExportArchive writes the export package for provider to w and returns
+// the manifest it embedded. Platforms are written in os/arch order with fixed
+// timestamps and stored (uncompressed) entries, so the same provider content
+// always produces an identical archive. Every platform binary must be present
+// in storage; the manifest is written last.
+func writeExportArchive(w io.Writer, files *proxy.ProxyService, provider *models.Provider) (ProviderExportManifest, error) {
 	manifest := ProviderExportManifest{
 		Namespace:   provider.Namespace,
 		Name:        provider.Name,
@@ -954,40 +1836,26 @@ func (h *MirrorHandler) ExportProvider(c *gin.Context) {
 		Description: provider.Description,
 		SourceType:  string(provider.SourceType),
 		Protocols:   provider.Protocols,
-		ExportedAt:  time.Now(),
 		Platforms:   make([]PlatformManifest, 0),
 	}
 
-	// Add each platform binary to the zip
-	for _, platform := range provider.Platforms {
-		if platform.FilePath == "" {
-			continue
+	platforms := make([]models.ProviderPlatform, len(provider.Platforms))
+	copy(platforms, provider.Platforms)
+	sort.Slice(platforms, func(i, j int) bool {
+		if platforms[i].OS != platforms[j].OS {
+			return platforms[i].OS < platforms[j].OS
 		}
+		return platforms[i].Arch < platforms[j].Arch
+	})
 
-		// Check if file exists
-		if _, err := os.Stat(platform.FilePath); os.IsNotExist(err) {
-			continue
-		}
-
-		// Open the source file
-		srcFile, err := os.Open(platform.FilePath) // #nosec G304 - path is from database record
-		if err != nil {
-			continue
-		}
+	zipWriter := zip.NewWriter(w)
 
+	// Add each platform binary to the zip
+	for _, platform := range platforms {
 		// Create entry in zip with relative path
 		entryName := fmt.Sprintf("%s/%s/%s", platform.OS, platform.Arch, platform.Filename)
-		writer, err := zipWriter.Create(entryName)
-		if err != nil {
-			_ = srcFile.Close() // #nosec G104 - best effort cleanup
-			continue
-		}
-
-		// Copy file content
-		_, err = io.Copy(writer, srcFile)
-		_ = srcFile.Close() // #nosec G104 - best effort cleanup
-		if err != nil {
-			continue
+		if err := addExportEntry(zipWriter, entryName, files, platform.FilePath); err != nil {
+			return manifest, err
 		}
 
 		// Add to manifest
@@ -1001,41 +1869,52 @@ func (h *MirrorHandler) ExportProvider(c *gin.Context) {
 		})
 	}
 
-	if len(manifest.Platforms) == 0 {
-		_ = zipWriter.Close() // #nosec G104 - best effort cleanup
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid platform binaries found"})
-		return
-	}
-
 	// Add manifest.json to zip
-	manifestJSON, _ := json.MarshalIndent(manifest, "", "  ")
-	manifestWriter, err := zipWriter.Create("manifest.json")
-	if err == nil {
-		_, _ = manifestWriter.Write(manifestJSON) // #nosec G104 - zip write errors caught on Close
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
 	}
-
-	// Close zip writer
-	if err := zipWriter.Close(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
-		return
+	manifestWriter, err := zipWriter.CreateHeader(exportEntryHeader("manifest.json"))
+	if err != nil {
+		return manifest, err
+	}
+	if _, err := manifestWriter.Write(manifestJSON); err != nil {
+		return manifest, err
 	}
 
-	// Get file size
-	_, _ = tempFile.Seek(0, 0) // #nosec G104 - seek on temp file
-	fileInfo, _ := tempFile.Stat()
+	return manifest, zipWriter.Close()
+}
 
-	// Set headers for download
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", zipFileName))
-	c.Header("Content-Type", "application/zip")
-	c.Header("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
-	c.Header("Content-Transfer-Encoding", "binary")
+// addExportEntry copies the stored file at srcPath into the archive as name.
+func addExportEntry(zipWriter *zip.Writer, name string, files *proxy.ProxyService, srcPath string) error {
+	srcFile, err := files.OpenFile(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = srcFile.Close() }()
 
-	// Stream the file
-	c.File(tempFile.Name())
+	writer, err := zipWriter.CreateHeader(exportEntryHeader(name))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, srcFile)
+	return err
+}
+
+// exportEntryHeader returns a zip header with normalized metadata. Entries are
+// stored rather than deflated: provider binaries are already zip archives, and
+// stored output does not depend on the compressor implementation.
+func exportEntryHeader(name string) *zip.FileHeader {
+	return &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: exportEpoch,
+	}
 }
 
 // ProviderExportManifest contains metadata for exported provider package.
+// ExportedAt is only present in packages from older releases; current exports
+// omit it so that the archive is reproducible.
 type ProviderExportManifest struct {
 	Namespace   string             `json:"namespace"`
 	Name        string             `json:"name"`
@@ -1043,7 +1922,7 @@ type ProviderExportManifest struct {
 	Description string             `json:"description"`
 	SourceType  string             `json:"source_type"`
 	Protocols   string             `json:"protocols"`
-	ExportedAt  time.Time          `json:"exported_at"`
+	ExportedAt  time.Time          `json:"exported_at,omitzero"`
 	Platforms   []PlatformManifest `json:"platforms"`
 }
 
@@ -1057,7 +1936,22 @@ type PlatformManifest struct {
 	ZipPath   string `json:"zip_path"`
 }
 
-// ImportProvider imports a provider from an exported package.
+// maxImportParallelism bounds the parallelism form field of ImportProvider.
+const maxImportParallelism = 8
+
+// Limits on import packages, which may come from parties that are not fully
+// trusted. Sizes are the uncompressed sizes declared in the zip, which
+// archive/zip refuses to read past.
+const (
+	maxImportFileSize     = 500 * 1024 * 1024 // 500MB max per file
+	maxImportTotalSize    = 10 << 30          // 10GB for all platforms of a package
+	maxImportEntries      = 1024
+	maxImportManifestSize = 1 << 20
+)
+
+// ImportProvider imports a provider from an exported package. Each platform
+// binary is verified against the manifest checksum; mismatches are rejected.
+// The optional parallelism form field extracts that many platforms at once.
 func (h *MirrorHandler) ImportProvider(c *gin.Context) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -1066,6 +1960,15 @@ func (h *MirrorHandler) ImportProvider(c *gin.Context) {
 	}
 	defer func() { _ = file.Close() }()
 
+	parallelism := 1
+	if value := c.PostForm("parallelism"); value != "" {
+		parallelism, err = strconv.Atoi(value)
+		if err != nil || parallelism < 1 || parallelism > maxImportParallelism {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("parallelism must be between 1 and %d", maxImportParallelism)})
+			return
+		}
+	}
+
 	// Save and open zip file
 	zipReader, cleanup, err := h.saveAndOpenZip(file)
 	if err != nil {
@@ -1074,6 +1977,11 @@ func (h *MirrorHandler) ImportProvider(c *gin.Context) {
 	}
 	defer cleanup()
 
+	if err := checkImportArchive(&zipReader.Reader); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Read manifest from zip
 	manifest, err := h.readManifestFromZip(zipReader)
 	if err != nil {
@@ -1081,6 +1989,21 @@ func (h *MirrorHandler) ImportProvider(c *gin.Context) {
 		return
 	}
 
+	if errMsg := validateProviderParams(manifest.Namespace, manifest.Name, manifest.Version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid manifest: " + errMsg})
+		return
+	}
+
+	if !h.authorizeNamespace(c, manifest.Namespace) {
+		return
+	}
+
+	locked := lockedPlatforms(h.db, manifest.Namespace, manifest.Name, manifest.Version)
+	if len(locked) > 0 && allPlatformsLocked(manifest.Platforms, locked) {
+		c.JSON(http.StatusConflict, gin.H{"error": immutableConflict(manifest.Namespace, manifest.Name, manifest.Version, "all platforms in the package")})
+		return
+	}
+
 	// Create or update provider
 	provider, err := h.getOrCreateImportedProvider(manifest)
 	if err != nil {
@@ -1089,21 +2012,51 @@ func (h *MirrorHandler) ImportProvider(c *gin.Context) {
 	}
 
 	// Extract and save platforms
-	importedPlatforms := h.extractPlatformsFromZip(zipReader, manifest, provider.ID)
+	importedPlatforms, rejectedPlatforms := h.extractPlatformsFromZip(zipReader, manifest, provider.ID, locked, parallelism)
 
 	if len(importedPlatforms) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No platforms were imported"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No platforms were imported", "rejected": rejectedPlatforms, "rejected_count": len(rejectedPlatforms)})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "Provider imported successfully",
-		"provider":  provider,
-		"platforms": importedPlatforms,
-		"file_name": header.Filename,
+		"message":        "Provider imported successfully",
+		"provider":       provider,
+		"platforms":      importedPlatforms,
+		"rejected":       rejectedPlatforms,
+		"verified_count": len(importedPlatforms),
+		"rejected_count": len(rejectedPlatforms),
+		"file_name":      header.Filename,
 	})
 }
 
+// RejectedPlatform describes a manifest platform entry that was not imported.
+type RejectedPlatform struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	ZipPath string `json:"zip_path"`
+	Reason  string `json:"reason"`
+}
+
+// validateImportPlatform checks a manifest platform entry against the manifest it came from.
+// The OS and arch must be well-formed, the zip path must be the os/arch/filename layout
+// written by ExportProvider, and the filename must embed the manifest's provider name,
+// version and platform. Returns the rejection reason, or "" if the entry is consistent.
+func validateImportPlatform(manifest *ProviderExportManifest, pm PlatformManifest) string {
+	safeOS, safeArch := validatePlatform(pm.OS, pm.Arch)
+	if safeOS == "invalid" || safeArch == "invalid" {
+		return "invalid os or arch"
+	}
+	if pm.ZipPath != pm.OS+"/"+pm.Arch+"/"+pm.Filename {
+		return "zip path does not match os/arch/filename"
+	}
+	expected := fmt.Sprintf("terraform-provider-%s_%s_%s_%s.zip", manifest.Name, manifest.Version, pm.OS, pm.Arch)
+	if pm.Filename != expected {
+		return fmt.Sprintf("filename does not match manifest: expected %s", expected)
+	}
+	return ""
+}
+
 // saveAndOpenZip saves the uploaded file and opens it as a zip reader.
 func (h *MirrorHandler) saveAndOpenZip(file io.Reader) (*zip.ReadCloser, func(), error) {
 	tempFile, err := os.CreateTemp("", "provider-import-*.zip")
@@ -1131,6 +2084,22 @@ func (h *MirrorHandler) saveAndOpenZip(file io.Reader) (*zip.ReadCloser, func(),
 	return zipReader, cleanup, nil
 }
 
+// checkImportArchive rejects packages with more entries or more uncompressed
+// data than any export produces, before anything is extracted from them.
+func checkImportArchive(zipReader *zip.Reader) error {
+	if len(zipReader.File) > maxImportEntries {
+		return fmt.Errorf("package has more than %d entries", maxImportEntries)
+	}
+	var total uint64
+	for _, f := range zipReader.File {
+		total += f.UncompressedSize64
+		if total > maxImportTotalSize {
+			return fmt.Errorf("package exceeds the maximum uncompressed size of %d bytes", uint64(maxImportTotalSize))
+		}
+	}
+	return nil
+}
+
 // readManifestFromZip reads the manifest.json from a zip file.
 func (h *MirrorHandler) readManifestFromZip(zipReader *zip.ReadCloser) (*ProviderExportManifest, error) {
 	for _, f := range zipReader.File {
@@ -1140,7 +2109,7 @@ func (h *MirrorHandler) readManifestFromZip(zipReader *zip.ReadCloser) (*Provide
 				continue
 			}
 			var manifest ProviderExportManifest
-			err = json.NewDecoder(rc).Decode(&manifest)
+			err = json.NewDecoder(io.LimitReader(rc, maxImportManifestSize)).Decode(&manifest)
 			_ = rc.Close()
 			if err == nil {
 				return &manifest, nil
@@ -1173,26 +2142,80 @@ func (h *MirrorHandler) getOrCreateImportedProvider(manifest *ProviderExportMani
 	return &provider, nil
 }
 
-// extractPlatformsFromZip extracts platform binaries from the zip file.
-func (h *MirrorHandler) extractPlatformsFromZip(zipReader *zip.ReadCloser, manifest *ProviderExportManifest, providerID uint) []PlatformManifest {
-	const maxFileSize = 500 * 1024 * 1024 // 500MB max per file
-	importedPlatforms := make([]PlatformManifest, 0)
+// extractPlatformsFromZip extracts platform binaries from the zip file, up to
+// parallelism at a time, and records the verified ones in manifest order.
+// Entries that fail validation, extraction or checksum verification, or that
+// would overwrite a locked platform, are returned as rejected, with the reason.
+func (h *MirrorHandler) extractPlatformsFromZip(zipReader *zip.ReadCloser, manifest *ProviderExportManifest, providerID uint, locked map[string]bool, parallelism int) ([]PlatformManifest, []RejectedPlatform) {
+	type outcome struct {
+		filePath string
+		reason   string
+	}
+	outcomes := make([]outcome, len(manifest.Platforms))
+	seen := make(map[string]bool)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i, pm := range manifest.Platforms {
+		platform := pm.OS + "/" + pm.Arch
+		if reason := validateImportPlatform(manifest, pm); reason != "" {
+			outcomes[i].reason = reason
+			continue
+		}
+		if seen[platform] {
+			outcomes[i].reason = "duplicate platform in manifest"
+			continue
+		}
+		seen[platform] = true
+		if locked[platform] {
+			outcomes[i].reason = "platform already published and versions are immutable"
+			continue
+		}
+		if pm.SHA256Sum == "" {
+			outcomes[i].reason = "manifest has no checksum for platform"
+			continue
+		}
 
-	for _, pm := range manifest.Platforms {
 		zipFile := h.findFileInZip(zipReader, pm.ZipPath)
-		if zipFile == nil || zipFile.UncompressedSize64 > maxFileSize {
+		if zipFile == nil {
+			outcomes[i].reason = "file not found in package"
 			continue
 		}
-
-		filePath, err := h.extractZipFile(zipFile, manifest.Namespace, manifest.Name, manifest.Version, pm)
-		if err != nil {
+		if zipFile.UncompressedSize64 > maxImportFileSize {
+			outcomes[i].reason = "file exceeds maximum size"
 			continue
 		}
 
-		h.saveImportedPlatform(providerID, pm, filePath)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			filePath, err := h.extractZipFile(zipFile, manifest.Namespace, manifest.Name, manifest.Version, pm)
+			switch {
+			case errors.Is(err, errImportChecksum):
+				outcomes[i].reason = "checksum does not match manifest"
+			case err != nil:
+				outcomes[i].reason = "failed to extract file"
+			default:
+				outcomes[i].filePath = filePath
+			}
+		}()
+	}
+	wg.Wait()
+
+	importedPlatforms := make([]PlatformManifest, 0)
+	rejectedPlatforms := make([]RejectedPlatform, 0)
+	for i, pm := range manifest.Platforms {
+		if reason := outcomes[i].reason; reason != "" {
+			rejectedPlatforms = append(rejectedPlatforms, RejectedPlatform{OS: pm.OS, Arch: pm.Arch, ZipPath: pm.ZipPath, Reason: reason})
+			continue
+		}
+		h.saveImportedPlatform(providerID, pm, outcomes[i].filePath)
 		importedPlatforms = append(importedPlatforms, pm)
 	}
-	return importedPlatforms
+	return importedPlatforms, rejectedPlatforms
 }
 
 // findFileInZip finds a file in the zip by path.
@@ -1205,45 +2228,40 @@ func (h *MirrorHandler) findFileInZip(zipReader *zip.ReadCloser, path string) *z
 	return nil
 }
 
-// extractZipFile extracts a single file from the zip.
-func (h *MirrorHandler) extractZipFile(zipFile *zip.File, namespace, name, version string, pm PlatformManifest) (string, error) {
-	const maxFileSize = 500 * 1024 * 1024
+// errImportChecksum reports an extracted file whose SHA256 differs from the manifest.
+var errImportChecksum = errors.New("checksum does not match manifest")
 
+// extractZipFile extracts a single file from the zip into storage. The file is
+// only kept once its SHA256 matches the manifest, so a corrupt package never
+// replaces a good binary.
+func (h *MirrorHandler) extractZipFile(zipFile *zip.File, namespace, name, version string, pm PlatformManifest) (string, error) {
 	// Build safe directory path using validated components
 	dirPath, err := proxy.BuildSafeProviderPath(h.storagePath, namespace, name, version, pm.OS, pm.Arch)
 	if err != nil {
 		return "", fmt.Errorf("invalid path components: %w", err)
 	}
 
-	if err := os.MkdirAll(dirPath, 0750); err != nil {
-		return "", err
-	}
-
 	// Sanitize filename
 	safeFilename, err := proxy.SanitizeFilename(pm.Filename)
 	if err != nil {
 		return "", fmt.Errorf("invalid filename: %w", err)
 	}
-
-	filePath := filepath.Join(dirPath, safeFilename)
-	// #nosec G304 -- filePath is constructed from validated components via BuildSafeProviderPath and SanitizeFilename
-	outFile, err := os.Create(filePath)
-	if err != nil {
-		return "", err
+	if pm.SHA256Sum == "" {
+		return "", errImportChecksum
 	}
 
 	rc, err := zipFile.Open()
 	if err != nil {
-		_ = outFile.Close()
 		return "", err
 	}
+	defer func() { _ = rc.Close() }()
 
-	_, err = io.Copy(outFile, io.LimitReader(rc, int64(maxFileSize)))
-	_ = rc.Close()
-	_ = outFile.Close()
-
+	filePath := filepath.Join(dirPath, safeFilename)
+	_, _, err = h.proxyService.StoreFile(filePath, io.LimitReader(rc, maxImportFileSize), pm.SHA256Sum)
+	if errors.Is(err, proxy.ErrChecksumMismatch) {
+		return "", errImportChecksum
+	}
 	if err != nil {
-		_ = os.Remove(filePath)
 		return "", err
 	}
 	return filePath, nil
@@ -1251,7 +2269,7 @@ func (h *MirrorHandler) extractZipFile(zipFile *zip.File, namespace, name, versi
 
 // saveImportedPlatform saves an imported platform to the database.
 func (h *MirrorHandler) saveImportedPlatform(providerID uint, pm PlatformManifest, filePath string) {
-	fileSize := getFileSize(filePath)
+	fileSize, _ := h.proxyService.FileSize(filePath)
 
 	var existingPlatform models.ProviderPlatform
 	if err := h.db.Where("provider_id = ? AND os = ? AND arch = ?",
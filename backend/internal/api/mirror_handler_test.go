@@ -0,0 +1,1269 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/retention"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestGetPlatformChecksum(t *testing.T) {
+	db := newTestDB(t)
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{
+		ProviderID: provider.ID,
+		OS:         "linux",
+		Arch:       "amd64",
+		Filename:   "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		FilePath:   "/nonexistent/terraform-provider-aws_5.0.0_linux_amd64.zip",
+		SHA256Sum:  "abc123",
+		FileSize:   42,
+	})
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.GET("/checksum/:namespace/:name/:version/:os/:arch", h.GetPlatformChecksum)
+
+	t.Run("cached platform", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checksum/hashicorp/aws/5.0.0/linux/amd64", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+		}
+		var body struct {
+			SHA256   string `json:"sha256"`
+			Size     int64  `json:"size"`
+			Filename string `json:"filename"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if body.SHA256 != "abc123" || body.Size != 42 || body.Filename != "terraform-provider-aws_5.0.0_linux_amd64.zip" {
+			t.Errorf("unexpected response: %+v", body)
+		}
+	})
+
+	t.Run("missing platform", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checksum/hashicorp/aws/5.0.0/darwin/arm64", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status code = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checksum/hashicorp/aws/latest/linux/amd64", nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status code = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestTouchPlatform(t *testing.T) {
+	db := newTestDB(t)
+	platform := models.ProviderPlatform{ProviderID: 1, OS: "linux", Arch: "amd64", Filename: "f.zip", FilePath: "/f.zip", SHA256Sum: "x"}
+	db.Create(&platform)
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+
+	h.touchPlatform(platform.ID)
+	var first models.ProviderPlatform
+	db.First(&first, platform.ID)
+	if first.LastDownloadedAt == nil {
+		t.Fatal("LastDownloadedAt should be set after first download")
+	}
+
+	// A second touch within the throttle window must not rewrite the timestamp.
+	h.touchPlatform(platform.ID)
+	var second models.ProviderPlatform
+	db.First(&second, platform.ID)
+	if !second.LastDownloadedAt.Equal(*first.LastDownloadedAt) {
+		t.Errorf("LastDownloadedAt changed within throttle window: %v -> %v", first.LastDownloadedAt, second.LastDownloadedAt)
+	}
+}
+
+// offlineSettings stores settings with online search disabled and an unreachable
+// proxy, so any attempt to contact upstream fails.
+func offlineSettings(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	settings := models.Settings{ProxyURL: "127.0.0.1:1", ProxyType: "http"}
+	db.Create(&settings)
+	// AllowOnlineSearch and ProxyEnabled carry gorm defaults, so zero values must be written explicitly.
+	db.Model(&settings).Updates(map[string]interface{}{"allow_online_search": false, "proxy_enabled": true})
+}
+
+func TestDownloadProvider_ServesCachedOffline(t *testing.T) {
+	const body = "provider-binary"
+
+	t.Run("cached with database row", func(t *testing.T) {
+		db := newTestDB(t)
+		offlineSettings(t, db)
+		storagePath := t.TempDir()
+		filePath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+		if err := os.WriteFile(filePath, []byte(body), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+		db.Create(&provider)
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: filepath.Base(filePath), FilePath: filePath, SHA256Sum: "x"})
+
+		w := serveDownload(t, NewMirrorHandler(db, storagePath, nil, false))
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Errorf("status = %d, body = %q; want 200 %q", w.Code, w.Body.String(), body)
+		}
+	})
+
+	t.Run("cached on disk without database row", func(t *testing.T) {
+		db := newTestDB(t)
+		offlineSettings(t, db)
+		storagePath := t.TempDir()
+		dirPath := filepath.Join(storagePath, "hashicorp", "aws", "5.0.0", "linux", "amd64")
+		if err := os.MkdirAll(dirPath, 0750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dirPath, "terraform-provider-aws_5.0.0_linux_amd64.zip"), []byte(body), 0600); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		w := serveDownload(t, NewMirrorHandler(db, storagePath, nil, false))
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Errorf("status = %d, body = %q; want 200 %q", w.Code, w.Body.String(), body)
+		}
+
+		var platform models.ProviderPlatform
+		if err := db.Where("os = ? AND arch = ?", "linux", "amd64").First(&platform).Error; err != nil {
+			t.Fatalf("platform should be registered after serving from disk: %v", err)
+		}
+		if platform.SHA256Sum == "" || platform.FileSize != int64(len(body)) {
+			t.Errorf("unexpected platform row: %+v", platform)
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		db := newTestDB(t)
+		offlineSettings(t, db)
+
+		w := serveDownload(t, NewMirrorHandler(db, t.TempDir(), nil, false))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+// serveDownload requests hashicorp/aws 5.0.0 linux/amd64 from h.DownloadProvider.
+func serveDownload(t *testing.T, h *MirrorHandler) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary", nil))
+	return w
+}
+
+func TestDownloadProvider_AttachmentAndRanges(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	content := make([]byte, 256)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	const filename = "terraform-provider-aws_5.0.0_linux_amd64.zip"
+	path := filepath.Join(storagePath, "stored.zip")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: filename, FilePath: path})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	router := gin.New()
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+	download := func(rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := download("")
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("status = %d, body length = %d", w.Code, w.Body.Len())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename="+filename {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/zip" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q", got)
+	}
+
+	w = download("bytes=0-99")
+	if w.Code != http.StatusPartialContent || !bytes.Equal(w.Body.Bytes(), content[:100]) {
+		t.Errorf("range: status = %d, body length = %d; want 206 with the first 100 bytes", w.Code, w.Body.Len())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 0-99/256" {
+		t.Errorf("Content-Range = %q", got)
+	}
+}
+
+func TestDownloadProvider_CountsCompletedDownloads(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	content := bytes.Repeat([]byte("x"), 256)
+	path := filepath.Join(storagePath, "stored.zip")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", FilePath: path, FileSize: int64(len(content))})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	router := gin.New()
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+	router.HEAD("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+	download := func(method, rangeHeader string) {
+		req := httptest.NewRequest(method, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	counts := func() (int64, int64) {
+		var got models.Provider
+		db.First(&got, provider.ID)
+		return got.Downloads, got.BytesServed
+	}
+
+	download(http.MethodGet, "")
+	if downloads, served := counts(); downloads != 1 || served != 256 {
+		t.Fatalf("after full download: downloads = %d, bytes = %d; want 1, 256", downloads, served)
+	}
+
+	// A resumed download counts once, when its last range is sent.
+	download(http.MethodGet, "bytes=0-99")
+	download(http.MethodHead, "")
+	if downloads, _ := counts(); downloads != 1 {
+		t.Errorf("after partial range and HEAD: downloads = %d, want 1", downloads)
+	}
+	download(http.MethodGet, "bytes=100-")
+	if downloads, served := counts(); downloads != 2 || served != 256+156 {
+		t.Errorf("after final range: downloads = %d, bytes = %d; want 2, %d", downloads, served, 256+156)
+	}
+}
+
+func TestDownloadProvider_CountsCacheFills(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+
+	for i := 0; i < 2; i++ {
+		if w := serveDownload(t, h); w.Code != http.StatusOK {
+			t.Fatalf("download %d: status = %d", i+1, w.Code)
+		}
+	}
+	var provider models.Provider
+	db.Where("namespace = ? AND name = ?", "hashicorp", "aws").First(&provider)
+	if provider.Downloads != 2 || provider.CacheFills != 1 {
+		t.Errorf("downloads = %d, cache fills = %d; want 2 downloads, one of them a fill", provider.Downloads, provider.CacheFills)
+	}
+}
+
+func TestValidateImportPlatform(t *testing.T) {
+	manifest := &ProviderExportManifest{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	valid := PlatformManifest{
+		OS:       "linux",
+		Arch:     "amd64",
+		Filename: "terraform-provider-aws_5.0.0_linux_amd64.zip",
+		ZipPath:  "linux/amd64/terraform-provider-aws_5.0.0_linux_amd64.zip",
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(pm *PlatformManifest)
+		wantErr bool
+	}{
+		{"consistent entry", func(pm *PlatformManifest) {}, false},
+		{"invalid os", func(pm *PlatformManifest) { pm.OS = "../etc" }, true},
+		{"zip path points elsewhere", func(pm *PlatformManifest) { pm.ZipPath = "manifest.json" }, true},
+		{"zip path traverses", func(pm *PlatformManifest) { pm.ZipPath = "../../" + pm.ZipPath }, true},
+		{"filename for another provider", func(pm *PlatformManifest) {
+			pm.Filename = "terraform-provider-google_5.0.0_linux_amd64.zip"
+			pm.ZipPath = "linux/amd64/" + pm.Filename
+		}, true},
+		{"filename for another platform", func(pm *PlatformManifest) {
+			pm.Filename = "terraform-provider-aws_5.0.0_darwin_arm64.zip"
+			pm.ZipPath = "linux/amd64/" + pm.Filename
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := valid
+			tt.mutate(&pm)
+			reason := validateImportPlatform(manifest, pm)
+			if tt.wantErr && reason == "" {
+				t.Error("expected rejection, got none")
+			}
+			if !tt.wantErr && reason != "" {
+				t.Errorf("unexpected rejection: %s", reason)
+			}
+		})
+	}
+}
+
+func TestExportProvider_Deterministic(t *testing.T) {
+	db := newTestDB(t)
+	storage := t.TempDir()
+	provider := models.Provider{Namespace: "hashicorp", Name: "null", Version: "3.2.0", SourceType: models.SourceMirror}
+	db.Create(&provider)
+
+	// Insert platforms out of os/arch order to check the archive is sorted.
+	for _, p := range []struct{ os, arch string }{{"linux", "amd64"}, {"darwin", "arm64"}} {
+		filename := "terraform-provider-null_3.2.0_" + p.os + "_" + p.arch + ".zip"
+		path := filepath.Join(storage, filename)
+		if err := os.WriteFile(path, []byte("binary-"+p.os+"-"+p.arch), 0600); err != nil {
+			t.Fatalf("failed to write binary: %v", err)
+		}
+		db.Create(&models.ProviderPlatform{
+			ProviderID: provider.ID,
+			OS:         p.os,
+			Arch:       p.arch,
+			Filename:   filename,
+			FilePath:   path,
+		})
+	}
+	// A platform whose binary is gone is left out of the archive.
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "windows", Arch: "amd64",
+		Filename: "missing.zip", FilePath: filepath.Join(storage, "missing.zip")})
+
+	h := NewMirrorHandler(db, storage, nil, false)
+	router := gin.New()
+	router.GET("/export/:id", h.ExportProvider)
+	export := func() []byte {
+		req := httptest.NewRequest(http.MethodGet, "/export/"+strconv.FormatUint(uint64(provider.ID), 10), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		return w.Body.Bytes()
+	}
+
+	first := export()
+	second := export()
+	if !bytes.Equal(first, second) {
+		t.Fatal("two exports of the same provider differ")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatalf("zip.NewReader error = %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	want := []string{
+		"darwin/arm64/terraform-provider-null_3.2.0_darwin_arm64.zip",
+		"linux/amd64/terraform-provider-null_3.2.0_linux_amd64.zip",
+		"manifest.json",
+	}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+
+	gone := models.Provider{Namespace: "hashicorp", Name: "gone", Version: "1.0.0", SourceType: models.SourceMirror}
+	db.Create(&gone)
+	db.Create(&models.ProviderPlatform{ProviderID: gone.ID, OS: "linux", Arch: "amd64",
+		Filename: "gone.zip", FilePath: filepath.Join(storage, "gone.zip")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export/"+strconv.FormatUint(uint64(gone.ID), 10), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("all binaries missing: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCheckImportArchive(t *testing.T) {
+	archive := func(t *testing.T, build func(zw *zip.Writer)) *zip.Reader {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		build(zw)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return zr
+	}
+
+	ok := archive(t, func(zw *zip.Writer) {
+		w, _ := zw.Create("manifest.json")
+		_, _ = w.Write([]byte("{}"))
+	})
+	if err := checkImportArchive(ok); err != nil {
+		t.Errorf("small package rejected: %v", err)
+	}
+
+	tooMany := archive(t, func(zw *zip.Writer) {
+		for i := 0; i <= maxImportEntries; i++ {
+			_, _ = zw.Create("entry-" + strconv.Itoa(i))
+		}
+	})
+	if err := checkImportArchive(tooMany); err == nil {
+		t.Error("package with too many entries accepted")
+	}
+
+	// Headers that declare more data than the budget, as a zip bomb would.
+	tooLarge := archive(t, func(zw *zip.Writer) {
+		for _, name := range []string{"a", "b", "c"} {
+			_, _ = zw.CreateRaw(&zip.FileHeader{Name: name, Method: zip.Deflate, UncompressedSize64: maxImportTotalSize / 2})
+		}
+	})
+	if err := checkImportArchive(tooLarge); err == nil {
+		t.Error("package over the size budget accepted")
+	}
+}
+
+func TestImportProvider_VerifiesChecksums(t *testing.T) {
+	manifest := ProviderExportManifest{Namespace: "hashicorp", Name: "null", Version: "3.2.0", SourceType: "mirror", Protocols: `["5.0"]`}
+	var pkg bytes.Buffer
+	zw := zip.NewWriter(&pkg)
+	for _, p := range []struct{ os, arch string }{{"linux", "amd64"}, {"darwin", "arm64"}, {"windows", "amd64"}} {
+		filename := "terraform-provider-null_3.2.0_" + p.os + "_" + p.arch + ".zip"
+		content := []byte("binary-" + p.os + "-" + p.arch)
+		sum := sha256.Sum256(content)
+		pm := PlatformManifest{OS: p.os, Arch: p.arch, Filename: filename, SHA256Sum: hex.EncodeToString(sum[:]),
+			ZipPath: p.os + "/" + p.arch + "/" + filename}
+		if p.os == "windows" {
+			content = []byte("corrupted")
+		}
+		w, _ := zw.Create(pm.ZipPath)
+		_, _ = w.Write(content)
+		manifest.Platforms = append(manifest.Platforms, pm)
+	}
+	w, _ := zw.Create("manifest.json")
+	_ = json.NewEncoder(w).Encode(manifest)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	importPackage := func(t *testing.T, h *MirrorHandler, parallelism string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "null.zip")
+		_, _ = fw.Write(pkg.Bytes())
+		_ = mw.WriteField("parallelism", parallelism)
+		_ = mw.Close()
+
+		router := gin.New()
+		router.POST("/import", h.ImportProvider)
+		req := httptest.NewRequest(http.MethodPost, "/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("parallel import", func(t *testing.T) {
+		db := newTestDB(t)
+		storage := t.TempDir()
+		rec := importPackage(t, NewMirrorHandler(db, storage, nil, false), "3")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Verified int                `json:"verified_count"`
+			Rejected []RejectedPlatform `json:"rejected"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		if resp.Verified != 2 || len(resp.Rejected) != 1 || resp.Rejected[0].OS != "windows" ||
+			resp.Rejected[0].Reason != "checksum does not match manifest" {
+			t.Errorf("verified = %d, rejected = %+v; want 2 verified and windows rejected", resp.Verified, resp.Rejected)
+		}
+
+		var count int64
+		db.Model(&models.ProviderPlatform{}).Count(&count)
+		if count != 2 {
+			t.Errorf("platform rows = %d, want 2", count)
+		}
+		windowsDir := filepath.Join(storage, "hashicorp", "null", "3.2.0", "windows", "amd64")
+		if entries, _ := os.ReadDir(windowsDir); len(entries) != 0 {
+			t.Errorf("rejected platform left files behind: %v", entries)
+		}
+	})
+
+	t.Run("parallelism out of range", func(t *testing.T) {
+		rec := importPackage(t, NewMirrorHandler(newTestDB(t), t.TempDir(), nil, false), "99")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDownloadProvider_RedirectUncached(t *testing.T) {
+	const downloadURL = "https://releases.example.com/terraform-provider-aws_5.0.0_linux_amd64.zip"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip","download_url":"` + downloadURL + `","shasum":"abc"}`))
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	settings := models.Settings{RedirectUncached: true}
+	db.Create(&settings)
+	db.Model(&settings).Updates(map[string]interface{}{"allow_online_search": false})
+
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+
+	t.Run("binary redirects to upstream", func(t *testing.T) {
+		w := serveDownload(t, h)
+		if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != downloadURL {
+			t.Errorf("status = %d, location = %q; want 307 %q", w.Code, w.Header().Get("Location"), downloadURL)
+		}
+		var count int64
+		db.Model(&models.Provider{}).Count(&count)
+		if count != 0 {
+			t.Errorf("redirect mode must not cache providers, found %d", count)
+		}
+	})
+
+	t.Run("download info points at upstream", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", h.GetProviderDownloadInfo)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var body struct {
+			DownloadURL string `json:"download_url"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if body.DownloadURL != downloadURL {
+			t.Errorf("download_url = %q, want %q", body.DownloadURL, downloadURL)
+		}
+	})
+
+	t.Run("unknown upstream version is not found", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/9.9.9/download/linux/amd64/binary", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestParsePublishedAfter(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"2024-01-15", "2024-01-15T00:00:00Z", false},
+		{"2024-01-15T10:30:00+02:00", "2024-01-15T08:30:00Z", false},
+		{"15/01/2024", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parsePublishedAfter(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePublishedAfter(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("parsePublishedAfter(%q) = %v, want nil", tt.value, got)
+			}
+			continue
+		}
+		if got == nil || got.UTC().Format(time.RFC3339) != tt.want {
+			t.Errorf("parsePublishedAfter(%q) = %v, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePublishDate(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"included": [{"type": "provider-versions", "attributes": {"version": "5.0.0", "published-at": "2023-05-25T18:40:36Z"}}]}`))
+	}))
+	defer upstream.Close()
+	ps := proxy.NewProxyService(t.TempDir(), upstream.URL)
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	published, err := resolvePublishDate(context.Background(), ps, "hashicorp", "aws", "5.0.0", nil)
+	if err != nil || published.Year() != 2023 {
+		t.Errorf("no filter: published = %v, err = %v", published, err)
+	}
+	if _, err := resolvePublishDate(context.Background(), ps, "hashicorp", "aws", "5.0.0", &before); err != nil {
+		t.Errorf("newer version rejected: %v", err)
+	}
+	if _, err := resolvePublishDate(context.Background(), ps, "hashicorp", "aws", "5.0.0", &after); err == nil {
+		t.Error("older version accepted")
+	}
+	if _, err := resolvePublishDate(context.Background(), ps, "hashicorp", "aws", "4.0.0", &before); err == nil {
+		t.Error("version with unknown publish date accepted")
+	}
+}
+
+// newFakeUpstream serves hashicorp/aws 5.0.0 for linux/amd64 through the v1
+// registry protocol, with the publish date on the v2 provider endpoint.
+func newFakeUpstream(t *testing.T, publishedAt string) *httptest.Server {
+	t.Helper()
+	const binary = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of binary
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"}]}]}`))
+		case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+			_, _ = w.Write([]byte(`{"protocols":["5.0"],"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip","download_url":"` +
+				server.URL + `/binary","shasum":"` + sum + `"}`))
+		case "/v2/providers/hashicorp/aws":
+			_, _ = w.Write([]byte(`{"data":{"attributes":{"description":"AWS provider","tier":"official","logo-url":"/images/providers/aws.png"}},` +
+				`"included":[{"type":"provider-versions","attributes":{"version":"5.0.0","published-at":"` + publishedAt + `"}}]}`))
+		case "/binary":
+			_, _ = w.Write([]byte(binary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMirrorProvider_KeepsUpstreamPublishDate(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	want := time.Date(2023, 5, 25, 18, 40, 36, 0, time.UTC)
+
+	t.Run("mirror", func(t *testing.T) {
+		db := newTestDB(t)
+		storagePath := t.TempDir()
+		h := NewMirrorHandler(db, storagePath, nil, false)
+		h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+		router := gin.New()
+		router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?version=5.0.0", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+
+		var provider models.Provider
+		db.Where("namespace = ? AND name = ? AND version = ?", "hashicorp", "aws", "5.0.0").First(&provider)
+		if !provider.Published.Equal(want) {
+			t.Errorf("Published = %v, want %v", provider.Published, want)
+		}
+	})
+
+	t.Run("read-through download", func(t *testing.T) {
+		db := newTestDB(t)
+		storagePath := t.TempDir()
+		h := NewMirrorHandler(db, storagePath, nil, false)
+		h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+
+		w := serveDownload(t, h)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+
+		var provider models.Provider
+		db.Where("namespace = ? AND name = ? AND version = ?", "hashicorp", "aws", "5.0.0").First(&provider)
+		if !provider.Published.Equal(want) {
+			t.Errorf("Published = %v, want %v", provider.Published, want)
+		}
+	})
+
+	t.Run("published_after filter", func(t *testing.T) {
+		db := newTestDB(t)
+		storagePath := t.TempDir()
+		h := NewMirrorHandler(db, storagePath, nil, false)
+		h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+		router := gin.New()
+		router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?published_after=2024-01-01", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestGetProviderDownloadInfo_VersionFallback(t *testing.T) {
+	db := newTestDB(t)
+	offlineSettings(t, db)
+	for _, v := range []string{"4.9.0", "5.0.1", "5.1.0", "6.0.0"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v}
+		db.Create(&provider)
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: "terraform-provider-aws_" + v + "_linux_amd64.zip", FilePath: "/x", SHA256Sum: "sum-" + v})
+	}
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", h.GetProviderDownloadInfo)
+
+	get := func(version, constraint string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/"+version+"/download/linux/amd64", nil)
+		if constraint != "" {
+			req.Header.Set(versionConstraintHeader, constraint)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("5.0.0", "~> 5.0"); w.Code != http.StatusNotFound {
+		t.Errorf("fallback disabled: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	db.Model(&models.Settings{}).Where("1 = 1").Update("version_fallback", true)
+
+	tests := []struct {
+		version    string
+		constraint string
+		want       string
+	}{
+		{"5.0.0", "~> 5.0", "5.0.1"},
+		{"5.0.5", "~> 5.0", "5.1.0"},
+		{"5.9.0", "~> 5.0", "5.1.0"},
+		{"5.0.0", "< 5.0.0", "4.9.0"},
+		{"7.0.0", ">= 7.0", ""},
+		{"5.0.0", "", ""},
+	}
+	for _, tt := range tests {
+		w := get(tt.version, tt.constraint)
+		if tt.want == "" {
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s %q: status = %d, want %d", tt.version, tt.constraint, w.Code, http.StatusNotFound)
+			}
+			continue
+		}
+		var body struct {
+			Shasum      string `json:"shasum"`
+			DownloadURL string `json:"download_url"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusOK || body.Shasum != "sum-"+tt.want || w.Header().Get(resolvedVersionHeader) != tt.want {
+			t.Errorf("%s %q: status = %d, shasum = %q, resolved = %q; want %s",
+				tt.version, tt.constraint, w.Code, body.Shasum, w.Header().Get(resolvedVersionHeader), tt.want)
+		}
+		if !strings.Contains(body.DownloadURL, "/"+tt.want+"/") {
+			t.Errorf("%s %q: download_url = %q, want it to point at %s", tt.version, tt.constraint, body.DownloadURL, tt.want)
+		}
+	}
+}
+
+func TestMirrorProvider_RecordsUpstreamDetails(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	// An older version mirrored before tiers were stored picks them up too, and
+	// loses its placeholder description. An uploaded version keeps its own.
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.0.0",
+		SourceType: models.SourceMirror, Description: "Auto-cached from upstream"})
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.1.0",
+		SourceType: models.SourceUpload, Description: "Patched build"})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?version=5.0.0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var providers []models.Provider
+	db.Where("namespace = ? AND name = ?", "hashicorp", "aws").Find(&providers)
+	if len(providers) != 3 {
+		t.Fatalf("got %d providers, want 3", len(providers))
+	}
+	for _, p := range providers {
+		if p.Tier != "official" || p.LogoURL != "/images/providers/aws.png" {
+			t.Errorf("%s: Tier = %q, LogoURL = %q", p.Version, p.Tier, p.LogoURL)
+		}
+		wantDescription := "AWS provider"
+		if p.Version == "4.1.0" {
+			wantDescription = "Patched build"
+		}
+		if p.Description != wantDescription {
+			t.Errorf("%s: Description = %q, want %q", p.Version, p.Description, wantDescription)
+		}
+	}
+}
+
+func TestGetProviderVersionsDetail_Paginated(t *testing.T) {
+	db := newTestDB(t)
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v}
+		db.Create(&provider)
+		for _, arch := range []string{"amd64", "arm64"} {
+			db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: arch, Filename: "f", FilePath: "f", SHA256Sum: "s"})
+		}
+	}
+
+	router := gin.New()
+	router.GET("/providers/:namespace/:name", NewMirrorHandler(db, t.TempDir(), nil, false).GetProviderVersionsDetail)
+
+	get := func(query string) (int, []map[string]interface{}, float64) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/providers/hashicorp/aws"+query, nil))
+		var body struct {
+			Versions []map[string]interface{} `json:"versions"`
+			Total    float64                  `json:"total"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Versions, body.Total
+	}
+
+	code, versions, total := get("?limit=2&page=2")
+	if code != http.StatusOK || total != 3 || len(versions) != 1 {
+		t.Fatalf("page 2: status = %d, total = %v, versions = %d; want 200, 3, 1", code, total, len(versions))
+	}
+	if versions[0]["version"] != "1.0.0" || versions[0]["platform_count"] != float64(2) {
+		t.Errorf("page 2 version = %v", versions[0])
+	}
+	if _, ok := versions[0]["platforms"]; ok {
+		t.Error("paged versions should not include the platform list")
+	}
+
+	_, versions, _ = get("?version=1.1.0")
+	if len(versions) != 1 || versions[0]["version"] != "1.1.0" {
+		t.Fatalf("single version = %v", versions)
+	}
+	if platforms, _ := versions[0]["platforms"].([]interface{}); len(platforms) != 2 {
+		t.Errorf("single version platforms = %v, want 2", versions[0]["platforms"])
+	}
+
+	if code, _, _ := get("?version=9.9.9"); code != http.StatusNotFound {
+		t.Errorf("unknown version: status = %d, want 404", code)
+	}
+}
+
+func TestMirrorProviderWithProgress_RejectsUnsignedProvider(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z") // publishes no SHA256SUMS signature
+	db := newTestDB(t)
+	db.Create(&models.Settings{AllowOnlineSearch: true, VerifySignatures: true})
+
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.GET("/mirror/:namespace/:name/progress", h.MirrorProviderWithProgress)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/hashicorp/aws/progress?version=5.0.0", nil))
+	if body := w.Body.String(); !strings.Contains(body, `"type":"error"`) || !strings.Contains(body, proxy.ErrSignatureInvalid.Error()) {
+		t.Fatalf("stream has no signature error event: %s", body)
+	}
+
+	var count int64
+	db.Model(&models.ProviderPlatform{}).Count(&count)
+	if count != 0 {
+		t.Errorf("platforms saved = %d, want 0", count)
+	}
+}
+
+func TestMirrorProviderWithProgress_AbortsOnDisconnect(t *testing.T) {
+	started, aborted := make(chan struct{}), make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","platforms":[{"os":"linux","arch":"amd64"}]}]}`))
+		case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+			_, _ = w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip","download_url":"` +
+				server.URL + `/binary"}`))
+		case "/binary":
+			// Stream part of the binary, then stall until the download is abandoned.
+			_, _ = w.Write([]byte("provider-"))
+			w.(http.Flusher).Flush()
+			close(started)
+			select {
+			case <-r.Context().Done():
+				close(aborted)
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, server.URL)
+	router := gin.New()
+	router.GET("/mirror/:namespace/:name/progress", h.MirrorProviderWithProgress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/mirror/hashicorp/aws/progress?version=5.0.0&os=linux&arch=amd64", nil).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	<-started
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream download was not aborted after the client disconnected")
+	}
+	<-done
+
+	var count int64
+	db.Model(&models.ProviderPlatform{}).Count(&count)
+	if count != 0 {
+		t.Errorf("platforms saved = %d, want 0", count)
+	}
+}
+
+func TestDownloadPlatformsWithProgress_Concurrent(t *testing.T) {
+	const binary = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of binary
+	const concurrency = 2
+
+	// Binary downloads wait until concurrency of them are in flight, so the test
+	// only finishes promptly if the platforms really are fetched in parallel.
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	bothStarted := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/binary/") {
+			mu.Lock()
+			inFlight++
+			peak = max(peak, inFlight)
+			if inFlight == concurrency {
+				select {
+				case <-bothStarted:
+				default:
+					close(bothStarted)
+				}
+			}
+			mu.Unlock()
+			select {
+			case <-bothStarted:
+			case <-time.After(2 * time.Second):
+			}
+			_, _ = w.Write([]byte(binary))
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return
+		}
+		parts := strings.Split(r.URL.Path, "/") // /v1/providers/hashicorp/aws/5.0.0/download/{os}/{arch}
+		if len(parts) != 9 || parts[6] != "download" {
+			http.NotFound(w, r)
+			return
+		}
+		osType, arch := parts[7], parts[8]
+		_, _ = w.Write([]byte(`{"protocols":["5.0"],"os":"` + osType + `","arch":"` + arch + `",` +
+			`"filename":"terraform-provider-aws_5.0.0_` + osType + `_` + arch + `.zip",` +
+			`"download_url":"` + server.URL + `/binary/` + osType + `_` + arch + `","shasum":"` + sum + `"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{AllowOnlineSearch: true, MirrorConcurrency: concurrency})
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	proxyService := proxy.NewProxyService(storagePath, server.URL)
+
+	platforms := []platformInfo{
+		{OS: "linux", Arch: "amd64"}, {OS: "linux", Arch: "arm64"},
+		{OS: "darwin", Arch: "arm64"}, {OS: "windows", Arch: "amd64"},
+	}
+	var events []MirrorProgress
+	mirrored, totalBytes, _, err := h.downloadPlatformsWithProgress(context.Background(), proxyService, "hashicorp", "aws", "5.0.0", platforms,
+		func(p MirrorProgress) { events = append(events, p) })
+	if err != nil {
+		t.Fatalf("downloadPlatformsWithProgress: %v", err)
+	}
+
+	if peak != concurrency {
+		t.Errorf("peak concurrent downloads = %d, want %d", peak, concurrency)
+	}
+	if len(mirrored) != len(platforms) || totalBytes != int64(len(platforms)*len(binary)) {
+		t.Fatalf("mirrored %d platforms, %d bytes", len(mirrored), totalBytes)
+	}
+	for i, p := range mirrored {
+		if p.OS != platforms[i].OS || p.Arch != platforms[i].Arch {
+			t.Errorf("mirrored[%d] = %s/%s, want %s/%s", i, p.OS, p.Arch, platforms[i].OS, platforms[i].Arch)
+		}
+	}
+
+	current := 0
+	for _, e := range events {
+		if e.Current < current || e.Total != len(platforms) {
+			t.Fatalf("event %+v after current %d", e, current)
+		}
+		current = e.Current
+	}
+	if current != len(platforms) {
+		t.Errorf("last event current = %d, want %d", current, len(platforms))
+	}
+}
+
+func TestMirrorProvider_RecordsUpstreamProtocols(t *testing.T) {
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of "provider-binary"
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/awscc/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0","protocols":["6.0"],"platforms":[{"os":"linux","arch":"amd64"}]}]}`))
+		case "/v1/providers/hashicorp/awscc/1.0.0/download/linux/amd64":
+			_, _ = w.Write([]byte(`{"protocols":["6.0"],"os":"linux","arch":"amd64","filename":"terraform-provider-awscc_1.0.0_linux_amd64.zip","download_url":"` +
+				upstream.URL + `/binary","shasum":"` + sum + `"}`))
+		case "/binary":
+			_, _ = w.Write([]byte("provider-binary"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+	router.GET("/v1/providers/:namespace/:name/versions", h.GetProviderVersions)
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", h.GetProviderDownloadInfo)
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/awscc?version=1.0.0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("mirror: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var provider models.Provider
+	db.Where("namespace = ? AND name = ?", "hashicorp", "awscc").First(&provider)
+	if provider.Protocols != `["6.0"]` {
+		t.Errorf("stored protocols = %s, want [\"6.0\"]", provider.Protocols)
+	}
+	if body := get("/v1/providers/hashicorp/awscc/versions"); !strings.Contains(body, `"protocols":["6.0"]`) {
+		t.Errorf("versions = %s, want protocol 6.0 only", body)
+	}
+	if body := get("/v1/providers/hashicorp/awscc/1.0.0/download/linux/amd64"); !strings.Contains(body, `"protocols":["6.0"]`) {
+		t.Errorf("download info = %s, want protocol 6.0 only", body)
+	}
+}
+
+func TestMirrorProvider_ServesSigningMaterial(t *testing.T) {
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of "provider-binary"
+	const sums = sum + "  terraform-provider-aws_5.0.0_linux_amd64.zip\n"
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"}]}]}`))
+		case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+			_, _ = w.Write([]byte(`{"protocols":["5.0"],"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip",` +
+				`"download_url":"` + upstream.URL + `/binary","shasum":"` + sum + `",` +
+				`"shasums_url":"` + upstream.URL + `/SHA256SUMS","shasums_signature_url":"` + upstream.URL + `/SHA256SUMS.sig",` +
+				`"signing_keys":{"gpg_public_keys":[{"key_id":"34365D9472D7468F","ascii_armor":"upstream-key","source":"HashiCorp"}]}}`))
+		case "/binary":
+			_, _ = w.Write([]byte("provider-binary"))
+		case "/SHA256SUMS":
+			_, _ = w.Write([]byte(sums))
+		case "/SHA256SUMS.sig":
+			_, _ = w.Write([]byte("signature"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	// A trust store key upstream also lists is advertised once.
+	db.Create(&models.SigningKey{KeyID: "34365D9472D7468F", ASCIIArmor: "trusted-copy"})
+	db.Create(&models.SigningKey{KeyID: "0123456789ABCDEF", ASCIIArmor: "trusted-key"})
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", h.GetProviderDownloadInfo)
+	router.GET("/v1/providers/:namespace/:name/:version/sha256sums", h.GetProviderChecksums)
+	router.GET("/v1/providers/:namespace/:name/:version/sha256sums.sig", h.GetProviderChecksums)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?version=5.0.0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("mirror: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = get("/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64")
+	var info proxy.DownloadInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("download info: %v, body = %s", err, w.Body.String())
+	}
+	if info.SHA256SumsURL != "http://example.com/v1/providers/hashicorp/aws/5.0.0/sha256sums" ||
+		info.SHA256SumsSignature != info.SHA256SumsURL+".sig" {
+		t.Errorf("shasums URLs = %q, %q", info.SHA256SumsURL, info.SHA256SumsSignature)
+	}
+	keys := info.SigningKeys.GPGPublicKeys
+	if len(keys) != 2 || keys[0].ASCIIArmor != "upstream-key" || keys[0].Source != "HashiCorp" || keys[1].KeyID != "0123456789ABCDEF" {
+		t.Errorf("signing keys = %+v, want the upstream key, then the other trust store key", keys)
+	}
+
+	if w := get("/v1/providers/hashicorp/aws/5.0.0/sha256sums"); w.Code != http.StatusOK || w.Body.String() != sums {
+		t.Errorf("shasums: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if w := get("/v1/providers/hashicorp/aws/5.0.0/sha256sums.sig"); w.Code != http.StatusOK || w.Body.String() != "signature" {
+		t.Errorf("shasums.sig: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if w := get("/v1/providers/hashicorp/aws/4.0.0/sha256sums"); w.Code != http.StatusNotFound {
+		t.Errorf("uncached shasums: status = %d, want 404", w.Code)
+	}
+
+	// Deleting the version removes its keys and checksum files.
+	var provider models.Provider
+	db.Where("namespace = ? AND name = ?", "hashicorp", "aws").First(&provider)
+	retention.DeleteVersion(db, h.proxyService, &provider)
+	var count int64
+	db.Model(&models.ProviderSigningKey{}).Count(&count)
+	if count != 0 {
+		t.Errorf("signing keys left after delete = %d", count)
+	}
+	if w := get("/v1/providers/hashicorp/aws/5.0.0/sha256sums"); w.Code != http.StatusNotFound {
+		t.Errorf("shasums after delete: status = %d, want 404", w.Code)
+	}
+}
+
+func TestMirrorProvider_PartialFailure(t *testing.T) {
+	// windows/arm64 is listed upstream but its archive cannot be downloaded.
+	const binary = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of binary
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.Path; {
+		case path == "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","protocols":["5.0"],"platforms":[{"os":"linux","arch":"amd64"},{"os":"windows","arch":"arm64"}]}]}`))
+		case strings.HasPrefix(path, "/v1/providers/hashicorp/aws/5.0.0/download/"):
+			platform := strings.ReplaceAll(strings.TrimPrefix(path, "/v1/providers/hashicorp/aws/5.0.0/download/"), "/", "_")
+			_, _ = w.Write([]byte(`{"protocols":["5.0"],"filename":"terraform-provider-aws_5.0.0_` + platform + `.zip","download_url":"` +
+				server.URL + `/binary/` + platform + `","shasum":"` + sum + `"}`))
+		case path == "/binary/linux_amd64":
+			_, _ = w.Write([]byte(binary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, server.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+	router.GET("/mirror/:namespace/:name/stream", h.MirrorProviderWithProgress)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?os=all&arch=all", nil))
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want 207; body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Partial         bool             `json:"partial"`
+		FailedPlatforms []FailedPlatform `json:"failed_platforms"`
+		Platforms       []models.ProviderPlatform
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Partial || len(resp.FailedPlatforms) != 1 || resp.FailedPlatforms[0].OS != "windows" ||
+		resp.FailedPlatforms[0].Arch != "arm64" || resp.FailedPlatforms[0].Error == "" {
+		t.Errorf("partial = %v, failed_platforms = %+v; want windows/arm64 with its error", resp.Partial, resp.FailedPlatforms)
+	}
+	if len(resp.Platforms) != 1 || resp.Platforms[0].OS != "linux" {
+		t.Errorf("platforms = %+v, want linux/amd64", resp.Platforms)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/hashicorp/aws/stream?os=all&arch=all", nil))
+	var last MirrorProgress
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			if err := json.Unmarshal([]byte(data), &last); err != nil {
+				t.Fatalf("event %q: %v", data, err)
+			}
+		}
+	}
+	if last.Type != "complete" || !last.Partial || len(last.FailedPlatforms) != 1 || !strings.Contains(last.Message, "windows/arm64") {
+		t.Errorf("final event = %+v, want a partial completion naming windows/arm64", last)
+	}
+}
@@ -2,17 +2,24 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
+	"sync"
 
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // validIdentifierStrict is used to validate OS/Arch values from upstream APIs.
@@ -68,27 +75,107 @@ func validatePlatform(os, arch string) (string, string) {
 // This implements the protocol defined at:
 // https://developer.hashicorp.com/terraform/internals/provider-network-mirror-protocol
 type ProviderMirrorHandler struct {
-	db           *gorm.DB
-	storagePath  string
-	proxyService *proxy.ProxyService
+	db               *gorm.DB
+	storagePath      string
+	proxyService     *proxy.ProxyService
+	downloadSigner   *auth.DownloadSigner
+	allowedUpstreams map[string]bool
 }
 
+// upstreamOverrideHeader lets a client point one request at an alternate
+// upstream registry, e.g. to try a new source with a single terraform init.
+const upstreamOverrideHeader = "X-Upstream-Registry"
+
 // NewProviderMirrorHandler creates a new ProviderMirrorHandler instance.
-func NewProviderMirrorHandler(db *gorm.DB, storagePath string) *ProviderMirrorHandler {
+// downloadSigner may be nil, in which case download URLs carry no token.
+// allowedUpstreams lists the hosts accepted in the X-Upstream-Registry header.
+func NewProviderMirrorHandler(db *gorm.DB, storagePath string, downloadSigner *auth.DownloadSigner, allowedUpstreams []string) *ProviderMirrorHandler {
 	h := &ProviderMirrorHandler{
-		db:           db,
-		storagePath:  storagePath,
-		proxyService: proxy.NewProxyService(storagePath, ""),
+		db:               db,
+		storagePath:      storagePath,
+		proxyService:     proxy.NewProxyService(storagePath, ""),
+		downloadSigner:   downloadSigner,
+		allowedUpstreams: make(map[string]bool, len(allowedUpstreams)),
+	}
+	for _, host := range allowedUpstreams {
+		h.allowedUpstreams[strings.ToLower(host)] = true
 	}
 	h.refreshProxySettings()
 	return h
 }
 
+// parseUpstreamOverride turns an X-Upstream-Registry value into a registry base
+// URL. The value is a host ("registry.opentofu.org", implying https) or an
+// http(s) URL without credentials or path; its host must be in allowed.
+// It returns the HTTP status to answer with when the value is rejected.
+func parseUpstreamOverride(value string, allowed map[string]bool) (string, int, error) {
+	u, ok := parseRegistryURL(value)
+	if !ok {
+		return "", http.StatusBadRequest, fmt.Errorf("invalid %s header: must be a registry host or base URL", upstreamOverrideHeader)
+	}
+	if !allowed[strings.ToLower(u.Host)] {
+		return "", http.StatusForbidden, fmt.Errorf("upstream registry %q is not allowed", u.Host)
+	}
+	return u.Scheme + "://" + u.Host, 0, nil
+}
+
+// parseRegistryURL parses a registry host ("registry.opentofu.org", implying
+// https) or an http(s) base URL without credentials, path, query or fragment.
+func parseRegistryURL(value string) (*url.URL, bool) {
+	raw := value
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" ||
+		u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// normalizeRegistryURL returns the base URL of a registry given as accepted by
+// parseRegistryURL, for storing in MirrorConfig and Settings.
+func normalizeRegistryURL(value string) (string, error) {
+	u, ok := parseRegistryURL(value)
+	if !ok {
+		return "", fmt.Errorf("upstream URL %q must be a registry host or http(s) base URL without a path", value)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// upstreamFor returns the proxy service to use for this request: a temporary
+// one for an allowed X-Upstream-Registry override, else one for the upstream
+// scheduler.UpstreamFor resolves, else the handler's own. overridden reports
+// that the header chose the upstream; what such a request fetches is not
+// cached, as the storage, the provider records and the stored checksums are
+// keyed without the upstream. It answers the request and returns false when
+// the override is rejected.
+func (h *ProviderMirrorHandler) upstreamFor(c *gin.Context, settings models.Settings) (upstream *proxy.ProxyService, overridden, ok bool) {
+	upstreamURL := scheduler.UpstreamFor(h.db, c.Param("namespace"), c.Param("name"))
+	if value := c.GetHeader(upstreamOverrideHeader); value != "" {
+		override, status, err := parseUpstreamOverride(value, h.allowedUpstreams)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return nil, false, false
+		}
+		upstreamURL, overridden = override, true
+	}
+	if upstreamURL == "" || upstreamURL == h.proxyService.UpstreamURL() {
+		return h.proxyService, overridden, true
+	}
+	ps := proxy.NewProxyService(h.storagePath, upstreamURL)
+	applyProxySettings(ps, settings)
+	ps.SetVerifySignatures(settings.VerifySignatures)
+	return ps, overridden, true
+}
+
 // refreshProxySettings loads proxy settings from database and updates the proxy service.
 func (h *ProviderMirrorHandler) refreshProxySettings() {
 	var settings models.Settings
 	if err := h.db.First(&settings).Error; err == nil {
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
 	}
 }
 
@@ -115,9 +202,21 @@ func getHostAndScheme(c *gin.Context) (string, string) {
 	return host, scheme
 }
 
+// providerBinaryURL builds the URL of the /binary route for a provider platform.
+// When signer is non-nil a signed download token is appended as the "sig" parameter.
+func providerBinaryURL(scheme, host, namespace, name, version, osType, arch string, signer *auth.DownloadSigner) string {
+	downloadURL := scheme + "://" + host + "/v1/providers/" + namespace + "/" + name + "/" + version + "/download/" + osType + "/" + arch + "/binary"
+	if signer != nil {
+		downloadURL += "?sig=" + url.QueryEscape(signer.Sign(namespace, name, version, osType, arch))
+	}
+	return downloadURL
+}
+
 // ListAvailableVersions returns the index.json for a provider.
 // Path: /{hostname}/{namespace}/{name}/index.json
-// Always queries upstream (if allowed) and merges with local versions.
+// Always queries upstream (if allowed) and merges with local versions. Only the newest
+// Settings.MaxUpstreamVersions upstream versions are merged; local versions are always listed.
+// Upstream prereleases are left out unless enabled; see includePrereleases.
 func (h *ProviderMirrorHandler) ListAvailableVersions(c *gin.Context) {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
@@ -149,16 +248,26 @@ func (h *ProviderMirrorHandler) ListAvailableVersions(c *gin.Context) {
 	if err := h.db.First(&settings).Error; err == nil {
 		allowOnline = settings.AllowOnlineSearch
 		// Refresh proxy settings
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
+	}
+	prereleases, err := includePrereleases(c, settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	upstream, overridden, ok := h.upstreamFor(c, settings)
+	if !ok {
+		return
 	}
 
 	// Always try to get upstream versions if online search is allowed
 	if allowOnline {
-		upstreamVersions, err := h.proxyService.GetProviderVersions(namespace, name)
+		upstreamVersions, err := listUpstreamVersions(c, upstream, overridden, namespace, name)
 		if err == nil {
 			// Add upstream versions to the response
-			for _, v := range upstreamVersions.Versions {
-				versions[v.Version] = struct{}{}
+			for _, v := range proxy.NewestVersions(upstreamVersions.Versions, settings.MaxUpstreamVersions, prereleases) {
+				versions[v] = struct{}{}
 			}
 		}
 	}
@@ -177,6 +286,25 @@ func (h *ProviderMirrorHandler) ListAvailableVersions(c *gin.Context) {
 	})
 }
 
+// prereleasesParam overrides Settings.IncludePrereleases for one version listing.
+const prereleasesParam = "prereleases"
+
+// includePrereleases reports whether upstream prerelease versions are advertised
+// for this request: the prereleases query flag if given, otherwise the setting.
+// Versions already in the local database are listed either way, so a prerelease
+// that was mirrored explicitly stays installable.
+func includePrereleases(c *gin.Context, settings models.Settings) (bool, error) {
+	value := c.Query(prereleasesParam)
+	if value == "" {
+		return settings.IncludePrereleases, nil
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", prereleasesParam)
+	}
+	return include, nil
+}
+
 // GetVersionArchives returns the version.json for a specific provider version.
 // Path: /{hostname}/{namespace}/{name}/{version}.json
 // Always returns all platforms from upstream, using local cache info when available.
@@ -202,7 +330,12 @@ func (h *ProviderMirrorHandler) GetVersionArchives(c *gin.Context) {
 	allowOnline := true
 	if err := h.db.First(&settings).Error; err == nil {
 		allowOnline = settings.AllowOnlineSearch
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
+		h.proxyService.SetVerifySignatures(settings.VerifySignatures)
+	}
+	upstream, overridden, ok := h.upstreamFor(c, settings)
+	if !ok {
+		return
 	}
 
 	// Query local platforms for this provider version
@@ -221,12 +354,13 @@ func (h *ProviderMirrorHandler) GetVersionArchives(c *gin.Context) {
 
 	// If online search is allowed, get upstream platforms to ensure we have complete list
 	var upstreamPlatforms []proxy.Platform
+	var upstreamProtocols []string
 	if allowOnline {
-		upstreamVersions, err := h.proxyService.GetProviderVersions(namespace, name)
+		upstreamVersions, err := listUpstreamVersions(c, upstream, overridden, namespace, name)
 		if err == nil {
 			for _, v := range upstreamVersions.Versions {
 				if v.Version == version {
-					upstreamPlatforms = v.Platforms
+					upstreamPlatforms, upstreamProtocols = v.Platforms, v.Protocols
 					break
 				}
 			}
@@ -239,22 +373,38 @@ func (h *ProviderMirrorHandler) GetVersionArchives(c *gin.Context) {
 		return
 	}
 
+	if overridden && len(upstreamPlatforms) > 0 {
+		c.Header("Content-Type", "application/json")
+		c.JSON(http.StatusOK, gin.H{"archives": overrideArchives(c.Request.Context(), upstream, namespace, name, version, upstreamPlatforms)})
+		return
+	}
+
 	// Build archives map
 	archives := make(map[string]ArchiveInfo)
 
 	// If we have upstream platforms, use them as the complete list
 	if len(upstreamPlatforms) > 0 {
+		var upstreamSums map[string]string
+		for _, p := range upstreamPlatforms {
+			if localPlatformMap[p.OS+"_"+p.Arch].SHA256Sum == "" {
+				upstreamSums = upstreamChecksums(c.Request.Context(), h.db, upstream, namespace, name, version, p)
+				break
+			}
+		}
+
 		for _, p := range upstreamPlatforms {
 			key := p.OS + "_" + p.Arch
-			downloadURL := scheme + "://" + host + "/v1/providers/" + namespace + "/" + name + "/" + version + "/download/" + p.OS + "/" + p.Arch + "/binary"
+			downloadURL := providerBinaryURL(scheme, host, namespace, name, version, p.OS, p.Arch, h.downloadSigner)
 
 			archive := ArchiveInfo{
 				URL: downloadURL,
 			}
 
-			// If we have local cache, add the hash
+			// Prefer the hash of the cached archive, then the upstream SHA256SUMS
 			if localP, ok := localPlatformMap[key]; ok && localP.SHA256Sum != "" {
 				archive.Hashes = []string{"zh:" + localP.SHA256Sum}
+			} else if sum := upstreamSums[key]; sum != "" {
+				archive.Hashes = []string{"zh:" + sum}
 			}
 
 			archives[key] = archive
@@ -262,7 +412,7 @@ func (h *ProviderMirrorHandler) GetVersionArchives(c *gin.Context) {
 
 		// Trigger async caching if we don't have all platforms locally
 		if len(localPlatforms) < len(upstreamPlatforms) {
-			go h.asyncCacheProvider(namespace, name, version, upstreamPlatforms)
+			go h.asyncCacheProvider(upstream, namespace, name, version, upstreamProtocols, upstreamPlatforms)
 		}
 	} else {
 		// No upstream, use local platforms only
@@ -273,13 +423,117 @@ func (h *ProviderMirrorHandler) GetVersionArchives(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"archives": archives})
 }
 
+// listUpstreamVersions lists the versions of a provider at upstream, from the
+// shared versions cache unless the request asks for a refresh. An upstream
+// chosen by override is always queried and never cached.
+func listUpstreamVersions(c *gin.Context, upstream *proxy.ProxyService, overridden bool, namespace, name string) (*proxy.VersionsResponse, error) {
+	if overridden {
+		return upstream.GetProviderVersions(c.Request.Context(), namespace, name)
+	}
+	return upstream.GetProviderVersionsCached(c.Request.Context(), namespace, name, c.Query("refresh") == "true")
+}
+
+// overrideDownloadWorkers bounds the download info requests overrideArchives
+// makes at once.
+const overrideDownloadWorkers = 4
+
+// overrideArchives returns the archives of a provider version at an upstream
+// chosen by override, pointing at its own download URLs, so that each binary
+// comes from the registry its hash was read from and nothing is cached here.
+// Platforms whose download info cannot be fetched are left out.
+func overrideArchives(ctx context.Context, upstream *proxy.ProxyService, namespace, name, version string, platforms []proxy.Platform) map[string]ArchiveInfo {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		archives = make(map[string]ArchiveInfo, len(platforms))
+		sem      = make(chan struct{}, overrideDownloadWorkers)
+	)
+	for _, p := range platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			info, err := upstream.GetProviderDownloadInfo(ctx, namespace, name, version, p.OS, p.Arch)
+			if err != nil || info.DownloadURL == "" {
+				slog.Warn("Failed to fetch download info from override upstream",
+					"component", "MirrorProtocol",
+					"upstream", logsafe.Clean(upstream.UpstreamURL()),
+					"platform", logsafe.Clean(p.OS+"_"+p.Arch),
+					"error", logsafe.CleanErr(err))
+				return
+			}
+			archive := ArchiveInfo{URL: info.DownloadURL}
+			if info.SHA256Sum != "" {
+				archive.Hashes = []string{"zh:" + info.SHA256Sum}
+			}
+			mu.Lock()
+			archives[p.OS+"_"+p.Arch] = archive
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return archives
+}
+
+// upstreamChecksums returns the upstream archive checksums of a provider version,
+// keyed by os_arch. Stored checksums are used when present; otherwise the
+// SHA256SUMS file named in the download info of platform is fetched and stored.
+// Failures return nil, leaving the archives without hashes as before.
+func upstreamChecksums(ctx context.Context, db *gorm.DB, proxyService *proxy.ProxyService, namespace, name, version string, platform proxy.Platform) map[string]string {
+	var stored []models.UpstreamChecksum
+	db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).Find(&stored)
+	if len(stored) > 0 {
+		sums := make(map[string]string, len(stored))
+		for _, s := range stored {
+			sums[s.OS+"_"+s.Arch] = s.SHA256Sum
+		}
+		return sums
+	}
+
+	info, err := proxyService.GetProviderDownloadInfo(ctx, namespace, name, version, platform.OS, platform.Arch)
+	if err != nil || info.SHA256SumsURL == "" {
+		return nil
+	}
+	files, err := proxyService.GetSHA256Sums(ctx, info.SHA256SumsURL)
+	if err != nil {
+		slog.Warn("Failed to fetch upstream SHA256SUMS",
+			"component", "MirrorProtocol",
+			"namespace", logsafe.Clean(namespace),
+			"name", logsafe.Clean(name),
+			"version", logsafe.Clean(version),
+			"error", logsafe.CleanErr(err))
+		return nil
+	}
+
+	// Archives are named terraform-provider-<name>_<version>_<os>_<arch>.zip.
+	prefix := "terraform-provider-" + name + "_" + version + "_"
+	sums := make(map[string]string)
+	for filename, sum := range files {
+		rest, ok := strings.CutPrefix(filename, prefix)
+		if !ok || !strings.HasSuffix(rest, ".zip") {
+			continue
+		}
+		osType, arch, ok := strings.Cut(strings.TrimSuffix(rest, ".zip"), "_")
+		if !ok || !validIdentifierStrict.MatchString(osType) || !validIdentifierStrict.MatchString(arch) {
+			continue
+		}
+		sums[osType+"_"+arch] = sum
+		db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UpstreamChecksum{
+			Namespace: namespace, Name: name, Version: version, OS: osType, Arch: arch, SHA256Sum: sum,
+		})
+	}
+	return sums
+}
+
 // buildLocalArchives creates archive info from local platforms.
 func (h *ProviderMirrorHandler) buildLocalArchives(platforms []models.ProviderPlatform, namespace, name, version, host, scheme string) map[string]ArchiveInfo {
 	archives := make(map[string]ArchiveInfo)
 
 	for _, p := range platforms {
 		key := p.OS + "_" + p.Arch
-		downloadURL := scheme + "://" + host + "/v1/providers/" + namespace + "/" + name + "/" + version + "/download/" + p.OS + "/" + p.Arch + "/binary"
+		downloadURL := providerBinaryURL(scheme, host, namespace, name, version, p.OS, p.Arch, h.downloadSigner)
 
 		archive := ArchiveInfo{
 			URL: downloadURL,
@@ -295,13 +549,15 @@ func (h *ProviderMirrorHandler) buildLocalArchives(platforms []models.ProviderPl
 	return archives
 }
 
-// asyncCacheProvider downloads and caches a provider version in the background.
+// asyncCacheProvider downloads and caches a provider version in the background
+// from the upstream behind proxyService. protocols are the plugin protocols
+// upstream lists for the version.
 // Callers must have run validateProviderParams() on namespace/name/version first.
 // The raw values are used for database and upstream calls; logsafe.Clean copies are
 // used for every log record. Do not reassign the parameters to the cleaned values.
-func (h *ProviderMirrorHandler) asyncCacheProvider(namespace, name, version string, platforms []proxy.Platform) {
+func (h *ProviderMirrorHandler) asyncCacheProvider(proxyService *proxy.ProxyService, namespace, name, version string, protocols []string, platforms []proxy.Platform) {
 	// Log-only copies. The unscrubbed namespace/name/version must keep flowing to
-	// h.db and h.proxyService below.
+	// h.db and proxyService below.
 	logNS, logName, logVer := logsafe.Clean(namespace), logsafe.Clean(name), logsafe.Clean(version)
 
 	slog.Info("Starting background cache",
@@ -312,7 +568,11 @@ func (h *ProviderMirrorHandler) asyncCacheProvider(namespace, name, version stri
 		"platforms", len(platforms))
 
 	// Refresh proxy settings
-	h.refreshProxySettings()
+	var settings models.Settings
+	if err := h.db.First(&settings).Error; err == nil {
+		applyProxySettings(proxyService, settings)
+		proxyService.SetVerifySignatures(settings.VerifySignatures)
+	}
 
 	// Check if already cached (in case of race condition)
 	var existingProvider models.Provider
@@ -328,14 +588,13 @@ func (h *ProviderMirrorHandler) asyncCacheProvider(namespace, name, version stri
 
 	// Create provider record
 	provider := models.Provider{
-		Namespace:   namespace,
-		Name:        name,
-		Version:     version,
-		Description: "Async cached from upstream",
-		SourceType:  models.SourceMirror,
-		SourceURL:   "https://registry.terraform.io",
-		Protocols:   `["5.0", "6.0"]`,
-		Published:   time.Now(),
+		Namespace:  namespace,
+		Name:       name,
+		Version:    version,
+		SourceType: models.SourceMirror,
+		SourceURL:  proxyService.UpstreamURL(),
+		Protocols:  proxy.EncodeProtocols(protocols),
+		Published:  upstreamPublishDate(context.Background(), proxyService, namespace, name, version),
 	}
 
 	if err := h.db.Create(&provider).Error; err != nil {
@@ -345,25 +604,16 @@ func (h *ProviderMirrorHandler) asyncCacheProvider(namespace, name, version stri
 		return
 	}
 
+	scheduler.RecordUpstreamDetails(context.Background(), h.db, proxyService, namespace, name)
+
 	successCount := 0
 	for _, p := range platforms {
-		// Download and store each platform binary
-		downloadInfo, err := h.proxyService.GetProviderDownloadInfo(namespace, name, version, p.OS, p.Arch)
+		// Shares the download with concurrent requests for the platform and
+		// stores it only if it matches the checksum upstream publishes.
+		filePath, sha256sum, downloadInfo, err := proxyService.DownloadAndCacheProviderInfo(context.Background(),
+			namespace, name, version, p.OS, p.Arch)
 		if err != nil {
 			// Validate OS/Arch from upstream API before logging
-			safeOS, safeArch := validatePlatform(p.OS, p.Arch)
-			slog.Warn("Failed to get download info",
-				"component", "AsyncCache",
-				"os", safeOS,
-				"arch", safeArch,
-				"error", logsafe.CleanErr(err))
-			continue
-		}
-
-		filePath, sha256sum, err := h.proxyService.DownloadAndStoreProvider(
-			namespace, name, version, p.OS, p.Arch, downloadInfo.DownloadURL,
-		)
-		if err != nil {
 			safeOS, safeArch := validatePlatform(p.OS, p.Arch)
 			slog.Warn("Failed to download provider",
 				"component", "AsyncCache",
@@ -390,6 +640,10 @@ func (h *ProviderMirrorHandler) asyncCacheProvider(namespace, name, version stri
 			continue
 		}
 
+		// Every platform of a version shares its signing keys.
+		if successCount == 0 {
+			scheduler.RecordSigningKeys(h.db, provider.ID, downloadInfo.SigningKeys)
+		}
 		successCount++
 		safeOS, safeArch := validatePlatform(p.OS, p.Arch)
 		slog.Info("Cached provider platform",
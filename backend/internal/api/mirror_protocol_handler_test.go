@@ -2,7 +2,19 @@
 package api
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
 )
 
 func TestValidateProviderParams(t *testing.T) {
@@ -132,3 +144,318 @@ func TestValidIdentifierStrictRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestVersionListings_Prereleases(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/providers/hashicorp/aws/versions" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0"},{"version":"5.1.0-beta1"},{"version":"4.9.0"},{"version":"5.0.0-rc1"}]}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{AllowOnlineSearch: true})
+	// An explicitly mirrored prerelease of another provider stays listed.
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "google", Version: "6.0.0-alpha1"})
+
+	storagePath := t.TempDir()
+	protocol := NewProviderMirrorHandler(db, storagePath, nil, nil)
+	protocol.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	mirror := NewMirrorHandler(db, storagePath, nil, false)
+	mirror.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.GET("/mirror/:namespace/:name/index.json", protocol.ListAvailableVersions)
+	router.GET("/v1/providers/:namespace/:name/versions", mirror.GetProviderVersions)
+
+	// listed returns the versions in an index.json or Terraform versions response.
+	listed := func(path string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		var index struct {
+			Versions json.RawMessage `json:"versions"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &index)
+		var versions []string
+		var byVersion map[string]struct{}
+		if json.Unmarshal(index.Versions, &byVersion) == nil {
+			for v := range byVersion {
+				versions = append(versions, v)
+			}
+		} else {
+			var list []struct {
+				Version string `json:"version"`
+			}
+			_ = json.Unmarshal(index.Versions, &list)
+			for _, v := range list {
+				versions = append(versions, v.Version)
+			}
+		}
+		semver.SortDescending(versions)
+		return versions
+	}
+
+	stable := []string{"5.0.0", "4.9.0"}
+	all := []string{"5.1.0-beta1", "5.0.0", "5.0.0-rc1", "4.9.0"}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/mirror/hashicorp/aws/index.json", stable},
+		{"/mirror/hashicorp/aws/index.json?prereleases=true", all},
+		{"/v1/providers/hashicorp/aws/versions", stable},
+		{"/v1/providers/hashicorp/aws/versions?prereleases=1", all},
+		{"/v1/providers/hashicorp/google/versions", []string{"6.0.0-alpha1"}},
+	}
+	for _, tt := range tests {
+		if got := listed(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	db.Model(&models.Settings{}).Where("1 = 1").Update("include_prereleases", true)
+	if got := listed("/mirror/hashicorp/aws/index.json"); !reflect.DeepEqual(got, all) {
+		t.Errorf("setting on: index.json = %v, want %v", got, all)
+	}
+	if got := listed("/mirror/hashicorp/aws/index.json?prereleases=false"); !reflect.DeepEqual(got, stable) {
+		t.Errorf("setting on, flag off: index.json = %v, want %v", got, stable)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/hashicorp/aws/index.json?prereleases=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid flag: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestParseUpstreamOverride(t *testing.T) {
+	allowed := map[string]bool{"registry.opentofu.org": true, "mirror.local:8443": true}
+
+	tests := []struct {
+		value      string
+		want       string
+		wantStatus int
+	}{
+		{"registry.opentofu.org", "https://registry.opentofu.org", 0},
+		{"Registry.OpenTofu.org", "https://Registry.OpenTofu.org", 0},
+		{"https://registry.opentofu.org/", "https://registry.opentofu.org", 0},
+		{"http://mirror.local:8443", "http://mirror.local:8443", 0},
+		{"mirror.local", "", http.StatusForbidden},
+		{"evil.example.com", "", http.StatusForbidden},
+		{"registry.opentofu.org.evil.example.com", "", http.StatusForbidden},
+		{"https://user:pw@registry.opentofu.org", "", http.StatusBadRequest},
+		{"https://registry.opentofu.org/v1/providers", "", http.StatusBadRequest},
+		{"ftp://registry.opentofu.org", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, status, err := parseUpstreamOverride(tt.value, allowed)
+			if status != tt.wantStatus || got != tt.want {
+				t.Errorf("parseUpstreamOverride(%q) = %q, %d, %v; want %q, %d", tt.value, got, status, err, tt.want, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestListAvailableVersions_UpstreamOverride(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	upstreamURL, _ := url.Parse(upstream.URL)
+
+	db := newTestDB(t)
+	h := NewProviderMirrorHandler(db, t.TempDir(), nil, []string{upstreamURL.Host})
+	router := gin.New()
+	router.GET("/:namespace/:name/index.json", h.ListAvailableVersions)
+
+	get := func(override string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/hashicorp/aws/index.json", nil)
+		req.Header.Set(upstreamOverrideHeader, override)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(upstream.URL)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"5.0.0"`) {
+		t.Errorf("allowed override: status = %d, body = %s; want 200 listing 5.0.0", w.Code, w.Body.String())
+	}
+	if w := get("registry.example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("disallowed override: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := get("http://user:pw@" + upstreamURL.Host); w.Code != http.StatusBadRequest {
+		t.Errorf("override with credentials: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetVersionArchives_UpstreamChecksums(t *testing.T) {
+	cached := strings.Repeat("a", 64)
+	upstreamSum := strings.Repeat("b", 64)
+	sumsRequests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","platforms":[{"os":"linux","arch":"amd64"},{"os":"darwin","arch":"arm64"}]}]}`))
+		case "/v1/providers/hashicorp/aws/5.0.0/download/darwin/arm64":
+			_, _ = w.Write([]byte(`{"os":"darwin","arch":"arm64","shasums_url":"` + server.URL + `/SHA256SUMS"}`))
+		case "/SHA256SUMS":
+			sumsRequests++
+			_, _ = w.Write([]byte(cached + "  terraform-provider-aws_5.0.0_linux_amd64.zip\n" +
+				upstreamSum + "  terraform-provider-aws_5.0.0_darwin_arm64.zip\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	// A cached provider row also keeps the background cache from starting.
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+		Filename: "aws.zip", FilePath: "aws.zip", SHA256Sum: cached})
+
+	h := NewProviderMirrorHandler(db, storagePath, nil, nil)
+	h.proxyService = proxy.NewProxyService(storagePath, server.URL)
+	router := gin.New()
+	router.GET("/:namespace/:name/:version", h.GetVersionArchives)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hashicorp/aws/5.0.0.json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Archives map[string]ArchiveInfo `json:"archives"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got := body.Archives["linux_amd64"].Hashes; len(got) != 1 || got[0] != "zh:"+cached {
+			t.Errorf("linux_amd64 hashes = %v, want cached checksum", got)
+		}
+		if got := body.Archives["darwin_arm64"].Hashes; len(got) != 1 || got[0] != "zh:"+upstreamSum {
+			t.Errorf("darwin_arm64 hashes = %v, want upstream checksum", got)
+		}
+	}
+	if sumsRequests != 1 {
+		t.Errorf("SHA256SUMS fetched %d times, want 1 (stored after the first request)", sumsRequests)
+	}
+}
+
+func TestGetVersionArchives_Override(t *testing.T) {
+	defaultSum := strings.Repeat("a", 64)
+	overrideSum := strings.Repeat("b", 64)
+	var versionRequests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/versions":
+			versionRequests.Add(1)
+			_, _ = w.Write([]byte(`{"versions":[{"version":"5.0.0","platforms":[{"os":"linux","arch":"amd64"},{"os":"darwin","arch":"arm64"}]}]}`))
+		case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+			_, _ = w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip",` +
+				`"download_url":"` + server.URL + `/binary","shasum":"` + overrideSum + `"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	serverURL, _ := url.Parse(server.URL)
+
+	db := newTestDB(t)
+	// Checksums stored for the configured upstream must not answer for the override.
+	db.Create(&models.UpstreamChecksum{Namespace: "hashicorp", Name: "aws", Version: "5.0.0",
+		OS: "linux", Arch: "amd64", SHA256Sum: defaultSum})
+
+	h := NewProviderMirrorHandler(db, t.TempDir(), nil, []string{serverURL.Host})
+	router := gin.New()
+	router.GET("/:namespace/:name/:version", h.GetVersionArchives)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/hashicorp/aws/5.0.0.json", nil)
+		req.Header.Set(upstreamOverrideHeader, server.URL)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var body struct {
+			Archives map[string]ArchiveInfo `json:"archives"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		// The archive is downloaded from the override, where its hash came from.
+		want := ArchiveInfo{URL: server.URL + "/binary", Hashes: []string{"zh:" + overrideSum}}
+		if len(body.Archives) != 1 || !reflect.DeepEqual(body.Archives["linux_amd64"], want) {
+			t.Errorf("archives = %+v, want only linux_amd64 = %+v", body.Archives, want)
+		}
+	}
+	if n := versionRequests.Load(); n != 2 {
+		t.Errorf("upstream version list fetched %d times, want 2 (overrides are not cached)", n)
+	}
+
+	var providers, checksums int64
+	db.Model(&models.Provider{}).Count(&providers)
+	db.Model(&models.UpstreamChecksum{}).Count(&checksums)
+	if providers != 0 || checksums != 1 {
+		t.Errorf("providers = %d, stored checksums = %d; want nothing persisted beyond the configured upstream's", providers, checksums)
+	}
+}
+
+func TestAsyncCacheProvider_VerifiesChecksum(t *testing.T) {
+	platforms := []proxy.Platform{{OS: "linux", Arch: "amd64"}}
+
+	t.Run("matching binary", func(t *testing.T) {
+		upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+		db := newTestDB(t)
+		storagePath := t.TempDir()
+		h := NewProviderMirrorHandler(db, storagePath, nil, nil)
+		h.asyncCacheProvider(proxy.NewProxyService(storagePath, upstream.URL), "hashicorp", "aws", "5.0.0", []string{"5.0"}, platforms)
+
+		var platform models.ProviderPlatform
+		if err := db.First(&platform).Error; err != nil {
+			t.Fatalf("platform not cached: %v", err)
+		}
+		if platform.SHA256Sum != "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" {
+			t.Errorf("sha256sum = %q", platform.SHA256Sum)
+		}
+	})
+
+	t.Run("tampered binary", func(t *testing.T) {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+				_, _ = w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.0.0_linux_amd64.zip","download_url":"` +
+					server.URL + `/binary","shasum":"` + strings.Repeat("a", 64) + `"}`))
+			case "/binary":
+				_, _ = w.Write([]byte("tampered-binary"))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(server.Close)
+		db := newTestDB(t)
+		storagePath := t.TempDir()
+		h := NewProviderMirrorHandler(db, storagePath, nil, nil)
+		h.asyncCacheProvider(proxy.NewProxyService(storagePath, server.URL), "hashicorp", "aws", "5.0.0", []string{"5.0"}, platforms)
+
+		var count int64
+		db.Model(&models.ProviderPlatform{}).Count(&count)
+		if count != 0 {
+			t.Errorf("platforms = %d, want a binary that fails its checksum not cached", count)
+		}
+		if _, cached := proxy.NewProxyService(storagePath, server.URL).GetCachedFilePath("hashicorp", "aws", "5.0.0", "linux", "amd64"); cached {
+			t.Error("binary that fails its checksum stored")
+		}
+	})
+}
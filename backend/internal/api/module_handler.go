@@ -0,0 +1,305 @@
+// Package api provides HTTP handlers for publishing and serving Terraform modules.
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// moduleStorageDir holds module archives under the storage path. Provider
+	// namespaces must start with an alphanumeric, so it cannot collide with one.
+	moduleStorageDir = "_modules"
+	// multipartOverhead is allowed on top of the archive size for form fields
+	// and multipart boundaries.
+	multipartOverhead = 1 << 20
+)
+
+var (
+	errModuleArchive  = errors.New("file is not a gzip-compressed tar archive")
+	errModuleTooLarge = errors.New("module archive is too large")
+)
+
+// ModuleHandler publishes module archives and serves them over the Terraform
+// module registry protocol.
+type ModuleHandler struct {
+	db                 *gorm.DB
+	storagePath        string
+	maxSize            int64
+	namespaceOwnership bool
+}
+
+// NewModuleHandler creates a new ModuleHandler instance. Uploaded archives larger
+// than maxSize bytes are rejected.
+func NewModuleHandler(db *gorm.DB, storagePath string, maxSize int64, namespaceOwnership bool) *ModuleHandler {
+	return &ModuleHandler{db: db, storagePath: storagePath, maxSize: maxSize, namespaceOwnership: namespaceOwnership}
+}
+
+// buildModulePath validates the module address and returns the directory its
+// archive is stored in, in the same way proxy.BuildSafeProviderPath does for
+// provider platforms.
+func buildModulePath(storagePath, namespace, name, provider, version string) (string, error) {
+	components := []struct{ field, value string }{
+		{"namespace", namespace}, {"name", name}, {"provider", provider}, {"version", version},
+	}
+	dir := filepath.Join(storagePath, moduleStorageDir)
+	for _, c := range components {
+		safe, err := proxy.SanitizePathComponent(c.value)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s: %w", c.field, err)
+		}
+		dir = filepath.Join(dir, safe)
+	}
+	return dir, nil
+}
+
+// moduleArchiveName returns the file name of a stored module archive.
+func moduleArchiveName(name, provider, version string) string {
+	return fmt.Sprintf("%s-%s-%s.tar.gz", name, provider, version)
+}
+
+// UploadModule publishes a module version from a .tar.gz of its source, sent as
+// the "file" form field with namespace, name, provider and version. Existing
+// versions are never replaced; publish a new version instead.
+func (h *ModuleHandler) UploadModule(c *gin.Context) {
+	namespace := c.PostForm("namespace")
+	name := c.PostForm("name")
+	provider := c.PostForm("provider")
+	version := c.PostForm("version")
+
+	if namespace == "" || name == "" || provider == "" || version == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace, name, provider, and version are required"})
+		return
+	}
+	if _, valid := semver.Parse(version); !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a semantic version"})
+		return
+	}
+	dir, err := buildModulePath(h.storagePath, namespace, name, provider, version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.namespaceOwnership && !canWriteNamespace(h.db, c, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{"error": namespaceForbidden(namespace)})
+		return
+	}
+
+	// Soft-deleted versions still hold their place in the unique index.
+	var existing int64
+	h.db.Unscoped().Model(&models.Module{}).
+		Where("namespace = ? AND name = ? AND provider = ? AND version = ?", namespace, name, provider, version).
+		Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("module %s/%s/%s version %s already exists", namespace, name, provider, version),
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("module archive exceeds %d bytes", h.maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer func() { _ = file.Close() }()
+	if header.Size > h.maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("module archive exceeds %d bytes", h.maxSize)})
+		return
+	}
+
+	filePath, sha256sum, size, err := storeModuleArchive(dir, moduleArchiveName(name, provider, version), file, h.maxSize)
+	switch {
+	case errors.Is(err, errModuleTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("module archive exceeds %d bytes", h.maxSize)})
+		return
+	case errors.Is(err, errModuleArchive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	module := models.Module{
+		Namespace:   namespace,
+		Name:        name,
+		Provider:    provider,
+		Version:     version,
+		Description: c.PostForm("description"),
+		Source:      c.PostForm("source"),
+		Published:   time.Now(),
+		FilePath:    filePath,
+		SHA256Sum:   sha256sum,
+		FileSize:    size,
+	}
+	if err := h.db.Create(&module).Error; err != nil {
+		_ = os.Remove(filePath) // #nosec G104 - best effort cleanup
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save module"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Module uploaded successfully",
+		"module":  module,
+	})
+}
+
+// storeModuleArchive writes r to dir/filename, reading at most maxSize bytes,
+// and keeps it only if it is a gzip-compressed tar archive. It returns the path,
+// SHA256 checksum and size of the stored file.
+func storeModuleArchive(dir, filename string, r io.Reader, maxSize int64) (string, string, int64, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	filePath := filepath.Join(dir, filename)
+	partPath := filePath + ".part"
+	out, err := os.Create(partPath) // #nosec G304 - path is built from validated components
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	hasher := sha256.New()
+	size, copyErr := io.Copy(io.MultiWriter(out, hasher), io.LimitReader(r, maxSize+1))
+	closeErr := out.Close()
+	switch {
+	case copyErr != nil:
+		err = fmt.Errorf("failed to write file: %w", copyErr)
+	case closeErr != nil:
+		err = fmt.Errorf("failed to write file: %w", closeErr)
+	case size > maxSize:
+		err = errModuleTooLarge
+	default:
+		err = validateModuleArchive(partPath)
+	}
+	if err == nil {
+		err = os.Rename(partPath, filePath)
+	}
+	if err != nil {
+		_ = os.Remove(partPath) // #nosec G104 - best effort cleanup
+		return "", "", 0, err
+	}
+	return filePath, hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// validateModuleArchive checks that path is a non-empty gzip-compressed tar
+// archive whose entries all stay inside the directory it is unpacked into.
+func validateModuleArchive(path string) error {
+	f, err := os.Open(path) // #nosec G304 - path is built from validated components
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errModuleArchive
+	}
+	tr := tar.NewReader(gz)
+	entries := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return errModuleArchive
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("%w: entry %q escapes the module directory", errModuleArchive, hdr.Name)
+		}
+		entries++
+	}
+	if entries == 0 {
+		return fmt.Errorf("%w: archive is empty", errModuleArchive)
+	}
+	return nil
+}
+
+// findModule loads the module version addressed by the request path.
+func (h *ModuleHandler) findModule(c *gin.Context) (models.Module, bool) {
+	var module models.Module
+	err := h.db.Where("namespace = ? AND name = ? AND provider = ? AND version = ?",
+		c.Param("namespace"), c.Param("name"), c.Param("provider"), c.Param("version")).First(&module).Error
+	if err != nil || module.FilePath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "module not found"})
+		return module, false
+	}
+	return module, true
+}
+
+// ListModuleVersions returns the published versions of a module, newest first,
+// following the Terraform module registry protocol.
+func (h *ModuleHandler) ListModuleVersions(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	provider := c.Param("provider")
+
+	var versions []string
+	h.db.Model(&models.Module{}).
+		Where("namespace = ? AND name = ? AND provider = ? AND file_path <> ''", namespace, name, provider).
+		Pluck("version", &versions)
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "module not found"})
+		return
+	}
+	semver.SortDescending(versions)
+
+	list := make([]gin.H, 0, len(versions))
+	for _, v := range versions {
+		list = append(list, gin.H{"version": v})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"modules": []gin.H{{
+			"source":   namespace + "/" + name + "/" + provider,
+			"versions": list,
+		}},
+	})
+}
+
+// DownloadModule answers the Terraform module registry download request with
+// 204 and the archive location in X-Terraform-Get, counting the download.
+func (h *ModuleHandler) DownloadModule(c *gin.Context) {
+	module, ok := h.findModule(c)
+	if !ok {
+		return
+	}
+	h.db.Model(&models.Module{}).Where("id = ?", module.ID).
+		UpdateColumn("downloads", gorm.Expr("downloads + ?", 1))
+
+	host, scheme := getHostAndScheme(c)
+	c.Header("X-Terraform-Get", fmt.Sprintf("%s://%s/v1/modules/%s/%s/%s/%s/archive.tar.gz",
+		scheme, host, module.Namespace, module.Name, module.Provider, module.Version))
+	c.Status(http.StatusNoContent)
+}
+
+// ServeModuleArchive serves the stored .tar.gz of a module version.
+func (h *ModuleHandler) ServeModuleArchive(c *gin.Context) {
+	module, ok := h.findModule(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", "application/gzip")
+	c.FileAttachment(module.FilePath, filepath.Base(module.FilePath))
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// moduleTarball returns a .tar.gz holding the given files.
+func moduleTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestModuleHandler_UploadAndServe(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewModuleHandler(db, storagePath, 4096, false)
+	router := gin.New()
+	router.POST("/modules", h.UploadModule)
+	router.GET("/v1/modules/:namespace/:name/:provider/versions", h.ListModuleVersions)
+	router.GET("/v1/modules/:namespace/:name/:provider/:version/download", h.DownloadModule)
+	router.GET("/v1/modules/:namespace/:name/:provider/:version/archive.tar.gz", h.ServeModuleArchive)
+
+	upload := func(version string, archive []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for field, value := range map[string]string{"namespace": "acme", "name": "network", "provider": "aws", "version": version} {
+			_ = mw.WriteField(field, value)
+		}
+		part, _ := mw.CreateFormFile("file", "module.tar.gz")
+		_, _ = part.Write(archive)
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/modules", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	archive := moduleTarball(t, map[string]string{"main.tf": `variable "cidr" {}`})
+	if w := upload("1.0.0", archive); w.Code != http.StatusCreated {
+		t.Fatalf("upload: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var module models.Module
+	db.Where("namespace = ? AND name = ? AND provider = ? AND version = ?", "acme", "network", "aws", "1.0.0").First(&module)
+	if module.FileSize != int64(len(archive)) || len(module.SHA256Sum) != 64 {
+		t.Errorf("module row: size = %d, sha256 = %q", module.FileSize, module.SHA256Sum)
+	}
+	if !strings.HasPrefix(module.FilePath, filepath.Join(storagePath, moduleStorageDir)) {
+		t.Errorf("FilePath = %q, want under %s", module.FilePath, moduleStorageDir)
+	}
+
+	rejected := []struct {
+		name    string
+		version string
+		archive []byte
+		want    int
+	}{
+		{"duplicate version", "1.0.0", archive, http.StatusConflict},
+		{"not gzip", "1.1.0", []byte("plain text"), http.StatusBadRequest},
+		{"gzip but not tar", "1.1.0", gzipped(t, "plain text"), http.StatusBadRequest},
+		{"entry escapes", "1.1.0", moduleTarball(t, map[string]string{"../evil.tf": "x"}), http.StatusBadRequest},
+		{"too large", "1.1.0", bytes.Repeat([]byte("x"), 8192), http.StatusRequestEntityTooLarge},
+		{"invalid version", "latest", archive, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		if w := upload(tt.version, tt.archive); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body = %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+	dir := filepath.Join(storagePath, moduleStorageDir, "acme", "network", "aws", "1.1.0")
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("rejected uploads left %d files behind", len(entries))
+	}
+
+	if w := upload("1.2.0", archive); w.Code != http.StatusCreated {
+		t.Fatalf("second upload: status = %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/acme/network/aws/versions", nil))
+	var versions struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &versions)
+	if len(versions.Modules) != 1 || len(versions.Modules[0].Versions) != 2 || versions.Modules[0].Versions[0].Version != "1.2.0" {
+		t.Errorf("versions: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/acme/network/aws/1.0.0/download", nil))
+	location := w.Header().Get("X-Terraform-Get")
+	if w.Code != http.StatusNoContent || !strings.HasSuffix(location, "/v1/modules/acme/network/aws/1.0.0/archive.tar.gz") {
+		t.Fatalf("download: status = %d, X-Terraform-Get = %q", w.Code, location)
+	}
+	db.First(&module, module.ID)
+	if module.Downloads != 1 {
+		t.Errorf("Downloads = %d, want 1", module.Downloads)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/acme/network/aws/1.0.0/archive.tar.gz", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), archive) {
+		t.Errorf("archive: status = %d, %d bytes", w.Code, w.Body.Len())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/modules/acme/network/aws/9.9.9/download", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown version: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// gzipped returns content compressed with gzip.
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(content))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,131 @@
+// Package api provides HTTP handlers for namespace ownership.
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NamespaceHandler manages which users own which provider namespaces.
+type NamespaceHandler struct {
+	db *gorm.DB
+}
+
+// NewNamespaceHandler creates a new NamespaceHandler instance.
+func NewNamespaceHandler(db *gorm.DB) *NamespaceHandler {
+	return &NamespaceHandler{db: db}
+}
+
+// canWriteNamespace reports whether the authenticated user may publish into namespace.
+// Admins may write to any namespace; other users need a NamespaceOwner grant.
+func canWriteNamespace(db *gorm.DB, c *gin.Context, namespace string) bool {
+	if role, _ := c.Get("role"); role == "admin" {
+		return true
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return false
+	}
+	var count int64
+	db.Model(&models.NamespaceOwner{}).
+		Where("namespace = ? AND user_id = ?", namespace, userID).
+		Count(&count)
+	return count > 0
+}
+
+// namespaceForbidden returns the error message for a denied namespace write.
+func namespaceForbidden(namespace string) string {
+	return fmt.Sprintf("no write access to namespace %q", namespace)
+}
+
+// authorizeNamespace enforces namespace ownership when it is enabled, writing a
+// 403 response and returning false if the user may not publish into namespace.
+func (h *MirrorHandler) authorizeNamespace(c *gin.Context, namespace string) bool {
+	if !h.namespaceOwnership || canWriteNamespace(h.db, c, namespace) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": namespaceForbidden(namespace)})
+	return false
+}
+
+// NamespaceOwnerInfo describes a user with write access to a namespace.
+type NamespaceOwnerInfo struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// ListOwners lists the users that own a namespace.
+func (h *NamespaceHandler) ListOwners(c *gin.Context) {
+	namespace := c.Param("namespace")
+	if len(namespace) > 64 || !validIdentifier.MatchString(namespace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid namespace"})
+		return
+	}
+
+	owners := make([]NamespaceOwnerInfo, 0)
+	err := h.db.Model(&models.NamespaceOwner{}).
+		Select("namespace_owners.user_id as user_id, users.username as username").
+		Joins("JOIN users ON users.id = namespace_owners.user_id AND users.deleted_at IS NULL").
+		Where("namespace_owners.namespace = ?", namespace).
+		Order("users.username").
+		Scan(&owners).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load namespace owners"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"namespace": namespace, "owners": owners})
+}
+
+// AddOwner grants a user write access to a namespace. Granting an existing owner is a no-op.
+func (h *NamespaceHandler) AddOwner(c *gin.Context) {
+	namespace := c.Param("namespace")
+	if len(namespace) > 64 || !validIdentifier.MatchString(namespace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid namespace"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	owner := models.NamespaceOwner{Namespace: namespace, UserID: user.ID}
+	if err := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&owner).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add namespace owner"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s now owns namespace %s", user.Username, namespace),
+	})
+}
+
+// RemoveOwner revokes a user's write access to a namespace.
+func (h *NamespaceHandler) RemoveOwner(c *gin.Context) {
+	namespace := c.Param("namespace")
+
+	var user models.User
+	if err := h.db.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	result := h.db.Where("namespace = ? AND user_id = ?", namespace, user.ID).Delete(&models.NamespaceOwner{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove namespace owner"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User does not own this namespace"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Namespace owner removed"})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadProvider_NamespaceOwnership(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.NamespaceOwner{Namespace: "acme", UserID: 2})
+
+	form := url.Values{
+		"namespace": {"acme"},
+		"name":      {"widget"},
+		"version":   {"1.0.0"},
+		"os":        {"linux"},
+		"arch":      {"amd64"},
+	}
+
+	tests := []struct {
+		name     string
+		enforce  bool
+		userID   uint
+		role     string
+		wantCode int
+	}{
+		{"not enforced", false, 3, "user", http.StatusBadRequest},
+		{"admin bypasses", true, 1, "admin", http.StatusBadRequest},
+		{"owner", true, 2, "user", http.StatusBadRequest},
+		{"not owner", true, 3, "user", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewMirrorHandler(db, t.TempDir(), nil, tt.enforce)
+			router := gin.New()
+			router.POST("/upload", func(c *gin.Context) {
+				c.Set("user_id", tt.userID)
+				c.Set("role", tt.role)
+			}, h.UploadProvider)
+
+			req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Allowed requests get past the ownership check and fail on the missing file.
+			if w.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestNamespaceHandler_Owners(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.User{Username: "alice", Email: "alice@example.com", Password: "x"})
+
+	h := NewNamespaceHandler(db)
+	router := gin.New()
+	router.GET("/namespaces/:namespace/owners", h.ListOwners)
+	router.PUT("/namespaces/:namespace/owners/:username", h.AddOwner)
+	router.DELETE("/namespaces/:namespace/owners/:username", h.RemoveOwner)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := do(http.MethodPut, "/namespaces/acme/owners/alice"); w.Code != http.StatusOK {
+		t.Fatalf("add owner status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := do(http.MethodPut, "/namespaces/acme/owners/alice"); w.Code != http.StatusOK {
+		t.Errorf("re-adding owner status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := do(http.MethodPut, "/namespaces/acme/owners/bob"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(http.MethodGet, "/namespaces/acme/owners"); !strings.Contains(w.Body.String(), `"username":"alice"`) {
+		t.Errorf("owners = %s, want alice listed", w.Body.String())
+	}
+	if w := do(http.MethodDelete, "/namespaces/acme/owners/alice"); w.Code != http.StatusOK {
+		t.Errorf("remove owner status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := do(http.MethodDelete, "/namespaces/acme/owners/alice"); w.Code != http.StatusNotFound {
+		t.Errorf("removing again status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
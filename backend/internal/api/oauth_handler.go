@@ -0,0 +1,65 @@
+// Package api provides the OAuth token endpoint for terraform login.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// oauthClientID is the OAuth client the login.v1 service is advertised for,
+// the ID Terraform and OpenTofu send when logging in.
+const oauthClientID = "terraform-cli"
+
+// oauthTokenPath is the OAuth token endpoint of the login.v1 service.
+const oauthTokenPath = "/oauth/token"
+
+// oauthError answers a token request with an RFC 6749 error response.
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// OAuthToken is the token endpoint of the login.v1 service. It implements the
+// OAuth password grant that terraform login and tofu login use when the
+// discovery document offers it, and answers with an API token for the CLI
+// credentials file, so the login does not expire. The token replaces the
+// user's previous API token, as CreateAPIToken does.
+func (h *AuthHandler) OAuthToken(c *gin.Context) {
+	if c.PostForm("grant_type") != "password" {
+		oauthError(c, http.StatusBadRequest, "unsupported_grant_type", "only the password grant is supported")
+		return
+	}
+	clientID := c.PostForm("client_id")
+	if id, _, ok := c.Request.BasicAuth(); ok {
+		clientID = id
+	}
+	if clientID != oauthClientID {
+		oauthError(c, http.StatusUnauthorized, "invalid_client", "unknown client")
+		return
+	}
+	username, password := c.PostForm("username"), c.PostForm("password")
+	if username == "" || password == "" {
+		oauthError(c, http.StatusBadRequest, "invalid_request", "username and password are required")
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("username = ?", username).First(&user).Error; err != nil || !auth.CheckPassword(password, user.Password) {
+		oauthError(c, http.StatusBadRequest, "invalid_grant", "invalid credentials")
+		return
+	}
+
+	token, msg := h.issueAPIToken(&user)
+	if msg != "" {
+		oauthError(c, http.StatusInternalServerError, "server_error", "failed to generate token")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "bearer",
+	})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestDiscovery(t *testing.T) {
+	discover := func(t *testing.T, authEnabled bool, header http.Header) map[string]interface{} {
+		t.Helper()
+		cfg := testConfig(t)
+		cfg.Auth.Enabled = authEnabled
+		router := SetupRouter(newTestDB(t), auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, cfg)
+		req := httptest.NewRequest(http.MethodGet, "http://registry.internal/.well-known/terraform.json", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var doc map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("decode discovery document: %v", err)
+		}
+		return doc
+	}
+
+	doc := discover(t, false, nil)
+	if doc["providers.v1"] != "/v1/providers/" || doc["modules.v1"] != "/v1/modules/" {
+		t.Errorf("services = %v", doc)
+	}
+	if doc["metadata.v1"] != "http://registry.internal/" {
+		t.Errorf("metadata.v1 = %v, want the plain-HTTP request host", doc["metadata.v1"])
+	}
+	if _, ok := doc["login.v1"]; ok {
+		t.Error("login.v1 advertised with auth disabled")
+	}
+
+	doc = discover(t, true, http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"registry.example.com"}})
+	if doc["metadata.v1"] != "https://registry.example.com/" {
+		t.Errorf("metadata.v1 = %v, want the forwarded host", doc["metadata.v1"])
+	}
+	login, _ := doc["login.v1"].(map[string]interface{})
+	if login["client"] != oauthClientID || login["token"] != oauthTokenPath {
+		t.Errorf("login.v1 = %v", doc["login.v1"])
+	}
+}
+
+func TestOAuthToken(t *testing.T) {
+	db := newTestDB(t)
+	hash, _ := auth.HashPassword("ci-password")
+	db.Create(&models.User{Username: "ci", Email: "ci@example.com", Password: hash, Role: "user"})
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+
+	post := func(form url.Values, basicClient string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, oauthTokenPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if basicClient != "" {
+			req.SetBasicAuth(basicClient, "")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	grant := func(password string) url.Values {
+		return url.Values{"grant_type": {"password"}, "client_id": {oauthClientID}, "username": {"ci"}, "password": {password}}
+	}
+
+	w := post(grant("ci-password"), "")
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.TokenType != "bearer" {
+		t.Fatalf("token: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if !auth.IsAPIToken(resp.AccessToken) {
+		t.Fatalf("access token = %q, want a long-lived API token", resp.AccessToken)
+	}
+	if claims, err := jwtManager.Authenticate(resp.AccessToken); err != nil || claims.Username != "ci" {
+		t.Errorf("access token = %+v, %v", claims, err)
+	}
+
+	// Clients may authenticate with HTTP basic auth instead of client_id.
+	form := grant("ci-password")
+	form.Del("client_id")
+	if w := post(form, oauthClientID); w.Code != http.StatusOK {
+		t.Errorf("basic auth client: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+		code   string
+	}{
+		{"wrong password", grant("nope"), http.StatusBadRequest, "invalid_grant"},
+		{"unknown client", url.Values{"grant_type": {"password"}, "client_id": {"other"}, "username": {"ci"}, "password": {"ci-password"}}, http.StatusUnauthorized, "invalid_client"},
+		{"authorization code grant", url.Values{"grant_type": {"authorization_code"}, "client_id": {oauthClientID}}, http.StatusBadRequest, "unsupported_grant_type"},
+	}
+	for _, tt := range tests {
+		w := post(tt.form, "")
+		var body map[string]string
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != tt.status || body["error"] != tt.code {
+			t.Errorf("%s: status = %d, body = %s; want %d %s", tt.name, w.Code, w.Body.String(), tt.status, tt.code)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package api provides pagination of list responses.
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes for ListProviders, ListMirroredProviders and ListModules.
+const (
+	defaultListPageSize = 20
+	maxListPageSize     = 100
+)
+
+// Pagination describes the page of a list response. Its fields are merged into
+// the response next to the items rather than nested, so page, limit and total
+// stay where clients already read them.
+type Pagination struct {
+	Page       int   `json:"page"`
+	Limit      int   `json:"limit"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+}
+
+// pageParams reads the page and limit query parameters. A missing or invalid
+// page is 1; a missing or invalid limit is defaultLimit, and one above
+// maxLimit is capped.
+func pageParams(c *gin.Context, defaultLimit, maxLimit int) (page, limit int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	return page, min(limit, maxLimit)
+}
+
+// newPagination returns the pagination of page out of total items, limit per page.
+func newPagination(page, limit int, total int64) Pagination {
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	return Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+// offset returns the number of items before the page.
+func (p Pagination) offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// paginated adds the fields of p to a list response body.
+func paginated(body gin.H, p Pagination) gin.H {
+	body["page"] = p.Page
+	body["limit"] = p.Limit
+	body["total"] = p.Total
+	body["total_pages"] = p.TotalPages
+	body["has_next"] = p.HasNext
+	body["has_prev"] = p.HasPrev
+	return body
+}
+
+// providerSortColumns maps the sort values accepted by ListProviders and
+// ListMirroredProviders to the aggregate they order by. Only these expressions
+// ever reach the ORDER BY clause.
+var providerSortColumns = map[string]string{
+	"downloads": "SUM(downloads)",
+	"name":      "name",
+	"updated":   "MAX(updated_at)",
+	"versions":  "COUNT(DISTINCT version)",
+}
+
+// providerSortOrder returns the ORDER BY clause selected by the sort and order
+// query parameters of a provider list, or defaultOrder when sort is not set.
+// order is asc or desc, defaulting to asc for name and desc otherwise; ties
+// are broken by namespace and name so that pages do not overlap.
+func providerSortOrder(c *gin.Context, defaultOrder string) (string, error) {
+	sort, order := c.Query("sort"), strings.ToLower(c.Query("order"))
+	if sort == "" {
+		if order != "" {
+			return "", fmt.Errorf("order requires sort")
+		}
+		return defaultOrder, nil
+	}
+	column, ok := providerSortColumns[sort]
+	if !ok {
+		return "", fmt.Errorf("invalid sort %q: must be one of downloads, name, updated, versions", sort)
+	}
+	switch order {
+	case "":
+		order = "desc"
+		if sort == "name" {
+			order = "asc"
+		}
+	case "asc", "desc":
+	default:
+		return "", fmt.Errorf("invalid order %q: must be asc or desc", order)
+	}
+	if sort == "name" {
+		return fmt.Sprintf("name %s, namespace %s", order, order), nil
+	}
+	return fmt.Sprintf("%s %s, namespace asc, name asc", column, order), nil
+}
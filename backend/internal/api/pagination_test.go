@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestListPagination_CountsFilteredResults(t *testing.T) {
+	db := newTestDB(t)
+	// Five hashicorp providers, one of them uploaded, and two of another namespace.
+	for _, name := range []string{"aws", "azurerm", "google", "null", "random"} {
+		source := models.SourceMirror
+		if name == "null" {
+			source = models.SourceUpload
+		}
+		for _, v := range []string{"1.0.0", "2.0.0"} {
+			db.Create(&models.Provider{Namespace: "hashicorp", Name: name, Version: v, SourceType: source})
+		}
+	}
+	for _, name := range []string{"kubernetes", "helm"} {
+		db.Create(&models.Provider{Namespace: "community", Name: name, Version: "1.0.0", SourceType: models.SourceUpload})
+	}
+	for _, name := range []string{"vpc", "eks", "rds"} {
+		db.Create(&models.Module{Namespace: "terraform-aws-modules", Name: name, Provider: "aws", Version: "1.0.0"})
+	}
+	db.Create(&models.Module{Namespace: "other", Name: "vpc", Provider: "aws", Version: "1.0.0"})
+
+	router := gin.New()
+	router.GET("/providers", NewHandler(db, "").ListProviders)
+	router.GET("/modules", NewHandler(db, "").ListModules)
+	router.GET("/mirror/providers", NewMirrorHandler(db, t.TempDir(), nil, false).ListMirroredProviders)
+
+	tests := []struct {
+		path  string
+		items int
+		want  Pagination
+	}{
+		{"/providers?namespace=hashicorp&limit=2", 2, Pagination{Page: 1, Limit: 2, Total: 5, TotalPages: 3, HasNext: true}},
+		{"/providers?namespace=hashicorp&limit=2&page=3", 1, Pagination{Page: 3, Limit: 2, Total: 5, TotalPages: 3, HasPrev: true}},
+		{"/providers?name=e", 4, Pagination{Page: 1, Limit: 20, Total: 4, TotalPages: 1}},
+		{"/providers?namespace=nobody", 0, Pagination{Page: 1, Limit: 20}},
+		{"/mirror/providers?source_type=mirror&limit=3&page=2", 1, Pagination{Page: 2, Limit: 3, Total: 4, TotalPages: 2, HasPrev: true}},
+		{"/mirror/providers?page=0&limit=1000", 7, Pagination{Page: 1, Limit: maxListPageSize, Total: 7, TotalPages: 1}},
+		{"/modules?namespace=terraform-aws-modules&limit=2&page=2", 1, Pagination{Page: 2, Limit: 2, Total: 3, TotalPages: 2, HasPrev: true}},
+		{"/modules?name=vpc&limit=-1", 2, Pagination{Page: 1, Limit: 20, Total: 2, TotalPages: 1}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", tt.path, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Pagination
+			Providers []json.RawMessage `json:"providers"`
+			Modules   []json.RawMessage `json:"modules"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if items := len(resp.Providers) + len(resp.Modules); items != tt.items {
+			t.Errorf("%s: %d items, want %d", tt.path, items, tt.items)
+		}
+		if resp.Pagination != tt.want {
+			t.Errorf("%s: pagination = %+v, want %+v", tt.path, resp.Pagination, tt.want)
+		}
+	}
+}
+
+func TestListProviders_Sort(t *testing.T) {
+	db := newTestDB(t)
+	providers := []struct {
+		name      string
+		versions  int
+		downloads int64
+	}{
+		// Downloads are counted per version, so the totals are 100, 30 and 60.
+		{"aws", 1, 100},
+		{"google", 3, 10},
+		{"azurerm", 2, 30},
+	}
+	for _, p := range providers {
+		for i := 0; i < p.versions; i++ {
+			db.Create(&models.Provider{
+				Namespace: "hashicorp", Name: p.name, Version: fmt.Sprintf("1.%d.0", i),
+				SourceType: models.SourceMirror, Downloads: p.downloads,
+			})
+		}
+	}
+
+	router := gin.New()
+	router.GET("/providers", NewHandler(db, "").ListProviders)
+	router.GET("/mirror/providers", NewMirrorHandler(db, t.TempDir(), nil, false).ListMirroredProviders)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"sort=downloads", []string{"aws", "azurerm", "google"}},
+		{"sort=downloads&order=asc", []string{"google", "azurerm", "aws"}},
+		{"sort=name", []string{"aws", "azurerm", "google"}},
+		{"sort=name&order=DESC", []string{"google", "azurerm", "aws"}},
+		{"sort=versions", []string{"google", "azurerm", "aws"}},
+	}
+	for _, base := range []string{"/providers", "/mirror/providers"} {
+		for _, tt := range tests {
+			path := base + "?" + tt.query
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			var resp struct {
+				Providers []struct {
+					Name string `json:"name"`
+				} `json:"providers"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d, body = %s", path, w.Code, w.Body.String())
+			}
+			got := make([]string, len(resp.Providers))
+			for i, p := range resp.Providers {
+				got[i] = p.Name
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("%s: order = %v, want %v", path, got, tt.want)
+			}
+		}
+
+		for _, query := range []string{"sort=downloads;DROP TABLE providers", "sort=name&order=sideways", "order=asc"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+"?"+url.PathEscape(query), nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s?%s: status = %d, want 400", base, query, w.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package api provides the middleware that enforces pending password changes.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// passwordChangeExemptRoutes stay available to users who must change their
+// password, so that they can see why and do it.
+var passwordChangeExemptRoutes = map[string]bool{
+	"/api/v1/auth/me":              true,
+	"/api/v1/auth/change-password": true,
+}
+
+// passwordChangeMiddleware rejects requests with 403 from users whose
+// MustChangePassword flag is set, until they change their password.
+func passwordChangeMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok || passwordChangeExemptRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := db.Select("must_change_password").First(&user, userID).Error; err == nil && user.MustChangePassword {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                "Password change required; set a new password with POST /api/v1/auth/change-password",
+				"must_change_password": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,221 @@
+// Package api provides HTTP handlers for pruning unused providers.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/retention"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultUnusedAge is the threshold used when older_than is not given.
+const defaultUnusedAge = 90 * 24 * time.Hour
+
+// UnusedProviderVersion represents a cached provider version that has not been
+// downloaded within the requested window.
+type UnusedProviderVersion struct {
+	ID               uint       `json:"id"`
+	Namespace        string     `json:"namespace"`
+	Name             string     `json:"name"`
+	Version          string     `json:"version"`
+	PlatformCount    int        `json:"platform_count"`
+	TotalSize        int64      `json:"total_size"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at"`
+}
+
+// parseAge parses an age such as "90d", "12h" or "30m".
+// A "d" suffix is accepted in addition to the units supported by time.ParseDuration.
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultUnusedAge, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return d, nil
+}
+
+// findUnusedVersions returns provider versions last downloaded before cutoff or
+// never downloaded at all, largest first. A version mirrored moments ago and not
+// used yet is listed too; LastDownloadedAt is nil for those.
+func (h *MirrorHandler) findUnusedVersions(cutoff time.Time) ([]UnusedProviderVersion, error) {
+	var results []UnusedProviderVersion
+	err := h.db.Model(&models.Provider{}).
+		Select(`
+			providers.id as id,
+			providers.namespace as namespace,
+			providers.name as name,
+			providers.version as version,
+			COUNT(provider_platforms.id) as platform_count,
+			COALESCE(SUM(provider_platforms.file_size), 0) as total_size
+		`).
+		Joins("LEFT JOIN provider_platforms ON provider_platforms.provider_id = providers.id AND provider_platforms.deleted_at IS NULL").
+		Group("providers.id").
+		Having("MAX(provider_platforms.last_downloaded_at) IS NULL OR MAX(provider_platforms.last_downloaded_at) < ?", cutoff).
+		Order("total_size DESC").
+		Scan(&results).Error
+	if err != nil || len(results) == 0 {
+		return results, err
+	}
+
+	// SQLite returns MAX() of a timestamp as text, so the latest download is
+	// picked from the platform rows rather than selected above.
+	index := make(map[uint]int, len(results))
+	ids := make([]uint, 0, len(results))
+	for i, r := range results {
+		index[r.ID] = i
+		ids = append(ids, r.ID)
+	}
+	var platforms []models.ProviderPlatform
+	if err := h.db.Select("provider_id", "last_downloaded_at").
+		Where("provider_id IN ? AND last_downloaded_at IS NOT NULL", ids).
+		Find(&platforms).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range platforms {
+		r := &results[index[p.ProviderID]]
+		if r.LastDownloadedAt == nil || p.LastDownloadedAt.After(*r.LastDownloadedAt) {
+			r.LastDownloadedAt = p.LastDownloadedAt
+		}
+	}
+	return results, nil
+}
+
+// ListUnusedProviders lists provider versions that have not been downloaded recently.
+// Query: older_than (e.g. "90d", default 90 days).
+func (h *MirrorHandler) ListUnusedProviders(c *gin.Context) {
+	age, err := parseAge(c.Query("older_than"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.findUnusedVersions(time.Now().Add(-age))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var totalSize int64
+	for _, r := range results {
+		totalSize += r.TotalSize
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers":  results,
+		"total":      len(results),
+		"total_size": totalSize,
+		"older_than": age.String(),
+	})
+}
+
+// DeleteUnusedRequest names the provider versions, by the IDs ListUnusedProviders
+// returned, that the caller reviewed and wants deleted.
+type DeleteUnusedRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// DeleteUnusedProviders deletes the reviewed provider versions of the request body
+// that are still unused for the same older_than; the others are listed as skipped.
+// The caller must pass confirm=true.
+func (h *MirrorHandler) DeleteUnusedProviders(c *gin.Context) {
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm=true is required to delete unused providers"})
+		return
+	}
+
+	age, err := parseAge(c.Query("older_than"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var req DeleteUnusedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids of the reviewed provider versions are required"})
+		return
+	}
+
+	results, err := h.findUnusedVersions(time.Now().Add(-age))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	unused := make(map[uint]bool, len(results))
+	for _, r := range results {
+		unused[r.ID] = true
+	}
+
+	var freed int64
+	deleted := 0
+	skipped := make([]uint, 0)
+	for _, id := range req.IDs {
+		if !unused[id] {
+			// Downloaded or deleted since it was listed.
+			skipped = append(skipped, id)
+			continue
+		}
+		delete(unused, id)
+		var provider models.Provider
+		if err := h.db.First(&provider, id).Error; err != nil {
+			continue
+		}
+		freed += retention.DeleteVersion(h.db, h.proxyService, &provider)
+		deleted++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     fmt.Sprintf("Deleted %d unused provider versions", deleted),
+		"deleted":     deleted,
+		"skipped":     skipped,
+		"freed_bytes": freed,
+	})
+}
+
+// PruneProvider keeps the keep newest versions of a provider, by semver
+// precedence, and deletes the rest along with their platform files.
+func (h *MirrorHandler) PruneProvider(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if errMsg := validateProviderParams(namespace, name, ""); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+	keep, err := strconv.Atoi(c.Query("keep"))
+	if err != nil || keep < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep must be a positive number of versions"})
+		return
+	}
+	if !h.authorizeNamespace(c, namespace) {
+		return
+	}
+
+	pruned, err := retention.Prune(h.db, h.proxyService, namespace, name, keep)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var freed int64
+	for _, p := range pruned {
+		freed += p.FreedBytes
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     fmt.Sprintf("Deleted %d provider versions", len(pruned)),
+		"deleted":     len(pruned),
+		"freed_bytes": freed,
+		"versions":    pruned,
+	})
+}
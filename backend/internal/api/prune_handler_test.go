@@ -0,0 +1,306 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", defaultUnusedAge, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"xd", 0, true},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAge(%q) expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestFindUnusedVersions(t *testing.T) {
+	db := newTestDB(t)
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	stale := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "1.0.0", CreatedAt: old}
+	fresh := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "2.0.0", CreatedAt: old}
+	unused := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "3.0.0", CreatedAt: old}
+	brandNew := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.0.0"}
+	for _, p := range []*models.Provider{&stale, &fresh, &unused, &brandNew} {
+		db.Create(p)
+	}
+	db.Create(&models.ProviderPlatform{ProviderID: stale.ID, OS: "linux", Arch: "amd64", Filename: "a", FilePath: "/a", SHA256Sum: "a", FileSize: 10, LastDownloadedAt: &old})
+	db.Create(&models.ProviderPlatform{ProviderID: fresh.ID, OS: "linux", Arch: "amd64", Filename: "b", FilePath: "/b", SHA256Sum: "b", FileSize: 20, LastDownloadedAt: &recent})
+	db.Create(&models.ProviderPlatform{ProviderID: unused.ID, OS: "linux", Arch: "amd64", Filename: "c", FilePath: "/c", SHA256Sum: "c", FileSize: 30})
+
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	results, err := h.findUnusedVersions(time.Now().Add(-90 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("findUnusedVersions() error = %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3: %+v", len(results), results)
+	}
+	// Largest first; never downloaded versions are listed however new they are.
+	if results[0].Version != "3.0.0" || results[1].Version != "1.0.0" || results[2].Version != "4.0.0" {
+		t.Errorf("unexpected versions: %s, %s, %s", results[0].Version, results[1].Version, results[2].Version)
+	}
+	if results[0].TotalSize != 30 {
+		t.Errorf("TotalSize = %d, want 30", results[0].TotalSize)
+	}
+	if results[0].LastDownloadedAt != nil {
+		t.Errorf("never downloaded: LastDownloadedAt = %v, want nil", results[0].LastDownloadedAt)
+	}
+	if last := results[1].LastDownloadedAt; last == nil || !last.Equal(old) {
+		t.Errorf("LastDownloadedAt = %v, want %v", last, old)
+	}
+}
+
+func TestDeleteUnusedProviders(t *testing.T) {
+	db := newTestDB(t)
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	var versions []models.Provider
+	for _, v := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v, CreatedAt: old}
+		db.Create(&provider)
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+			Filename: v, FilePath: "/" + v, SHA256Sum: v, LastDownloadedAt: &old})
+		versions = append(versions, provider)
+	}
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.DELETE("/mirror/unused", h.DeleteUnusedProviders)
+	del := func(query, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/mirror/unused"+query, strings.NewReader(body)))
+		return w
+	}
+
+	if w := del("?confirm=true", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("without ids: status = %d, want 400", w.Code)
+	}
+	// 2.0.0 was downloaded after it was listed, and 3.0.0 was not reviewed.
+	now := time.Now()
+	db.Model(&models.ProviderPlatform{}).Where("provider_id = ?", versions[1].ID).Update("last_downloaded_at", now)
+	body := fmt.Sprintf(`{"ids":[%d,%d]}`, versions[0].ID, versions[1].ID)
+	w := del("?confirm=true", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if want := fmt.Sprintf(`"skipped":[%d]`, versions[1].ID); !strings.Contains(w.Body.String(), want) {
+		t.Errorf("body = %s, want %s", w.Body.String(), want)
+	}
+	var kept []string
+	db.Model(&models.Provider{}).Order("version").Pluck("version", &kept)
+	if strings.Join(kept, ",") != "2.0.0,3.0.0" {
+		t.Errorf("kept = %v, want [2.0.0 3.0.0]", kept)
+	}
+}
+
+func TestPruneProvider(t *testing.T) {
+	db := newTestDB(t)
+	for _, v := range []string{"1.0.0", "2.0.0", "10.0.0"} {
+		db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: v})
+	}
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.DELETE("/mirror/providers/:namespace/:name/prune", h.PruneProvider)
+
+	for _, keep := range []string{"", "0", "many"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/mirror/providers/hashicorp/aws/prune?keep="+keep, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("keep=%q: status = %d, want %d", keep, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/mirror/providers/hashicorp/aws/prune?keep=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var kept []string
+	db.Model(&models.Provider{}).Pluck("version", &kept)
+	if len(kept) != 1 || kept[0] != "10.0.0" {
+		t.Errorf("kept = %v, want [10.0.0]", kept)
+	}
+}
+
+func TestDeleteProviderVersion(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	router := gin.New()
+	router.DELETE("/mirror/providers/:namespace/:name/prune", h.PruneProvider)
+	router.DELETE("/mirror/providers/:namespace/:name/:version", h.DeleteProviderVersion)
+
+	var bad models.Provider
+	for _, v := range []string{"5.0.0", "5.0.1"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v}
+		db.Create(&provider)
+		if v == "5.0.0" {
+			bad = provider
+		}
+		for _, platform := range []models.ProviderPlatform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}} {
+			dir := filepath.Join(storagePath, "hashicorp", "aws", v, platform.OS, platform.Arch)
+			if err := os.MkdirAll(dir, 0750); err != nil {
+				t.Fatal(err)
+			}
+			filePath := filepath.Join(dir, "terraform-provider-aws.zip")
+			if err := os.WriteFile(filePath, []byte("binary"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			platform.ProviderID, platform.FilePath, platform.FileSize = provider.ID, filePath, 6
+			db.Create(&platform)
+		}
+	}
+
+	del := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		return w
+	}
+	if w := del("/mirror/providers/hashicorp/aws/9.9.9"); w.Code != http.StatusNotFound {
+		t.Errorf("missing version: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := del("/mirror/providers/hashicorp/aws/latest;1"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid version: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := del("/mirror/providers/hashicorp/aws/5.0.0")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"freed_bytes":12`) {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var versions []string
+	db.Model(&models.Provider{}).Pluck("version", &versions)
+	var platforms int64
+	db.Model(&models.ProviderPlatform{}).Where("provider_id = ?", bad.ID).Count(&platforms)
+	if len(versions) != 1 || versions[0] != "5.0.1" || platforms != 0 {
+		t.Errorf("versions = %v, platforms of deleted version = %d; want [5.0.1], 0", versions, platforms)
+	}
+	if _, err := os.Stat(filepath.Join(storagePath, "hashicorp", "aws", "5.0.0")); !os.IsNotExist(err) {
+		t.Errorf("version directory still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storagePath, "hashicorp", "aws", "5.0.1", "linux", "amd64", "terraform-provider-aws.zip")); err != nil {
+		t.Errorf("file of the remaining version removed: %v", err)
+	}
+}
+
+func TestDeleteProviderVersion_MirrorsAgain(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+	router.DELETE("/mirror/providers/:namespace/:name/:version", h.DeleteProviderVersion)
+
+	serve := func(method, path string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, body = %s", method, path, w.Code, w.Body.String())
+		}
+	}
+	serve(http.MethodPost, "/mirror/hashicorp/aws?version=5.0.0")
+	serve(http.MethodDelete, "/mirror/providers/hashicorp/aws/5.0.0")
+	serve(http.MethodPost, "/mirror/hashicorp/aws?version=5.0.0")
+
+	var platforms int64
+	db.Model(&models.ProviderPlatform{}).Joins("JOIN providers ON providers.id = provider_platforms.provider_id").
+		Where("providers.version = ?", "5.0.0").Count(&platforms)
+	if platforms != 1 {
+		t.Errorf("platforms after mirroring again = %d, want 1", platforms)
+	}
+}
+
+func TestDeleteProviderPlatform(t *testing.T) {
+	db := newTestDB(t)
+	settings := models.Settings{}
+	db.Create(&settings)
+	db.Model(&settings).Updates(map[string]interface{}{"allow_online_search": false})
+	storagePath := t.TempDir()
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	for _, platform := range []models.ProviderPlatform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}} {
+		dir := filepath.Join(storagePath, "hashicorp", "aws", "5.0.0", platform.OS, platform.Arch)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		platform.FilePath = filepath.Join(dir, "terraform-provider-aws.zip")
+		if err := os.WriteFile(platform.FilePath, []byte("binary"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		platform.ProviderID, platform.FileSize, platform.SHA256Sum = provider.ID, 6, strings.Repeat("a", 64)
+		db.Create(&platform)
+	}
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	mirror := NewProviderMirrorHandler(db, storagePath, nil, nil)
+	router := gin.New()
+	router.DELETE("/mirror/providers/:namespace/:name/:version/:os/:arch", h.DeleteProviderPlatform)
+	router.GET("/registry.terraform.io/:namespace/:name/:version", mirror.GetVersionArchives)
+
+	del := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		return w.Code
+	}
+	if code := del("/mirror/providers/hashicorp/aws/5.0.0/windows/amd64"); code != http.StatusNotFound {
+		t.Errorf("missing platform: status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := del("/mirror/providers/hashicorp/aws/5.0.0/darwin/arm64"); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+
+	var remaining []models.ProviderPlatform
+	db.Where("provider_id = ?", provider.ID).Find(&remaining)
+	var versions int64
+	db.Model(&models.Provider{}).Count(&versions)
+	if len(remaining) != 1 || remaining[0].OS != "linux" || versions != 1 {
+		t.Errorf("remaining platforms = %+v, versions = %d; want linux/amd64 and the version kept", remaining, versions)
+	}
+	if _, err := os.Stat(filepath.Join(storagePath, "hashicorp", "aws", "5.0.0", "darwin")); !os.IsNotExist(err) {
+		t.Errorf("directory of the deleted platform still exists: %v", err)
+	}
+	if _, err := os.Stat(remaining[0].FilePath); err != nil {
+		t.Errorf("file of the kept platform removed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/aws/5.0.0.json", nil))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "darwin_arm64") || !strings.Contains(w.Body.String(), "linux_amd64") {
+		t.Errorf("archives: status = %d, body = %s; want only linux_amd64", w.Code, w.Body.String())
+	}
+}
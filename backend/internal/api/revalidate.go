@@ -0,0 +1,106 @@
+// Package api provides revalidation of cached providers against upstream.
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+)
+
+// revalidateIfStale starts a background re-check of a cached mirrored version
+// when it has not been checked against upstream for Settings.RevalidateHours.
+// The caller keeps serving the cached file; the result only updates
+// Provider.UpstreamStatus. Revalidation is off while RevalidateHours is 0.
+func (h *MirrorHandler) revalidateIfStale(settings models.Settings, provider models.Provider) {
+	if settings.RevalidateHours <= 0 || provider.SourceType != models.SourceMirror {
+		return
+	}
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(settings.RevalidateHours) * time.Hour)
+	// Claim the check first so a burst of downloads starts only one of them.
+	result := h.db.Model(&models.Provider{}).
+		Where("id = ? AND COALESCE(revalidated_at, created_at) < ?", provider.ID, cutoff).
+		UpdateColumn("revalidated_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	go h.revalidate(h.proxyService, provider)
+}
+
+// revalidate compares a cached version with upstream and records the outcome.
+// Upstream errors other than not found leave the recorded status unchanged.
+func (h *MirrorHandler) revalidate(proxyService *proxy.ProxyService, provider models.Provider) {
+	status, err := h.upstreamStatus(proxyService, provider)
+	if err != nil {
+		slog.Warn("Revalidation failed",
+			"component", "Revalidate",
+			"namespace", logsafe.Clean(provider.Namespace),
+			"name", logsafe.Clean(provider.Name),
+			"version", logsafe.Clean(provider.Version),
+			"error", logsafe.CleanErr(err))
+		return
+	}
+	if status == provider.UpstreamStatus {
+		return
+	}
+	h.db.Model(&models.Provider{}).Where("id = ?", provider.ID).UpdateColumn("upstream_status", status)
+	if status != "" {
+		slog.Warn("Cached provider no longer matches upstream",
+			"component", "Revalidate",
+			"namespace", logsafe.Clean(provider.Namespace),
+			"name", logsafe.Clean(provider.Name),
+			"version", logsafe.Clean(provider.Version),
+			"status", status)
+	}
+}
+
+// upstreamStatus reports models.UpstreamYanked if upstream no longer lists the
+// version, models.UpstreamChanged if any cached platform is gone upstream or
+// has a different checksum there, and "" if everything still matches.
+func (h *MirrorHandler) upstreamStatus(proxyService *proxy.ProxyService, provider models.Provider) (string, error) {
+	versions, err := proxyService.GetProviderVersions(context.Background(), provider.Namespace, provider.Name)
+	if isNotFound(err) {
+		return models.UpstreamYanked, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	listed := false
+	for _, v := range versions.Versions {
+		if v.Version == provider.Version {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		return models.UpstreamYanked, nil
+	}
+
+	var platforms []models.ProviderPlatform
+	h.db.Where("provider_id = ?", provider.ID).Find(&platforms)
+	for _, p := range platforms {
+		info, err := proxyService.GetProviderDownloadInfo(context.Background(), provider.Namespace, provider.Name, provider.Version, p.OS, p.Arch)
+		if isNotFound(err) {
+			return models.UpstreamChanged, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.SHA256Sum != "" && info.SHA256Sum != p.SHA256Sum {
+			return models.UpstreamChanged, nil
+		}
+	}
+	return "", nil
+}
+
+// isNotFound reports whether err is an upstream 404.
+func isNotFound(err error) bool {
+	var statusErr *proxy.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
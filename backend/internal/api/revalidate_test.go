@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+)
+
+func TestUpstreamStatus(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9"
+
+	tests := []struct {
+		name      string
+		namespace string
+		version   string
+		sha256sum string
+		want      string
+	}{
+		{"matches", "hashicorp", "5.0.0", sum, ""},
+		{"checksum changed", "hashicorp", "5.0.0", "deadbeef", models.UpstreamChanged},
+		{"version yanked", "hashicorp", "4.0.0", sum, models.UpstreamYanked},
+		{"provider removed", "gone", "5.0.0", sum, models.UpstreamYanked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			provider := models.Provider{Namespace: tt.namespace, Name: "aws", Version: tt.version, SourceType: models.SourceMirror}
+			db.Create(&provider)
+			db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+				Filename: "provider.zip", FilePath: "/unused", SHA256Sum: tt.sha256sum})
+
+			h := NewMirrorHandler(db, t.TempDir(), nil, false)
+			got, err := h.upstreamStatus(proxy.NewProxyService(t.TempDir(), upstream.URL), provider)
+			if err != nil {
+				t.Fatalf("upstreamStatus() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("upstreamStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadProvider_RevalidatesStaleCache(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	db.Create(&models.Settings{RevalidateHours: 24})
+
+	storagePath := t.TempDir()
+	filePath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	if err := os.WriteFile(filePath, []byte("republished-binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0",
+		SourceType: models.SourceMirror, CreatedAt: time.Now().Add(-48 * time.Hour)}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+		Filename: filepath.Base(filePath), FilePath: filePath, SHA256Sum: "deadbeef"})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+
+	// The cached file is served right away; the check runs in the background.
+	if w := serveDownload(t, h); w.Code != http.StatusOK || w.Body.String() != "republished-binary" {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		db.First(&provider, provider.ID)
+		if provider.UpstreamStatus == models.UpstreamChanged {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("UpstreamStatus = %q, want %q", provider.UpstreamStatus, models.UpstreamChanged)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if provider.RevalidatedAt == nil || time.Since(*provider.RevalidatedAt) > time.Minute {
+		t.Errorf("RevalidatedAt = %v, want about now", provider.RevalidatedAt)
+	}
+}
@@ -2,15 +2,34 @@
 package api
 
 import (
+	"net/http"
+
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logbuffer"
+	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SetupRouter configures and returns the HTTP router.
-func SetupRouter(db *gorm.DB, jwtManager *auth.JWTManager, authEnabled bool, storagePath string) *gin.Engine {
+// downloadSigner enables signed binary downloads when non-nil, downloads
+// batches download counts when non-nil, and schedules is told about bulk
+// schedule changes when non-nil. cfg.Storage.Path must already be resolved
+// to the directory provider files are stored in.
+func SetupRouter(db *gorm.DB, jwtManager *auth.JWTManager, downloadSigner *auth.DownloadSigner, downloads *DownloadCounter, schedules ScheduleController, cfg *config.Config) *gin.Engine {
+	storagePath := cfg.Storage.Path
+	instanceID := cfg.Server.InstanceID
+	authEnabled := cfg.Auth.Enabled
+
 	router := gin.Default()
 
+	// Answer a known path with the wrong method with 405 rather than 404.
+	// Gin fills in the Allow header with the methods registered for the path.
+	router.HandleMethodNotAllowed = true
+	router.NoMethod(func(c *gin.Context) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method not allowed"})
+	})
+
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -26,58 +45,116 @@ func SetupRouter(db *gorm.DB, jwtManager *auth.JWTManager, authEnabled bool, sto
 		c.Next()
 	})
 
-	handler := NewHandler(db)
-	mirrorHandler := NewMirrorHandler(db, storagePath)
+	// Identify which instance answered, for deployments behind a load balancer.
+	if instanceID != "" {
+		router.Use(func(c *gin.Context) {
+			c.Header("X-Registry-Instance", instanceID)
+			c.Next()
+		})
+	}
+
+	jwtManager.SetAPITokenResolver(apiTokenResolver(db))
+
+	handler := NewHandler(db, instanceID)
+	handler.loginEnabled = authEnabled
+	mirrorHandler := NewMirrorHandler(db, storagePath, downloadSigner, cfg.Auth.NamespaceOwnership)
+	mirrorHandler.downloads = downloads
 	authHandler := NewAuthHandler(db, jwtManager)
-	settingsHandler := NewSettingsHandler(db)
+	settingsHandler := NewSettingsHandler(db, cfg.Server.Frozen)
 	syncHandler := NewSyncHandler(db, storagePath)
+	syncHandler.schedules = schedules
 	searchHandler := NewSearchHandler(db, storagePath)
+	namespaceHandler := NewNamespaceHandler(db)
+	signingKeyHandler := NewSigningKeyHandler(db)
+	webhookHandler := NewWebhookHandler(db)
+	logsHandler := NewLogsHandler(logbuffer.Default)
+	configHandler := NewConfigHandler(cfg)
+	storageMigrationHandler := NewStorageMigrationHandler(db, storagePath)
+	storageMigrationHandler.storageType = cfg.Storage.Type
+	if cfg.Storage.S3.Bucket != "" {
+		storageMigrationHandler.useS3(cfg.Storage.S3)
+	}
+	moduleHandler := NewModuleHandler(db, storagePath, cfg.Storage.MaxModuleSize, cfg.Auth.NamespaceOwnership)
+	clientVersions := newClientVersionRecorder(db)
 
 	// Terraform Registry Protocol Discovery
-	router.GET("/.well-known/terraform.json", func(c *gin.Context) {
-		host := c.Request.Host
-		c.JSON(200, gin.H{
-			"providers.v1": "/v1/providers/",
-			"modules.v1":   "/v1/modules/",
-			"metadata.v1":  "https://" + host + "/",
-		})
-	})
+	router.GET("/.well-known/terraform.json", handler.Discovery)
 
 	// Terraform Provider Mirror Protocol
 	// https://developer.hashicorp.com/terraform/internals/provider-network-mirror-protocol
-	mirrorProtocolHandler := NewProviderMirrorHandler(db, storagePath)
+	mirrorProtocolHandler := NewProviderMirrorHandler(db, storagePath, downloadSigner, cfg.Upstream.AllowedOverrides)
 	router.GET("/registry.terraform.io/:namespace/:name/index.json", mirrorProtocolHandler.ListAvailableVersions)
-	router.GET("/registry.terraform.io/:namespace/:name/:version", mirrorProtocolHandler.GetVersionArchives)
+	router.GET("/registry.terraform.io/:namespace/:name/:version", recordClientVersions(clientVersions), mirrorProtocolHandler.GetVersionArchives)
+
+	// Static filesystem-style mirror of the cache (opt-in); see static_mirror.go
+	if cfg.Server.StaticMirror {
+		router.GET("/registry.terraform.io/", mirrorHandler.ListStaticNamespaces)
+		router.GET("/registry.terraform.io/:namespace/", mirrorHandler.ListStaticProviders)
+		router.GET("/registry.terraform.io/:namespace/:name/", mirrorHandler.ListStaticVersions)
+		router.GET("/registry.terraform.io/:namespace/:name/:version/", mirrorHandler.ListStaticArchives)
+		router.GET("/registry.terraform.io/:namespace/:name/:version/:archive", mirrorHandler.ServeStaticArchive)
+	}
 
 	// Terraform Provider Registry Protocol v1
 	router.GET("/v1/providers/:namespace/:name/versions", mirrorHandler.GetProviderVersions)
-	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", mirrorHandler.GetProviderDownloadInfo)
-	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", mirrorHandler.DownloadProvider)
+	router.GET("/v1/providers/:namespace/:name/:version", handler.GetProvider) // Content-negotiated; see RegistryMediaType
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch", recordClientVersions(clientVersions), mirrorHandler.GetProviderDownloadInfo)
+	router.GET("/v1/providers/:namespace/:name/:version/sha256sums", mirrorHandler.GetProviderChecksums)
+	router.GET("/v1/providers/:namespace/:name/:version/sha256sums.sig", mirrorHandler.GetProviderChecksums)
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary",
+		auth.DownloadTokenMiddleware(downloadSigner, jwtManager), mirrorHandler.DownloadProvider)
+	router.HEAD("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary",
+		auth.DownloadTokenMiddleware(downloadSigner, jwtManager), mirrorHandler.HeadProviderBinary)
+
+	// Terraform Module Registry Protocol v1
+	router.GET("/v1/modules/:namespace/:name/:provider/versions", moduleHandler.ListModuleVersions)
+	router.GET("/v1/modules/:namespace/:name/:provider/:version/download", moduleHandler.DownloadModule)
+	router.GET("/v1/modules/:namespace/:name/:provider/:version/archive.tar.gz", moduleHandler.ServeModuleArchive)
 
 	// Auth routes (always public)
 	router.POST("/api/v1/auth/login", authHandler.Login)
+	router.POST("/api/v1/auth/refresh", authHandler.Refresh)
+	router.POST("/api/v1/auth/revoke", authHandler.Revoke)
+	router.POST(oauthTokenPath, authHandler.OAuthToken)
 	router.GET("/api/v1/auth/status", func(c *gin.Context) {
 		c.JSON(200, gin.H{"auth_enabled": authEnabled})
 	})
 
 	// Public read-only routes (no auth required)
 	router.GET("/health", handler.HealthCheck)
+	router.GET("/api/v1/version", handler.GetVersion)
 	router.GET("/api/v1/providers", handler.ListProviders)
-	router.GET("/api/v1/providers/:namespace/:name/:version", handler.GetProvider)
 	router.GET("/api/v1/providers/search", searchHandler.SearchProviders)
 	router.GET("/api/v1/modules", handler.ListModules)
 	router.GET("/api/v1/modules/:namespace/:name/:provider/:version", handler.GetModule)
 	router.GET("/api/v1/mirror/providers", mirrorHandler.ListMirroredProviders)
 	router.GET("/api/v1/mirror/providers/:namespace/:name", mirrorHandler.GetProviderVersionsDetail)
+	router.GET("/api/v1/mirror/providers/:namespace/:name/latest", mirrorHandler.GetLatestProviderVersion)
+	router.GET("/api/v1/mirror/providers/:namespace/:name/:version/exists", mirrorHandler.GetProviderVersionExists)
+	router.GET("/api/v1/mirror/providers/:namespace/:name/:version/:os/:arch/checksum", mirrorHandler.GetPlatformChecksum)
 	router.GET("/api/v1/settings", settingsHandler.GetSettings)
 	router.GET("/api/v1/sync/schedules", syncHandler.ListSchedules)
 
 	// Protected routes (auth required for write operations)
 	authorized := router.Group("/api/v1")
-	authorized.Use(auth.AuthMiddleware(jwtManager))
+	authorized.Use(auth.AuthMiddleware(jwtManager), passwordChangeMiddleware(db), freezeMiddleware(db, cfg.Server.Frozen))
 	{
 		// Auth
 		authorized.GET("/auth/me", authHandler.GetCurrentUser)
+		authorized.POST("/auth/change-password", authHandler.ChangePassword)
+		authorized.POST("/auth/api-token", authHandler.CreateAPIToken)
+		authorized.DELETE("/auth/api-token", authHandler.RevokeAPIToken)
+
+		// User management (admin only)
+		users := authorized.Group("/users", auth.RequireRole("admin"))
+		users.GET("", authHandler.ListUsers)
+		users.POST("", authHandler.CreateUser)
+		users.DELETE("/:id", authHandler.DeleteUser)
+
+		// API tokens of other users (admin only)
+		userTokens := authorized.Group("/admin/users/:id/api-token", auth.RequireRole("admin"))
+		userTokens.POST("", authHandler.CreateUserAPIToken)
+		userTokens.DELETE("", authHandler.RevokeUserAPIToken)
 
 		// Provider management (requires auth)
 		authorized.POST("/providers", handler.CreateProvider)
@@ -86,22 +163,77 @@ func SetupRouter(db *gorm.DB, jwtManager *auth.JWTManager, authEnabled bool, sto
 
 		// Mirror operations (requires auth)
 		authorized.GET("/mirror/upstream/:namespace/:name", mirrorHandler.ListUpstreamVersions)
+		authorized.POST("/mirror/batch", mirrorHandler.MirrorBatch)
+		authorized.POST("/mirror/from-lockfile", mirrorHandler.MirrorFromLockFile)
 		authorized.POST("/mirror/:namespace/:name", mirrorHandler.MirrorProvider)
 		authorized.GET("/mirror/:namespace/:name/stream", mirrorHandler.MirrorProviderWithProgress)
 		authorized.GET("/mirror/export/:id", mirrorHandler.ExportProvider)
 		authorized.POST("/mirror/import", mirrorHandler.ImportProvider)
+		authorized.POST("/mirror/verify-lock", mirrorHandler.VerifyLock)
+		authorized.GET("/mirror/providers/:namespace/:name/:version/lockfile", mirrorHandler.GenerateLockFile)
+		authorized.GET("/mirror/providers/:namespace/:name/:version/platforms", mirrorHandler.ListPlatformFiles)
+		authorized.GET("/mirror/unused", mirrorHandler.ListUnusedProviders)
+		authorized.DELETE("/mirror/unused", mirrorHandler.DeleteUnusedProviders)
+		authorized.DELETE("/mirror/providers/:namespace/:name/prune", mirrorHandler.PruneProvider)
+		authorized.DELETE("/mirror/providers/:namespace/:name/:version", mirrorHandler.DeleteProviderVersion)
+		authorized.DELETE("/mirror/providers/:namespace/:name/:version/:os/:arch", mirrorHandler.DeleteProviderPlatform)
+		authorized.GET("/mirror/coverage", mirrorHandler.GetPlatformCoverage)
+		authorized.POST("/mirror/verify", auth.RequireRole("admin"), mirrorHandler.VerifyIntegrity)
+		authorized.GET("/mirror/configs", auth.RequireRole("admin"), mirrorHandler.ListMirrorConfigs)
+		authorized.PUT("/mirror/configs/:namespace/:name", auth.RequireRole("admin"), mirrorHandler.PutMirrorConfig)
+		authorized.DELETE("/mirror/configs/:namespace/:name", auth.RequireRole("admin"), mirrorHandler.DeleteMirrorConfig)
+		authorized.GET("/mirror/updates-available", mirrorHandler.GetUpdatesAvailable)
+		authorized.GET("/mirror/providers/:namespace/:name/clients", mirrorHandler.GetProviderClients)
+		authorized.GET("/mirror/providers/:namespace/:name/usage", mirrorHandler.GetProviderUsage)
+
+		// Namespace ownership (admin only)
+		owners := authorized.Group("/namespaces/:namespace/owners", auth.RequireRole("admin"))
+		owners.GET("", namespaceHandler.ListOwners)
+		owners.PUT("/:username", namespaceHandler.AddOwner)
+		owners.DELETE("/:username", namespaceHandler.RemoveOwner)
+
+		// Signing key trust store (admin only)
+		signingKeys := authorized.Group("/signing-keys", auth.RequireRole("admin"))
+		signingKeys.GET("", signingKeyHandler.ListSigningKeys)
+		signingKeys.POST("", signingKeyHandler.CreateSigningKey)
+		signingKeys.DELETE("/:id", signingKeyHandler.DeleteSigningKey)
+
+		// Event webhooks (admin only)
+		webhooks := authorized.Group("/webhooks", auth.RequireRole("admin"))
+		webhooks.GET("", webhookHandler.ListWebhooks)
+		webhooks.POST("", webhookHandler.CreateWebhook)
+		webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooks.POST("/:id/test", webhookHandler.TestWebhook)
+
+		// Recent server logs (admin only)
+		adminLogs := authorized.Group("/admin/logs", auth.RequireRole("admin"))
+		adminLogs.GET("", logsHandler.ListLogs)
+		adminLogs.GET("/stream", logsHandler.StreamLogs)
+
+		// Effective configuration, secrets redacted (admin only)
+		authorized.GET("/admin/config", auth.RequireRole("admin"), configHandler.GetConfig)
+
+		// Storage migration to a new location (admin only)
+		migrateStorage := authorized.Group("/admin/migrate-storage", auth.RequireRole("admin"))
+		migrateStorage.POST("", storageMigrationHandler.StartMigration)
+		migrateStorage.GET("", storageMigrationHandler.GetMigrationStatus)
 
 		// Settings (requires auth)
 		authorized.PUT("/settings", settingsHandler.UpdateSettings)
+		authorized.PUT("/settings/freeze", auth.RequireRole("admin"), settingsHandler.SetFreeze)
 
 		// Sync schedules (requires auth)
 		authorized.POST("/sync/schedules", syncHandler.CreateSchedule)
+		authorized.POST("/sync/schedules/bulk", syncHandler.BulkUpdateSchedules)
+		authorized.POST("/sync/run-all", auth.RequireRole("admin"), syncHandler.RunAllSchedules)
 		authorized.PUT("/sync/schedules/:id", syncHandler.UpdateSchedule)
 		authorized.DELETE("/sync/schedules/:id", syncHandler.DeleteSchedule)
 		authorized.POST("/sync/schedules/:id/run", syncHandler.RunScheduleNow)
+		authorized.GET("/sync/schedules/:id/runs", syncHandler.ListScheduleRuns)
 
 		// Module management
-		authorized.POST("/modules", handler.CreateProvider)
+		authorized.POST("/modules", moduleHandler.UploadModule)
 	}
 
 	return router
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB opens a migrated SQLite database in a temp directory for handler tests.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Provider{},
+		&models.Module{},
+		&models.User{},
+		&models.ProviderPlatform{},
+		&models.MirrorConfig{},
+		&models.Settings{},
+		&models.SyncSchedule{},
+		&models.SyncRun{},
+		&models.ProviderClientVersion{},
+		&models.NamespaceOwner{},
+		&models.SigningKey{},
+		&models.ProviderSigningKey{},
+		&models.UpstreamChecksum{},
+		&models.RefreshToken{},
+		&models.Webhook{},
+	); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// testConfig returns the router configuration used by tests.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	return &config.Config{
+		Server:  config.ServerConfig{InstanceID: "test-instance"},
+		Storage: config.StorageConfig{Path: t.TempDir(), Type: "local"},
+		Auth:    config.AuthConfig{Enabled: true, SecretKey: "test-secret"},
+	}
+}
+
+func TestSetupRouter_MethodNotAllowed(t *testing.T) {
+	db := newTestDB(t)
+	router := SetupRouter(db, auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, testConfig(t))
+
+	t.Run("wrong method on known path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/health", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status code = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+		}
+		if allow := w.Header().Get("Allow"); allow != http.MethodGet {
+			t.Errorf("Allow = %q, want %q", allow, http.MethodGet)
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/does-not-exist", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status code = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestSetupRouter_InstanceIdentity(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.Settings{RegistryName: "eu-mirror"})
+	router := SetupRouter(db, auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, testConfig(t))
+
+	for _, path := range []string{"/.well-known/terraform.json", "/api/v1/version"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status code = %d, want %d", w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("X-Registry-Instance"); got != "test-instance" {
+				t.Errorf("X-Registry-Instance = %q, want %q", got, "test-instance")
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body["registry_name"] != "eu-mirror" || body["instance_id"] != "test-instance" {
+				t.Errorf("unexpected identity fields: %v", body)
+			}
+		})
+	}
+}
+
+func TestSetupRouter_ProviderContentNegotiation(t *testing.T) {
+	db := newTestDB(t)
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", Protocols: `["5.0"]`, Tier: "official"}
+	db.Create(&provider)
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.10.0"})
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: "aws.zip", FilePath: "/unused", SHA256Sum: "abc"})
+	router := SetupRouter(db, auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, testConfig(t))
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/5.0.0", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, accept := range []string{"", "application/json", "*/*"} {
+		w := get(accept)
+		var model models.Provider
+		if err := json.Unmarshal(w.Body.Bytes(), &model); err != nil || w.Code != http.StatusOK || model.ID != provider.ID {
+			t.Errorf("Accept %q: status = %d, body = %s; want the admin model", accept, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "/unused") {
+			t.Errorf("Accept %q: body = %s; want no storage paths", accept, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/providers/hashicorp/aws/5.0.0", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("old /api/v1 route: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = get(RegistryMediaType + ", application/json;q=0.5")
+	if got := w.Header().Get("Content-Type"); got != RegistryMediaType {
+		t.Errorf("Content-Type = %q, want %q", got, RegistryMediaType)
+	}
+	var doc RegistryProviderVersion
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if doc.ID != "hashicorp/aws/5.0.0" || doc.Tier != "official" || len(doc.Protocols) != 1 ||
+		len(doc.Platforms) != 1 || doc.Platforms[0] != (RegistryPlatform{OS: "linux", Arch: "amd64"}) {
+		t.Errorf("unexpected registry document: %+v", doc)
+	}
+	if len(doc.Versions) != 2 || doc.Versions[0] != "5.10.0" {
+		t.Errorf("Versions = %v, want newest first", doc.Versions)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept" {
+		t.Errorf("Vary = %q, want Accept", got)
+	}
+}
@@ -2,8 +2,10 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
@@ -31,7 +33,7 @@ func NewSearchHandler(db *gorm.DB, storagePath string) *SearchHandler {
 func (h *SearchHandler) refreshProxySettings() {
 	var settings models.Settings
 	if err := h.db.First(&settings).Error; err == nil {
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
 	}
 }
 
@@ -45,26 +47,70 @@ type ProviderSearchResult struct {
 	Source      string `json:"source"` // "local" or "upstream"
 	IsCached    bool   `json:"is_cached"`
 	Tier        string `json:"tier"` // "official", "partner", or "community"
+	LogoURL     string `json:"logo_url,omitempty"`
 }
 
-// determineTier returns the tier for a provider based on namespace and source.
+// determineTier guesses the tier of a provider that upstream reports none
+// for. source is the upstream source attribute: normally the URL of the
+// provider's repository, where providers kept in HashiCorp's own GitHub
+// organization are official, and in some older responses the tier itself. A
+// repository URL cannot tell partner providers from community ones, so without
+// a tier those count as community.
 func determineTier(namespace, source string) string {
-	if namespace == "hashicorp" {
-		return "official"
+	source = strings.ToLower(strings.TrimSpace(source))
+	if validTiers[source] {
+		return source
 	}
-	if source == "partner" {
-		return "partner"
+	if namespace == "hashicorp" || isHashiCorpRepository(source) {
+		return "official"
 	}
 	return "community"
 }
 
+// isHashiCorpRepository reports whether source, lower-cased, is the URL of a
+// repository in the hashicorp GitHub organization, with or without a scheme.
+func isHashiCorpRepository(source string) bool {
+	source = strings.TrimPrefix(strings.TrimPrefix(source, "https://"), "http://")
+	source = strings.TrimPrefix(source, "www.")
+	rest, ok := strings.CutPrefix(source, "github.com/hashicorp/")
+	return ok && rest != ""
+}
+
+// upstreamTier returns the tier of an upstream search result: the tier
+// upstream reports, or determineTier's guess when it reports none it knows.
+func upstreamTier(p proxy.SearchResult) string {
+	if tier := strings.ToLower(strings.TrimSpace(p.Tier)); validTiers[tier] {
+		return tier
+	}
+	return determineTier(p.Namespace, p.Source)
+}
+
+// localTier returns the tier of a local provider: the stored upstream tier, or a
+// guess from the namespace for uploads and providers mirrored before it was stored.
+func localTier(p models.Provider) string {
+	if p.Tier != "" {
+		return p.Tier
+	}
+	return determineTier(p.Namespace, "")
+}
+
 // searchLocalProviders searches for providers in the local database.
-func (h *SearchHandler) searchLocalProviders(query string, offset, limit int) ([]models.Provider, int64, error) {
+// A non-empty tier keeps only providers for which localTier would return it.
+func (h *SearchHandler) searchLocalProviders(query, tier string, offset, limit int) ([]models.Provider, int64, error) {
 	var providers []models.Provider
 	dbQuery := h.db.Model(&models.Provider{})
 
 	if query != "" {
-		dbQuery = dbQuery.Where("name LIKE ? OR namespace LIKE ?", "%"+query+"%", "%"+query+"%")
+		like := "%" + query + "%"
+		dbQuery = dbQuery.Where("name LIKE ? OR namespace LIKE ? OR description LIKE ?", like, like, like)
+	}
+	switch tier {
+	case "official":
+		dbQuery = dbQuery.Where("tier = ? OR (tier = '' AND namespace = 'hashicorp')", tier)
+	case "community":
+		dbQuery = dbQuery.Where("tier = ? OR (tier = '' AND namespace <> 'hashicorp')", tier)
+	case "partner":
+		dbQuery = dbQuery.Where("tier = ?", tier)
 	}
 
 	var total int64
@@ -91,55 +137,140 @@ func (h *SearchHandler) buildLocalResults(providers []models.Provider) ([]Provid
 				Downloads:   p.Downloads,
 				Source:      "local",
 				IsCached:    true,
-				Tier:        determineTier(p.Namespace, ""),
+				Tier:        localTier(p),
+				LogoURL:     p.LogoURL,
 			})
 		}
 	}
 	return results, nameMap
 }
 
+// validTiers lists the accepted values of the tier filter.
+var validTiers = map[string]bool{"official": true, "partner": true, "community": true}
+
 // appendUpstreamResults adds upstream providers to results if not already present.
-func (h *SearchHandler) appendUpstreamResults(results []ProviderSearchResult, nameMap map[string]bool, query string, remaining int) []ProviderSearchResult {
-	upstreamResults, err := h.proxyService.SearchProviders(query, remaining)
+// Providers cached here are marked IsCached, or left out with uncachedOnly. A
+// non-empty opts.Tier also drops results of another tier, for upstreams that
+// ignore the filter. It reports whether upstream has a further page of results.
+func (h *SearchHandler) appendUpstreamResults(ctx context.Context, results []ProviderSearchResult, nameMap map[string]bool, opts proxy.SearchOptions, uncachedOnly bool) ([]ProviderSearchResult, bool) {
+	upstreamResults, err := h.proxyService.SearchProviders(ctx, opts)
 	if err != nil || upstreamResults == nil {
-		return results
+		return results, false
 	}
 
+	cached := h.cachedProviders(upstreamResults.Providers)
 	for _, p := range upstreamResults.Providers {
 		key := p.Namespace + "/" + p.Name
-		if !nameMap[key] {
-			nameMap[key] = true
-			results = append(results, ProviderSearchResult{
-				Namespace:   p.Namespace,
-				Name:        p.Name,
-				Description: p.Description,
-				Downloads:   p.Downloads,
-				Source:      "upstream",
-				IsCached:    false,
-				Tier:        determineTier(p.Namespace, p.Source),
-			})
+		if nameMap[key] {
+			continue
 		}
+		nameMap[key] = true
+		tier := upstreamTier(p)
+		if (opts.Tier != "" && tier != opts.Tier) || (uncachedOnly && cached[key]) {
+			continue
+		}
+		results = append(results, ProviderSearchResult{
+			Namespace:   p.Namespace,
+			Name:        p.Name,
+			Description: p.Description,
+			Downloads:   p.Downloads,
+			Source:      "upstream",
+			IsCached:    cached[key],
+			Tier:        tier,
+			LogoURL:     p.LogoURL,
+		})
+	}
+	return results, upstreamResults.HasMore
+}
+
+// cachedProviders returns the namespace/name keys of the upstream results that
+// have a version cached here.
+func (h *SearchHandler) cachedProviders(upstream []proxy.SearchResult) map[string]bool {
+	cached := make(map[string]bool)
+	if len(upstream) == 0 {
+		return cached
+	}
+	keys := make([]string, len(upstream))
+	for i, p := range upstream {
+		keys[i] = p.Namespace + "/" + p.Name
 	}
-	return results
+	var rows []struct{ Namespace, Name string }
+	h.db.Model(&models.Provider{}).Select("namespace, name").
+		Where("namespace || '/' || name IN ?", keys).Find(&rows)
+	for _, r := range rows {
+		cached[r.Namespace+"/"+r.Name] = true
+	}
+	return cached
 }
 
+// Search modes reported in the mode field of a SearchProviders response.
+const (
+	// searchModeSearch matches the query locally and, if allowed, upstream.
+	searchModeSearch = "search"
+	// searchModeBrowse lists the most-downloaded local providers for an empty query.
+	searchModeBrowse = "browse"
+
+	// defaultSearchLimit and maxSearchLimit bound the limit parameter.
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
 // SearchProviders searches for providers locally and optionally from upstream.
+// The query (q, or name) matches local providers by name, namespace or description.
+// page starts at 1; limit defaults to 20 and is capped at 100.
+// The optional tier filter (official, partner, community) applies to local results
+// and to upstream ones, and is passed through to the upstream search with the
+// page number. cached=true keeps local results only; cached=false keeps only
+// upstream providers that are not cached here.
+// An empty query is browse mode: the most-downloaded local providers, without
+// an upstream search.
 func (h *SearchHandler) SearchProviders(c *gin.Context) {
-	query := c.Query("q")
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		query = strings.TrimSpace(c.Query("name"))
+	}
+	mode := searchModeSearch
 	if query == "" {
-		query = c.Query("name")
+		mode = searchModeBrowse
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
 	offset := (page - 1) * limit
 
-	// Get local providers
-	localProviders, localTotal, err := h.searchLocalProviders(query, offset, limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	tier := c.Query("tier")
+	if tier != "" && !validTiers[tier] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tier must be one of official, partner, community"})
 		return
 	}
+	wantLocal, wantUpstream := true, true
+	if cached := c.Query("cached"); cached != "" {
+		onlyCached, err := strconv.ParseBool(cached)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cached must be true or false"})
+			return
+		}
+		wantLocal, wantUpstream = onlyCached, !onlyCached
+	}
+
+	// Get local providers
+	var localProviders []models.Provider
+	var localTotal int64
+	if wantLocal {
+		var err error
+		localProviders, localTotal, err = h.searchLocalProviders(query, tier, offset, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	// Build results from local providers
 	results, nameMap := h.buildLocalResults(localProviders)
@@ -149,20 +280,30 @@ func (h *SearchHandler) SearchProviders(c *gin.Context) {
 	allowOnline := true
 	if err := h.db.First(&settings).Error; err == nil {
 		allowOnline = settings.AllowOnlineSearch
-		h.proxyService.SetProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType)
+		applyProxySettings(h.proxyService, settings)
 	}
 
-	// If we have a query and online search is allowed, search upstream too
-	if query != "" && allowOnline && len(results) < limit {
-		results = h.appendUpstreamResults(results, nameMap, query, limit-len(results))
+	// In search mode with online search allowed, search upstream too.
+	// Upstream is always asked for full pages so page boundaries stay stable
+	// as the caller pages deeper, even though page 1 may then exceed limit.
+	upstreamHasMore := false
+	if mode == searchModeSearch && allowOnline && wantUpstream && len(results) < limit {
+		results, upstreamHasMore = h.appendUpstreamResults(c.Request.Context(), results, nameMap, proxy.SearchOptions{
+			Query: query,
+			Limit: limit,
+			Page:  page,
+			Tier:  tier,
+		}, !wantLocal)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"providers":     results,
-		"page":          page,
-		"limit":         limit,
-		"total":         len(results),
-		"local_total":   localTotal,
-		"online_search": allowOnline,
+		"providers":         results,
+		"mode":              mode,
+		"page":              page,
+		"limit":             limit,
+		"total":             len(results),
+		"local_total":       localTotal,
+		"online_search":     allowOnline,
+		"upstream_has_more": upstreamHasMore,
 	})
 }
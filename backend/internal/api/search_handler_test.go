@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSearchProviders_LocalTier(t *testing.T) {
+	db := newTestDB(t)
+	offlineSettings(t, db)
+	db.Create(&models.Provider{Namespace: "cloudflare", Name: "cloudflare", Version: "4.0.0", Tier: "partner", LogoURL: "/logo.png"})
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"})
+	db.Create(&models.Provider{Namespace: "integrations", Name: "github", Version: "6.0.0", Tier: "official"})
+	db.Create(&models.Provider{Namespace: "acme", Name: "widgets", Version: "1.0.0"})
+
+	router := gin.New()
+	router.GET("/search", NewSearchHandler(db, t.TempDir()).SearchProviders)
+
+	search := func(query string) map[string]ProviderSearchResult {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", query, w.Code, w.Body.String())
+		}
+		var body struct {
+			Providers []ProviderSearchResult `json:"providers"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		results := make(map[string]ProviderSearchResult)
+		for _, p := range body.Providers {
+			results[p.Namespace+"/"+p.Name] = p
+		}
+		return results
+	}
+
+	all := search("")
+	if got := all["cloudflare/cloudflare"]; got.Tier != "partner" || got.LogoURL != "/logo.png" {
+		t.Errorf("cloudflare: Tier = %q, LogoURL = %q; want stored values", got.Tier, got.LogoURL)
+	}
+	if got := all["integrations/github"].Tier; got != "official" {
+		t.Errorf("integrations/github Tier = %q, want stored official", got)
+	}
+	if got := all["hashicorp/aws"].Tier; got != "official" {
+		t.Errorf("hashicorp/aws Tier = %q, want official from namespace", got)
+	}
+
+	tests := map[string][]string{
+		"official":  {"hashicorp/aws", "integrations/github"},
+		"partner":   {"cloudflare/cloudflare"},
+		"community": {"acme/widgets"},
+	}
+	for tier, want := range tests {
+		got := search("?tier=" + tier)
+		if len(got) != len(want) {
+			t.Errorf("tier=%s: got %d results, want %v", tier, len(got), want)
+		}
+		for _, key := range want {
+			if _, ok := got[key]; !ok {
+				t.Errorf("tier=%s: missing %s", tier, key)
+			}
+		}
+	}
+}
+
+func TestSearchProviders_BrowseMode(t *testing.T) {
+	var upstreamHits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHits.Add(1)
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{})
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", Downloads: 10})
+	db.Create(&models.Provider{Namespace: "acme", Name: "widgets", Version: "1.0.0", Downloads: 50})
+
+	h := NewSearchHandler(db, t.TempDir())
+	h.proxyService = proxy.NewProxyService(t.TempDir(), upstream.URL)
+	router := gin.New()
+	router.GET("/search", h.SearchProviders)
+
+	tests := []struct {
+		query        string
+		wantMode     string
+		wantUpstream bool
+	}{
+		{"", searchModeBrowse, false},
+		{"?q=%20%20", searchModeBrowse, false},
+		{"?q=aws", searchModeSearch, true},
+	}
+	for _, tt := range tests {
+		upstreamHits.Store(0)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search"+tt.query, nil))
+		var body struct {
+			Mode      string                 `json:"mode"`
+			Providers []ProviderSearchResult `json:"providers"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusOK || body.Mode != tt.wantMode {
+			t.Errorf("%q: status = %d, mode = %q; want 200 and %q", tt.query, w.Code, body.Mode, tt.wantMode)
+		}
+		if got := upstreamHits.Load() > 0; got != tt.wantUpstream {
+			t.Errorf("%q: searched upstream = %v, want %v", tt.query, got, tt.wantUpstream)
+		}
+		if tt.wantMode == searchModeBrowse && (len(body.Providers) != 2 || body.Providers[0].Name != "widgets") {
+			t.Errorf("%q: providers = %+v, want most-downloaded first", tt.query, body.Providers)
+		}
+	}
+}
+
+func TestSearchProviders_MatchesDescription(t *testing.T) {
+	db := newTestDB(t)
+	offlineSettings(t, db)
+	db.Create(&models.Provider{Namespace: "telmate", Name: "proxmox", Version: "2.9.0", Description: "Proxmox Virtual Environment"})
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0", Description: "Amazon Web Services"})
+
+	router := gin.New()
+	router.GET("/search", NewSearchHandler(db, t.TempDir()).SearchProviders)
+
+	tests := []struct {
+		query     string
+		want      []string
+		wantLimit int
+	}{
+		{"?q=virtual", []string{"telmate/proxmox"}, 20},
+		{"?q=telmate", []string{"telmate/proxmox"}, 20},
+		{"?name=amazon", []string{"hashicorp/aws"}, 20},
+		{"?q=nothing", nil, 20},
+		{"?q=e&page=0&limit=-5", []string{"telmate/proxmox", "hashicorp/aws"}, 20},
+		{"?q=e&limit=5000", []string{"telmate/proxmox", "hashicorp/aws"}, maxSearchLimit},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", tt.query, w.Code, w.Body.String())
+		}
+		var body struct {
+			Providers []ProviderSearchResult `json:"providers"`
+			Page      int                    `json:"page"`
+			Limit     int                    `json:"limit"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.query, err)
+		}
+		got := make(map[string]bool)
+		for _, p := range body.Providers {
+			got[p.Namespace+"/"+p.Name] = true
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+		for _, key := range tt.want {
+			if !got[key] {
+				t.Errorf("%s: missing %s", tt.query, key)
+			}
+		}
+		if body.Page < 1 || body.Limit != tt.wantLimit {
+			t.Errorf("%s: page = %d, limit = %d; want page >= 1, limit %d", tt.query, body.Page, body.Limit, tt.wantLimit)
+		}
+	}
+}
+
+func TestSearchProviders_CachedAndTierFilters(t *testing.T) {
+	// The upstream ignores filter[tier], so the handler has to filter itself.
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[
+			{"attributes":{"namespace":"hashicorp","name":"aws","tier":"official"}},
+			{"attributes":{"namespace":"acme","name":"cloud","source":"https://github.com/hashicorp/terraform-provider-cloud"}},
+			{"attributes":{"namespace":"vendor","name":"cloudsql","tier":"Partner"}},
+			{"attributes":{"namespace":"someone","name":"cloudy","source":"https://github.com/someone/terraform-provider-cloudy"}}
+		]}`))
+	}))
+	defer upstream.Close()
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{})
+	db.Create(&models.Provider{Namespace: "vendor", Name: "cloudsql", Version: "1.0.0", Tier: "partner"})
+
+	h := NewSearchHandler(db, t.TempDir())
+	h.proxyService = proxy.NewProxyService(t.TempDir(), upstream.URL)
+	router := gin.New()
+	router.GET("/search", h.SearchProviders)
+
+	search := func(query string) []ProviderSearchResult {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=cloud"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", query, w.Code, w.Body.String())
+		}
+		var body struct {
+			Providers []ProviderSearchResult `json:"providers"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Providers
+	}
+	keys := func(results []ProviderSearchResult) []string {
+		out := make([]string, len(results))
+		for i, p := range results {
+			out[i] = p.Namespace + "/" + p.Name + ":" + p.Tier + ":" + p.Source
+		}
+		return out
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"vendor/cloudsql:partner:local", "hashicorp/aws:official:upstream", "acme/cloud:official:upstream", "someone/cloudy:community:upstream"}},
+		{"&tier=official", []string{"hashicorp/aws:official:upstream", "acme/cloud:official:upstream"}},
+		{"&cached=true", []string{"vendor/cloudsql:partner:local"}},
+		{"&cached=false", []string{"hashicorp/aws:official:upstream", "acme/cloud:official:upstream", "someone/cloudy:community:upstream"}},
+		{"&cached=false&tier=partner", []string{}},
+	}
+	for _, tt := range tests {
+		if got := keys(search(tt.query)); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q: results = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Upstream results cached here but not among the local results, here
+	// because the stored tier differs, are marked as cached.
+	db.Create(&models.Provider{Namespace: "someone", Name: "cloudy", Version: "1.0.0", Tier: "partner"})
+	results := search("&tier=community")
+	if len(results) != 1 || results[0].Source != "upstream" || !results[0].IsCached {
+		t.Errorf("tier=community: results = %+v, want someone/cloudy from upstream marked cached", results)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=cloud&cached=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("cached=maybe: status = %d, want 400", w.Code)
+	}
+}
+
+func TestDetermineTier(t *testing.T) {
+	tests := []struct {
+		namespace, source, want string
+	}{
+		{"hashicorp", "", "official"},
+		{"acme", "https://github.com/hashicorp/terraform-provider-acme", "official"},
+		{"acme", "github.com/HashiCorp/terraform-provider-acme", "official"},
+		{"acme", "https://github.com/hashicorp", "community"},
+		{"acme", "https://github.com/hashicorp-community/terraform-provider-acme", "community"},
+		{"acme", "https://github.com/acme/terraform-provider-acme", "community"},
+		{"acme", " Partner ", "partner"},
+		{"acme", "", "community"},
+	}
+	for _, tt := range tests {
+		if got := determineTier(tt.namespace, tt.source); got != tt.want {
+			t.Errorf("determineTier(%q, %q) = %q, want %q", tt.namespace, tt.source, got, tt.want)
+		}
+	}
+}
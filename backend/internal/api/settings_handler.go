@@ -2,41 +2,93 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SettingsHandler handles settings-related HTTP requests.
 type SettingsHandler struct {
-	db *gorm.DB
+	db           *gorm.DB
+	forcedFrozen bool
 }
 
 // NewSettingsHandler creates a new SettingsHandler.
-func NewSettingsHandler(db *gorm.DB) *SettingsHandler {
-	return &SettingsHandler{db: db}
+// forcedFrozen reports that read-only mode is forced by configuration.
+func NewSettingsHandler(db *gorm.DB, forcedFrozen bool) *SettingsHandler {
+	return &SettingsHandler{db: db, forcedFrozen: forcedFrozen}
 }
 
+// maxUpstreamRateBurst bounds Settings.UpstreamRateBurst.
+const maxUpstreamRateBurst = 1000
+
 // SettingsResponse represents the settings API response.
 type SettingsResponse struct {
-	AllowOnlineSearch  bool   `json:"allow_online_search"`
-	DefaultUpstreamURL string `json:"default_upstream_url"`
-	RegistryURL        string `json:"registry_url"`
-	ProxyEnabled       bool   `json:"proxy_enabled"`
-	ProxyURL           string `json:"proxy_url"`
-	ProxyType          string `json:"proxy_type"`
+	AllowOnlineSearch   bool    `json:"allow_online_search"`
+	RedirectUncached    bool    `json:"redirect_uncached"`
+	ImmutableVersions   bool    `json:"immutable_versions"`
+	VersionFallback     bool    `json:"version_fallback"`
+	VerifySignatures    bool    `json:"verify_signatures"`
+	IncludePrereleases  bool    `json:"include_prereleases"`
+	DefaultUpstreamURL  string  `json:"default_upstream_url"`
+	RegistryURL         string  `json:"registry_url"`
+	RegistryName        string  `json:"registry_name"`
+	ProxyEnabled        bool    `json:"proxy_enabled"`
+	ProxyURL            string  `json:"proxy_url"` // Without any user:pass@
+	ProxyType           string  `json:"proxy_type"`
+	ProxyUsername       string  `json:"proxy_username"`
+	ProxyPasswordSet    bool    `json:"proxy_password_set"`    // The password itself is never returned
+	ProxyError          string  `json:"proxy_error,omitempty"` // Why the enabled proxy cannot be used; upstream requests fail meanwhile
+	MaxUpstreamVersions int     `json:"max_upstream_versions"`
+	RevalidateHours     int     `json:"revalidate_hours"`
+	MirrorConcurrency   int     `json:"mirror_concurrency"`
+	DefaultOS           string  `json:"default_os"`
+	DefaultArch         string  `json:"default_arch"`
+	RetainVersions      int     `json:"retain_versions"`
+	UpstreamRateLimit   float64 `json:"upstream_rate_limit"`
+	UpstreamRateBurst   int     `json:"upstream_rate_burst"`
+	Frozen              bool    `json:"frozen"`
 }
 
 // UpdateSettingsRequest represents the request to update settings.
 type UpdateSettingsRequest struct {
-	AllowOnlineSearch  *bool   `json:"allow_online_search"`
-	DefaultUpstreamURL *string `json:"default_upstream_url"`
-	RegistryURL        *string `json:"registry_url"`
-	ProxyEnabled       *bool   `json:"proxy_enabled"`
-	ProxyURL           *string `json:"proxy_url"`
-	ProxyType          *string `json:"proxy_type"`
+	AllowOnlineSearch   *bool    `json:"allow_online_search"`
+	RedirectUncached    *bool    `json:"redirect_uncached"`
+	ImmutableVersions   *bool    `json:"immutable_versions"`
+	VersionFallback     *bool    `json:"version_fallback"`
+	VerifySignatures    *bool    `json:"verify_signatures"`
+	IncludePrereleases  *bool    `json:"include_prereleases"`
+	DefaultUpstreamURL  *string  `json:"default_upstream_url"`
+	RegistryURL         *string  `json:"registry_url"`
+	RegistryName        *string  `json:"registry_name"`
+	ProxyEnabled        *bool    `json:"proxy_enabled"`
+	ProxyURL            *string  `json:"proxy_url"`
+	ProxyType           *string  `json:"proxy_type"`
+	ProxyUsername       *string  `json:"proxy_username"`
+	ProxyPassword       *string  `json:"proxy_password"`
+	MaxUpstreamVersions *int     `json:"max_upstream_versions"`
+	RevalidateHours     *int     `json:"revalidate_hours"`
+	MirrorConcurrency   *int     `json:"mirror_concurrency"`
+	DefaultOS           *string  `json:"default_os"`
+	DefaultArch         *string  `json:"default_arch"`
+	RetainVersions      *int     `json:"retain_versions"`
+	UpstreamRateLimit   *float64 `json:"upstream_rate_limit"`
+	UpstreamRateBurst   *int     `json:"upstream_rate_burst"`
+}
+
+// proxyError returns why the proxy enabled in settings cannot be used, or "".
+func proxyError(settings models.Settings) string {
+	if !settings.ProxyEnabled || settings.ProxyURL == "" {
+		return ""
+	}
+	if err := proxy.CheckProxy(settings.ProxyURL, settings.ProxyType); err != nil {
+		return err.Error()
+	}
+	return ""
 }
 
 // GetSettings returns the current application settings.
@@ -56,13 +108,32 @@ func (h *SettingsHandler) GetSettings(c *gin.Context) {
 		}
 	}
 
+	proxyURL, urlPassword := proxy.StripProxyCredentials(settings.ProxyURL)
 	c.JSON(http.StatusOK, SettingsResponse{
-		AllowOnlineSearch:  settings.AllowOnlineSearch,
-		DefaultUpstreamURL: settings.DefaultUpstreamURL,
-		RegistryURL:        settings.RegistryURL,
-		ProxyEnabled:       settings.ProxyEnabled,
-		ProxyURL:           settings.ProxyURL,
-		ProxyType:          settings.ProxyType,
+		AllowOnlineSearch:   settings.AllowOnlineSearch,
+		RedirectUncached:    settings.RedirectUncached,
+		ImmutableVersions:   settings.ImmutableVersions,
+		VersionFallback:     settings.VersionFallback,
+		VerifySignatures:    settings.VerifySignatures,
+		IncludePrereleases:  settings.IncludePrereleases,
+		DefaultUpstreamURL:  settings.DefaultUpstreamURL,
+		RegistryURL:         settings.RegistryURL,
+		RegistryName:        settings.RegistryName,
+		ProxyEnabled:        settings.ProxyEnabled,
+		ProxyURL:            proxyURL,
+		ProxyType:           settings.ProxyType,
+		ProxyUsername:       settings.ProxyUsername,
+		ProxyPasswordSet:    settings.ProxyPassword != "" || urlPassword,
+		ProxyError:          proxyError(settings),
+		MaxUpstreamVersions: settings.MaxUpstreamVersions,
+		RevalidateHours:     settings.RevalidateHours,
+		MirrorConcurrency:   settings.MirrorConcurrency,
+		DefaultOS:           settings.DefaultOS,
+		DefaultArch:         settings.DefaultArch,
+		RetainVersions:      settings.RetainVersions,
+		UpstreamRateLimit:   settings.UpstreamRateLimit,
+		UpstreamRateBurst:   settings.UpstreamRateBurst,
+		Frozen:              h.forcedFrozen || settings.Frozen,
 	})
 }
 
@@ -73,6 +144,50 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.ProxyType != nil && *req.ProxyType != "http" && *req.ProxyType != "https" && *req.ProxyType != "socks5" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proxy_type must be one of http, https, socks5"})
+		return
+	}
+	if req.MaxUpstreamVersions != nil && *req.MaxUpstreamVersions < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_upstream_versions must not be negative"})
+		return
+	}
+	if req.RevalidateHours != nil && *req.RevalidateHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "revalidate_hours must not be negative"})
+		return
+	}
+	if req.MirrorConcurrency != nil && (*req.MirrorConcurrency < 1 || *req.MirrorConcurrency > maxMirrorConcurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("mirror_concurrency must be between 1 and %d", maxMirrorConcurrency)})
+		return
+	}
+	if req.RetainVersions != nil && *req.RetainVersions < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retain_versions must not be negative"})
+		return
+	}
+	if req.UpstreamRateLimit != nil && *req.UpstreamRateLimit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "upstream_rate_limit must not be negative"})
+		return
+	}
+	if req.UpstreamRateBurst != nil && (*req.UpstreamRateBurst < 1 || *req.UpstreamRateBurst > maxUpstreamRateBurst) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("upstream_rate_burst must be between 1 and %d", maxUpstreamRateBurst)})
+		return
+	}
+	if req.DefaultUpstreamURL != nil {
+		upstream, err := normalizeRegistryURL(*req.DefaultUpstreamURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "default_upstream_url: " + err.Error()})
+			return
+		}
+		req.DefaultUpstreamURL = &upstream
+	}
+	if req.DefaultOS != nil && !validIdentifierStrict.MatchString(*req.DefaultOS) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `default_os must be "all" or an OS name such as linux`})
+		return
+	}
+	if req.DefaultArch != nil && !validIdentifierStrict.MatchString(*req.DefaultArch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": `default_arch must be "all" or an architecture name such as amd64`})
+		return
+	}
 
 	var settings models.Settings
 	result := h.db.First(&settings)
@@ -92,33 +207,135 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 	if req.AllowOnlineSearch != nil {
 		settings.AllowOnlineSearch = *req.AllowOnlineSearch
 	}
+	if req.RedirectUncached != nil {
+		settings.RedirectUncached = *req.RedirectUncached
+	}
+	if req.ImmutableVersions != nil {
+		settings.ImmutableVersions = *req.ImmutableVersions
+	}
+	if req.VersionFallback != nil {
+		settings.VersionFallback = *req.VersionFallback
+	}
+	if req.VerifySignatures != nil {
+		settings.VerifySignatures = *req.VerifySignatures
+	}
+	if req.IncludePrereleases != nil {
+		settings.IncludePrereleases = *req.IncludePrereleases
+	}
 	if req.DefaultUpstreamURL != nil {
 		settings.DefaultUpstreamURL = *req.DefaultUpstreamURL
 	}
 	if req.RegistryURL != nil {
 		settings.RegistryURL = *req.RegistryURL
 	}
+	if req.RegistryName != nil {
+		settings.RegistryName = *req.RegistryName
+	}
 	if req.ProxyEnabled != nil {
 		settings.ProxyEnabled = *req.ProxyEnabled
 	}
-	if req.ProxyURL != nil {
+	// The proxy URL is returned without its credentials; sending that back
+	// unchanged keeps them.
+	if stripped, _ := proxy.StripProxyCredentials(settings.ProxyURL); req.ProxyURL != nil && *req.ProxyURL != stripped {
 		settings.ProxyURL = *req.ProxyURL
 	}
 	if req.ProxyType != nil {
 		settings.ProxyType = *req.ProxyType
 	}
+	if req.ProxyUsername != nil {
+		settings.ProxyUsername = *req.ProxyUsername
+	}
+	if req.ProxyPassword != nil {
+		settings.ProxyPassword = *req.ProxyPassword
+	}
+	if req.MaxUpstreamVersions != nil {
+		settings.MaxUpstreamVersions = *req.MaxUpstreamVersions
+	}
+	if req.RevalidateHours != nil {
+		settings.RevalidateHours = *req.RevalidateHours
+	}
+	if req.MirrorConcurrency != nil {
+		settings.MirrorConcurrency = *req.MirrorConcurrency
+	}
+	if req.DefaultOS != nil {
+		settings.DefaultOS = *req.DefaultOS
+	}
+	if req.DefaultArch != nil {
+		settings.DefaultArch = *req.DefaultArch
+	}
+	if req.RetainVersions != nil {
+		settings.RetainVersions = *req.RetainVersions
+	}
+	if req.UpstreamRateLimit != nil {
+		settings.UpstreamRateLimit = *req.UpstreamRateLimit
+	}
+	if req.UpstreamRateBurst != nil {
+		settings.UpstreamRateBurst = *req.UpstreamRateBurst
+	}
 
 	if err := h.db.Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settings"})
 		return
 	}
+	proxy.SetUpstreamRateLimit(settings.UpstreamRateLimit, settings.UpstreamRateBurst)
 
+	proxyURL, urlPassword := proxy.StripProxyCredentials(settings.ProxyURL)
 	c.JSON(http.StatusOK, SettingsResponse{
-		AllowOnlineSearch:  settings.AllowOnlineSearch,
-		DefaultUpstreamURL: settings.DefaultUpstreamURL,
-		RegistryURL:        settings.RegistryURL,
-		ProxyEnabled:       settings.ProxyEnabled,
-		ProxyURL:           settings.ProxyURL,
-		ProxyType:          settings.ProxyType,
+		AllowOnlineSearch:   settings.AllowOnlineSearch,
+		RedirectUncached:    settings.RedirectUncached,
+		ImmutableVersions:   settings.ImmutableVersions,
+		VersionFallback:     settings.VersionFallback,
+		VerifySignatures:    settings.VerifySignatures,
+		IncludePrereleases:  settings.IncludePrereleases,
+		DefaultUpstreamURL:  settings.DefaultUpstreamURL,
+		RegistryURL:         settings.RegistryURL,
+		RegistryName:        settings.RegistryName,
+		ProxyEnabled:        settings.ProxyEnabled,
+		ProxyURL:            proxyURL,
+		ProxyType:           settings.ProxyType,
+		ProxyUsername:       settings.ProxyUsername,
+		ProxyPasswordSet:    settings.ProxyPassword != "" || urlPassword,
+		ProxyError:          proxyError(settings),
+		MaxUpstreamVersions: settings.MaxUpstreamVersions,
+		RevalidateHours:     settings.RevalidateHours,
+		MirrorConcurrency:   settings.MirrorConcurrency,
+		DefaultOS:           settings.DefaultOS,
+		DefaultArch:         settings.DefaultArch,
+		RetainVersions:      settings.RetainVersions,
+		UpstreamRateLimit:   settings.UpstreamRateLimit,
+		UpstreamRateBurst:   settings.UpstreamRateBurst,
+		Frozen:              h.forcedFrozen || settings.Frozen,
 	})
 }
+
+// FreezeRequest represents the request to toggle read-only mode.
+type FreezeRequest struct {
+	Frozen *bool `json:"frozen" binding:"required"`
+}
+
+// SetFreeze turns read-only mode on or off. It is exempt from the freeze itself
+// so that an admin can lift it; a freeze forced by configuration cannot be lifted.
+func (h *SettingsHandler) SetFreeze(c *gin.Context) {
+	var req FreezeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frozen is required"})
+		return
+	}
+	if h.forcedFrozen && !*req.Frozen {
+		c.JSON(http.StatusConflict, gin.H{"error": "Registry is frozen by configuration (SERVER_FROZEN); restart without it to unfreeze"})
+		return
+	}
+
+	var settings models.Settings
+	if err := h.db.FirstOrCreate(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get settings"})
+		return
+	}
+	// Update through a map so that false is written despite the column default.
+	if err := h.db.Model(&settings).Updates(map[string]interface{}{"frozen": *req.Frozen}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"frozen": h.forcedFrozen || *req.Frozen})
+}
@@ -0,0 +1,164 @@
+// Package api provides HTTP handlers for the signing key trust store.
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validKeyID matches a 16 hex digit GPG long key ID.
+var validKeyID = regexp.MustCompile(`^[0-9A-F]{16}$`)
+
+// pgpPublicKeyHeader opens an ASCII-armored GPG public key.
+const pgpPublicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// SigningKeyHandler manages the GPG public keys advertised for locally served providers.
+type SigningKeyHandler struct {
+	db *gorm.DB
+}
+
+// NewSigningKeyHandler creates a new SigningKeyHandler instance.
+func NewSigningKeyHandler(db *gorm.DB) *SigningKeyHandler {
+	return &SigningKeyHandler{db: db}
+}
+
+// trustedSigningKeys returns the trust store keys that apply to namespace:
+// its own keys followed by the registry-wide ones.
+func trustedSigningKeys(db *gorm.DB, namespace string) proxy.SigningKeys {
+	var keys []models.SigningKey
+	db.Where("namespace = ? OR namespace = ''", namespace).
+		Order("namespace DESC, key_id").
+		Find(&keys)
+
+	signingKeys := proxy.SigningKeys{GPGPublicKeys: make([]proxy.GPGPublicKey, 0, len(keys))}
+	for _, k := range keys {
+		signingKeys.GPGPublicKeys = append(signingKeys.GPGPublicKeys, proxy.GPGPublicKey{
+			KeyID:          k.KeyID,
+			ASCIIArmor:     k.ASCIIArmor,
+			TrustSignature: k.TrustSignature,
+			Source:         k.Source,
+			SourceURL:      k.SourceURL,
+		})
+	}
+	return signingKeys
+}
+
+// providerSigningKeys returns the keys advertised for a cached provider
+// version: those upstream listed for it, then the trust store keys for
+// namespace that are not among them.
+func providerSigningKeys(db *gorm.DB, providerID uint, namespace string) proxy.SigningKeys {
+	var stored []models.ProviderSigningKey
+	db.Where("provider_id = ?", providerID).Order("id").Find(&stored)
+
+	signingKeys := proxy.SigningKeys{GPGPublicKeys: make([]proxy.GPGPublicKey, 0, len(stored))}
+	seen := make(map[string]bool, len(stored))
+	for _, k := range stored {
+		seen[k.KeyID] = true
+		signingKeys.GPGPublicKeys = append(signingKeys.GPGPublicKeys, proxy.GPGPublicKey{
+			KeyID:          k.KeyID,
+			ASCIIArmor:     k.ASCIIArmor,
+			TrustSignature: k.TrustSignature,
+			Source:         k.Source,
+			SourceURL:      k.SourceURL,
+		})
+	}
+	for _, k := range trustedSigningKeys(db, namespace).GPGPublicKeys {
+		if !seen[k.KeyID] {
+			signingKeys.GPGPublicKeys = append(signingKeys.GPGPublicKeys, k)
+		}
+	}
+	return signingKeys
+}
+
+// CreateSigningKeyRequest represents the request to add a key to the trust store.
+// An empty namespace makes the key apply registry-wide.
+type CreateSigningKeyRequest struct {
+	Namespace      string `json:"namespace"`
+	KeyID          string `json:"key_id" binding:"required"`
+	ASCIIArmor     string `json:"ascii_armor" binding:"required"`
+	TrustSignature string `json:"trust_signature"`
+	Source         string `json:"source"`
+	SourceURL      string `json:"source_url"`
+}
+
+// ListSigningKeys lists trust store keys. Query: namespace (optional; "" lists all).
+func (h *SigningKeyHandler) ListSigningKeys(c *gin.Context) {
+	query := h.db.Order("namespace, key_id")
+	if namespace, ok := c.GetQuery("namespace"); ok {
+		query = query.Where("namespace = ?", namespace)
+	}
+
+	keys := make([]models.SigningKey, 0)
+	if err := query.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signing keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"signing_keys": keys})
+}
+
+// CreateSigningKey adds a GPG public key to the trust store.
+func (h *SigningKeyHandler) CreateSigningKey(c *gin.Context) {
+	var req CreateSigningKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_id and ascii_armor are required"})
+		return
+	}
+
+	req.KeyID = strings.ToUpper(strings.TrimSpace(req.KeyID))
+	if !validKeyID.MatchString(req.KeyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_id must be a 16 hex digit GPG key ID"})
+		return
+	}
+	if !strings.HasPrefix(strings.TrimSpace(req.ASCIIArmor), pgpPublicKeyHeader) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ascii_armor must be an ASCII-armored GPG public key"})
+		return
+	}
+	if req.Namespace != "" && (len(req.Namespace) > 64 || !validIdentifier.MatchString(req.Namespace)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid namespace"})
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.SigningKey{}).Where("namespace = ? AND key_id = ?", req.Namespace, req.KeyID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Signing key already exists for this namespace"})
+		return
+	}
+
+	key := models.SigningKey{
+		Namespace:      req.Namespace,
+		KeyID:          req.KeyID,
+		ASCIIArmor:     strings.TrimSpace(req.ASCIIArmor),
+		TrustSignature: req.TrustSignature,
+		Source:         req.Source,
+		SourceURL:      req.SourceURL,
+	}
+	if err := h.db.Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save signing key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// DeleteSigningKey removes a key from the trust store.
+func (h *SigningKeyHandler) DeleteSigningKey(c *gin.Context) {
+	result := h.db.Delete(&models.SigningKey{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete signing key"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Signing key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signing key deleted"})
+}
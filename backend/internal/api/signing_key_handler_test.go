@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const testArmor = "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQENBF...\n-----END PGP PUBLIC KEY BLOCK-----"
+
+func TestTrustedSigningKeys(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.SigningKey{Namespace: "", KeyID: "AAAAAAAAAAAAAAAA", ASCIIArmor: testArmor})
+	db.Create(&models.SigningKey{Namespace: "acme", KeyID: "BBBBBBBBBBBBBBBB", ASCIIArmor: testArmor})
+	db.Create(&models.SigningKey{Namespace: "other", KeyID: "CCCCCCCCCCCCCCCC", ASCIIArmor: testArmor})
+
+	keys := trustedSigningKeys(db, "acme").GPGPublicKeys
+	if len(keys) != 2 {
+		t.Fatalf("len(keys) = %d, want 2", len(keys))
+	}
+	if keys[0].KeyID != "BBBBBBBBBBBBBBBB" || keys[1].KeyID != "AAAAAAAAAAAAAAAA" {
+		t.Errorf("keys = %s, %s; want namespace key before registry-wide key", keys[0].KeyID, keys[1].KeyID)
+	}
+
+	if keys := trustedSigningKeys(db, "empty").GPGPublicKeys; len(keys) != 1 {
+		t.Errorf("len(keys) for namespace without own keys = %d, want 1", len(keys))
+	}
+}
+
+func TestCreateSigningKey(t *testing.T) {
+	db := newTestDB(t)
+	h := NewSigningKeyHandler(db)
+	router := gin.New()
+	router.POST("/signing-keys", h.CreateSigningKey)
+
+	armor := strings.ReplaceAll(testArmor, "\n", `\n`)
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"valid", `{"namespace":"acme","key_id":"34365d9472d7468f","ascii_armor":"` + armor + `"}`, http.StatusCreated},
+		{"duplicate", `{"namespace":"acme","key_id":"34365D9472D7468F","ascii_armor":"` + armor + `"}`, http.StatusConflict},
+		{"short key id", `{"key_id":"7468F","ascii_armor":"` + armor + `"}`, http.StatusBadRequest},
+		{"not a public key", `{"key_id":"34365D9472D7468F","ascii_armor":"hello"}`, http.StatusBadRequest},
+		{"invalid namespace", `{"namespace":"Bad NS","key_id":"34365D9472D7468F","ascii_armor":"` + armor + `"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/signing-keys", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Package api provides HTTP handlers for the static filesystem mirror.
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// The static mirror exposes the cache as a plain file tree for clients that
+// walk directory listings instead of speaking the network mirror protocol:
+//
+//	/registry.terraform.io/                                  namespaces
+//	/registry.terraform.io/{namespace}/                      providers
+//	/registry.terraform.io/{namespace}/{name}/               index.json, {version}.json, {version}/
+//	/registry.terraform.io/{namespace}/{name}/{version}/     {os}_{arch}.zip
+//
+// index.json and {version}.json are the network mirror documents served by
+// ProviderMirrorHandler, so both kinds of client read the same tree. Only
+// cached platforms are listed or served; nothing is fetched from upstream.
+
+// cachedPlatforms selects cached platforms joined with their provider version.
+func (h *MirrorHandler) cachedPlatforms() *gorm.DB {
+	return h.db.Model(&models.ProviderPlatform{}).
+		Joins("JOIN providers ON providers.id = provider_platforms.provider_id AND providers.deleted_at IS NULL").
+		Where("provider_platforms.file_path <> ''")
+}
+
+// writeDirectoryListing renders entries as a minimal HTML index in the style of
+// a web server's autoindex. Entries ending in "/" are subdirectories.
+func writeDirectoryListing(c *gin.Context, entries []string) {
+	var b strings.Builder
+	title := html.EscapeString(c.Request.URL.Path)
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><title>Index of %s</title></head>\n<body><h1>Index of %s</h1><pre>\n", title, title)
+	b.WriteString("<a href=\"../\">../</a>\n")
+	for _, entry := range entries {
+		href := url.PathEscape(strings.TrimSuffix(entry, "/"))
+		if strings.HasSuffix(entry, "/") {
+			href += "/"
+		}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", href, html.EscapeString(entry))
+	}
+	b.WriteString("</pre></body></html>\n")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(b.String()))
+}
+
+// ListStaticNamespaces lists the namespaces with cached platforms.
+func (h *MirrorHandler) ListStaticNamespaces(c *gin.Context) {
+	var namespaces []string
+	h.cachedPlatforms().Distinct("providers.namespace").Order("providers.namespace").Pluck("providers.namespace", &namespaces)
+	entries := make([]string, len(namespaces))
+	for i, ns := range namespaces {
+		entries[i] = ns + "/"
+	}
+	writeDirectoryListing(c, entries)
+}
+
+// ListStaticProviders lists the providers of a namespace with cached platforms.
+func (h *MirrorHandler) ListStaticProviders(c *gin.Context) {
+	namespace := c.Param("namespace")
+	var names []string
+	h.cachedPlatforms().Where("providers.namespace = ?", namespace).
+		Distinct("providers.name").Order("providers.name").Pluck("providers.name", &names)
+	if len(names) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "namespace not found"})
+		return
+	}
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = name + "/"
+	}
+	writeDirectoryListing(c, entries)
+}
+
+// ListStaticVersions lists the mirror documents and version directories of a
+// provider, newest version first.
+func (h *MirrorHandler) ListStaticVersions(c *gin.Context) {
+	var versions []string
+	h.cachedPlatforms().Where("providers.namespace = ? AND providers.name = ?", c.Param("namespace"), c.Param("name")).
+		Distinct("providers.version").Pluck("providers.version", &versions)
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+	semver.SortDescending(versions)
+	entries := []string{"index.json"}
+	for _, v := range versions {
+		entries = append(entries, v+".json", v+"/")
+	}
+	writeDirectoryListing(c, entries)
+}
+
+// ListStaticArchives lists the cached platform archives of a provider version.
+func (h *MirrorHandler) ListStaticArchives(c *gin.Context) {
+	var platforms []models.ProviderPlatform
+	h.cachedPlatforms().
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ?",
+			c.Param("namespace"), c.Param("name"), c.Param("version")).
+		Find(&platforms)
+	if len(platforms) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+	entries := make([]string, len(platforms))
+	for i, p := range platforms {
+		entries[i] = p.OS + "_" + p.Arch + ".zip"
+	}
+	sort.Strings(entries)
+	writeDirectoryListing(c, entries)
+}
+
+// ServeStaticArchive serves a cached platform archive named {os}_{arch}.zip.
+// Downloads are counted as they are through the registry protocol.
+func (h *MirrorHandler) ServeStaticArchive(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	osType, arch, ok := strings.Cut(strings.TrimSuffix(c.Param("archive"), ".zip"), "_")
+	if !ok || !strings.HasSuffix(c.Param("archive"), ".zip") ||
+		!validIdentifierStrict.MatchString(osType) || !validIdentifierStrict.MatchString(arch) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "archive not found"})
+		return
+	}
+
+	var platform models.ProviderPlatform
+	err := h.cachedPlatforms().
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND provider_platforms.os = ? AND provider_platforms.arch = ?",
+			namespace, name, version, osType, arch).
+		First(&platform).Error
+	if err != nil || !h.proxyService.FileExists(platform.FilePath) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "archive not found"})
+		return
+	}
+
+	h.touchPlatform(platform.ID)
+	h.serveFile(c, platform.FilePath, platform.Filename)
+	h.countDownload(c, platform.ProviderID, platform.FileSize, false)
+}
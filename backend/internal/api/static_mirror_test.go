@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestStaticMirror(t *testing.T) {
+	db := newTestDB(t)
+	cfg := testConfig(t)
+	cfg.Server.StaticMirror = true
+	router := SetupRouter(db, auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, cfg)
+
+	archive := filepath.Join(cfg.Storage.Path, "aws_5.0.0_linux_amd64.zip")
+	if err := os.WriteFile(archive, []byte("zip-bytes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", FilePath: archive})
+	// Not cached: must be neither listed nor served.
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "darwin", Arch: "arm64"})
+	older := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.9.0"}
+	db.Create(&older)
+	db.Create(&models.ProviderPlatform{ProviderID: older.ID, OS: "linux", Arch: "amd64", FilePath: archive})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	listings := []struct {
+		path string
+		want []string
+		not  []string
+	}{
+		{"/registry.terraform.io/", []string{`href="hashicorp/"`}, nil},
+		{"/registry.terraform.io/hashicorp/", []string{`href="aws/"`}, nil},
+		{"/registry.terraform.io/hashicorp/aws/", []string{`href="index.json"`, `href="5.0.0.json"`, `href="5.0.0/"`, `href="4.9.0/"`}, nil},
+		{"/registry.terraform.io/hashicorp/aws/5.0.0/", []string{`href="linux_amd64.zip"`}, []string{"darwin_arm64"}},
+	}
+	for _, tt := range listings {
+		w := get(tt.path)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("%s: status = %d, content type = %q", tt.path, w.Code, w.Header().Get("Content-Type"))
+			continue
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(w.Body.String(), s) {
+				t.Errorf("%s: listing missing %s; body = %s", tt.path, s, w.Body.String())
+			}
+		}
+		for _, s := range tt.not {
+			if strings.Contains(w.Body.String(), s) {
+				t.Errorf("%s: listing includes %s", tt.path, s)
+			}
+		}
+	}
+	body := get("/registry.terraform.io/hashicorp/aws/").Body.String()
+	if strings.Index(body, "5.0.0/") > strings.Index(body, "4.9.0/") {
+		t.Errorf("versions not listed newest first: %s", body)
+	}
+
+	w := get("/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64.zip")
+	if w.Code != http.StatusOK || w.Body.String() != "zip-bytes" {
+		t.Fatalf("archive: status = %d, body = %q", w.Code, w.Body.String())
+	}
+	db.First(&provider, provider.ID)
+	if provider.Downloads != 1 {
+		t.Errorf("Downloads = %d, want 1", provider.Downloads)
+	}
+
+	for _, path := range []string{
+		"/registry.terraform.io/hashicorp/aws/5.0.0/darwin_arm64.zip",
+		"/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64.tar",
+		"/registry.terraform.io/hashicorp/aws/6.0.0/",
+		"/registry.terraform.io/acme/",
+	} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+
+	// The network mirror documents keep being served alongside the tree.
+	if w := get("/registry.terraform.io/hashicorp/aws/index.json"); w.Code != http.StatusOK {
+		t.Errorf("index.json: status = %d", w.Code)
+	}
+}
+
+func TestStaticMirror_Disabled(t *testing.T) {
+	db := newTestDB(t)
+	router := SetupRouter(db, auth.NewJWTManager("test-secret", time.Hour), nil, nil, nil, testConfig(t))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/registry.terraform.io/hashicorp/aws/5.0.0/linux_amd64.zip", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+// memStorage is an in-memory storage.Storage standing in for a remote backend.
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+type memObject struct{ *bytes.Reader }
+
+func (memObject) Close() error { return nil }
+
+func (m *memStorage) Save(path string, data io.Reader) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[path] = b
+	return nil
+}
+
+func (m *memStorage) Get(path string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return memObject{bytes.NewReader(b)}, nil
+}
+
+func (m *memStorage) Delete(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, path)
+	return nil
+}
+
+func (m *memStorage) Exists(path string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.objects[path]
+	return ok, nil
+}
+
+func (m *memStorage) Size(path string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.objects[path]
+	if !ok {
+		return 0, fs.ErrNotExist
+	}
+	return int64(len(b)), nil
+}
+
+func TestMirrorHandler_UsesConfiguredStorage(t *testing.T) {
+	store := &memStorage{objects: map[string][]byte{}}
+	proxy.UseStorage(store)
+	t.Cleanup(func() { proxy.UseStorage(nil) })
+
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+
+	// The first download reads through from upstream, the second is served
+	// from the stored copy.
+	for i := 0; i < 2; i++ {
+		w := serveDownload(t, h)
+		if w.Code != http.StatusOK || w.Body.String() != "provider-binary" {
+			t.Fatalf("download %d: status = %d, body = %q", i+1, w.Code, w.Body.String())
+		}
+	}
+
+	if len(store.objects) != 1 {
+		t.Fatalf("stored objects = %v, want the provider binary", store.objects)
+	}
+	var platform models.ProviderPlatform
+	db.First(&platform)
+	if platform.FileSize != int64(len("provider-binary")) {
+		t.Errorf("FileSize = %d", platform.FileSize)
+	}
+	_ = filepath.WalkDir(storagePath, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("file %s written to the local storage path", path)
+		}
+		return nil
+	})
+
+	// Range requests are answered from the backend's reader.
+	router := gin.New()
+	router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary", h.DownloadProvider)
+	req := httptest.NewRequest(http.MethodGet, "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary", nil)
+	req.Header.Set("Range", "bytes=9-")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "binary" {
+		t.Errorf("range: status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,280 @@
+// Package api provides the admin job that moves cached provider files to a new
+// storage location.
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/storage"
+	"github.com/Veritas-Calculus/vc-terraform-registry/pkg/config"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Storage migration states.
+const (
+	migrationIdle      = "idle"
+	migrationRunning   = "running"
+	migrationCompleted = "completed"
+	migrationFailed    = "failed"
+)
+
+// StorageMigrationStatus reports the progress of the current or last migration.
+type StorageMigrationStatus struct {
+	State      string     `json:"state"` // idle, running, completed, failed
+	Target     string     `json:"target,omitempty"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Copied     int        `json:"copied"`  // Files copied, or found intact in the target
+	Skipped    int        `json:"skipped"` // Rows already pointing into the target
+	Missing    int        `json:"missing"` // Rows whose source file is gone
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// StorageMigrationHandler copies provider platform files from the configured
+// storage backend to a new one. The target is either another directory, into
+// which the database rows are repointed, or the bucket configured under
+// storage.s3, whose object keys are the same paths relative to the storage
+// path, so the rows are left as they are. Switch STORAGE_PATH or STORAGE_TYPE
+// to the target and restart once the migration has completed.
+type StorageMigrationHandler struct {
+	db          *gorm.DB
+	storagePath string
+	// storageType is the configured storage.type; empty means local.
+	storageType string
+	// openS3 opens the storage.s3 bucket a migration to S3 copies into; nil
+	// when no bucket is configured. s3Target names it in the status.
+	openS3   func() (storage.Storage, error)
+	s3Target string
+
+	mu     sync.Mutex
+	status StorageMigrationStatus
+}
+
+// NewStorageMigrationHandler creates a new StorageMigrationHandler instance.
+func NewStorageMigrationHandler(db *gorm.DB, storagePath string) *StorageMigrationHandler {
+	return &StorageMigrationHandler{
+		db:          db,
+		storagePath: storagePath,
+		status:      StorageMigrationStatus{State: migrationIdle},
+	}
+}
+
+// useS3 makes the bucket described by cfg the target of migrations to S3.
+func (h *StorageMigrationHandler) useS3(cfg config.S3Config) {
+	h.s3Target = "s3://" + strings.Trim(cfg.Bucket+"/"+cfg.Prefix, "/")
+	h.openS3 = func() (storage.Storage, error) { return storage.OpenS3(cfg) }
+}
+
+// StartStorageMigrationRequest is the body of POST /api/v1/admin/migrate-storage.
+// TargetType is "local", the default, with TargetPath the new directory, or
+// "s3" for the bucket configured under storage.s3.
+type StartStorageMigrationRequest struct {
+	TargetType string `json:"target_type"`
+	TargetPath string `json:"target_path"`
+}
+
+// migrationTarget is where a migration copies files to. Files hold them under
+// the storage path root; dir is the directory rows are repointed into, empty
+// when they keep their path.
+type migrationTarget struct {
+	files *proxy.ProxyService
+	root  string
+	dir   string
+}
+
+// StartMigration starts copying every platform file into the target in the
+// background. Running it again resumes: rows already in the target are skipped
+// and intact files are not copied twice. Source files are left in place.
+func (h *StorageMigrationHandler) StartMigration(c *gin.Context) {
+	var req StartStorageMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target migrationTarget
+	var name string
+	switch req.TargetType {
+	case "s3":
+		if h.storageType == "s3" {
+			c.JSON(http.StatusConflict, gin.H{"error": "provider files are already stored in S3"})
+			return
+		}
+		if h.openS3 == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no S3 bucket is configured under storage.s3"})
+			return
+		}
+		store, err := h.openS3()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot open the S3 bucket: %v", err)})
+			return
+		}
+		target = migrationTarget{files: proxy.NewProxyServiceWithStorage(h.storagePath, "", store), root: h.storagePath}
+		name = h.s3Target
+	case "", "local":
+		if req.TargetPath == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target_path is required"})
+			return
+		}
+		dir, err := filepath.Abs(req.TargetPath)
+		if err != nil || strings.ContainsRune(req.TargetPath, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_path"})
+			return
+		}
+		if config.PathWithin(dir, h.storagePath) || config.PathWithin(h.storagePath, dir) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target_path must not overlap the current storage path"})
+			return
+		}
+		store, err := storage.NewLocalStorage(dir)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot create target_path: %v", err)})
+			return
+		}
+		target = migrationTarget{files: proxy.NewProxyServiceWithStorage(dir, "", store), root: dir, dir: dir}
+		name = dir
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type must be local or s3"})
+		return
+	}
+
+	h.mu.Lock()
+	if h.status.State == migrationRunning {
+		status := h.status
+		h.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "a storage migration is already running", "status": status})
+		return
+	}
+	now := time.Now()
+	h.status = StorageMigrationStatus{State: migrationRunning, Target: name, StartedAt: &now}
+	status := h.status
+	h.mu.Unlock()
+
+	go h.run(target, name)
+	c.JSON(http.StatusAccepted, status)
+}
+
+// GetMigrationStatus returns the progress of the current or last migration.
+func (h *StorageMigrationHandler) GetMigrationStatus(c *gin.Context) {
+	h.mu.Lock()
+	status := h.status
+	h.mu.Unlock()
+	c.JSON(http.StatusOK, status)
+}
+
+// update applies fn to the status under the lock.
+func (h *StorageMigrationHandler) update(fn func(s *StorageMigrationStatus)) {
+	h.mu.Lock()
+	fn(&h.status)
+	h.mu.Unlock()
+}
+
+// run migrates every platform row to target.
+func (h *StorageMigrationHandler) run(target migrationTarget, name string) {
+	var platforms []models.ProviderPlatform
+	if err := h.db.Order("id").Find(&platforms).Error; err != nil {
+		h.finish(migrationFailed, fmt.Sprintf("failed to load platforms: %v", err))
+		return
+	}
+	h.update(func(s *StorageMigrationStatus) { s.Total = len(platforms) })
+	log.Printf("Storage migration: moving %d platform files to %s", len(platforms), logsafe.Clean(name))
+
+	source := proxy.NewProxyService(h.storagePath, "")
+	for i, p := range platforms {
+		if i > 0 && i%backfillProgressInterval == 0 {
+			log.Printf("Storage migration: processed %d/%d", i, len(platforms))
+		}
+		outcome := h.migratePlatform(p, source, target)
+		h.update(func(s *StorageMigrationStatus) {
+			s.Processed++
+			switch outcome {
+			case "copied":
+				s.Copied++
+			case "skipped":
+				s.Skipped++
+			case "missing":
+				s.Missing++
+			default:
+				s.Failed++
+			}
+		})
+	}
+
+	h.mu.Lock()
+	status := h.status
+	h.mu.Unlock()
+	log.Printf("Storage migration: done, %d copied, %d skipped, %d missing, %d failed",
+		status.Copied, status.Skipped, status.Missing, status.Failed)
+	if status.Failed > 0 {
+		h.finish(migrationFailed, fmt.Sprintf("%d platform files could not be migrated; run the migration again to retry them", status.Failed))
+		return
+	}
+	h.finish(migrationCompleted, "")
+}
+
+// finish records the final state of a run.
+func (h *StorageMigrationHandler) finish(state, errMsg string) {
+	now := time.Now()
+	h.update(func(s *StorageMigrationStatus) {
+		s.State = state
+		s.Error = errMsg
+		s.FinishedAt = &now
+	})
+}
+
+// migratePlatform copies one platform file to the same relative location in
+// the target, verifying its checksum, and repoints the row if the target is a
+// directory. It returns "copied", "skipped", "missing" or "failed".
+func (h *StorageMigrationHandler) migratePlatform(p models.ProviderPlatform, source *proxy.ProxyService, target migrationTarget) string {
+	if target.dir != "" && config.PathWithin(p.FilePath, target.dir) {
+		return "skipped"
+	}
+	rel, err := filepath.Rel(h.storagePath, p.FilePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		log.Printf("Storage migration: platform %d file %s is outside the storage path", p.ID, logsafe.Clean(p.FilePath))
+		return "failed"
+	}
+	if !source.FileExists(p.FilePath) {
+		return "missing"
+	}
+
+	dest := filepath.Join(target.root, rel)
+	if sum, err := target.files.CalculateFileSHA256(dest); err != nil || (p.SHA256Sum != "" && !strings.EqualFold(sum, p.SHA256Sum)) {
+		if err := copyPlatformFile(source, p, target.files, dest); err != nil {
+			log.Printf("Storage migration: failed to copy platform %d: %s", p.ID, logsafe.CleanErr(err))
+			return "failed"
+		}
+	}
+
+	if dest != p.FilePath {
+		if err := h.db.Model(&models.ProviderPlatform{}).Where("id = ?", p.ID).UpdateColumn("file_path", dest).Error; err != nil {
+			log.Printf("Storage migration: failed to update platform %d: %s", p.ID, logsafe.CleanErr(err))
+			return "failed"
+		}
+	}
+	return "copied"
+}
+
+// copyPlatformFile copies a platform file to dest in the target, which keeps
+// it only if it matches the platform's checksum (if recorded).
+func copyPlatformFile(source *proxy.ProxyService, p models.ProviderPlatform, target *proxy.ProxyService, dest string) error {
+	r, err := source.OpenFile(p.FilePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	_, _, err = target.StoreFile(dest, r, p.SHA256Sum)
+	return err
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStorageMigration(t *testing.T) {
+	db := newTestDB(t)
+	source := t.TempDir()
+	target := filepath.Join(t.TempDir(), "new-storage")
+
+	const content = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9"
+	rel := filepath.Join("hashicorp", "aws", "5.0.0", "linux", "amd64", "provider.zip")
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(source, rel)), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, rel), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	good := models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: "provider.zip",
+		FilePath: filepath.Join(source, rel), SHA256Sum: sum}
+	gone := models.ProviderPlatform{ProviderID: provider.ID, OS: "darwin", Arch: "arm64", Filename: "gone.zip",
+		FilePath: filepath.Join(source, "gone.zip"), SHA256Sum: sum}
+	db.Create(&good)
+	db.Create(&gone)
+
+	router := gin.New()
+	h := NewStorageMigrationHandler(db, source)
+	router.POST("/migrate", h.StartMigration)
+	router.GET("/migrate", h.GetMigrationStatus)
+
+	migrate := func(targetPath string) (int, StorageMigrationStatus) {
+		targetType := ""
+		if targetPath == "s3" {
+			targetType, targetPath = "s3", ""
+		}
+		w := httptest.NewRecorder()
+		body, _ := json.Marshal(StartStorageMigrationRequest{TargetType: targetType, TargetPath: targetPath})
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/migrate", bytes.NewReader(body)))
+		if w.Code != http.StatusAccepted {
+			return w.Code, StorageMigrationStatus{}
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/migrate", nil))
+			var status StorageMigrationStatus
+			_ = json.Unmarshal(w.Body.Bytes(), &status)
+			if status.State != migrationRunning || time.Now().After(deadline) {
+				return http.StatusAccepted, status
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if code, _ := migrate(filepath.Join(source, "nested")); code != http.StatusBadRequest {
+		t.Errorf("target inside source: status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := migrate("s3"); code != http.StatusBadRequest {
+		t.Errorf("s3 without a bucket: status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	_, status := migrate(target)
+	if status.State != migrationCompleted || status.Total != 2 || status.Copied != 1 || status.Missing != 1 {
+		t.Fatalf("first run status = %+v", status)
+	}
+	var moved models.ProviderPlatform
+	db.First(&moved, good.ID)
+	if moved.FilePath != filepath.Join(target, rel) {
+		t.Errorf("FilePath = %q, want it under the target", moved.FilePath)
+	}
+	if data, err := os.ReadFile(moved.FilePath); err != nil || string(data) != content {
+		t.Errorf("migrated file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(source, rel)); err != nil {
+		t.Errorf("source file should be kept: %v", err)
+	}
+
+	// A second run resumes: the migrated row is skipped.
+	_, status = migrate(target)
+	if status.State != migrationCompleted || status.Skipped != 1 || status.Copied != 0 {
+		t.Errorf("second run status = %+v", status)
+	}
+}
+
+func TestStorageMigration_ToS3(t *testing.T) {
+	db := newTestDB(t)
+	source := t.TempDir()
+	const content = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9"
+	rel := filepath.Join("hashicorp", "aws", "5.0.0", "linux", "amd64", "provider.zip")
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(source, rel)), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, rel), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	platform := models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", Filename: "provider.zip",
+		FilePath: filepath.Join(source, rel), SHA256Sum: sum}
+	db.Create(&platform)
+	tampered := models.ProviderPlatform{ProviderID: provider.ID, OS: "darwin", Arch: "arm64", Filename: "provider.zip",
+		FilePath: filepath.Join(source, rel), SHA256Sum: strings.Repeat("a", 64)}
+	db.Create(&tampered)
+
+	// A local backend stands in for the bucket, with the same keys.
+	bucket := t.TempDir()
+	h := NewStorageMigrationHandler(db, source)
+	h.s3Target = "s3://registry"
+	h.openS3 = func() (storage.Storage, error) { return storage.NewLocalStorage(bucket) }
+	router := gin.New()
+	router.POST("/migrate", h.StartMigration)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/migrate", strings.NewReader(`{"target_type":"s3"}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h.mu.Lock()
+		status := h.status
+		h.mu.Unlock()
+		if status.State != migrationRunning || time.Now().After(deadline) {
+			if status.State != migrationFailed || status.Target != "s3://registry" || status.Copied != 1 || status.Failed != 1 {
+				t.Errorf("status = %+v, want the intact file copied and the one failing its checksum failed", status)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(bucket, rel)); err != nil || string(data) != content {
+		t.Errorf("object = %q, %v", data, err)
+	}
+	var got models.ProviderPlatform
+	db.First(&got, platform.ID)
+	if got.FilePath != platform.FilePath {
+		t.Errorf("FilePath = %q, want it kept for the S3 keys", got.FilePath)
+	}
+
+	h.storageType = "s3"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/migrate", strings.NewReader(`{"target_type":"s3"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("already on s3: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
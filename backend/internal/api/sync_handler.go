@@ -2,13 +2,14 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
 	"github.com/gin-gonic/gin"
-	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
 
@@ -16,6 +17,15 @@ import (
 type SyncHandler struct {
 	db          *gorm.DB
 	storagePath string
+	schedules   ScheduleController // nil leaves changes to the scheduler's periodic refresh
+}
+
+// ScheduleController is the part of the scheduler the sync API drives.
+type ScheduleController interface {
+	// Reload applies sync schedule changes to the running scheduler.
+	Reload()
+	// TriggerSync starts a run of a schedule in the background.
+	TriggerSync(scheduleID uint) error
 }
 
 // NewSyncHandler creates a new SyncHandler.
@@ -28,20 +38,56 @@ func NewSyncHandler(db *gorm.DB, storagePath string) *SyncHandler {
 
 // CreateScheduleRequest represents the request to create a sync schedule.
 type CreateScheduleRequest struct {
-	Namespace string `json:"namespace" binding:"required"`
-	Name      string `json:"name" binding:"required"`
-	CronExpr  string `json:"cron_expr" binding:"required"`
-	Enabled   bool   `json:"enabled"`
-	SyncOS    string `json:"sync_os"`
-	SyncArch  string `json:"sync_arch"`
+	Namespace      string `json:"namespace" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	CronExpr       string `json:"cron_expr" binding:"required"`
+	Enabled        bool   `json:"enabled"`
+	SyncOS         string `json:"sync_os"`         // Defaults to Settings.DefaultOS
+	SyncArch       string `json:"sync_arch"`       // Defaults to Settings.DefaultArch
+	PublishedAfter string `json:"published_after"` // YYYY-MM-DD or RFC 3339; older versions are not synced
+	Mode           string `json:"mode"`            // "latest" (default) or "latest-n"
+	VersionCount   int    `json:"version_count"`   // Releases kept cached in "latest-n" mode
 }
 
 // UpdateScheduleRequest represents the request to update a sync schedule.
 type UpdateScheduleRequest struct {
-	CronExpr *string `json:"cron_expr"`
-	Enabled  *bool   `json:"enabled"`
-	SyncOS   *string `json:"sync_os"`
-	SyncArch *string `json:"sync_arch"`
+	CronExpr       *string `json:"cron_expr"`
+	Enabled        *bool   `json:"enabled"`
+	SyncOS         *string `json:"sync_os"`
+	SyncArch       *string `json:"sync_arch"`
+	PublishedAfter *string `json:"published_after"` // An empty string clears the filter
+	Mode           *string `json:"mode"`
+	VersionCount   *int    `json:"version_count"`
+}
+
+// maxScheduleVersionCount bounds how many releases a "latest-n" schedule keeps cached.
+const maxScheduleVersionCount = 50
+
+// validateSyncMode checks a schedule's mode and version count. Returns the
+// error message, or "" if they are valid.
+func validateSyncMode(mode string, versionCount int) string {
+	switch mode {
+	case models.SyncModeLatest:
+		return ""
+	case models.SyncModeLatestN:
+		if versionCount < 1 || versionCount > maxScheduleVersionCount {
+			return fmt.Sprintf("version_count must be between 1 and %d in %s mode", maxScheduleVersionCount, models.SyncModeLatestN)
+		}
+		return ""
+	default:
+		return fmt.Sprintf("mode must be %q or %q", models.SyncModeLatest, models.SyncModeLatestN)
+	}
+}
+
+// BulkScheduleRequest enables or disables many sync schedules at once. The
+// schedules are selected by IDs, by namespace (optionally narrowed to one
+// provider name), or with All.
+type BulkScheduleRequest struct {
+	IDs       []uint `json:"ids"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	All       bool   `json:"all"`
+	Enabled   *bool  `json:"enabled" binding:"required"`
 }
 
 // ListSchedules returns all sync schedules.
@@ -70,37 +116,52 @@ func (h *SyncHandler) CreateSchedule(c *gin.Context) {
 		return
 	}
 
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(req.CronExpr)
+	nextRun, err := scheduler.NextRun(req.CronExpr, time.Now())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cron expression: " + err.Error()})
 		return
 	}
 
-	var existing models.SyncSchedule
-	if err := h.db.Where("namespace = ? AND name = ?", req.Namespace, req.Name).First(&existing).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Schedule already exists for this provider"})
+	publishedAfter, err := parsePublishedAfter(req.PublishedAfter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	syncOS := req.SyncOS
-	if syncOS == "" {
-		syncOS = "all"
+	if req.Mode == "" {
+		req.Mode = models.SyncModeLatest
 	}
-	syncArch := req.SyncArch
-	if syncArch == "" {
-		syncArch = "all"
+	if errMsg := validateSyncMode(req.Mode, req.VersionCount); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	// Omitted platforms take the configured defaults at creation time; later
+	// changes to the defaults leave existing schedules alone.
+	syncOS, syncArch := defaultPlatform(h.db)
+	if req.SyncOS != "" {
+		syncOS = req.SyncOS
+	}
+	if req.SyncArch != "" {
+		syncArch = req.SyncArch
+	}
+
+	if h.scheduleExists(req.Namespace, req.Name, syncOS, syncArch, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule already exists for this provider and platform selection"})
+		return
 	}
 
-	nextRun := schedule.Next(time.Now())
 	newSchedule := models.SyncSchedule{
-		Namespace: req.Namespace,
-		Name:      req.Name,
-		CronExpr:  req.CronExpr,
-		Enabled:   req.Enabled,
-		SyncOS:    syncOS,
-		SyncArch:  syncArch,
-		NextRunAt: &nextRun,
+		Namespace:      req.Namespace,
+		Name:           req.Name,
+		CronExpr:       req.CronExpr,
+		Enabled:        req.Enabled,
+		SyncOS:         syncOS,
+		SyncArch:       syncArch,
+		NextRunAt:      &nextRun,
+		PublishedAfter: publishedAfter,
+		Mode:           req.Mode,
+		VersionCount:   req.VersionCount,
 	}
 
 	if err := h.db.Create(&newSchedule).Error; err != nil {
@@ -111,6 +172,18 @@ func (h *SyncHandler) CreateSchedule(c *gin.Context) {
 	c.JSON(http.StatusCreated, newSchedule)
 }
 
+// scheduleExists reports whether another schedule already targets the same
+// provider and platform selection. A provider may have several schedules as
+// long as each syncs a different OS/arch subset; excludeID skips the schedule
+// being updated.
+func (h *SyncHandler) scheduleExists(namespace, name, syncOS, syncArch string, excludeID uint) bool {
+	var count int64
+	h.db.Model(&models.SyncSchedule{}).
+		Where("namespace = ? AND name = ? AND sync_os = ? AND sync_arch = ? AND id <> ?", namespace, name, syncOS, syncArch, excludeID).
+		Count(&count)
+	return count > 0
+}
+
 // UpdateSchedule updates an existing sync schedule.
 func (h *SyncHandler) UpdateSchedule(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -132,15 +205,17 @@ func (h *SyncHandler) UpdateSchedule(c *gin.Context) {
 	}
 
 	if req.CronExpr != nil {
-		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		cronSchedule, err := parser.Parse(*req.CronExpr)
+		nextRun, err := scheduler.NextRun(*req.CronExpr, time.Now())
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cron expression: " + err.Error()})
 			return
 		}
 		schedule.CronExpr = *req.CronExpr
-		nextRun := cronSchedule.Next(time.Now())
 		schedule.NextRunAt = &nextRun
+		if schedule.LastStatus == scheduler.StatusInvalid {
+			schedule.LastStatus = ""
+			schedule.LastError = ""
+		}
 	}
 
 	if req.Enabled != nil {
@@ -152,6 +227,32 @@ func (h *SyncHandler) UpdateSchedule(c *gin.Context) {
 	if req.SyncArch != nil {
 		schedule.SyncArch = *req.SyncArch
 	}
+	if req.PublishedAfter != nil {
+		publishedAfter, err := parsePublishedAfter(*req.PublishedAfter)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		schedule.PublishedAfter = publishedAfter
+	}
+	if req.Mode != nil {
+		schedule.Mode = *req.Mode
+	}
+	if req.VersionCount != nil {
+		schedule.VersionCount = *req.VersionCount
+	}
+	if req.Mode != nil || req.VersionCount != nil {
+		if errMsg := validateSyncMode(schedule.Mode, schedule.VersionCount); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+
+	if (req.SyncOS != nil || req.SyncArch != nil) &&
+		h.scheduleExists(schedule.Namespace, schedule.Name, schedule.SyncOS, schedule.SyncArch, schedule.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Schedule already exists for this provider and platform selection"})
+		return
+	}
 
 	if err := h.db.Save(&schedule).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
@@ -177,7 +278,46 @@ func (h *SyncHandler) DeleteSchedule(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted"})
 }
 
-// RunScheduleNow triggers an immediate sync for a schedule.
+// Page sizes of ListScheduleRuns.
+const (
+	defaultRunsPageSize = 20
+	maxRunsPageSize     = 100
+)
+
+// ListScheduleRuns returns a schedule's recorded sync attempts, newest first.
+// The scheduler keeps the last 100 per schedule.
+func (h *SyncHandler) ListScheduleRuns(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+	var schedule models.SyncSchedule
+	if err := h.db.First(&schedule, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	page, limit := pageParams(c, defaultRunsPageSize, maxRunsPageSize)
+
+	query := h.db.Model(&models.SyncRun{}).Where("schedule_id = ?", schedule.ID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list runs"})
+		return
+	}
+	pagination := newPagination(page, limit, total)
+	runs := make([]models.SyncRun, 0)
+	if err := query.Order("id DESC").Offset(pagination.offset()).Limit(limit).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginated(gin.H{"runs": runs}, pagination))
+}
+
+// RunScheduleNow starts a sync of a schedule in the background and answers
+// right away; the run's progress shows in the schedule's status and its runs.
 func (h *SyncHandler) RunScheduleNow(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -191,14 +331,100 @@ func (h *SyncHandler) RunScheduleNow(c *gin.Context) {
 		return
 	}
 
-	now := time.Now()
-	schedule.LastRunAt = &now
-	schedule.LastStatus = "running"
-	schedule.LastError = ""
-	h.db.Save(&schedule)
+	if h.schedules == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is not available"})
+		return
+	}
+	if err := h.schedules.TriggerSync(schedule.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
 		"message":  "Sync triggered",
 		"schedule": schedule,
 	})
 }
+
+// RunAllSchedules starts a sync of every enabled schedule in the background,
+// for example after a proxy or credential change, and reports how many were
+// triggered. Schedules whose sync is still running are skipped.
+func (h *SyncHandler) RunAllSchedules(c *gin.Context) {
+	if h.schedules == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is not available"})
+		return
+	}
+
+	var schedules []models.SyncSchedule
+	if err := h.db.Where("enabled = ?", true).Order("id").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load schedules"})
+		return
+	}
+
+	triggered, skipped := 0, 0
+	for _, schedule := range schedules {
+		if schedule.LastStatus == scheduler.StatusRunning {
+			skipped++
+			continue
+		}
+		if err := h.schedules.TriggerSync(schedule.ID); err != nil {
+			skipped++
+			continue
+		}
+		triggered++
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":   "Sync triggered",
+		"triggered": triggered,
+		"skipped":   skipped,
+	})
+}
+
+// BulkUpdateSchedules enables or disables the selected schedules in one
+// transaction and reports how many actually changed.
+func (h *SyncHandler) BulkUpdateSchedules(c *gin.Context) {
+	var req BulkScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 && req.Namespace == "" && !req.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids, namespace, or all is required"})
+		return
+	}
+	if req.Name != "" && req.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name requires namespace"})
+		return
+	}
+
+	var changed int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.SyncSchedule{}).Where("enabled <> ?", *req.Enabled)
+		if len(req.IDs) > 0 {
+			query = query.Where("id IN ?", req.IDs)
+		}
+		if req.Namespace != "" {
+			query = query.Where("namespace = ?", req.Namespace)
+		}
+		if req.Name != "" {
+			query = query.Where("name = ?", req.Name)
+		}
+		result := query.Update("enabled", *req.Enabled)
+		changed = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedules"})
+		return
+	}
+
+	if changed > 0 && h.schedules != nil {
+		h.schedules.Reload()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changed": changed,
+		"enabled": *req.Enabled,
+	})
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSchedules_PlatformSubsets(t *testing.T) {
+	db := newTestDB(t)
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.POST("/schedules", h.CreateSchedule)
+	router.PUT("/schedules/:id", h.UpdateSchedule)
+
+	send := func(method, path, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	create := func(syncOS string) int {
+		return send(http.MethodPost, "/schedules",
+			fmt.Sprintf(`{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","sync_os":%q}`, syncOS))
+	}
+
+	if code := create("linux"); code != http.StatusCreated {
+		t.Fatalf("linux schedule: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := create("windows"); code != http.StatusCreated {
+		t.Fatalf("windows schedule: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := create("linux"); code != http.StatusConflict {
+		t.Errorf("duplicate linux schedule: status = %d, want %d", code, http.StatusConflict)
+	}
+
+	// Moving the windows schedule onto linux would duplicate the first one.
+	if code := send(http.MethodPut, "/schedules/2", `{"sync_os":"linux"}`); code != http.StatusConflict {
+		t.Errorf("update to duplicate: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := send(http.MethodPut, "/schedules/2", `{"sync_os":"windows","sync_arch":"amd64"}`); code != http.StatusOK {
+		t.Errorf("update own platforms: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+// fakeScheduler records what the sync API asked the scheduler to do.
+type fakeScheduler struct {
+	reloads   int
+	triggered []uint
+}
+
+func (f *fakeScheduler) Reload() { f.reloads++ }
+
+func (f *fakeScheduler) TriggerSync(scheduleID uint) error {
+	f.triggered = append(f.triggered, scheduleID)
+	return nil
+}
+
+func TestSchedules_RunAll(t *testing.T) {
+	db := newTestDB(t)
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.POST("/sync/run-all", h.RunAllSchedules)
+	run := func() (int, map[string]int) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sync/run-all", nil))
+		var resp map[string]int
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := run(); code != http.StatusServiceUnavailable {
+		t.Errorf("without a scheduler: status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	scheduler := &fakeScheduler{}
+	h.schedules = scheduler
+	for _, s := range []models.SyncSchedule{
+		{Namespace: "hashicorp", Name: "aws", CronExpr: "0 2 * * *", Enabled: true},
+		{Namespace: "hashicorp", Name: "google", CronExpr: "0 2 * * *", Enabled: true, LastStatus: "running"},
+		{Namespace: "acme", Name: "dns", CronExpr: "0 2 * * *", Enabled: true, LastStatus: "failed"},
+		{Namespace: "acme", Name: "paused", CronExpr: "0 2 * * *"},
+	} {
+		db.Create(&s)
+	}
+	// Enabled defaults to true, so a false value is not written on create.
+	db.Model(&models.SyncSchedule{}).Where("name = ?", "paused").Update("enabled", false)
+
+	code, resp := run()
+	if code != http.StatusAccepted || resp["triggered"] != 2 || resp["skipped"] != 1 {
+		t.Errorf("status = %d, response = %v; want 2 triggered and the running one skipped", code, resp)
+	}
+	if len(scheduler.triggered) != 2 || scheduler.triggered[0] != 1 || scheduler.triggered[1] != 3 {
+		t.Errorf("triggered = %v, want [1 3]", scheduler.triggered)
+	}
+}
+
+func TestSchedules_BulkUpdate(t *testing.T) {
+	db := newTestDB(t)
+	reloader := &fakeScheduler{}
+	h := NewSyncHandler(db, t.TempDir())
+	h.schedules = reloader
+	router := gin.New()
+	router.POST("/schedules/bulk", h.BulkUpdateSchedules)
+
+	for _, s := range []models.SyncSchedule{
+		{Namespace: "hashicorp", Name: "aws", CronExpr: "0 2 * * *", SyncOS: "all", SyncArch: "all", Enabled: true},
+		{Namespace: "hashicorp", Name: "google", CronExpr: "0 2 * * *", SyncOS: "all", SyncArch: "all", Enabled: true},
+		{Namespace: "acme", Name: "dns", CronExpr: "0 2 * * *", SyncOS: "all", SyncArch: "all"},
+	} {
+		db.Create(&s)
+	}
+	// Enabled defaults to true, so a false value is not written on create.
+	db.Model(&models.SyncSchedule{}).Where("namespace = ?", "acme").Update("enabled", false)
+
+	bulk := func(body string) (int, int64) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/schedules/bulk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Changed int64 `json:"changed"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Changed
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantChanged int64
+	}{
+		{"no selector", `{"enabled":false}`, http.StatusBadRequest, 0},
+		{"missing enabled", `{"all":true}`, http.StatusBadRequest, 0},
+		{"name without namespace", `{"name":"aws","enabled":false}`, http.StatusBadRequest, 0},
+		{"pause namespace", `{"namespace":"hashicorp","enabled":false}`, http.StatusOK, 2},
+		{"already paused", `{"namespace":"hashicorp","enabled":false}`, http.StatusOK, 0},
+		{"resume by id", `{"ids":[1,3],"enabled":true}`, http.StatusOK, 2},
+		{"pause all", `{"all":true,"enabled":false}`, http.StatusOK, 2},
+	}
+	for _, tt := range tests {
+		code, changed := bulk(tt.body)
+		if code != tt.wantStatus || changed != tt.wantChanged {
+			t.Errorf("%s: status = %d, changed = %d; want %d, %d", tt.name, code, changed, tt.wantStatus, tt.wantChanged)
+		}
+	}
+
+	var enabled int64
+	db.Model(&models.SyncSchedule{}).Where("enabled = ?", true).Count(&enabled)
+	if enabled != 0 {
+		t.Errorf("%d schedules still enabled after pausing all", enabled)
+	}
+	// Requests that changed nothing do not reload the scheduler.
+	if reloader.reloads != 3 {
+		t.Errorf("reloads = %d, want 3", reloader.reloads)
+	}
+}
+
+func TestSchedules_InheritDefaultPlatform(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&models.Settings{AllowOnlineSearch: true, DefaultOS: "linux", DefaultArch: "arm64"})
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.POST("/schedules", h.CreateSchedule)
+
+	create := func(body string) models.SyncSchedule {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/schedules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, body = %s", body, w.Code, w.Body.String())
+		}
+		var schedule models.SyncSchedule
+		_ = json.Unmarshal(w.Body.Bytes(), &schedule)
+		return schedule
+	}
+
+	inherited := create(`{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *"}`)
+	if inherited.SyncOS != "linux" || inherited.SyncArch != "arm64" {
+		t.Errorf("omitted platforms = %s/%s, want linux/arm64", inherited.SyncOS, inherited.SyncArch)
+	}
+	explicit := create(`{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","sync_os":"darwin"}`)
+	if explicit.SyncOS != "darwin" || explicit.SyncArch != "arm64" {
+		t.Errorf("explicit os = %s/%s, want darwin/arm64", explicit.SyncOS, explicit.SyncArch)
+	}
+}
+
+func TestSchedules_NextRunMatchesScheduler(t *testing.T) {
+	db := newTestDB(t)
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.POST("/schedules", h.CreateSchedule)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/schedules",
+		strings.NewReader(`{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","enabled":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var created models.SyncSchedule
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.NextRunAt == nil {
+		t.Fatalf("create: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	s := scheduler.New(db, t.TempDir(), scheduler.RetryPolicy{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Stop()
+
+	// The running cron fills in Entry.Next shortly after it starts.
+	var next time.Time
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var ok bool
+		if next, ok = s.NextRunOf(created.ID); ok && !next.IsZero() {
+			break
+		}
+	}
+	if !next.Equal(*created.NextRunAt) {
+		t.Errorf("scheduler next run = %v, handler stored %v", next, *created.NextRunAt)
+	}
+
+	var stored models.SyncSchedule
+	db.First(&stored, created.ID)
+	if stored.NextRunAt == nil || !stored.NextRunAt.Equal(next) {
+		t.Errorf("next_run_at after scheduler start = %v, want %v", stored.NextRunAt, next)
+	}
+}
+
+func TestSchedules_LatestNMode(t *testing.T) {
+	db := newTestDB(t)
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.POST("/schedules", h.CreateSchedule)
+	router.PUT("/schedules/:id", h.UpdateSchedule)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"unknown mode", `{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","mode":"all"}`, http.StatusBadRequest},
+		{"latest-n without count", `{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","mode":"latest-n"}`, http.StatusBadRequest},
+		{"latest-n count too large", `{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","mode":"latest-n","version_count":51}`, http.StatusBadRequest},
+		{"latest-n", `{"namespace":"hashicorp","name":"aws","cron_expr":"0 2 * * *","mode":"latest-n","version_count":3}`, http.StatusCreated},
+		{"default mode", `{"namespace":"hashicorp","name":"google","cron_expr":"0 2 * * *"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		if w := send(http.MethodPost, "/schedules", tt.body); w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.status, w.Body.String())
+		}
+	}
+
+	var schedules []models.SyncSchedule
+	db.Order("id").Find(&schedules)
+	if len(schedules) != 2 || schedules[0].Mode != models.SyncModeLatestN || schedules[0].VersionCount != 3 ||
+		schedules[1].Mode != models.SyncModeLatest {
+		t.Fatalf("schedules = %+v", schedules)
+	}
+
+	path := fmt.Sprintf("/schedules/%d", schedules[1].ID)
+	if w := send(http.MethodPut, path, `{"mode":"latest-n"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("switch to latest-n without count: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := send(http.MethodPut, path, `{"mode":"latest-n","version_count":5}`); w.Code != http.StatusOK {
+		t.Errorf("switch to latest-n: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSchedules_ListRuns(t *testing.T) {
+	db := newTestDB(t)
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "google", CronExpr: "0 2 * * *"}
+	db.Create(&schedule)
+	for i, status := range []string{"success", "failed", "success"} {
+		db.Create(&models.SyncRun{ScheduleID: schedule.ID, Attempt: i + 1, Status: status, PlatformsDownloaded: i, Bytes: int64(i * 100)})
+	}
+	db.Create(&models.SyncRun{ScheduleID: schedule.ID + 1, Attempt: 1, Status: "success"})
+
+	h := NewSyncHandler(db, t.TempDir())
+	router := gin.New()
+	router.GET("/schedules/:id/runs", h.ListScheduleRuns)
+	list := func(path string) (int, []models.SyncRun, int64) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body struct {
+			Runs  []models.SyncRun `json:"runs"`
+			Total int64            `json:"total"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Runs, body.Total
+	}
+
+	code, runs, total := list(fmt.Sprintf("/schedules/%d/runs?limit=2", schedule.ID))
+	if code != http.StatusOK || total != 3 || len(runs) != 2 || runs[0].Attempt != 3 || runs[1].Status != "failed" {
+		t.Fatalf("first page: status = %d, total = %d, runs = %+v", code, total, runs)
+	}
+	if runs[0].PlatformsDownloaded != 2 || runs[0].Bytes != 200 {
+		t.Errorf("newest run = %+v", runs[0])
+	}
+	if _, runs, _ := list(fmt.Sprintf("/schedules/%d/runs?limit=2&page=2", schedule.ID)); len(runs) != 1 || runs[0].Attempt != 1 {
+		t.Errorf("second page = %+v", runs)
+	}
+	if code, _, _ := list("/schedules/999/runs"); code != http.StatusNotFound {
+		t.Errorf("unknown schedule: status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestSchedules_RunNow(t *testing.T) {
+	db := newTestDB(t)
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "0 2 * * *"}
+	db.Create(&schedule)
+
+	run := func(h *SyncHandler, id uint) int {
+		router := gin.New()
+		router.POST("/schedules/:id/run", h.RunScheduleNow)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/schedules/%d/run", id), nil))
+		return w.Code
+	}
+
+	h := NewSyncHandler(db, t.TempDir())
+	fake := &fakeScheduler{}
+	h.schedules = fake
+	if code := run(h, schedule.ID); code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	if len(fake.triggered) != 1 || fake.triggered[0] != schedule.ID {
+		t.Errorf("triggered = %v, want [%d]", fake.triggered, schedule.ID)
+	}
+	if code := run(h, 999); code != http.StatusNotFound || len(fake.triggered) != 1 {
+		t.Errorf("unknown schedule: status = %d, triggered = %v", code, fake.triggered)
+	}
+
+	if code := run(NewSyncHandler(db, t.TempDir()), schedule.ID); code != http.StatusServiceUnavailable {
+		t.Errorf("without a scheduler: status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
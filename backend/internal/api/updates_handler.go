@@ -0,0 +1,175 @@
+// Package api provides HTTP handlers for reporting mirrored providers behind upstream.
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// upstreamStableTTL is how long an upstream version list is reused before
+	// an updates report fetches it again.
+	upstreamStableTTL = 15 * time.Minute
+	// updatesWorkers bounds the upstream requests one updates report makes at once.
+	updatesWorkers = 4
+)
+
+// ProviderUpdate describes a mirrored provider whose newest cached stable version
+// is behind upstream. UpstreamVersion is empty when Error is set.
+type ProviderUpdate struct {
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	CachedVersion   string `json:"cached_version"`
+	UpstreamVersion string `json:"upstream_version,omitempty"`
+	VersionsBehind  int    `json:"versions_behind"` // Upstream stable versions newer than CachedVersion
+	Error           string `json:"error,omitempty"`
+}
+
+// upstreamStableCache remembers the stable versions of upstream providers,
+// newest first, so repeated reports do not refetch every provider.
+type upstreamStableCache struct {
+	mu      sync.Mutex
+	entries map[string]upstreamStableEntry
+}
+
+type upstreamStableEntry struct {
+	versions  []string
+	fetchedAt time.Time
+}
+
+// stableVersions returns the stable versions of a provider at the upstream
+// behind proxyService, newest first, from the cache while it is fresh.
+// Failures are not cached.
+func (u *upstreamStableCache) stableVersions(ctx context.Context, proxyService *proxy.ProxyService, namespace, name string) ([]string, error) {
+	key := proxyService.UpstreamURL() + " " + namespace + "/" + name
+	u.mu.Lock()
+	entry, ok := u.entries[key]
+	u.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < upstreamStableTTL {
+		return entry.versions, nil
+	}
+
+	upstream, err := proxyService.GetProviderVersions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(upstream.Versions))
+	for _, v := range upstream.Versions {
+		if _, valid := semver.Parse(v.Version); valid && !semver.IsPrerelease(v.Version) {
+			versions = append(versions, v.Version)
+		}
+	}
+	semver.SortDescending(versions)
+
+	u.mu.Lock()
+	if u.entries == nil {
+		u.entries = make(map[string]upstreamStableEntry)
+	}
+	u.entries[key] = upstreamStableEntry{versions: versions, fetchedAt: time.Now()}
+	u.mu.Unlock()
+	return versions, nil
+}
+
+// cachedLatestStable returns the newest cached stable version of each mirrored
+// provider, keyed by namespace/name.
+func (h *MirrorHandler) cachedLatestStable() (map[string]ProviderUpdate, error) {
+	var rows []models.Provider
+	if err := h.db.Select("namespace", "name", "version").
+		Where("source_type = ?", models.SourceMirror).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	latest := make(map[string]ProviderUpdate)
+	for _, row := range rows {
+		if _, valid := semver.Parse(row.Version); !valid || semver.IsPrerelease(row.Version) {
+			continue
+		}
+		key := row.Namespace + "/" + row.Name
+		if current, ok := latest[key]; !ok || semver.Compare(row.Version, current.CachedVersion) > 0 {
+			latest[key] = ProviderUpdate{Namespace: row.Namespace, Name: row.Name, CachedVersion: row.Version}
+		}
+	}
+	return latest, nil
+}
+
+// GetUpdatesAvailable lists mirrored providers whose newest cached stable version
+// is older than upstream's newest stable version, each checked against the
+// upstream scheduler.UpstreamFor resolves for it. Providers whose upstream lookup
+// fails are reported under failed rather than failing the whole request.
+func (h *MirrorHandler) GetUpdatesAvailable(c *gin.Context) {
+	var settings models.Settings
+	if err := h.db.First(&settings).Error; err == nil {
+		if !settings.AllowOnlineSearch {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Online search is disabled"})
+			return
+		}
+		applyProxySettings(h.proxyService, settings)
+	}
+
+	latest, err := h.cachedLatestStable()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load providers"})
+		return
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		updates = make([]ProviderUpdate, 0)
+		failed  = make([]ProviderUpdate, 0)
+		sem     = make(chan struct{}, updatesWorkers)
+	)
+	for _, entry := range latest {
+		upstream := h.getProxyService(entry.Namespace, entry.Name, "")
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			versions, err := h.upstreamStable.stableVersions(c.Request.Context(), upstream, entry.Namespace, entry.Name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				entry.Error = err.Error()
+				failed = append(failed, entry)
+				return
+			}
+			for _, v := range versions {
+				if semver.Compare(v, entry.CachedVersion) <= 0 {
+					break
+				}
+				entry.VersionsBehind++
+			}
+			if entry.VersionsBehind > 0 {
+				entry.UpstreamVersion = versions[0]
+				updates = append(updates, entry)
+			}
+		}()
+	}
+	wg.Wait()
+
+	byName := func(list []ProviderUpdate) func(i, j int) bool {
+		return func(i, j int) bool {
+			if list[i].Namespace != list[j].Namespace {
+				return list[i].Namespace < list[j].Namespace
+			}
+			return list[i].Name < list[j].Name
+		}
+	}
+	sort.Slice(updates, byName(updates))
+	sort.Slice(failed, byName(failed))
+
+	c.JSON(http.StatusOK, gin.H{
+		"providers": updates,
+		"failed":    failed,
+		"total":     len(updates),
+	})
+}
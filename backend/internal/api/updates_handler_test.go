@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetUpdatesAvailable(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	for _, p := range []models.Provider{
+		{Namespace: "hashicorp", Name: "aws", Version: "4.0.0", SourceType: models.SourceMirror},
+		{Namespace: "hashicorp", Name: "aws", Version: "5.1.0-beta1", SourceType: models.SourceMirror},
+		{Namespace: "gone", Name: "missing", Version: "1.0.0", SourceType: models.SourceMirror},
+		{Namespace: "myorg", Name: "internal", Version: "1.0.0", SourceType: models.SourceUpload},
+	} {
+		db.Create(&p)
+	}
+
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.GET("/mirror/updates-available", h.GetUpdatesAvailable)
+
+	var body struct {
+		Providers []ProviderUpdate `json:"providers"`
+		Failed    []ProviderUpdate `json:"failed"`
+	}
+	get := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/updates-available", nil))
+		body.Providers, body.Failed = nil, nil
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	want := ProviderUpdate{Namespace: "hashicorp", Name: "aws", CachedVersion: "4.0.0", UpstreamVersion: "5.0.0", VersionsBehind: 1}
+	if len(body.Providers) != 1 || body.Providers[0] != want {
+		t.Errorf("providers = %+v, want [%+v]", body.Providers, want)
+	}
+	if len(body.Failed) != 1 || body.Failed[0].Namespace != "gone" || body.Failed[0].Error == "" {
+		t.Errorf("failed = %+v, want gone/missing with an error", body.Failed)
+	}
+
+	// Upstream version lists are reused while fresh.
+	upstream.Close()
+	if get(); len(body.Providers) != 1 {
+		t.Errorf("with upstream down: providers = %+v, want the cached result", body.Providers)
+	}
+
+	offlineSettings(t, db)
+	if code := get(); code != http.StatusForbidden {
+		t.Errorf("offline: status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestGetUpdatesAvailable_PerProviderUpstream(t *testing.T) {
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	unused := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the default upstream: %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(unused.Close)
+
+	db := newTestDB(t)
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.0.0", SourceType: models.SourceMirror})
+	db.Create(&models.MirrorConfig{Namespace: "hashicorp", Name: "aws", UpstreamURL: upstream.URL})
+
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, unused.URL)
+	router := gin.New()
+	router.GET("/mirror/updates-available", h.GetUpdatesAvailable)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/updates-available", nil))
+	var body struct {
+		Providers []ProviderUpdate `json:"providers"`
+		Failed    []ProviderUpdate `json:"failed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	want := ProviderUpdate{Namespace: "hashicorp", Name: "aws", CachedVersion: "4.0.0", UpstreamVersion: "5.0.0", VersionsBehind: 1}
+	if len(body.Providers) != 1 || body.Providers[0] != want || len(body.Failed) != 0 {
+		t.Errorf("providers = %+v, failed = %+v; want [%+v] from the configured upstream", body.Providers, body.Failed, want)
+	}
+}
@@ -0,0 +1,79 @@
+// Package api provides HTTP handlers for provider usage statistics.
+package api
+
+import (
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// VersionUsage compares what one provider version has served with what it
+// occupies in storage.
+type VersionUsage struct {
+	Version     string `json:"version"`
+	Downloads   int64  `json:"downloads"`
+	CacheFills  int64  `json:"cache_fills"`
+	BytesServed int64  `json:"bytes_served"`
+	CachedBytes int64  `json:"cached_bytes"`
+	Platforms   int    `json:"platforms"`
+}
+
+// GetProviderUsage reports the bytes a provider has served from the cache
+// (egress) against the bytes its cached platforms take up (storage), in total
+// and per version, newest version first. Downloads redirected to upstream
+// serve nothing from the cache and are not counted.
+func (h *MirrorHandler) GetProviderUsage(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	if msg := validateProviderParams(namespace, name, ""); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var providers []models.Provider
+	if err := h.db.Preload("Platforms").Where("namespace = ? AND name = ?", namespace, name).Find(&providers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load provider usage"})
+		return
+	}
+	if len(providers) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+		return
+	}
+	sortProvidersByVersion(providers)
+
+	versions := make([]VersionUsage, 0, len(providers))
+	var total VersionUsage
+	for _, p := range providers {
+		usage := VersionUsage{Version: p.Version, Downloads: p.Downloads, CacheFills: p.CacheFills, BytesServed: p.BytesServed}
+		for _, platform := range p.Platforms {
+			if platform.FilePath == "" {
+				continue
+			}
+			usage.CachedBytes += platform.FileSize
+			usage.Platforms++
+		}
+		versions = append(versions, usage)
+		total.Downloads += usage.Downloads
+		total.CacheFills += usage.CacheFills
+		total.BytesServed += usage.BytesServed
+		total.CachedBytes += usage.CachedBytes
+	}
+
+	// Egress per stored byte: how many times the cache has paid for itself.
+	var ratio float64
+	if total.CachedBytes > 0 {
+		ratio = float64(total.BytesServed) / float64(total.CachedBytes)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":    namespace,
+		"name":         name,
+		"downloads":    total.Downloads,
+		"cache_fills":  total.CacheFills,
+		"bytes_served": total.BytesServed,
+		"cached_bytes": total.CachedBytes,
+		"serve_ratio":  ratio,
+		"versions":     versions,
+	})
+}
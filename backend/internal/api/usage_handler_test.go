@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetProviderUsage(t *testing.T) {
+	const body = "provider-binary"
+	db := newTestDB(t)
+	offlineSettings(t, db)
+	storagePath := t.TempDir()
+	filePath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	if err := os.WriteFile(filePath, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	current := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	older := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "4.0.0", Downloads: 2, BytesServed: 300}
+	db.Create(&current)
+	db.Create(&older)
+	db.Create(&models.ProviderPlatform{ProviderID: current.ID, OS: "linux", Arch: "amd64", Filename: filepath.Base(filePath), FilePath: filePath, SHA256Sum: "x", FileSize: int64(len(body))})
+	db.Create(&models.ProviderPlatform{ProviderID: older.ID, OS: "linux", Arch: "amd64", Filename: "old.zip", FilePath: "/old.zip", SHA256Sum: "y", FileSize: 100})
+
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	for i := 0; i < 3; i++ {
+		if w := serveDownload(t, h); w.Code != http.StatusOK {
+			t.Fatalf("download: status = %d", w.Code)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/mirror/providers/:namespace/:name/usage", h.GetProviderUsage)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/providers/hashicorp/aws/usage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Downloads   int64          `json:"downloads"`
+		BytesServed int64          `json:"bytes_served"`
+		CachedBytes int64          `json:"cached_bytes"`
+		Versions    []VersionUsage `json:"versions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	served := int64(3 * len(body))
+	if resp.Downloads != 5 || resp.BytesServed != served+300 || resp.CachedBytes != int64(len(body))+100 {
+		t.Errorf("totals = %d downloads, %d served, %d cached", resp.Downloads, resp.BytesServed, resp.CachedBytes)
+	}
+	if len(resp.Versions) != 2 || resp.Versions[0].Version != "5.0.0" || resp.Versions[0].BytesServed != served || resp.Versions[0].Platforms != 1 {
+		t.Errorf("versions = %+v, want 5.0.0 first with %d bytes served", resp.Versions, served)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mirror/providers/hashicorp/nope/usage", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,128 @@
+// Package api provides HTTP handlers for user management.
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateUserRequest represents the body of an admin creating a user. Role
+// defaults to "user".
+type CreateUserRequest struct {
+	Username           string `json:"username" binding:"required,min=3,max=50"`
+	Email              string `json:"email" binding:"required,email"`
+	Password           string `json:"password" binding:"required,min=6"`
+	Role               string `json:"role" binding:"omitempty,oneof=user admin"`
+	MustChangePassword bool   `json:"must_change_password"`
+}
+
+// errLastAdmin stops DeleteUser from leaving the registry without an admin,
+// which an admin whose role changed since their token was issued could do.
+var errLastAdmin = errors.New("cannot delete the last admin")
+
+// ListUsers lists the users ordered by username (admin only).
+func (h *AuthHandler) ListUsers(c *gin.Context) {
+	page, limit := pageParams(c, defaultListPageSize, maxListPageSize)
+	var total int64
+	h.db.Model(&models.User{}).Count(&total)
+	pagination := newPagination(page, limit, total)
+
+	users := make([]models.User, 0)
+	if err := h.db.Order("username").Offset(pagination.offset()).Limit(limit).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load users"})
+		return
+	}
+	c.JSON(http.StatusOK, paginated(gin.H{"users": users}, pagination))
+}
+
+// CreateUser creates a user with the given password (admin only). With
+// must_change_password the user has to change it at first login.
+func (h *AuthHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if req.Role == "" {
+		req.Role = "user"
+	}
+
+	// Deleted users are removed for good, so only live rows can conflict.
+	var existingUser models.User
+	if err := h.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+	user := models.User{
+		Username:           req.Username,
+		Email:              req.Email,
+		Password:           hashedPassword,
+		Role:               req.Role,
+		MustChangePassword: req.MustChangePassword,
+	}
+	if err := h.db.Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"user": user})
+}
+
+// DeleteUser deletes the user in the id path parameter with their refresh
+// tokens and namespace grants (admin only). Admins cannot delete themselves
+// or the last admin. Access tokens already issued stay valid until they
+// expire.
+func (h *AuthHandler) DeleteUser(c *gin.Context) {
+	var user models.User
+	if err := h.db.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if currentID, _ := c.Get("user_id"); currentID == user.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "You cannot delete your own account"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if user.Role == "admin" {
+			var admins int64
+			tx.Model(&models.User{}).Where("role = ?", "admin").Count(&admins)
+			if admins <= 1 {
+				return errLastAdmin
+			}
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.NamespaceOwner{}).Error; err != nil {
+			return err
+		}
+		// Unscoped, so that the username and email can be used again.
+		return tx.Unscoped().Delete(&user).Error
+	})
+	if errors.Is(err, errLastAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted", "username": user.Username})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/auth"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+)
+
+func TestUserManagement(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Username: "admin", Email: "admin@localhost", Password: "x", Role: "admin"}
+	db.Create(&admin)
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	router := SetupRouter(db, jwtManager, nil, nil, nil, testConfig(t))
+	adminJWT, _ := jwtManager.Generate(admin.ID, admin.Username, admin.Role)
+
+	do := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/users", adminJWT, CreateUserRequest{Username: "ci-bot", Email: "ci@example.com", Password: "ci-password"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		User models.User `json:"user"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.User.Role != "user" {
+		t.Errorf("created role = %q, want user", created.User.Role)
+	}
+	for _, req := range []CreateUserRequest{
+		{Username: "ci-bot", Email: "other@example.com", Password: "ci-password"},
+		{Username: "ci-bot2", Email: "ci@example.com", Password: "ci-password"},
+	} {
+		if w := do(http.MethodPost, "/api/v1/users", adminJWT, req); w.Code != http.StatusConflict {
+			t.Errorf("%+v: status = %d, want 409", req, w.Code)
+		}
+	}
+	if w := do(http.MethodPost, "/api/v1/users", adminJWT, CreateUserRequest{Username: "ops", Email: "ops@example.com", Password: "ops-password", Role: "root"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid role: status = %d, want 400", w.Code)
+	}
+
+	ciJWT, _ := jwtManager.Generate(created.User.ID, "ci-bot", "user")
+	if w := do(http.MethodGet, "/api/v1/users", ciJWT, nil); w.Code != http.StatusForbidden {
+		t.Errorf("list as user: status = %d, want 403", w.Code)
+	}
+	w = do(http.MethodGet, "/api/v1/users?limit=1", adminJWT, nil)
+	var list struct {
+		Users []models.User `json:"users"`
+		Total int64         `json:"total"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Total != 2 || len(list.Users) != 1 || list.Users[0].Username != "admin" {
+		t.Errorf("list: status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodDelete, "/api/v1/users/"+strconv.FormatUint(uint64(admin.ID), 10), adminJWT, nil); w.Code != http.StatusConflict {
+		t.Errorf("delete self: status = %d, want 409", w.Code)
+	}
+	db.Create(&models.NamespaceOwner{Namespace: "acme", UserID: created.User.ID})
+	userPath := "/api/v1/users/" + strconv.FormatUint(uint64(created.User.ID), 10)
+	if w := do(http.MethodDelete, userPath, adminJWT, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var owners int64
+	db.Model(&models.NamespaceOwner{}).Count(&owners)
+	if owners != 0 {
+		t.Errorf("namespace grants left = %d, want 0", owners)
+	}
+	if w := do(http.MethodDelete, userPath, adminJWT, nil); w.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want 404", w.Code)
+	}
+	// The username is free again.
+	if w := do(http.MethodPost, "/api/v1/users", adminJWT, CreateUserRequest{Username: "ci-bot", Email: "ci@example.com", Password: "ci-password"}); w.Code != http.StatusCreated {
+		t.Errorf("recreate: status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,186 @@
+// Package api provides lock-file generation and hash verification against cached providers.
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyLockRequest is the body of POST /api/v1/mirror/verify-lock: one provider
+// block of a .terraform.lock.hcl file.
+type VerifyLockRequest struct {
+	Namespace string   `json:"namespace" binding:"required"`
+	Name      string   `json:"name" binding:"required"`
+	Version   string   `json:"version" binding:"required"`
+	Hashes    []string `json:"hashes" binding:"required,min=1,max=256"`
+}
+
+// LockHashResult reports whether one lock-file hash matches a cached platform.
+type LockHashResult struct {
+	Hash     string `json:"hash"`
+	Status   string `json:"status"`             // "match", "mismatch", or "unsupported" for other hash schemes
+	Platform string `json:"platform,omitempty"` // os_arch of the matching platform
+}
+
+// CachedPlatformHashes lists the hashes this mirror would serve for a platform.
+type CachedPlatformHashes struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	ZH   string `json:"zh"`
+	H1   string `json:"h1,omitempty"` // computed only when the request has h1: hashes
+}
+
+// VerifyLock compares lock-file hashes with the cached platforms of a provider
+// version. zh: hashes are checked against the stored archive checksums; h1:
+// hashes are computed from the cached archives. A mismatch for a platform that
+// is not cached yet is expected; the platforms list shows what is cached.
+func (h *MirrorHandler) VerifyLock(c *gin.Context) {
+	var req VerifyLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: namespace, name, version and 1-256 hashes are required"})
+		return
+	}
+	if errMsg := validateProviderParams(req.Namespace, req.Name, req.Version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	platforms, err := h.versionPlatforms(req.Namespace, req.Name, req.Version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(platforms) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider version is not cached"})
+		return
+	}
+
+	needH1 := false
+	for _, hash := range req.Hashes {
+		if strings.HasPrefix(hash, "h1:") {
+			needH1 = true
+			break
+		}
+	}
+
+	cached := make([]CachedPlatformHashes, 0, len(platforms))
+	known := make(map[string]string) // hash -> os_arch
+	for _, p := range platforms {
+		entry := CachedPlatformHashes{OS: p.OS, Arch: p.Arch}
+		if p.SHA256Sum != "" {
+			entry.ZH = "zh:" + p.SHA256Sum
+			known[entry.ZH] = p.OS + "_" + p.Arch
+		}
+		if needH1 && h.proxyService.FileExists(p.FilePath) {
+			if h1, err := h.proxyService.HashFileH1(p.FilePath); err == nil {
+				entry.H1 = h1
+				known[h1] = p.OS + "_" + p.Arch
+			}
+		}
+		cached = append(cached, entry)
+	}
+
+	results := make([]LockHashResult, 0, len(req.Hashes))
+	allMatch := true
+	for _, hash := range req.Hashes {
+		result := LockHashResult{Hash: hash}
+		switch {
+		case !strings.HasPrefix(hash, "zh:") && !strings.HasPrefix(hash, "h1:"):
+			result.Status = "unsupported"
+		case known[hash] != "":
+			result.Status = "match"
+			result.Platform = known[hash]
+		default:
+			result.Status = "mismatch"
+			allMatch = false
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": req.Namespace,
+		"name":      req.Name,
+		"version":   req.Version,
+		"results":   results,
+		"platforms": cached,
+		"all_match": allMatch,
+	})
+}
+
+// versionPlatforms returns the cached platforms of a provider version ordered
+// by os and arch.
+func (h *MirrorHandler) versionPlatforms(namespace, name, version string) ([]models.ProviderPlatform, error) {
+	var platforms []models.ProviderPlatform
+	err := h.db.Joins("JOIN providers ON providers.id = provider_platforms.provider_id").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND providers.deleted_at IS NULL",
+			namespace, name, version).
+		Order("provider_platforms.os, provider_platforms.arch").
+		Find(&platforms).Error
+	return platforms, err
+}
+
+// lockFileHost is the registry hostname generated lock files name providers
+// under, as Terraform does for providers installed through this mirror.
+const lockFileHost = "registry.terraform.io"
+
+// GenerateLockFile returns the .terraform.lock.hcl provider block for a cached
+// provider version, with the zh: hash of every cached platform's archive and
+// the h1: hash of its contents. Only cached platforms are listed, so the block
+// pins exactly what this mirror serves.
+func (h *MirrorHandler) GenerateLockFile(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	version := c.Param("version")
+	if errMsg := validateProviderParams(namespace, name, version); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	platforms, err := h.versionPlatforms(namespace, name, version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(platforms) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider version is not cached"})
+		return
+	}
+
+	hashes := make([]string, 0, 2*len(platforms))
+	for _, p := range platforms {
+		if p.SHA256Sum != "" {
+			hashes = append(hashes, "zh:"+p.SHA256Sum)
+		}
+		h1, err := h.proxyService.HashFileH1(p.FilePath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to hash %s_%s: %v", p.OS, p.Arch, err)})
+			return
+		}
+		hashes = append(hashes, h1)
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(lockFileBlock(namespace, name, version, hashes)))
+}
+
+// lockFileBlock formats a provider block as terraform init writes it, with
+// the hashes sorted and without duplicates.
+func lockFileBlock(namespace, name, version string, hashes []string) string {
+	sort.Strings(hashes)
+	var b strings.Builder
+	fmt.Fprintf(&b, "provider %q {\n", lockFileHost+"/"+namespace+"/"+name)
+	fmt.Fprintf(&b, "  version = %q\n", version)
+	b.WriteString("  hashes = [\n")
+	for i, hash := range hashes {
+		if i > 0 && hash == hashes[i-1] {
+			continue
+		}
+		fmt.Fprintf(&b, "    %q,\n", hash)
+	}
+	b.WriteString("  ]\n}\n")
+	return b.String()
+}
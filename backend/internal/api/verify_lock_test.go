@@ -0,0 +1,145 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifyLock(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+
+	zipPath := filepath.Join(storagePath, "terraform-provider-aws_5.0.0_linux_amd64.zip")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("terraform-provider-aws_v5.0.0")
+	_, _ = w.Write([]byte("provider"))
+	_ = zw.Close()
+	if err := os.WriteFile(zipPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h1, err := proxy.HashZipH1(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64",
+		Filename: filepath.Base(zipPath), FilePath: zipPath, SHA256Sum: "aaaa"})
+	db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "darwin", Arch: "arm64",
+		Filename: "missing.zip", FilePath: filepath.Join(storagePath, "missing.zip"), SHA256Sum: "bbbb"})
+
+	router := gin.New()
+	router.POST("/verify-lock", NewMirrorHandler(db, storagePath, nil, false).VerifyLock)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/verify-lock", bytes.NewBufferString(body)))
+		return w
+	}
+
+	rec := post(`{"namespace":"hashicorp","name":"aws","version":"5.0.0",
+		"hashes":["zh:bbbb","zh:cccc","` + h1 + `","md5:dddd"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Results   []LockHashResult       `json:"results"`
+		Platforms []CachedPlatformHashes `json:"platforms"`
+		AllMatch  bool                   `json:"all_match"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := []LockHashResult{
+		{Hash: "zh:bbbb", Status: "match", Platform: "darwin_arm64"},
+		{Hash: "zh:cccc", Status: "mismatch"},
+		{Hash: h1, Status: "match", Platform: "linux_amd64"},
+		{Hash: "md5:dddd", Status: "unsupported"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i := range want {
+		if resp.Results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, resp.Results[i], want[i])
+		}
+	}
+	if resp.AllMatch {
+		t.Error("all_match = true, want false")
+	}
+	if len(resp.Platforms) != 2 || resp.Platforms[1].H1 != h1 || resp.Platforms[0].H1 != "" {
+		t.Errorf("platforms = %+v, want h1 only for the file on disk", resp.Platforms)
+	}
+
+	if rec := post(`{"namespace":"hashicorp","name":"aws","version":"4.0.0","hashes":["zh:aaaa"]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("uncached version: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := post(`{"namespace":"hashicorp","name":"aws","version":"5.0.0","hashes":[]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("no hashes: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGenerateLockFile(t *testing.T) {
+	db := newTestDB(t)
+	storagePath := t.TempDir()
+	provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+	db.Create(&provider)
+	for _, plat := range []struct{ os, arch, sum string }{{"linux", "amd64", "1111"}, {"darwin", "arm64", "2222"}} {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create("terraform-provider-aws_v5.0.0")
+		_, _ = w.Write([]byte("provider-" + plat.os + "_" + plat.arch))
+		_ = zw.Close()
+		zipPath := filepath.Join(storagePath, plat.os+"_"+plat.arch+".zip")
+		if err := os.WriteFile(zipPath, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: plat.os, Arch: plat.arch,
+			Filename: filepath.Base(zipPath), FilePath: zipPath, SHA256Sum: plat.sum})
+	}
+
+	router := gin.New()
+	router.GET("/lockfile/:namespace/:name/:version", NewMirrorHandler(db, storagePath, nil, false).GenerateLockFile)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// The h1: hashes are those dirhash.HashZip computes for the archives.
+	want := `provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.0.0"
+  hashes = [
+    "h1:ZTgVTPROkwfnIz/t0z+NRZzKqiOTWyGDpvSquTEBg1Y=",
+    "h1:oey+uhFra1nj9cNDal3fb7tmfsGOSzHx/MewSlfnAlE=",
+    "zh:1111",
+    "zh:2222",
+  ]
+}
+`
+	rec := get("/lockfile/hashicorp/aws/5.0.0")
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Fatalf("status = %d, body =\n%s\nwant\n%s", rec.Code, rec.Body.String(), want)
+	}
+
+	if rec := get("/lockfile/hashicorp/aws/4.0.0"); rec.Code != http.StatusNotFound {
+		t.Errorf("uncached version: status = %d, want 404", rec.Code)
+	}
+	_ = os.Remove(filepath.Join(storagePath, "linux_amd64.zip"))
+	if rec := get("/lockfile/hashicorp/aws/5.0.0"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("missing archive: status = %d, want 500", rec.Code)
+	}
+}
@@ -0,0 +1,46 @@
+// Package api provides semver ordering of provider versions.
+package api
+
+import (
+	"sort"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"gorm.io/gorm"
+)
+
+// sortProvidersByVersion sorts provider versions newest first by semver
+// precedence, so 1.10.0 comes before 1.9.0 and 2.0.0 before 2.0.0-rc1.
+// SQL ordering on the version column compares strings and gets both wrong.
+func sortProvidersByVersion(providers []models.Provider) {
+	sort.SliceStable(providers, func(i, j int) bool {
+		return semver.Compare(providers[i].Version, providers[j].Version) > 0
+	})
+}
+
+// latestVersions returns the highest version by semver precedence of each
+// provider in keys, given as "namespace/name". query selects the provider rows
+// to consider and must not have been executed yet.
+func latestVersions(query *gorm.DB, keys []string) (map[string]string, error) {
+	latest := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return latest, nil
+	}
+	var rows []struct {
+		Namespace string
+		Name      string
+		Version   string
+	}
+	if err := query.Select("namespace, name, version").
+		Where("namespace || '/' || name IN ?", keys).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		key := r.Namespace + "/" + r.Name
+		if current, ok := latest[key]; !ok || semver.Compare(r.Version, current) > 0 {
+			latest[key] = r.Version
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersionOrdering_Semver(t *testing.T) {
+	db := newTestDB(t)
+	// Lexically, 1.9.0 > 1.10.0 and 2.0.0-rc1 > 2.0.0.
+	for _, v := range []string{"1.9.0", "2.0.0-rc1", "1.10.0", "2.0.0"} {
+		db.Create(&models.Provider{Namespace: "hashicorp", Name: "aws", Version: v, SourceType: models.SourceMirror})
+	}
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.GET("/providers", NewHandler(db, "").ListProviders)
+	router.GET("/mirror/providers", h.ListMirroredProviders)
+	router.GET("/mirror/providers/:namespace/:name", h.GetProviderVersionsDetail)
+	router.GET("/v1/providers/:namespace/:name/versions", h.GetProviderVersions)
+
+	get := func(path string, v any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", path, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+
+	var summaries struct {
+		Providers []struct {
+			Version string `json:"version"`
+		} `json:"providers"`
+	}
+	for _, path := range []string{"/providers", "/mirror/providers"} {
+		get(path, &summaries)
+		if len(summaries.Providers) != 1 || summaries.Providers[0].Version != "2.0.0" {
+			t.Errorf("%s: latest = %+v, want 2.0.0", path, summaries.Providers)
+		}
+	}
+
+	want := []string{"2.0.0", "2.0.0-rc1", "1.10.0", "1.9.0"}
+	var detail struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	}
+	get("/mirror/providers/hashicorp/aws?limit=2&page=2", &detail)
+	if len(detail.Versions) != 2 || detail.Versions[0].Version != want[2] || detail.Versions[1].Version != want[3] {
+		t.Errorf("detail page 2 = %+v, want %v", detail.Versions, want[2:])
+	}
+
+	var listing struct {
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	}
+	get("/v1/providers/hashicorp/aws/versions", &listing)
+	if len(listing.Versions) != len(want) {
+		t.Fatalf("versions = %+v, want %v", listing.Versions, want)
+	}
+	for i, v := range listing.Versions {
+		if v.Version != want[i] {
+			t.Errorf("versions[%d] = %s, want %s", i, v.Version, want[i])
+		}
+	}
+}
+
+func TestGetLatestProviderVersion(t *testing.T) {
+	db := newTestDB(t)
+	for _, v := range []string{"1.9.0", "1.10.0", "2.0.0-rc1"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v, SourceType: models.SourceMirror}
+		db.Create(&provider)
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64"})
+	}
+	h := NewMirrorHandler(db, t.TempDir(), nil, false)
+	router := gin.New()
+	router.GET("/mirror/providers/:namespace/:name/latest", h.GetLatestProviderVersion)
+
+	get := func(path string) (int, ProviderVersionSummary) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var got ProviderVersionSummary
+		_ = json.Unmarshal(w.Body.Bytes(), &got)
+		return w.Code, got
+	}
+
+	code, got := get("/mirror/providers/hashicorp/aws/latest")
+	if code != http.StatusOK || got.Version != "1.10.0" || got.PlatformCount != 1 || len(got.Platforms) != 1 {
+		t.Errorf("latest = %d %+v, want 1.10.0 with its platform", code, got)
+	}
+	if code, got := get("/mirror/providers/hashicorp/aws/latest?include_prerelease=true"); code != http.StatusOK || got.Version != "2.0.0-rc1" {
+		t.Errorf("latest with prereleases = %d %s, want 2.0.0-rc1", code, got.Version)
+	}
+	if code, _ := get("/mirror/providers/hashicorp/google/latest"); code != http.StatusNotFound {
+		t.Errorf("unknown provider: status = %d, want 404", code)
+	}
+}
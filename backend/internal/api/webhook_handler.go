@@ -0,0 +1,231 @@
+// Package api provides HTTP handlers for webhooks.
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookHandler manages the webhooks notified of registry events.
+type WebhookHandler struct {
+	db         *gorm.DB
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance.
+func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
+	return &WebhookHandler{db: db, dispatcher: webhook.Default}
+}
+
+// CreateWebhookRequest represents the request to add a webhook. Events are
+// names from webhook.Events, or "*" for all of them. Without a secret one is
+// generated and returned once.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events" binding:"required"`
+}
+
+// UpdateWebhookRequest represents a partial update of a webhook.
+type UpdateWebhookRequest struct {
+	URL     *string  `json:"url"`
+	Secret  *string  `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute http or https URL.
+func validateWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "url must be an absolute http or https URL"
+	}
+	return ""
+}
+
+// validateWebhookEvents checks the events of a webhook and returns them
+// comma-separated for storage.
+func validateWebhookEvents(events []string) (string, string) {
+	if len(events) == 0 {
+		return "", "at least one event is required"
+	}
+	for _, e := range events {
+		if e != webhook.AllEvents && !slices.Contains(webhook.Events, e) {
+			return "", fmt.Sprintf("unknown event %q; must be %q or one of %s", e, webhook.AllEvents, strings.Join(webhook.Events, ", "))
+		}
+	}
+	return strings.Join(events, ","), ""
+}
+
+// ListWebhooks lists the webhooks with the outcome of their latest delivery.
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	hooks := make([]models.Webhook, 0)
+	if err := h.db.Order("id").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "events": webhook.Events})
+}
+
+// CreateWebhook adds a webhook.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateWebhookURL(req.URL); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	events, msg := validateWebhookEvents(req.Events)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+		secret = hex.EncodeToString(b)
+	}
+	hook := models.Webhook{URL: req.URL, Secret: secret, Events: events, Enabled: true}
+	if err := h.db.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	resp := gin.H{"webhook": hook}
+	if req.Secret == "" {
+		resp["secret"] = secret
+		resp["message"] = "Store this secret now; it cannot be shown again"
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// UpdateWebhook changes the fields of a webhook present in the request.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := h.db.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if msg := validateWebhookURL(*req.URL); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "secret must not be empty"})
+			return
+		}
+		updates["secret"] = *req.Secret
+	}
+	if req.Events != nil {
+		events, msg := validateWebhookEvents(req.Events)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		updates["events"] = events
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if len(updates) > 0 {
+		if err := h.db.Model(&hook).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+			return
+		}
+	}
+
+	h.db.First(&hook, hook.ID)
+	c.JSON(http.StatusOK, gin.H{"webhook": hook})
+}
+
+// DeleteWebhook removes a webhook.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	result := h.db.Delete(&models.Webhook{}, c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// TestWebhook sends a webhook.test event to a webhook, enabled or not, in the
+// background; its outcome is recorded on the webhook like any delivery.
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := h.db.First(&hook, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	h.dispatcher.Send(h.db, hook, webhook.EventTest, gin.H{"webhook_id": hook.ID})
+	c.JSON(http.StatusAccepted, gin.H{"message": "Test event queued"})
+}
+
+// MirrorEvent is the data of a mirror.completed webhook event.
+type MirrorEvent struct {
+	Namespace       string           `json:"namespace"`
+	Name            string           `json:"name"`
+	Version         string           `json:"version"`
+	Platforms       []string         `json:"platforms"` // Mirrored platforms as os_arch
+	Bytes           int64            `json:"bytes"`
+	Partial         bool             `json:"partial"`
+	FailedPlatforms []FailedPlatform `json:"failed_platforms,omitempty"`
+}
+
+// notifyMirrorCompleted sends the mirror.completed event for a provider
+// version mirrored through the API.
+func notifyMirrorCompleted(db *gorm.DB, namespace, name, version string, mirrored []models.ProviderPlatform, failed []FailedPlatform) {
+	event := MirrorEvent{
+		Namespace: namespace, Name: name, Version: version,
+		Platforms: make([]string, 0, len(mirrored)), Partial: len(failed) > 0, FailedPlatforms: failed,
+	}
+	for _, p := range mirrored {
+		event.Platforms = append(event.Platforms, p.OS+"_"+p.Arch)
+		event.Bytes += p.FileSize
+	}
+	webhook.Dispatch(db, webhook.EventMirrorCompleted, event)
+}
+
+// notifyProviderDeleted sends the provider.deleted event for a provider
+// version deleted through the API.
+func notifyProviderDeleted(db *gorm.DB, provider models.Provider, freed int64) {
+	webhook.Dispatch(db, webhook.EventProviderDeleted, gin.H{
+		"id":          provider.ID,
+		"namespace":   provider.Namespace,
+		"name":        provider.Name,
+		"version":     provider.Version,
+		"freed_bytes": freed,
+	})
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	db := newTestDB(t)
+	h := NewWebhookHandler(db)
+	router := gin.New()
+	router.GET("/webhooks", h.ListWebhooks)
+	router.POST("/webhooks", h.CreateWebhook)
+	router.PUT("/webhooks/:id", h.UpdateWebhook)
+	router.DELETE("/webhooks/:id", h.DeleteWebhook)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	w := do(http.MethodPost, "/webhooks", `{"url":"https://hooks.example.com/registry","events":["sync.failed","mirror.completed"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Webhook models.Webhook `json:"webhook"`
+		Secret  string         `json:"secret"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Secret == "" || created.Webhook.Events != "sync.failed,mirror.completed" || !created.Webhook.Enabled {
+		t.Errorf("create: body = %s", w.Body.String())
+	}
+	var stored models.Webhook
+	db.First(&stored, created.Webhook.ID)
+	if stored.Secret != created.Secret {
+		t.Error("returned secret is not the stored one")
+	}
+
+	for _, body := range []string{
+		`{"url":"ftp://hooks.example.com","events":["sync.failed"]}`,
+		`{"url":"https://hooks.example.com","events":["sync.maybe"]}`,
+		`{"url":"https://hooks.example.com","events":[]}`,
+	} {
+		if w := do(http.MethodPost, "/webhooks", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, w.Code)
+		}
+	}
+
+	path := "/webhooks/" + strconv.FormatUint(uint64(created.Webhook.ID), 10)
+	w = do(http.MethodPut, path, `{"enabled":false,"events":["*"]}`)
+	var updated struct {
+		Webhook models.Webhook `json:"webhook"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Webhook.Enabled || updated.Webhook.Events != "*" {
+		t.Errorf("update: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Errorf("delete: status = %d", w.Code)
+	}
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("delete again: status = %d, want 404", w.Code)
+	}
+}
+
+func TestMirrorProvider_NotifiesWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []webhook.Payload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p webhook.Payload
+		_ = json.Unmarshal(body, &p)
+		mu.Lock()
+		events = append(events, p)
+		mu.Unlock()
+	}))
+	t.Cleanup(receiver.Close)
+
+	upstream := newFakeUpstream(t, "2023-05-25T18:40:36Z")
+	db := newTestDB(t)
+	db.Create(&models.Webhook{URL: receiver.URL, Secret: "s", Events: webhook.EventMirrorCompleted, Enabled: true})
+	storagePath := t.TempDir()
+	h := NewMirrorHandler(db, storagePath, nil, false)
+	h.proxyService = proxy.NewProxyService(storagePath, upstream.URL)
+	router := gin.New()
+	router.POST("/mirror/:namespace/:name", h.MirrorProvider)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mirror/hashicorp/aws?os=linux&arch=amd64", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("mirror: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	webhook.Default.Wait(context.Background())
+
+	if len(events) != 1 || events[0].Event != webhook.EventMirrorCompleted {
+		t.Fatalf("events = %+v, want one mirror.completed", events)
+	}
+	data, _ := json.Marshal(events[0].Data)
+	var mirrored MirrorEvent
+	_ = json.Unmarshal(data, &mirrored)
+	if mirrored.Version != "5.0.0" || len(mirrored.Platforms) != 1 || mirrored.Platforms[0] != "linux_amd64" || mirrored.Partial {
+		t.Errorf("event data = %s", data)
+	}
+}
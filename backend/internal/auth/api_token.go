@@ -0,0 +1,60 @@
+// Package auth provides authentication and authorization functionality.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// APITokenPrefix starts every static API token, which tells them apart from JWTs.
+const APITokenPrefix = "vctr_"
+
+// ErrInvalidAPIToken is returned when an API token does not belong to any user.
+var ErrInvalidAPIToken = errors.New("invalid api token")
+
+// APITokenResolver returns the claims of the user an API token belongs to, or
+// ErrInvalidAPIToken.
+type APITokenResolver func(token string) (*Claims, error)
+
+// GenerateAPIToken returns a new random API token and the hash to store for it.
+// Only the hash is kept, so the token itself can be shown just once.
+func GenerateAPIToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + hex.EncodeToString(b)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken returns the stored form of an API token.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIToken reports whether token has the form of an API token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// SetAPITokenResolver makes Authenticate accept static API tokens, looked up
+// with resolve. Without a resolver only JWTs are accepted.
+func (m *JWTManager) SetAPITokenResolver(resolve APITokenResolver) {
+	m.resolveAPIToken = resolve
+}
+
+// Authenticate verifies a bearer credential, which is either a JWT access
+// token or, when a resolver is set, a static API token.
+func (m *JWTManager) Authenticate(token string) (*Claims, error) {
+	if IsAPIToken(token) {
+		if m.resolveAPIToken == nil {
+			return nil, ErrInvalidAPIToken
+		}
+		return m.resolveAPIToken(token)
+	}
+	return m.Verify(token)
+}
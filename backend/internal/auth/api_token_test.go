@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTManager_Authenticate(t *testing.T) {
+	manager := NewJWTManager("test-secret-key", time.Hour)
+	token, hash, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatalf("GenerateAPIToken() error = %v", err)
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) || hash != HashAPIToken(token) || hash == token {
+		t.Fatalf("GenerateAPIToken() = %q, %q", token, hash)
+	}
+
+	if _, err := manager.Authenticate(token); err != ErrInvalidAPIToken {
+		t.Errorf("without resolver: error = %v, want ErrInvalidAPIToken", err)
+	}
+
+	manager.SetAPITokenResolver(func(got string) (*Claims, error) {
+		if HashAPIToken(got) != hash {
+			return nil, ErrInvalidAPIToken
+		}
+		return &Claims{UserID: 9, Username: "ci", Role: "user"}, nil
+	})
+	if claims, err := manager.Authenticate(token); err != nil || claims.UserID != 9 {
+		t.Errorf("Authenticate(api token) = %+v, %v", claims, err)
+	}
+	if _, err := manager.Authenticate(APITokenPrefix + "unknown"); err != ErrInvalidAPIToken {
+		t.Errorf("unknown api token: error = %v, want ErrInvalidAPIToken", err)
+	}
+
+	jwt, _ := manager.Generate(1, "admin", "admin")
+	if claims, err := manager.Authenticate(jwt); err != nil || claims.Username != "admin" {
+		t.Errorf("Authenticate(jwt) = %+v, %v", claims, err)
+	}
+	refresh, _, _ := manager.GenerateRefreshToken(1, "admin", "admin")
+	if _, err := manager.Authenticate(refresh); err != ErrInvalidToken {
+		t.Errorf("Authenticate(refresh) error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -2,6 +2,8 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -14,48 +16,153 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 	// ErrExpiredToken is returned when a token has expired.
 	ErrExpiredToken = errors.New("expired token")
+	// ErrRevokedToken is returned when a refresh token has been revoked.
+	ErrRevokedToken = errors.New("revoked token")
 )
 
+// Token types carried in Claims.Type. Access tokens issued before refresh
+// tokens existed have no type and are treated as access tokens.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// DefaultRefreshDuration is how long refresh tokens last unless changed with
+// SetRefreshDuration.
+const DefaultRefreshDuration = 30 * 24 * time.Hour
+
 // Claims represents JWT claims.
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Type     string `json:"typ,omitempty"` // TokenTypeAccess or TokenTypeRefresh
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles JWT token operations.
 type JWTManager struct {
-	secretKey     string
-	tokenDuration time.Duration
+	secretKey       string
+	tokenDuration   time.Duration
+	refreshDuration time.Duration
+	resolveAPIToken APITokenResolver // Optional; see SetAPITokenResolver
 }
 
 // NewJWTManager creates a new JWTManager.
 func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
 	return &JWTManager{
-		secretKey:     secretKey,
-		tokenDuration: tokenDuration,
+		secretKey:       secretKey,
+		tokenDuration:   tokenDuration,
+		refreshDuration: DefaultRefreshDuration,
 	}
 }
 
-// Generate creates a new JWT token.
+// SetRefreshDuration changes how long newly issued refresh tokens last.
+func (m *JWTManager) SetRefreshDuration(d time.Duration) {
+	m.refreshDuration = d
+}
+
+// TokenDuration returns how long access tokens last.
+func (m *JWTManager) TokenDuration() time.Duration {
+	return m.tokenDuration
+}
+
+// RefreshDuration returns how long refresh tokens last.
+func (m *JWTManager) RefreshDuration() time.Duration {
+	return m.refreshDuration
+}
+
+// Generate creates a new JWT access token.
 func (m *JWTManager) Generate(userID uint, username, role string) (string, error) {
-	claims := &Claims{
+	claims := m.newClaims(userID, username, role, TokenTypeAccess, m.tokenDuration)
+	return m.sign(claims)
+}
+
+// GenerateRefreshToken creates a refresh token, which can only be exchanged for
+// access tokens with RefreshAccessToken. The returned claims carry the token's
+// unique ID (jti) and expiry, which the caller records so it can be revoked.
+func (m *JWTManager) GenerateRefreshToken(userID uint, username, role string) (string, *Claims, error) {
+	claims := m.newClaims(userID, username, role, TokenTypeRefresh, m.refreshDuration)
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	claims.ID = hex.EncodeToString(id)
+	token, err := m.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// RefreshAccessToken verifies a refresh token and issues a new access token for
+// the same user. current returns the user's claims as they are now, so that a
+// changed role takes effect, or false when the refresh token is no longer
+// recorded, is revoked or its user is gone; the refresh then fails with
+// ErrRevokedToken. The returned claims are those of the new access token.
+func (m *JWTManager) RefreshAccessToken(refreshToken string, current func(*Claims) (*Claims, bool)) (string, *Claims, error) {
+	claims, err := m.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		return "", nil, err
+	}
+	user, ok := current(claims)
+	if !ok {
+		return "", nil, ErrRevokedToken
+	}
+	token, err := m.Generate(user.UserID, user.Username, user.Role)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+func (m *JWTManager) newClaims(userID uint, username, role, tokenType string, ttl time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
+		Type:     tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.tokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
+}
 
+func (m *JWTManager) sign(claims *Claims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(m.secretKey))
 }
 
-// Verify validates a JWT token and returns the claims.
+// Verify validates a JWT access token and returns the claims. Refresh tokens
+// are rejected, so they cannot authenticate API requests.
 func (m *JWTManager) Verify(tokenString string) (*Claims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != "" && claims.Type != TokenTypeAccess {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// VerifyRefreshToken validates a refresh token and returns the claims. It does
+// not check revocation; see RefreshAccessToken.
+func (m *JWTManager) VerifyRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != TokenTypeRefresh || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// parse checks a token's signature and expiry.
+func (m *JWTManager) parse(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
@@ -87,6 +87,75 @@ func TestJWTManager_Verify(t *testing.T) {
 	})
 }
 
+func TestJWTManager_RefreshToken(t *testing.T) {
+	manager := NewJWTManager("test-secret-key", time.Hour)
+	active := func(c *Claims) (*Claims, bool) { return c, true }
+
+	refresh, claims, err := manager.GenerateRefreshToken(7, "ci", "user")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	if claims.ID == "" || claims.Type != TokenTypeRefresh {
+		t.Errorf("claims = %+v, want a jti and type %q", claims, TokenTypeRefresh)
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl < DefaultRefreshDuration-time.Minute {
+		t.Errorf("refresh token expires in %v, want about %v", ttl, DefaultRefreshDuration)
+	}
+
+	t.Run("refresh token is not an access token", func(t *testing.T) {
+		if _, err := manager.Verify(refresh); err != ErrInvalidToken {
+			t.Errorf("Verify(refresh) error = %v, want ErrInvalidToken", err)
+		}
+	})
+
+	t.Run("access token is not a refresh token", func(t *testing.T) {
+		access, _ := manager.Generate(7, "ci", "user")
+		if _, _, err := manager.RefreshAccessToken(access, active); err != ErrInvalidToken {
+			t.Errorf("RefreshAccessToken(access) error = %v, want ErrInvalidToken", err)
+		}
+	})
+
+	t.Run("refresh issues an access token", func(t *testing.T) {
+		access, _, err := manager.RefreshAccessToken(refresh, active)
+		if err != nil {
+			t.Fatalf("RefreshAccessToken() error = %v", err)
+		}
+		got, err := manager.Verify(access)
+		if err != nil || got.UserID != 7 || got.Role != "user" || got.Type != TokenTypeAccess {
+			t.Errorf("Verify(new access) = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("refresh uses the current role", func(t *testing.T) {
+		access, _, err := manager.RefreshAccessToken(refresh, func(c *Claims) (*Claims, bool) {
+			return &Claims{UserID: c.UserID, Username: c.Username, Role: "admin"}, true
+		})
+		if err != nil {
+			t.Fatalf("RefreshAccessToken() error = %v", err)
+		}
+		if got, err := manager.Verify(access); err != nil || got.Role != "admin" {
+			t.Errorf("Verify(new access) = %+v, %v; want role admin", got, err)
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		var seen string
+		_, _, err := manager.RefreshAccessToken(refresh, func(c *Claims) (*Claims, bool) { seen = c.ID; return nil, false })
+		if err != ErrRevokedToken || seen != claims.ID {
+			t.Errorf("error = %v, active saw jti %q; want ErrRevokedToken and %q", err, seen, claims.ID)
+		}
+	})
+
+	t.Run("custom duration", func(t *testing.T) {
+		short := NewJWTManager("test-secret-key", time.Hour)
+		short.SetRefreshDuration(2 * time.Hour)
+		_, claims, _ := short.GenerateRefreshToken(1, "u", "user")
+		if ttl := time.Until(claims.ExpiresAt.Time); ttl > 2*time.Hour || ttl < time.Hour {
+			t.Errorf("refresh token expires in %v, want about 2h", ttl)
+		}
+	})
+}
+
 func TestHashPassword(t *testing.T) {
 	t.Run("hash password successfully", func(t *testing.T) {
 		hash, err := HashPassword("mysecretpassword")
@@ -0,0 +1,64 @@
+// Package auth provides authentication and authorization functionality.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDownloadToken is returned when a download token is malformed, does not
+// match the requested coordinates, or has expired.
+var ErrInvalidDownloadToken = errors.New("invalid download token")
+
+// DownloadSigner issues and verifies short-lived tokens that authorize downloading
+// a single provider binary.
+type DownloadSigner struct {
+	secretKey []byte
+	ttl       time.Duration
+}
+
+// NewDownloadSigner creates a new DownloadSigner. Tokens expire after ttl.
+func NewDownloadSigner(secretKey string, ttl time.Duration) *DownloadSigner {
+	return &DownloadSigner{
+		secretKey: []byte(secretKey),
+		ttl:       ttl,
+	}
+}
+
+// Sign returns a token for the given provider binary coordinates.
+// The token has the form "<unix expiry>.<hex HMAC-SHA256>".
+func (s *DownloadSigner) Sign(namespace, name, version, osType, arch string) string {
+	expiry := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+	return expiry + "." + s.mac(expiry, namespace, name, version, osType, arch)
+}
+
+// Verify checks that token was issued for the given coordinates and has not expired.
+func (s *DownloadSigner) Verify(token, namespace, name, version, osType, arch string) error {
+	expiry, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidDownloadToken
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return ErrInvalidDownloadToken
+	}
+	expected := s.mac(expiry, namespace, name, version, osType, arch)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return ErrInvalidDownloadToken
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrInvalidDownloadToken
+	}
+	return nil
+}
+
+func (s *DownloadSigner) mac(expiry, namespace, name, version, osType, arch string) string {
+	h := hmac.New(sha256.New, s.secretKey)
+	h.Write([]byte(strings.Join([]string{namespace, name, version, osType, arch, expiry}, "/")))
+	return hex.EncodeToString(h.Sum(nil))
+}
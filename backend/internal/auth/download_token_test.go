@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownloadSigner(t *testing.T) {
+	signer := NewDownloadSigner("test-secret", time.Minute)
+	token := signer.Sign("hashicorp", "aws", "5.0.0", "linux", "amd64")
+
+	t.Run("valid token", func(t *testing.T) {
+		if err := signer.Verify(token, "hashicorp", "aws", "5.0.0", "linux", "amd64"); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	})
+
+	t.Run("different coordinates", func(t *testing.T) {
+		if err := signer.Verify(token, "hashicorp", "aws", "5.0.0", "darwin", "amd64"); err != ErrInvalidDownloadToken {
+			t.Errorf("Verify() error = %v, want %v", err, ErrInvalidDownloadToken)
+		}
+	})
+
+	t.Run("different secret", func(t *testing.T) {
+		other := NewDownloadSigner("other-secret", time.Minute)
+		if err := other.Verify(token, "hashicorp", "aws", "5.0.0", "linux", "amd64"); err != ErrInvalidDownloadToken {
+			t.Errorf("Verify() error = %v, want %v", err, ErrInvalidDownloadToken)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired := NewDownloadSigner("test-secret", -time.Minute)
+		stale := expired.Sign("hashicorp", "aws", "5.0.0", "linux", "amd64")
+		if err := signer.Verify(stale, "hashicorp", "aws", "5.0.0", "linux", "amd64"); err != ErrInvalidDownloadToken {
+			t.Errorf("Verify() error = %v, want %v", err, ErrInvalidDownloadToken)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		for _, bad := range []string{"", "nodot", "abc.def"} {
+			if err := signer.Verify(bad, "hashicorp", "aws", "5.0.0", "linux", "amd64"); err != ErrInvalidDownloadToken {
+				t.Errorf("Verify(%q) error = %v, want %v", bad, err, ErrInvalidDownloadToken)
+			}
+		}
+	})
+}
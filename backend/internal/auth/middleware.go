@@ -8,15 +8,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware creates a middleware for JWT authentication.
-// It supports both Authorization header and URL query parameter (for SSE).
+// AuthMiddleware creates a middleware for JWT and API token authentication.
+// It supports the Authorization header, the X-API-Key header for API tokens,
+// and a URL query parameter (for SSE).
 func AuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var token string
+		token := c.GetHeader("X-API-Key")
+		if token != "" && !IsAPIToken(token) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": ErrInvalidAPIToken.Error()})
+			c.Abort()
+			return
+		}
 
-		// First, try to get token from Authorization header
+		// Otherwise, try to get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
+		if token == "" && authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token = parts[1]
@@ -34,7 +40,7 @@ func AuthMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := jwtManager.Verify(token)
+		claims, err := jwtManager.Authenticate(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
@@ -76,3 +82,30 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// DownloadTokenMiddleware requires a valid signed download token in the "sig" query
+// parameter for provider binary routes. Requests carrying a valid Bearer JWT or API
+// token are also allowed. When signer is nil the middleware is a no-op, so signed downloads stay optional.
+func DownloadTokenMiddleware(signer *DownloadSigner, jwtManager *JWTManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if signer == nil {
+			c.Next()
+			return
+		}
+
+		if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "Bearer" {
+			if _, err := jwtManager.Authenticate(parts[1]); err == nil {
+				c.Next()
+				return
+			}
+		}
+
+		if err := signer.Verify(c.Query("sig"), c.Param("namespace"), c.Param("name"),
+			c.Param("version"), c.Param("os"), c.Param("arch")); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
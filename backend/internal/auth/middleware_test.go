@@ -202,3 +202,48 @@ func TestMultipleRoles(t *testing.T) {
 		}
 	})
 }
+
+func TestDownloadTokenMiddleware(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key", time.Hour)
+	signer := NewDownloadSigner("test-secret-key", time.Minute)
+	const path = "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64/binary"
+
+	newRouter := func(s *DownloadSigner) *gin.Engine {
+		router := gin.New()
+		router.GET("/v1/providers/:namespace/:name/:version/download/:os/:arch/binary",
+			DownloadTokenMiddleware(s, jwtManager), func(c *gin.Context) {
+				c.String(http.StatusOK, "binary")
+			})
+		return router
+	}
+
+	tests := []struct {
+		name     string
+		signer   *DownloadSigner
+		url      string
+		bearer   bool
+		wantCode int
+	}{
+		{"disabled", nil, path, false, http.StatusOK},
+		{"missing token", signer, path, false, http.StatusForbidden},
+		{"valid token", signer, path + "?sig=" + signer.Sign("hashicorp", "aws", "5.0.0", "linux", "amd64"), false, http.StatusOK},
+		{"token for other platform", signer, path + "?sig=" + signer.Sign("hashicorp", "aws", "5.0.0", "darwin", "arm64"), false, http.StatusForbidden},
+		{"authenticated user", signer, path, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.bearer {
+				token, _ := jwtManager.Generate(1, "admin", "admin")
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			newRouter(tt.signer).ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Package logbuffer keeps recent log records in memory so they can be served to admins.
+package logbuffer
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of records kept by Default.
+const DefaultCapacity = 1000
+
+// subscriberBuffer is how many undelivered events a subscriber may queue before
+// further events are dropped for it.
+const subscriberBuffer = 64
+
+// Default is the process-wide buffer attached to the standard logger by main.
+// slog's default handler writes through the standard logger, so it captures both.
+var Default = New(DefaultCapacity)
+
+// Event is one captured log record.
+type Event struct {
+	ID      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// Buffer is a fixed-size ring of recent log events. It implements io.Writer so it
+// can be attached to a logger; each written line becomes one event.
+type Buffer struct {
+	mu          sync.Mutex
+	events      []Event
+	start       int // index of the oldest event once the ring is full
+	nextID      uint64
+	subscribers map[chan Event]struct{}
+}
+
+// New creates a Buffer holding at most capacity events.
+func New(capacity int) *Buffer {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Buffer{
+		events:      make([]Event, 0, capacity),
+		nextID:      1,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// stdPrefix matches the date and time the standard logger puts before each line.
+var stdPrefix = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// levels are the level names slog's default handler writes after the prefix.
+var levels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// Write records each line of p as an event. It never fails, so a logger using a
+// MultiWriter keeps writing to its other outputs.
+func (b *Buffer) Write(p []byte) (int, error) {
+	now := time.Now()
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		msg := strings.TrimSpace(stdPrefix.ReplaceAllString(string(line), ""))
+		if msg == "" {
+			continue
+		}
+		level := "INFO"
+		for _, l := range levels {
+			if rest, ok := strings.CutPrefix(msg, l+" "); ok {
+				level, msg = l, rest
+				break
+			}
+		}
+		b.add(Event{Time: now, Level: level, Message: Redact(msg)})
+	}
+	return len(p), nil
+}
+
+// add appends an event, evicting the oldest when full, and fans it out to subscribers.
+func (b *Buffer) add(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.ID = b.nextID
+	b.nextID++
+	if len(b.events) < cap(b.events) {
+		b.events = append(b.events, e)
+	} else {
+		b.events[b.start] = e
+		b.start = (b.start + 1) % len(b.events)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default: // slow subscriber; it will miss this event
+		}
+	}
+}
+
+// Since returns buffered events with an ID greater than afterID and a time not
+// before notBefore, oldest first. Use zero values to get everything.
+func (b *Buffer) Since(afterID uint64, notBefore time.Time) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]Event, 0)
+	for i := range b.events {
+		e := b.events[(b.start+i)%len(b.events)]
+		if e.ID > afterID && !e.Time.Before(notBefore) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Subscribe returns a channel receiving events as they are written, and a function
+// that cancels the subscription. Events are dropped if the reader falls behind.
+func (b *Buffer) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+var (
+	// secretAssignment matches key=value and "key": "value" pairs whose key names a secret.
+	secretAssignment = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key)[\w-]*["']?\s*[:=]\s*["']?)([^\s"'&,;]+)`)
+	// bearerToken matches an Authorization header value.
+	bearerToken = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+	// urlCredentials matches the user:password part of a URL.
+	urlCredentials = regexp.MustCompile(`(://)[^/@\s:]+:[^/@\s]+@`)
+)
+
+// Redact masks credentials in a log message: secret-named key/value pairs,
+// bearer and basic authorization values, and passwords embedded in URLs.
+func Redact(msg string) string {
+	msg = secretAssignment.ReplaceAllString(msg, "${1}[REDACTED]")
+	msg = bearerToken.ReplaceAllString(msg, "${1} [REDACTED]")
+	return urlCredentials.ReplaceAllString(msg, "${1}[REDACTED]@")
+}
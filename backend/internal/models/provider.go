@@ -17,22 +17,36 @@ const (
 	SourceMirror SourceType = "mirror"
 )
 
+// Upstream statuses recorded by revalidation of mirrored providers.
+const (
+	// UpstreamYanked means upstream no longer lists the version.
+	UpstreamYanked = "yanked"
+	// UpstreamChanged means upstream now publishes a different checksum for a cached platform.
+	UpstreamChanged = "changed"
+)
+
 // Provider represents a Terraform provider.
 type Provider struct {
-	ID          uint               `gorm:"primarykey" json:"id"`
-	Namespace   string             `gorm:"index:idx_provider,unique;not null" json:"namespace"`
-	Name        string             `gorm:"index:idx_provider,unique;not null" json:"name"`
-	Version     string             `gorm:"index:idx_provider,unique;not null" json:"version"`
-	Description string             `json:"description"`
-	SourceType  SourceType         `gorm:"type:varchar(20);default:'upload'" json:"source_type"`
-	SourceURL   string             `json:"source_url"`
-	Protocols   string             `json:"protocols"` // JSON array of protocol versions
-	Published   time.Time          `json:"published"`
-	Downloads   int64              `json:"downloads"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt     `gorm:"index" json:"-"`
-	Platforms   []ProviderPlatform `gorm:"foreignKey:ProviderID" json:"platforms,omitempty"`
+	ID             uint               `gorm:"primarykey" json:"id"`
+	Namespace      string             `gorm:"index:idx_provider,unique;not null" json:"namespace"`
+	Name           string             `gorm:"index:idx_provider,unique;not null" json:"name"`
+	Version        string             `gorm:"index:idx_provider,unique;not null" json:"version"`
+	Description    string             `json:"description"`
+	SourceType     SourceType         `gorm:"type:varchar(20);default:'upload'" json:"source_type"`
+	SourceURL      string             `json:"source_url"`
+	Protocols      string             `json:"protocols"` // JSON array of protocol versions
+	Published      time.Time          `json:"published"`
+	Downloads      int64              `json:"downloads"`                              // Completed binary downloads, counted once per client request
+	CacheFills     int64              `gorm:"not null;default:0" json:"cache_fills"`  // The part of Downloads that first fetched the binary from upstream
+	BytesServed    int64              `gorm:"not null;default:0" json:"bytes_served"` // Cumulative size of binaries served from the cache
+	Tier           string             `json:"tier"`                                   // Upstream tier of mirrored providers; empty if unknown
+	LogoURL        string             `json:"logo_url"`                               // Upstream logo of mirrored providers
+	RevalidatedAt  *time.Time         `json:"revalidated_at"`                         // Last re-check of a mirrored version against upstream
+	UpstreamStatus string             `json:"upstream_status"`                        // Empty while upstream matches; UpstreamYanked or UpstreamChanged otherwise
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt     `gorm:"index" json:"-"`
+	Platforms      []ProviderPlatform `gorm:"foreignKey:ProviderID" json:"platforms,omitempty"`
 }
 
 // Module represents a Terraform module.
@@ -46,6 +60,9 @@ type Module struct {
 	Source      string         `json:"source"`
 	Published   time.Time      `json:"published"`
 	Downloads   int64          `json:"downloads"`
+	FilePath    string         `json:"-"`         // Stored .tar.gz archive; empty for rows created before uploads existed
+	SHA256Sum   string         `json:"sha256sum"` // Of the stored archive
+	FileSize    int64          `json:"file_size"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -58,32 +75,50 @@ type User struct {
 	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
 	Password  string         `gorm:"not null" json:"-"`
 	Role      string         `gorm:"not null;default:'user'" json:"role"`
-	APIToken  string         `gorm:"uniqueIndex" json:"-"`
+	APIToken  string         `gorm:"uniqueIndex;default:null" json:"-"` // SHA-256 of the user's API token; NULL when none is issued
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// MustChangePassword restricts the user to changing their password until
+	// they do, as for a bootstrap admin with a generated password.
+	MustChangePassword bool `gorm:"not null;default:false" json:"must_change_password"`
+}
+
+// RefreshToken records an issued refresh token by its JWT ID so it can be
+// revoked before it expires.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	JTI       string     `gorm:"uniqueIndex;not null" json:"-"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 // ProviderPlatform represents platform-specific provider binaries.
 type ProviderPlatform struct {
-	ID         uint           `gorm:"primarykey" json:"id"`
-	ProviderID uint           `gorm:"not null;index" json:"provider_id"`
-	OS         string         `gorm:"not null" json:"os"`
-	Arch       string         `gorm:"not null" json:"arch"`
-	Filename   string         `gorm:"not null" json:"filename"`
-	FilePath   string         `gorm:"not null" json:"file_path"`
-	SHA256Sum  string         `gorm:"not null" json:"sha256sum"`
-	FileSize   int64          `json:"file_size"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primarykey" json:"id"`
+	ProviderID       uint           `gorm:"not null;index" json:"provider_id"`
+	OS               string         `gorm:"not null" json:"os"`
+	Arch             string         `gorm:"not null" json:"arch"`
+	Filename         string         `gorm:"not null" json:"filename"`
+	FilePath         string         `gorm:"not null" json:"-"` // Storage location; not exposed by the API
+	SHA256Sum        string         `gorm:"not null" json:"sha256sum"`
+	FileSize         int64          `json:"file_size"`
+	LastDownloadedAt *time.Time     `json:"last_downloaded_at"` // Throttled; see MirrorHandler.touchPlatform
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// MirrorConfig represents configuration for mirroring from upstream.
+// MirrorConfig represents configuration for mirroring from upstream. It maps a
+// provider, or with Name "*" a whole namespace, to the registry it is mirrored
+// from.
 type MirrorConfig struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
-	Namespace   string         `gorm:"not null" json:"namespace"`
-	Name        string         `gorm:"not null" json:"name"`
+	Namespace   string         `gorm:"not null;index:idx_mirror_config,unique" json:"namespace"`
+	Name        string         `gorm:"not null;index:idx_mirror_config,unique" json:"name"`
 	UpstreamURL string         `gorm:"not null;default:'https://registry.terraform.io'" json:"upstream_url"`
 	AutoSync    bool           `gorm:"default:false" json:"auto_sync"`
 	LastSyncAt  *time.Time     `json:"last_sync_at"`
@@ -94,30 +129,146 @@ type MirrorConfig struct {
 
 // Settings represents global application settings.
 type Settings struct {
-	ID                 uint      `gorm:"primarykey" json:"id"`
-	AllowOnlineSearch  bool      `gorm:"default:true" json:"allow_online_search"`
-	DefaultUpstreamURL string    `gorm:"default:'https://registry.terraform.io'" json:"default_upstream_url"`
-	RegistryURL        string    `gorm:"default:''" json:"registry_url"` // Custom registry URL for Terraform config
-	ProxyEnabled       bool      `gorm:"default:false" json:"proxy_enabled"`
-	ProxyURL           string    `gorm:"default:''" json:"proxy_url"`
-	ProxyType          string    `gorm:"default:'http'" json:"proxy_type"` // http, socks5
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                  uint      `gorm:"primarykey" json:"id"`
+	AllowOnlineSearch   bool      `gorm:"default:true" json:"allow_online_search"`
+	RedirectUncached    bool      `gorm:"default:false" json:"redirect_uncached"` // With online search off, send uncached downloads to upstream instead of 404
+	DefaultUpstreamURL  string    `gorm:"default:'https://registry.terraform.io'" json:"default_upstream_url"`
+	RegistryURL         string    `gorm:"default:''" json:"registry_url"`  // Custom registry URL for Terraform config
+	RegistryName        string    `gorm:"default:''" json:"registry_name"` // Display name reported in discovery and version responses
+	ProxyEnabled        bool      `gorm:"default:false" json:"proxy_enabled"`
+	ProxyURL            string    `gorm:"default:''" json:"proxy_url"`
+	ProxyType           string    `gorm:"default:'http'" json:"proxy_type"` // http, socks5
+	ProxyUsername       string    `gorm:"default:''" json:"proxy_username"` // Proxy credentials, in place of user:pass@ in ProxyURL
+	ProxyPassword       string    `gorm:"default:''" json:"-"`
+	MaxUpstreamVersions int       `gorm:"default:0" json:"max_upstream_versions"`   // Newest upstream versions merged into index.json; 0 means all
+	Frozen              bool      `gorm:"default:false" json:"frozen"`              // Read-only maintenance mode; see api.freezeMiddleware
+	ImmutableVersions   bool      `gorm:"default:false" json:"immutable_versions"`  // Reject changes to the files of already published platforms
+	VersionFallback     bool      `gorm:"default:false" json:"version_fallback"`    // Serve the nearest cached version matching X-Provider-Version-Constraint
+	RevalidateHours     int       `gorm:"default:0" json:"revalidate_hours"`        // Re-check cached mirrored versions against upstream after this many hours; 0 disables
+	VerifySignatures    bool      `gorm:"default:false" json:"verify_signatures"`   // Require a valid upstream GPG signature before caching a mirrored binary
+	IncludePrereleases  bool      `gorm:"default:false" json:"include_prereleases"` // Advertise upstream prerelease versions; cached prereleases are always listed
+	MirrorConcurrency   int       `gorm:"default:4" json:"mirror_concurrency"`      // Platforms downloaded at once when mirroring a provider
+	DefaultOS           string    `gorm:"default:'all'" json:"default_os"`          // OS mirrored, or synced by a new schedule, when the request names none
+	DefaultArch         string    `gorm:"default:'all'" json:"default_arch"`        // Architecture mirrored, or synced by a new schedule, when the request names none
+	RetainVersions      int       `gorm:"default:0" json:"retain_versions"`         // Newest versions kept per provider after a successful scheduled sync; 0 keeps all
+	UpstreamRateLimit   float64   `gorm:"default:0" json:"upstream_rate_limit"`     // Requests per second sent to upstream registries; 0 means unlimited
+	UpstreamRateBurst   int       `gorm:"default:10" json:"upstream_rate_burst"`    // Requests sent to upstream at once before the rate limit applies
+	UpdatedAt           time.Time `json:"updated_at"`
 }
 
+// Sync schedule modes.
+const (
+	// SyncModeLatest syncs the newest upstream version on every run.
+	SyncModeLatest = "latest"
+	// SyncModeLatestN keeps the newest VersionCount upstream releases cached,
+	// downloading only the platforms that are not cached yet.
+	SyncModeLatestN = "latest-n"
+)
+
 // SyncSchedule represents a scheduled sync task for a provider.
 type SyncSchedule struct {
-	ID         uint           `gorm:"primarykey" json:"id"`
-	Namespace  string         `gorm:"not null;index:idx_sync_provider" json:"namespace"`
-	Name       string         `gorm:"not null;index:idx_sync_provider" json:"name"`
-	CronExpr   string         `gorm:"not null" json:"cron_expr"`
-	Enabled    bool           `gorm:"default:true" json:"enabled"`
-	SyncOS     string         `gorm:"default:'all'" json:"sync_os"`
-	SyncArch   string         `gorm:"default:'all'" json:"sync_arch"`
-	LastRunAt  *time.Time     `json:"last_run_at"`
-	LastStatus string         `json:"last_status"`
-	LastError  string         `json:"last_error"`
-	NextRunAt  *time.Time     `json:"next_run_at"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	Namespace      string         `gorm:"not null;index:idx_sync_provider" json:"namespace"`
+	Name           string         `gorm:"not null;index:idx_sync_provider" json:"name"`
+	CronExpr       string         `gorm:"not null" json:"cron_expr"`
+	Enabled        bool           `gorm:"default:true" json:"enabled"`
+	SyncOS         string         `gorm:"default:'all';index:idx_sync_provider" json:"sync_os"`
+	SyncArch       string         `gorm:"default:'all';index:idx_sync_provider" json:"sync_arch"`
+	PublishedAfter *time.Time     `json:"published_after"`                // Skip upstream versions published earlier; nil syncs any version
+	Mode           string         `gorm:"default:'latest'" json:"mode"`   // SyncModeLatest or SyncModeLatestN
+	VersionCount   int            `gorm:"default:0" json:"version_count"` // Releases kept cached in SyncModeLatestN
+	LastRunAt      *time.Time     `json:"last_run_at"`
+	LastStatus     string         `json:"last_status"`
+	LastError      string         `json:"last_error"`
+	NextRunAt      *time.Time     `json:"next_run_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// ProviderClientVersion counts downloads of a provider per Terraform client version.
+type ProviderClientVersion struct {
+	ID               uint      `gorm:"primarykey" json:"-"`
+	Namespace        string    `gorm:"not null;index:idx_provider_client,unique" json:"namespace"`
+	Name             string    `gorm:"not null;index:idx_provider_client,unique" json:"name"`
+	TerraformVersion string    `gorm:"not null;index:idx_provider_client,unique" json:"terraform_version"`
+	Downloads        int64     `gorm:"not null;default:0" json:"downloads"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+}
+
+// NamespaceOwner grants a user write access to a provider namespace.
+type NamespaceOwner struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	Namespace string    `gorm:"not null;index:idx_namespace_owner,unique" json:"namespace"`
+	UserID    uint      `gorm:"not null;index:idx_namespace_owner,unique" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SigningKey is a GPG public key in the registry trust store. Keys with an empty
+// Namespace apply to every namespace.
+type SigningKey struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	Namespace      string    `gorm:"not null;default:'';index:idx_signing_key,unique" json:"namespace"`
+	KeyID          string    `gorm:"not null;index:idx_signing_key,unique" json:"key_id"`
+	ASCIIArmor     string    `gorm:"not null" json:"ascii_armor"`
+	TrustSignature string    `json:"trust_signature"`
+	Source         string    `json:"source"`
+	SourceURL      string    `json:"source_url"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ProviderSigningKey is a GPG public key upstream listed for a provider
+// version, served back with the version's download info.
+type ProviderSigningKey struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	ProviderID     uint      `gorm:"not null;index" json:"provider_id"`
+	KeyID          string    `gorm:"not null" json:"key_id"`
+	ASCIIArmor     string    `gorm:"not null" json:"ascii_armor"`
+	TrustSignature string    `json:"trust_signature"`
+	Source         string    `json:"source"`
+	SourceURL      string    `json:"source_url"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SyncRun records one attempt of a scheduled sync.
+type SyncRun struct {
+	ID                  uint      `gorm:"primarykey" json:"id"`
+	ScheduleID          uint      `gorm:"not null;index" json:"schedule_id"`
+	Attempt             int       `json:"attempt"` // 1-based attempt within the run
+	Retry               bool      `json:"retry"`   // Part of the follow-up run scheduled after a failure
+	Status              string    `json:"status"`  // success, failed
+	Error               string    `json:"error"`
+	PlatformsDownloaded int       `json:"platforms_downloaded"` // Platforms newly cached by the attempt
+	Bytes               int64     `json:"bytes"`                // Total size of those platforms
+	StartedAt           time.Time `json:"started_at"`
+	FinishedAt          time.Time `json:"finished_at"`
+}
+
+// UpstreamChecksum is a platform archive checksum from an upstream SHA256SUMS
+// file, kept so mirror responses can carry hashes for platforms not cached yet.
+type UpstreamChecksum struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	Namespace string    `gorm:"not null;index:idx_upstream_checksum,unique" json:"namespace"`
+	Name      string    `gorm:"not null;index:idx_upstream_checksum,unique" json:"name"`
+	Version   string    `gorm:"not null;index:idx_upstream_checksum,unique" json:"version"`
+	OS        string    `gorm:"not null;index:idx_upstream_checksum,unique" json:"os"`
+	Arch      string    `gorm:"not null;index:idx_upstream_checksum,unique" json:"arch"`
+	SHA256Sum string    `gorm:"not null" json:"sha256sum"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Webhook is an HTTP endpoint notified of registry events. Deliveries are
+// signed with Secret; LastStatus is "delivered" or "failed" after the latest
+// delivery, with LastError explaining a failure.
+type Webhook struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	URL            string     `gorm:"not null" json:"url"`
+	Secret         string     `gorm:"not null" json:"-"`
+	Events         string     `gorm:"not null" json:"events"` // Comma-separated event names; "*" for every event
+	Enabled        bool       `gorm:"default:true" json:"enabled"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultProvidersPath is the providers.v1 path registry.terraform.io uses, and
+// the fallback for registries whose discovery document cannot be read.
+const defaultProvidersPath = "/v1/providers/"
+
+// Discovery results are reused for discoveryTTL, or failedDiscoveryTTL when the
+// document could not be read, since every ProxyService for an upstream needs it.
+const (
+	discoveryTTL       = time.Hour
+	failedDiscoveryTTL = 5 * time.Minute
+	discoveryTimeout   = 10 * time.Second
+)
+
+type discoveredEndpoint struct {
+	providersURL string
+	expires      time.Time
+}
+
+var (
+	discoveryMu    sync.Mutex
+	discoveryCache = make(map[string]discoveredEndpoint)
+)
+
+var errNoProvidersService = errors.New("upstream does not advertise providers.v1")
+
+// discoveryDocument is the part of /.well-known/terraform.json the mirror uses.
+type discoveryDocument struct {
+	ProvidersV1 string `json:"providers.v1"`
+}
+
+// providersURL returns the base URL of the upstream's provider registry API,
+// without a trailing slash, as advertised by the "providers.v1" entry of its
+// /.well-known/terraform.json. Registries that do not publish one are assumed
+// to serve it at /v1/providers/.
+func (p *ProxyService) providersURL() string {
+	discoveryMu.Lock()
+	cached, ok := discoveryCache[p.upstreamURL]
+	discoveryMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.providersURL
+	}
+
+	endpoint, err := p.discoverProviders()
+	ttl := discoveryTTL
+	if err != nil {
+		endpoint, ttl = resolveServiceURL(p.upstreamURL, defaultProvidersPath), failedDiscoveryTTL
+	}
+	discoveryMu.Lock()
+	discoveryCache[p.upstreamURL] = discoveredEndpoint{providersURL: endpoint, expires: time.Now().Add(ttl)}
+	discoveryMu.Unlock()
+	return endpoint
+}
+
+// discoverProviders reads the providers.v1 endpoint from the upstream's
+// service discovery document.
+func (p *ProxyService) discoverProviders() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.upstreamURL+"/.well-known/terraform.json", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{Op: "discovery", StatusCode: resp.StatusCode}
+	}
+
+	var doc discoveryDocument
+	if err := decodeJSONResponse(resp.Body, &doc); err != nil {
+		return "", err
+	}
+	if doc.ProvidersV1 == "" {
+		return "", errNoProvidersService
+	}
+	return resolveServiceURL(p.upstreamURL, doc.ProvidersV1), nil
+}
+
+// resolveServiceURL resolves a discovered service path, which may be relative
+// or absolute, against the upstream base URL.
+func resolveServiceURL(upstreamURL, service string) string {
+	base, err := url.Parse(upstreamURL + "/")
+	if err != nil {
+		return strings.TrimSuffix(upstreamURL+service, "/")
+	}
+	ref, err := url.Parse(service)
+	if err != nil {
+		return strings.TrimSuffix(upstreamURL+defaultProvidersPath, "/")
+	}
+	return strings.TrimSuffix(base.ResolveReference(ref).String(), "/")
+}
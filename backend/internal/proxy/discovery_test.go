@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProvidersURL_Discovery(t *testing.T) {
+	var discoveries atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		discoveries.Add(1)
+		_, _ = w.Write([]byte(`{"providers.v1":"/api/registry/v1/providers/"}`))
+	})
+	mux.HandleFunc("/api/registry/v1/providers/acme/widget/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	versions, err := ps.GetProviderVersions(context.Background(), "acme", "widget")
+	if err != nil || len(versions.Versions) != 1 {
+		t.Fatalf("GetProviderVersions() = %+v, %v", versions, err)
+	}
+	// A second service for the same upstream reuses the discovery result.
+	if _, err := NewProxyService(t.TempDir(), server.URL).GetProviderVersions(context.Background(), "acme", "widget"); err != nil {
+		t.Fatalf("second GetProviderVersions() error = %v", err)
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("discovery fetched %d times, want 1", n)
+	}
+}
+
+func TestProvidersURL_Fallback(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/providers/acme/widget/versions", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	if _, err := NewProxyService(t.TempDir(), server.URL).GetProviderVersions(context.Background(), "acme", "widget"); err != nil {
+		t.Fatalf("GetProviderVersions() without discovery document: %v", err)
+	}
+}
+
+func TestResolveServiceURL(t *testing.T) {
+	tests := []struct {
+		upstream, service, want string
+	}{
+		{"https://registry.terraform.io", "/v1/providers/", "https://registry.terraform.io/v1/providers"},
+		{"https://gitlab.example.com", "/api/v4/packages/terraform/providers/", "https://gitlab.example.com/api/v4/packages/terraform/providers"},
+		{"https://registry.example.com", "https://api.example.com/providers/v1/", "https://api.example.com/providers/v1"},
+		{"https://registry.example.com", "v1/providers/", "https://registry.example.com/v1/providers"},
+	}
+	for _, tt := range tests {
+		if got := resolveServiceURL(tt.upstream, tt.service); got != tt.want {
+			t.Errorf("resolveServiceURL(%q, %q) = %q, want %q", tt.upstream, tt.service, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/storage"
+)
+
+// Provider files are addressed by the FilePath recorded for them, which is
+// under the storage path. The part relative to the storage path is the key in
+// the Storage backend, so local files stay where they always were.
+var (
+	sharedStorageMu sync.RWMutex
+	sharedStorage   storage.Storage
+)
+
+var errNoStorage = errors.New("provider storage is not available")
+
+// ErrChecksumMismatch is returned when a file does not match the SHA256
+// checksum it was stored with, in which case it is not stored.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// UseStorage makes every ProxyService created afterwards keep provider files in
+// store rather than on the local filesystem. The server installs the backend
+// selected by storage.type at startup; nil restores the default.
+func UseStorage(store storage.Storage) {
+	sharedStorageMu.Lock()
+	defer sharedStorageMu.Unlock()
+	sharedStorage = store
+}
+
+// storageFor returns the backend a new ProxyService keeps provider files in.
+func storageFor(storagePath string) storage.Storage {
+	sharedStorageMu.RLock()
+	store := sharedStorage
+	sharedStorageMu.RUnlock()
+	if store != nil {
+		return store
+	}
+	local, err := storage.NewLocalStorage(storagePath)
+	if err != nil {
+		return nil
+	}
+	return local
+}
+
+// storageKey returns the key of the provider file at filePath. Paths outside
+// the storage path, left by a storage migration until the restart, resolve to
+// keys starting with "..", which only the local backend accepts.
+func (p *ProxyService) storageKey(filePath string) string {
+	rel, err := filepath.Rel(p.storagePath, filePath)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// OpenFile opens a stored provider file. The reader implements io.Seeker and
+// io.ReaderAt for both built-in backends.
+func (p *ProxyService) OpenFile(filePath string) (io.ReadCloser, error) {
+	if p.store == nil {
+		return nil, errNoStorage
+	}
+	return p.store.Get(p.storageKey(filePath))
+}
+
+// FileExists reports whether a provider file is stored at filePath.
+func (p *ProxyService) FileExists(filePath string) bool {
+	if p.store == nil || filePath == "" {
+		return false
+	}
+	ok, err := p.store.Exists(p.storageKey(filePath))
+	return err == nil && ok
+}
+
+// FileSize returns the size of a stored provider file.
+func (p *ProxyService) FileSize(filePath string) (int64, error) {
+	if p.store == nil {
+		return 0, errNoStorage
+	}
+	return p.store.Size(p.storageKey(filePath))
+}
+
+// RemoveFile deletes a stored provider file.
+func (p *ProxyService) RemoveFile(filePath string) error {
+	if p.store == nil {
+		return errNoStorage
+	}
+	return p.store.Delete(p.storageKey(filePath))
+}
+
+// StoreFile stores a provider file at filePath and returns its SHA256
+// checksum and size. When wantSHA256 is set and the data does not match it,
+// nothing is stored and an ErrChecksumMismatch error is returned.
+func (p *ProxyService) StoreFile(filePath string, data io.Reader, wantSHA256 string) (string, int64, error) {
+	if p.store == nil {
+		return "", 0, errNoStorage
+	}
+	r := &verifyingReader{r: data, hash: sha256.New(), want: wantSHA256}
+	if err := p.store.Save(p.storageKey(filePath), r); err != nil {
+		if r.mismatch != nil {
+			return "", 0, r.mismatch
+		}
+		return "", 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	return hex.EncodeToString(r.hash.Sum(nil)), r.size, nil
+}
+
+// verifyingReader hashes what is read through it and, when want is set,
+// fails with a checksum mismatch instead of returning io.EOF if the data does
+// not match. Storage backends then discard the file rather than keep it.
+type verifyingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	want     string
+	size     int64
+	mismatch error
+}
+
+func (v *verifyingReader) Read(b []byte) (int, error) {
+	n, err := v.r.Read(b)
+	v.hash.Write(b[:n])
+	v.size += int64(n)
+	if err == io.EOF && v.want != "" {
+		if got := hex.EncodeToString(v.hash.Sum(nil)); !strings.EqualFold(got, v.want) {
+			v.mismatch = fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, v.want, got)
+			return n, v.mismatch
+		}
+	}
+	return n, err
+}
+
+// HashFileH1 computes the "h1:" hash of a stored provider package; see HashZipH1.
+func (p *ProxyService) HashFileH1(filePath string) (string, error) {
+	f, err := p.OpenFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	readerAt, ok := f.(io.ReaderAt)
+	if !ok {
+		return "", fmt.Errorf("storage backend does not support random access")
+	}
+	size, err := p.FileSize(filePath)
+	if err != nil {
+		return "", err
+	}
+	z, err := zip.NewReader(readerAt, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open zip: %w", err)
+	}
+	return hashZip(z)
+}
+
+// RemoveEmptyVersionDirs removes the directories of a provider version under
+// the local storage path that no longer hold any file, and the version
+// directory itself once it is empty. Directories that still hold files are
+// kept; with a remote backend there is nothing to remove.
+func (p *ProxyService) RemoveEmptyVersionDirs(namespace, name, version string) {
+	versionDir, err := p.versionDir(namespace, name, version)
+	if err != nil {
+		return
+	}
+
+	var dirs []string
+	_ = filepath.WalkDir(versionDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	// Children come after their parents in walk order, so removing in reverse
+	// empties each directory before its parent is tried.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i]) // #nosec G104 - fails, as intended, on directories that are not empty
+	}
+}
+
+// versionDir returns the directory under the storage path that holds the
+// files of a provider version.
+func (p *ProxyService) versionDir(namespace, name, version string) (string, error) {
+	parts := []string{p.storagePath}
+	for _, component := range []string{namespace, name, version} {
+		safe, err := sanitizePathComponent(component)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, safe)
+	}
+	return filepath.Join(parts...), nil
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// HashZipH1 computes the "h1:" hash Terraform records in .terraform.lock.hcl for a
+// provider package: the dirhash Hash1 of the files inside the zip archive. Unlike
+// the "zh:" hash it does not depend on how the archive itself was compressed.
+func HashZipH1(zipPath string) (string, error) {
+	z, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer func() { _ = z.Close() }()
+	return hashZip(&z.Reader)
+}
+
+// hashZip computes the "h1:" hash of an opened zip archive.
+func hashZip(z *zip.Reader) (string, error) {
+	files := make([]*zip.File, len(z.File))
+	copy(files, z.File)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	summary := sha256.New()
+	for _, f := range files {
+		if strings.Contains(f.Name, "\n") {
+			return "", fmt.Errorf("zip contains a file name with a newline")
+		}
+		sum, err := hashZipEntry(f)
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(summary, "%x  %s\n", sum, f.Name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// hashZipEntry returns the SHA-256 of the uncompressed contents of a zip entry.
+func hashZipEntry(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip: %w", f.Name, err)
+	}
+	defer func() { _ = r.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil { // #nosec G110 - entries are hashed, not extracted
+		return nil, fmt.Errorf("failed to read %s in zip: %w", f.Name, err)
+	}
+	return h.Sum(nil), nil
+}
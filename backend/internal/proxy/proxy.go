@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -16,14 +17,137 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/storage"
 	"golang.org/x/net/proxy"
+	"golang.org/x/sync/singleflight"
 )
 
 // UpstreamRegistry is the default Terraform registry URL.
 const UpstreamRegistry = "https://registry.terraform.io"
 
+// DefaultMaxResponseSize is the default cap on upstream metadata and search responses.
+const DefaultMaxResponseSize int64 = 32 << 20
+
+// ErrResponseTooLarge is returned when an upstream metadata or search response
+// exceeds the limit set with SetMaxResponseSize.
+var ErrResponseTooLarge = errors.New("upstream response exceeds size limit")
+
+// maxResponseSize holds the current response size limit in bytes.
+var maxResponseSize atomic.Int64
+
+func init() {
+	maxResponseSize.Store(DefaultMaxResponseSize)
+	metadataTimeout.Store(int64(DefaultMetadataTimeout))
+}
+
+// SetMaxResponseSize sets the maximum size in bytes of upstream JSON responses.
+// Provider binaries are not subject to this limit. Non-positive values restore the default.
+func SetMaxResponseSize(n int64) {
+	if n <= 0 {
+		n = DefaultMaxResponseSize
+	}
+	maxResponseSize.Store(n)
+}
+
+// DefaultMetadataTimeout is the default time allowed for an upstream metadata
+// or search request, including reading its response.
+const DefaultMetadataTimeout = 30 * time.Second
+
+// metadataTimeout holds the current metadata request timeout in nanoseconds.
+var metadataTimeout atomic.Int64
+
+// SetMetadataTimeout sets how long an upstream JSON or checksum request may
+// take. Provider binaries have no overall timeout, only the transport's dial
+// and handshake timeouts. Non-positive values restore the default.
+func SetMetadataTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultMetadataTimeout
+	}
+	metadataTimeout.Store(int64(d))
+}
+
+// getMetadata issues a GET for a small upstream response, bounded by ctx and
+// the metadata timeout until its body is closed.
+func (p *ProxyService) getMetadata(ctx context.Context, rawURL string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(metadataTimeout.Load()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// getBinary issues a GET for a provider binary, bounded only by ctx.
+func (p *ProxyService) getBinary(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.httpClient.Do(req)
+}
+
+// cancelOnClose releases a request's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// decodeJSONResponse decodes an upstream JSON body into v, reading at most the
+// configured maximum response size.
+func decodeJSONResponse(body io.Reader, v interface{}) error {
+	limit := maxResponseSize.Load()
+	limited := &io.LimitedReader{R: body, N: limit + 1}
+	if err := json.NewDecoder(limited).Decode(v); err != nil {
+		if limited.N <= 0 {
+			return fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, limit)
+		}
+		return err
+	}
+	return nil
+}
+
+// StatusError reports an unexpected HTTP status from the upstream registry.
+type StatusError struct {
+	// Op names the request that failed, e.g. "upstream" or "download".
+	Op         string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Op, e.StatusCode)
+}
+
+// IsTransient reports whether err is likely to succeed on retry: network
+// failures, timeouts, rate limiting and upstream 5xx responses.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
 // pathComponentRegex validates path components to prevent path traversal attacks.
 // Only allows alphanumeric characters, hyphens, underscores, and dots.
 // Must start with alphanumeric character.
@@ -128,23 +252,38 @@ func sanitizeFilename(filename string) (string, error) {
 type ProxyService struct {
 	httpClient   *http.Client
 	storagePath  string
+	store        storage.Storage // holds the provider files under storagePath
 	upstreamURL  string
 	proxyURL     string
 	proxyType    string
 	proxyEnabled bool
-	mu           sync.RWMutex
+	// proxyUsername and proxyPassword, when set, replace credentials in proxyURL.
+	proxyUsername string
+	proxyPassword string
+	// proxyErr is why the proxy could not be set up; see ProxyError.
+	proxyErr error
+	// verifySignatures requires a valid GPG signature on SHA256SUMS before caching.
+	verifySignatures bool
+	mu               sync.RWMutex
 }
 
 // NewProxyService creates a new ProxyService instance.
 func NewProxyService(storagePath, upstreamURL string) *ProxyService {
+	return NewProxyServiceWithStorage(storagePath, upstreamURL, storageFor(storagePath))
+}
+
+// NewProxyServiceWithStorage creates a ProxyService that keeps provider files
+// in store instead of the backend installed by UseStorage.
+func NewProxyServiceWithStorage(storagePath, upstreamURL string, store storage.Storage) *ProxyService {
 	if upstreamURL == "" {
 		upstreamURL = UpstreamRegistry
 	}
 	return &ProxyService{
 		httpClient: &http.Client{
-			Timeout: 5 * time.Minute,
+			Transport: &throttledTransport{base: http.DefaultTransport},
 		},
 		storagePath: storagePath,
+		store:       store,
 		upstreamURL: upstreamURL,
 	}
 }
@@ -157,6 +296,7 @@ func NewProxyServiceWithProxy(storagePath, upstreamURL, proxyURL, proxyType stri
 
 	ps := &ProxyService{
 		storagePath:  storagePath,
+		store:        storageFor(storagePath),
 		upstreamURL:  upstreamURL,
 		proxyURL:     proxyURL,
 		proxyType:    proxyType,
@@ -166,13 +306,27 @@ func NewProxyServiceWithProxy(storagePath, upstreamURL, proxyURL, proxyType stri
 	return ps
 }
 
+// UpstreamURL returns the base URL of the registry this service reads from.
+func (p *ProxyService) UpstreamURL() string {
+	return p.upstreamURL
+}
+
 // SetProxy updates the proxy configuration.
 func (p *ProxyService) SetProxy(enabled bool, proxyURL, proxyType string) {
+	p.SetAuthenticatedProxy(enabled, proxyURL, proxyType, "", "")
+}
+
+// SetAuthenticatedProxy is SetProxy with credentials the proxy is
+// authenticated with, in place of any given as user:pass@ in proxyURL. With an
+// empty username the credentials in proxyURL, if any, are used.
+func (p *ProxyService) SetAuthenticatedProxy(enabled bool, proxyURL, proxyType, username, password string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.proxyEnabled = enabled
 	p.proxyURL = proxyURL
 	p.proxyType = proxyType
+	p.proxyUsername = username
+	p.proxyPassword = password
 	p.updateHTTPClient()
 }
 
@@ -189,35 +343,116 @@ func (p *ProxyService) updateHTTPClient() {
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
+	p.proxyErr = nil
 	if p.proxyEnabled && p.proxyURL != "" {
-		proxyURL := p.proxyURL
-		proxyType := strings.ToLower(p.proxyType)
-
-		if proxyType == "socks5" {
-			// SOCKS5 proxy
-			dialer, err := proxy.SOCKS5("tcp", strings.TrimPrefix(strings.TrimPrefix(proxyURL, "socks5://"), "socks5h://"), nil, proxy.Direct)
-			if err == nil {
-				transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-					return dialer.Dial(network, addr)
-				}
-			}
-		} else {
-			// HTTP/HTTPS proxy
-			if !strings.HasPrefix(proxyURL, "http://") && !strings.HasPrefix(proxyURL, "https://") {
-				proxyURL = "http://" + proxyURL
-			}
-			if proxyU, err := url.Parse(proxyURL); err == nil {
-				transport.Proxy = http.ProxyURL(proxyU)
+		if err := configureProxy(transport, p.proxyURL, p.proxyType, p.proxyUsername, p.proxyPassword); err != nil {
+			// Fail every request rather than silently connecting directly.
+			p.proxyErr = err
+			transport.Proxy = nil
+			transport.DialContext = func(context.Context, string, string) (net.Conn, error) {
+				return nil, err
 			}
 		}
 	}
 
 	p.httpClient = &http.Client{
-		Timeout:   5 * time.Minute,
-		Transport: transport,
+		Transport: &throttledTransport{base: transport},
 	}
 }
 
+// ProxyError returns why the configured proxy could not be set up, or nil. While
+// it is set every upstream request fails with it.
+func (p *ProxyService) ProxyError() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.proxyErr
+}
+
+// CheckProxy reports whether a proxy address of the given type can be used,
+// returning the error ProxyError would report for it.
+func CheckProxy(proxyURL, proxyType string) error {
+	return configureProxy(&http.Transport{}, proxyURL, proxyType, "", "")
+}
+
+// StripProxyCredentials returns proxyURL with any user:pass@ in it removed,
+// and whether a password was among them. It works on the raw string, so that
+// addresses that do not parse are stripped too.
+func StripProxyCredentials(proxyURL string) (string, bool) {
+	prefix, rest := "", proxyURL
+	if i := strings.Index(proxyURL, "://"); i >= 0 {
+		prefix, rest = proxyURL[:i+3], proxyURL[i+3:]
+	}
+	authority := rest
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		authority = rest[:i]
+	}
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return proxyURL, false
+	}
+	return prefix + rest[at+1:], strings.Contains(authority[:at], ":")
+}
+
+// configureProxy makes transport connect through the proxy at proxyURL.
+// Credentials given as username and password replace any in proxyURL.
+func configureProxy(transport *http.Transport, proxyURL, proxyType, username, password string) error {
+	proxyType = strings.ToLower(proxyType)
+	proxyU, err := parseProxyURL(proxyURL, proxyType)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if username != "" {
+		proxyU.User = url.UserPassword(username, password)
+	}
+
+	if proxyType != "socks5" {
+		// HTTP/HTTPS proxy; http.ProxyURL sends the URL's credentials as
+		// Proxy-Authorization.
+		transport.Proxy = http.ProxyURL(proxyU)
+		return nil
+	}
+
+	var auth *proxy.Auth
+	if proxyU.User != nil {
+		password, _ := proxyU.User.Password()
+		auth = &proxy.Auth{User: proxyU.User.Username(), Password: password}
+	}
+	dialer, err := proxy.SOCKS5("tcp", proxyU.Host, auth, proxy.Direct)
+	if err != nil {
+		return fmt.Errorf("invalid SOCKS5 proxy: %w", err)
+	}
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		transport.DialContext = contextDialer.DialContext
+	} else {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.Dial(network, addr)
+		}
+	}
+	return nil
+}
+
+// parseProxyURL parses a proxy address, which may omit the scheme and may carry
+// user:pass@ credentials.
+func parseProxyURL(rawURL, proxyType string) (*url.URL, error) {
+	if !strings.Contains(rawURL, "://") {
+		scheme := "http"
+		if proxyType == "socks5" {
+			scheme = "socks5"
+		}
+		rawURL = scheme + "://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The error would quote the URL, or the part of it that failed to
+		// parse, which may be the credentials.
+		return nil, errors.New("malformed URL")
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	return u, nil
+}
+
 // VersionsResponse represents the response from the versions endpoint.
 type VersionsResponse struct {
 	Versions []Version `json:"versions"`
@@ -230,6 +465,51 @@ type Version struct {
 	Platforms []Platform `json:"platforms"`
 }
 
+// NewestVersions returns the version strings of upstream, newest first, capped at limit.
+// A limit of zero or less returns every version. Prereleases are dropped before the
+// cap is applied unless prereleases is set.
+func NewestVersions(upstream []Version, limit int, prereleases bool) []string {
+	result := make([]string, 0, len(upstream))
+	for _, v := range upstream {
+		if !prereleases && semver.IsPrerelease(v.Version) {
+			continue
+		}
+		result = append(result, v.Version)
+	}
+	semver.SortDescending(result)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// DefaultProtocols is the plugin protocol assumed for a provider whose
+// protocols are not known, such as an uploaded one.
+var DefaultProtocols = []string{"5.0"}
+
+// EncodeProtocols returns the JSON array of protocol versions stored in a
+// provider row, falling back to DefaultProtocols when protocols is empty.
+func EncodeProtocols(protocols []string) string {
+	if len(protocols) == 0 {
+		protocols = DefaultProtocols
+	}
+	data, err := json.Marshal(protocols)
+	if err != nil {
+		return `["5.0"]`
+	}
+	return string(data)
+}
+
+// DecodeProtocols parses the protocols stored in a provider row. Empty or
+// malformed values yield DefaultProtocols.
+func DecodeProtocols(stored string) []string {
+	var protocols []string
+	if err := json.Unmarshal([]byte(stored), &protocols); err != nil || len(protocols) == 0 {
+		return append([]string(nil), DefaultProtocols...)
+	}
+	return protocols
+}
+
 // Platform represents an OS/arch combination.
 type Platform struct {
 	OS   string `json:"os"`
@@ -264,198 +544,304 @@ type GPGPublicKey struct {
 }
 
 // GetProviderVersions fetches available versions from upstream registry.
-func (p *ProxyService) GetProviderVersions(namespace, name string) (*VersionsResponse, error) {
-	url := fmt.Sprintf("%s/v1/providers/%s/%s/versions", p.upstreamURL, namespace, name)
+func (p *ProxyService) GetProviderVersions(ctx context.Context, namespace, name string) (*VersionsResponse, error) {
+	url := fmt.Sprintf("%s/%s/%s/versions", p.providersURL(), namespace, name)
 
-	resp, err := p.httpClient.Get(url)
+	resp, err := p.getMetadata(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch versions: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		return nil, &StatusError{Op: "upstream", StatusCode: resp.StatusCode}
 	}
 
 	var versions VersionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+	if err := decodeJSONResponse(resp.Body, &versions); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return &versions, nil
 }
 
-// GetProviderDownloadInfo fetches download information for a specific provider version.
-func (p *ProxyService) GetProviderDownloadInfo(namespace, name, version, osType, arch string) (*DownloadInfo, error) {
-	url := fmt.Sprintf("%s/v1/providers/%s/%s/%s/download/%s/%s",
-		p.upstreamURL, namespace, name, version, osType, arch)
+// GetProviderPublishDates fetches when each version of a provider was published
+// upstream, keyed by version. The v1 versions endpoint omits publish dates, so
+// they come from the v2 API's included provider-versions.
+func (p *ProxyService) GetProviderPublishDates(ctx context.Context, namespace, name string) (map[string]time.Time, error) {
+	url := fmt.Sprintf("%s/v2/providers/%s/%s?include=provider-versions", p.upstreamURL, namespace, name)
 
-	resp, err := p.httpClient.Get(url)
+	resp, err := p.getMetadata(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch download info: %w", err)
+		return nil, fmt.Errorf("failed to fetch publish dates: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		return nil, &StatusError{Op: "upstream", StatusCode: resp.StatusCode}
 	}
 
-	var info DownloadInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+	var v2Response struct {
+		Included []struct {
+			Type       string `json:"type"`
+			Attributes struct {
+				Version     string `json:"version"`
+				PublishedAt string `json:"published-at"`
+			} `json:"attributes"`
+		} `json:"included"`
+	}
+	if err := decodeJSONResponse(resp.Body, &v2Response); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &info, nil
+	dates := make(map[string]time.Time, len(v2Response.Included))
+	for _, item := range v2Response.Included {
+		if item.Type != "provider-versions" {
+			continue
+		}
+		published, err := time.Parse(time.RFC3339, item.Attributes.PublishedAt)
+		if err != nil {
+			continue
+		}
+		dates[item.Attributes.Version] = published
+	}
+	return dates, nil
 }
 
-// DownloadAndCacheProvider downloads a provider from upstream and caches it locally.
-func (p *ProxyService) DownloadAndCacheProvider(namespace, name, version, osType, arch string) (string, string, error) {
-	// Build safe directory path with validation
-	dirPath, err := buildSafeProviderPath(p.storagePath, namespace, name, version, osType, arch)
+// ProviderDetails holds provider-level metadata from the upstream v2 API.
+type ProviderDetails struct {
+	Description string
+	Tier        string // "official", "partner", or "community"
+	LogoURL     string
+}
+
+// GetProviderDetails fetches the description, tier and logo of a provider from upstream.
+func (p *ProxyService) GetProviderDetails(ctx context.Context, namespace, name string) (*ProviderDetails, error) {
+	url := fmt.Sprintf("%s/v2/providers/%s/%s", p.upstreamURL, namespace, name)
+
+	resp, err := p.getMetadata(ctx, url)
 	if err != nil {
-		return "", "", err
+		return nil, fmt.Errorf("failed to fetch provider details: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	// Get download info
-	info, err := p.GetProviderDownloadInfo(namespace, name, version, osType, arch)
-	if err != nil {
-		return "", "", err
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Op: "upstream", StatusCode: resp.StatusCode}
 	}
 
-	// Create storage directory
-	if err := os.MkdirAll(dirPath, 0750); err != nil {
-		return "", "", fmt.Errorf("failed to create directory: %w", err)
+	var v2Response struct {
+		Data struct {
+			Attributes struct {
+				Description string `json:"description"`
+				Tier        string `json:"tier"`
+				LogoURL     string `json:"logo-url"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := decodeJSONResponse(resp.Body, &v2Response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Sanitize filename and build file path
-	safeFilename, err := sanitizeFilename(info.Filename)
+	return &ProviderDetails{
+		Description: v2Response.Data.Attributes.Description,
+		Tier:        v2Response.Data.Attributes.Tier,
+		LogoURL:     v2Response.Data.Attributes.LogoURL,
+	}, nil
+}
+
+// GetSHA256Sums fetches an upstream SHA256SUMS file and returns the checksums it
+// lists, keyed by file name.
+func (p *ProxyService) GetSHA256Sums(ctx context.Context, sumsURL string) (map[string]string, error) {
+	data, err := p.fetchArtifact(ctx, sumsURL, "shasums")
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	filePath := filepath.Join(dirPath, safeFilename)
-	if _, err := os.Stat(filePath); err == nil {
-		// File exists, verify checksum
-		existingSHA256, _ := p.calculateFileSHA256(filePath)
-		if existingSHA256 == info.SHA256Sum {
-			return filePath, existingSHA256, nil
+	return parseSHA256Sums(string(data)), nil
+}
+
+// sha256Hex matches a hex-encoded SHA-256 digest.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// parseSHA256Sums parses "<sha256>  <filename>" lines as written by sha256sum,
+// skipping lines that are not in that form.
+func parseSHA256Sums(data string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !sha256Hex.MatchString(fields[0]) {
+			continue
 		}
+		sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
 	}
+	return sums
+}
 
-	// Download the file
-	resp, err := p.httpClient.Get(info.DownloadURL)
+// GetProviderDownloadInfo fetches download information for a specific provider version.
+func (p *ProxyService) GetProviderDownloadInfo(ctx context.Context, namespace, name, version, osType, arch string) (*DownloadInfo, error) {
+	url := fmt.Sprintf("%s/%s/%s/%s/download/%s/%s",
+		p.providersURL(), namespace, name, version, osType, arch)
+
+	resp, err := p.getMetadata(ctx, url)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to download provider: %w", err)
+		return nil, fmt.Errorf("failed to fetch download info: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("download returned status %d", resp.StatusCode)
+		return nil, &StatusError{Op: "upstream", StatusCode: resp.StatusCode}
 	}
 
-	// Create temp file
-	tempPath := filePath + ".tmp"
-	file, err := os.Create(tempPath) // #nosec G304 - path is constructed from validated components
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create file: %w", err)
+	var info DownloadInfo
+	if err := decodeJSONResponse(resp.Body, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-	defer func() { _ = file.Close() }()
 
-	// Write and calculate checksum simultaneously
-	hasher := sha256.New()
-	writer := io.MultiWriter(file, hasher)
+	return &info, nil
+}
 
-	if _, err := io.Copy(writer, resp.Body); err != nil {
-		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("failed to write file: %w", err)
-	}
+// downloads deduplicates concurrent downloads of the same provider platform.
+// It is shared by every ProxyService, since handlers create one per request.
+var downloads singleflight.Group
 
-	calculatedSHA256 := hex.EncodeToString(hasher.Sum(nil))
+// A shared download runs under a context of its own, cancelled once every
+// caller waiting for it has given up, so that one client disconnecting does
+// not abort the download for the others.
+var (
+	downloadsMu     sync.Mutex
+	sharedDownloads = map[string]*sharedDownload{}
+)
 
-	// Verify checksum
-	if calculatedSHA256 != info.SHA256Sum {
-		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("checksum mismatch: expected %s, got %s", info.SHA256Sum, calculatedSHA256)
-	}
+type sharedDownload struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
 
-	// Rename to final path
-	if err := os.Rename(tempPath, filePath); err != nil {
-		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("failed to rename file: %w", err)
+// joinDownload registers a caller waiting for the download under key.
+func joinDownload(key string) *sharedDownload {
+	downloadsMu.Lock()
+	defer downloadsMu.Unlock()
+	d, ok := sharedDownloads[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		d = &sharedDownload{ctx: ctx, cancel: cancel}
+		sharedDownloads[key] = d
+	}
+	d.waiters++
+	return d
+}
+
+// leaveDownload unregisters a caller of joinDownload, cancelling the download
+// when it was the last one.
+func leaveDownload(key string, d *sharedDownload) {
+	downloadsMu.Lock()
+	defer downloadsMu.Unlock()
+	d.waiters--
+	if d.waiters == 0 {
+		d.cancel()
+		delete(sharedDownloads, key)
 	}
+}
 
-	return filePath, calculatedSHA256, nil
+// cachedDownload is the result of a DownloadAndCacheProvider call.
+type cachedDownload struct {
+	filePath string
+	sha256   string
+	info     *DownloadInfo
 }
 
-// DownloadAndStoreProvider downloads a provider from a given URL and stores it locally.
-// This is used for async caching when the download URL is already known.
-func (p *ProxyService) DownloadAndStoreProvider(namespace, name, version, osType, arch, downloadURL string) (string, string, error) {
+// DownloadAndCacheProvider downloads a provider from upstream and caches it in storage.
+// The file is only stored if it matches the checksum the upstream publishes.
+// Concurrent calls for the same platform share a single download, which is
+// aborted once ctx is done for every one of them.
+func (p *ProxyService) DownloadAndCacheProvider(ctx context.Context, namespace, name, version, osType, arch string) (string, string, error) {
+	filePath, sha256sum, _, err := p.DownloadAndCacheProviderInfo(ctx, namespace, name, version, osType, arch)
+	return filePath, sha256sum, err
+}
+
+// DownloadAndCacheProviderInfo is DownloadAndCacheProvider that also returns
+// the upstream download info the provider was fetched with.
+func (p *ProxyService) DownloadAndCacheProviderInfo(ctx context.Context, namespace, name, version, osType, arch string) (string, string, *DownloadInfo, error) {
 	// Build safe directory path with validation
 	dirPath, err := buildSafeProviderPath(p.storagePath, namespace, name, version, osType, arch)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
-	// Create storage directory
-	if err := os.MkdirAll(dirPath, 0750); err != nil {
-		return "", "", fmt.Errorf("failed to create directory: %w", err)
-	}
+	// The directory identifies namespace/name/version/os/arch under this
+	// storage path, which is also what the downloaded file is stored under.
+	for {
+		shared := joinDownload(dirPath)
+		results := downloads.DoChan(dirPath, func() (interface{}, error) {
+			return p.downloadAndCacheProvider(shared.ctx, dirPath, namespace, name, version, osType, arch)
+		})
+		var res singleflight.Result
+		select {
+		case res = <-results:
+		case <-ctx.Done():
+			leaveDownload(dirPath, shared)
+			return "", "", nil, ctx.Err()
+		}
+		leaveDownload(dirPath, shared)
 
-	// Extract filename from URL and sanitize
-	parts := strings.Split(downloadURL, "/")
-	filename, err := sanitizeFilename(parts[len(parts)-1])
-	if err != nil || filename == "" {
-		// Generate safe default filename from validated components
-		safeName, _ := sanitizePathComponent(name)
-		safeVer, _ := sanitizePathComponent(version)
-		safeOS, _ := sanitizePathComponent(osType)
-		safeArch, _ := sanitizePathComponent(arch)
-		filename = fmt.Sprintf("terraform-provider-%s_%s_%s_%s.zip", safeName, safeVer, safeOS, safeArch)
+		// The download was joined just after everyone before had given up on
+		// it, and was cancelled on their behalf; start it again.
+		if errors.Is(res.Err, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+		if res.Err != nil {
+			return "", "", nil, res.Err
+		}
+		download := res.Val.(cachedDownload)
+		return download.filePath, download.sha256, download.info, nil
 	}
+}
 
-	filePath := filepath.Join(dirPath, filename)
+// downloadAndCacheProvider does the work of DownloadAndCacheProvider.
+func (p *ProxyService) downloadAndCacheProvider(ctx context.Context, dirPath, namespace, name, version, osType, arch string) (cachedDownload, error) {
+	// Get download info
+	info, err := p.GetProviderDownloadInfo(ctx, namespace, name, version, osType, arch)
+	if err != nil {
+		return cachedDownload{}, err
+	}
+	if p.VerifySignatures() {
+		if err := p.VerifyDownloadSignature(ctx, info); err != nil {
+			return cachedDownload{}, err
+		}
+	}
 
-	// Check if file already exists
-	if existingSHA256, err := p.calculateFileSHA256(filePath); err == nil {
-		return filePath, existingSHA256, nil
+	// Sanitize filename and build file path
+	safeFilename, err := sanitizeFilename(info.Filename)
+	if err != nil {
+		return cachedDownload{}, err
+	}
+	filePath := filepath.Join(dirPath, safeFilename)
+	if existingSHA256, err := p.calculateFileSHA256(filePath); err == nil && existingSHA256 == info.SHA256Sum {
+		return cachedDownload{filePath: filePath, sha256: existingSHA256, info: info}, nil
 	}
 
 	// Download the file
-	resp, err := p.httpClient.Get(downloadURL)
+	resp, err := p.getBinary(ctx, info.DownloadURL)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to download provider: %w", err)
+		return cachedDownload{}, fmt.Errorf("failed to download provider: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("download returned status %d", resp.StatusCode)
+		return cachedDownload{}, &StatusError{Op: "download", StatusCode: resp.StatusCode}
 	}
 
-	// Create temp file
-	tempPath := filePath + ".tmp"
-	file, err := os.Create(tempPath) // #nosec G304 - path is constructed from validated components
+	// Stream into storage, which discards the file on a checksum mismatch
+	calculatedSHA256, _, err := p.StoreFile(filePath, resp.Body, info.SHA256Sum)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer func() { _ = file.Close() }()
-
-	// Write and calculate checksum simultaneously
-	hasher := sha256.New()
-	writer := io.MultiWriter(file, hasher)
-
-	if _, err := io.Copy(writer, resp.Body); err != nil {
-		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("failed to write file: %w", err)
+		return cachedDownload{}, err
 	}
 
-	calculatedSHA256 := hex.EncodeToString(hasher.Sum(nil))
+	// The checksum files are served back to clients if present; the binary is
+	// usable without them.
+	_ = p.StoreChecksumFiles(ctx, namespace, name, version, info) // #nosec G104 - best effort
 
-	// Rename to final path
-	if err := os.Rename(tempPath, filePath); err != nil {
-		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("failed to rename file: %w", err)
-	}
-
-	return filePath, calculatedSHA256, nil
+	return cachedDownload{filePath: filePath, sha256: calculatedSHA256, info: info}, nil
 }
 
 // SaveUploadedProvider saves an uploaded provider file.
@@ -472,37 +858,22 @@ func (p *ProxyService) SaveUploadedProvider(namespace, name, version, osType, ar
 		return "", "", err
 	}
 
-	// Create storage directory
-	if err := os.MkdirAll(dirPath, 0750); err != nil {
-		return "", "", fmt.Errorf("failed to create directory: %w", err)
-	}
-
 	filePath := filepath.Join(dirPath, safeFilename)
-
-	// Create file
-	// #nosec G304 -- filePath is constructed from validated components via buildSafeProviderPath and sanitizeFilename
-	outFile, err := os.Create(filePath)
+	sha256sum, _, err := p.StoreFile(filePath, file, "")
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer func() { _ = outFile.Close() }()
-
-	// Write and calculate checksum
-	hasher := sha256.New()
-	writer := io.MultiWriter(outFile, hasher)
-
-	if _, err := io.Copy(writer, file); err != nil {
-		_ = os.Remove(filePath) // #nosec G104 - best effort cleanup
-		return "", "", fmt.Errorf("failed to write file: %w", err)
+		return "", "", err
 	}
-
-	sha256sum := hex.EncodeToString(hasher.Sum(nil))
 	return filePath, sha256sum, nil
 }
 
-// calculateFileSHA256 calculates the SHA256 checksum of a file.
+// CalculateFileSHA256 calculates the SHA256 checksum of a file.
+func (p *ProxyService) CalculateFileSHA256(filePath string) (string, error) {
+	return p.calculateFileSHA256(filePath)
+}
+
+// calculateFileSHA256 calculates the SHA256 checksum of a stored file.
 func (p *ProxyService) calculateFileSHA256(filePath string) (string, error) {
-	file, err := os.Open(filePath) // #nosec G304 - path is from internal storage
+	file, err := p.OpenFile(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -517,6 +888,8 @@ func (p *ProxyService) calculateFileSHA256(filePath string) (string, error) {
 }
 
 // GetCachedFilePath returns the path to a cached provider file if it exists.
+// It lists the local storage directory, so with another backend it finds
+// nothing and callers fall back to the database or the upstream.
 func (p *ProxyService) GetCachedFilePath(namespace, name, version, osType, arch string) (string, bool) {
 	// Build safe directory path with validation
 	dirPath, err := buildSafeProviderPath(p.storagePath, namespace, name, version, osType, arch)
@@ -530,7 +903,8 @@ func (p *ProxyService) GetCachedFilePath(namespace, name, version, osType, arch
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// Skip partial downloads left behind by an interrupted write.
+		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), ".tmp") {
 			// entry.Name() is safe as it comes from filesystem, not user input
 			return filepath.Join(dirPath, entry.Name()), true
 		}
@@ -552,19 +926,37 @@ type SearchResult struct {
 	Description string `json:"description"`
 	Downloads   int64  `json:"downloads"`
 	Source      string `json:"source"`
+	Tier        string `json:"tier"`
+	LogoURL     string `json:"logo_url,omitempty"`
 }
 
 // SearchResponse represents the response from upstream search.
 type SearchResponse struct {
 	Providers []SearchResult `json:"providers"`
 	Total     int            `json:"total"`
+	HasMore   bool           `json:"has_more"` // Upstream reported a further page
+}
+
+// SearchOptions controls an upstream provider search.
+type SearchOptions struct {
+	Query string
+	Limit int    // Page size; defaults to 20
+	Page  int    // 1-based upstream page number; defaults to 1
+	Tier  string // "official", "partner", "community", or "" for any
 }
 
 // SearchProviders searches for providers in upstream registry.
-func (p *ProxyService) SearchProviders(query string, limit int) (*SearchResponse, error) {
+// Pagination and the tier filter are passed through to the v2 API as
+// page[number], page[size] and filter[tier].
+func (p *ProxyService) SearchProviders(ctx context.Context, opts SearchOptions) (*SearchResponse, error) {
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 20
 	}
+	page := opts.Page
+	if page <= 0 {
+		page = 1
+	}
 
 	result := &SearchResponse{
 		Providers: make([]SearchResult, 0),
@@ -572,23 +964,32 @@ func (p *ProxyService) SearchProviders(query string, limit int) (*SearchResponse
 	}
 	seen := make(map[string]bool)
 
-	// First, try to find official hashicorp provider with exact name match
-	hashicorpURL := fmt.Sprintf("%s/v2/providers?filter[namespace]=hashicorp&filter[name]=%s&page[size]=1",
-		p.upstreamURL, url.QueryEscape(query))
-	if hashicorpResults, err := p.fetchSearchResults(hashicorpURL); err == nil {
-		for _, r := range hashicorpResults {
-			key := r.Namespace + "/" + r.Name
-			if !seen[key] {
-				seen[key] = true
-				result.Providers = append(result.Providers, r)
+	tierFilter := ""
+	if opts.Tier != "" {
+		tierFilter = "&filter[tier]=" + url.QueryEscape(opts.Tier)
+	}
+
+	// First, try to find official hashicorp provider with exact name match.
+	// It is only promoted on the first page, and only when official providers are wanted.
+	if page == 1 && (opts.Tier == "" || opts.Tier == "official") {
+		hashicorpURL := fmt.Sprintf("%s/v2/providers?filter[namespace]=hashicorp&filter[name]=%s&page[size]=1",
+			p.upstreamURL, url.QueryEscape(opts.Query))
+		if hashicorpResults, _, err := p.fetchSearchResults(ctx, hashicorpURL); err == nil {
+			for _, r := range hashicorpResults {
+				key := r.Namespace + "/" + r.Name
+				if !seen[key] {
+					seen[key] = true
+					result.Providers = append(result.Providers, r)
+				}
 			}
 		}
 	}
 
 	// Then search by name across all namespaces
-	searchURL := fmt.Sprintf("%s/v2/providers?filter[name]=%s&page[size]=%d",
-		p.upstreamURL, url.QueryEscape(query), limit)
-	if searchResults, err := p.fetchSearchResults(searchURL); err == nil {
+	searchURL := fmt.Sprintf("%s/v2/providers?filter[name]=%s%s&page[size]=%d&page[number]=%d",
+		p.upstreamURL, url.QueryEscape(opts.Query), tierFilter, limit, page)
+	if searchResults, hasMore, err := p.fetchSearchResults(ctx, searchURL); err == nil {
+		result.HasMore = hasMore
 		for _, r := range searchResults {
 			key := r.Namespace + "/" + r.Name
 			if !seen[key] && len(result.Providers) < limit {
@@ -603,15 +1004,16 @@ func (p *ProxyService) SearchProviders(query string, limit int) (*SearchResponse
 }
 
 // fetchSearchResults fetches search results from a URL.
-func (p *ProxyService) fetchSearchResults(searchURL string) ([]SearchResult, error) {
-	resp, err := p.httpClient.Get(searchURL)
+// It also reports whether the upstream pagination metadata names a next page.
+func (p *ProxyService) fetchSearchResults(ctx context.Context, searchURL string) ([]SearchResult, bool, error) {
+	resp, err := p.getMetadata(ctx, searchURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search providers: %w", err)
+		return nil, false, fmt.Errorf("failed to search providers: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		return nil, false, &StatusError{Op: "upstream", StatusCode: resp.StatusCode}
 	}
 
 	// Parse the v2 API response
@@ -623,12 +1025,19 @@ func (p *ProxyService) fetchSearchResults(searchURL string) ([]SearchResult, err
 				Description string `json:"description"`
 				Downloads   int64  `json:"downloads"`
 				Source      string `json:"source"`
+				Tier        string `json:"tier"`
+				LogoURL     string `json:"logo-url"`
 			} `json:"attributes"`
 		} `json:"data"`
+		Meta struct {
+			Pagination struct {
+				NextPage *int `json:"next-page"`
+			} `json:"pagination"`
+		} `json:"meta"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&v2Response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeJSONResponse(resp.Body, &v2Response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	results := make([]SearchResult, 0, len(v2Response.Data))
@@ -639,8 +1048,10 @@ func (p *ProxyService) fetchSearchResults(searchURL string) ([]SearchResult, err
 			Description: item.Attributes.Description,
 			Downloads:   item.Attributes.Downloads,
 			Source:      item.Attributes.Source,
+			Tier:        item.Attributes.Tier,
+			LogoURL:     item.Attributes.LogoURL,
 		})
 	}
 
-	return results, nil
+	return results, v2Response.Meta.Pagination.NextPage != nil, nil
 }
@@ -2,10 +2,31 @@
 package proxy
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"       //nolint:staticcheck // see signature.go
+	"golang.org/x/crypto/openpgp/armor" //nolint:staticcheck // see signature.go
 )
 
 func TestSanitizePathComponent(t *testing.T) {
@@ -225,29 +246,712 @@ func TestProxyService_SetProxy(t *testing.T) {
 	})
 }
 
-func TestProxyService_DownloadAndStoreProvider(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "proxy-test-*")
+func TestProxyService_AuthenticatedProxy(t *testing.T) {
+	t.Run("http", func(t *testing.T) {
+		var mu sync.Mutex
+		var gotAuth string
+		proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			gotAuth = r.Header.Get("Proxy-Authorization")
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"versions": []}`))
+		}))
+		defer proxyServer.Close()
+		proxyHost := strings.TrimPrefix(proxyServer.URL, "http://")
+		ps := NewProxyService(t.TempDir(), "http://registry.invalid")
+
+		for _, tt := range []struct {
+			proxyURL, username, password, want string
+		}{
+			{"user:secret@" + proxyHost, "", "", "user:secret"},
+			{"http://user:secret@" + proxyHost, "other", "pw", "other:pw"},
+		} {
+			ps.SetAuthenticatedProxy(true, tt.proxyURL, "http", tt.username, tt.password)
+			if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err != nil {
+				t.Fatalf("GetProviderVersions() through %s error = %v", tt.proxyURL, err)
+			}
+			mu.Lock()
+			if want := "Basic " + base64.StdEncoding.EncodeToString([]byte(tt.want)); gotAuth != want {
+				t.Errorf("Proxy-Authorization = %q, want %q", gotAuth, want)
+			}
+			mu.Unlock()
+		}
+	})
+
+	t.Run("socks5", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = listener.Close() }()
+		creds := make(chan string, 1)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go serveSOCKS5Auth(conn, creds)
+			}
+		}()
+
+		ps := NewProxyService(t.TempDir(), "http://registry.invalid")
+		ps.SetAuthenticatedProxy(true, listener.Addr().String(), "socks5", "user", "secret")
+		// The fake proxy refuses to connect anywhere once authenticated.
+		if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err == nil {
+			t.Fatal("GetProviderVersions() through a refusing proxy succeeded")
+		}
+		select {
+		case got := <-creds:
+			if got != "user:secret" {
+				t.Errorf("SOCKS5 credentials = %q, want %q", got, "user:secret")
+			}
+		default:
+			t.Error("proxy was not authenticated with a username and password")
+		}
+	})
+
+	t.Run("invalid proxy URL", func(t *testing.T) {
+		var requests atomic.Int32
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(`{"versions": []}`))
+		}))
+		defer upstream.Close()
+
+		ps := NewProxyService(t.TempDir(), upstream.URL)
+		ps.SetProxy(true, "http://user:secret@[::1", "http")
+		_, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws")
+		if err == nil || requests.Load() != 0 {
+			t.Fatalf("GetProviderVersions() error = %v after %d direct requests, want a failure without any", err, requests.Load())
+		}
+		if strings.Contains(err.Error(), "secret") {
+			t.Errorf("error %q reveals the proxy password", err)
+		}
+	})
+}
+
+func TestProxyService_MalformedSOCKS5Proxy(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"versions": []}`))
+	}))
+	defer upstream.Close()
+
+	ps := NewProxyService(t.TempDir(), upstream.URL)
+	ps.SetProxy(true, "socks5://", "socks5")
+	if ps.ProxyError() == nil {
+		t.Fatal("ProxyError() = nil for a SOCKS5 URL without a host")
+	}
+	if err := CheckProxy("socks5://", "socks5"); err == nil || err.Error() != ps.ProxyError().Error() {
+		t.Errorf("CheckProxy() = %v, want %v", err, ps.ProxyError())
+	}
+	if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err == nil || requests.Load() != 0 {
+		t.Fatalf("GetProviderVersions() error = %v after %d direct requests, want a failure without any", err, requests.Load())
+	}
+
+	// Fixing or disabling the proxy clears the error.
+	ps.SetProxy(false, "socks5://", "socks5")
+	if err := ps.ProxyError(); err != nil {
+		t.Errorf("ProxyError() with the proxy disabled = %v", err)
+	}
+	if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err != nil {
+		t.Errorf("GetProviderVersions() without a proxy error = %v", err)
+	}
+}
+
+// serveSOCKS5Auth performs the server side of a SOCKS5 username/password
+// handshake on conn, reports the credentials on creds and then refuses the
+// connect request.
+func serveSOCKS5Auth(conn net.Conn, creds chan<- string) {
+	defer func() { _ = conn.Close() }()
+	buf := make([]byte, 256)
+	// Greeting: version, number of methods, methods.
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil || !bytes.Contains(buf[:buf[1]], []byte{0x02}) {
+		_, _ = conn.Write([]byte{0x05, 0xff})
+		return
+	}
+	_, _ = conn.Write([]byte{0x05, 0x02})
+	// Username/password: version, username length, username, password length, password.
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	user := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+		return
+	}
+	password := make([]byte, buf[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return
+	}
+	select {
+	case creds <- string(user) + ":" + string(password):
+	default:
+	}
+	_, _ = conn.Write([]byte{0x01, 0x00})
+	// Refuse the connect request with a general failure.
+	_, _ = conn.Read(buf)
+	_, _ = conn.Write([]byte{0x05, 0x01, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+}
+
+func TestProxyService_DownloadAndCacheProvider_PathTraversal(t *testing.T) {
+	ps := NewProxyService(t.TempDir(), "")
+
+	_, _, err := ps.DownloadAndCacheProvider(context.Background(), "../../../etc", "passwd", "1.0.0", "linux", "amd64")
+	if err == nil {
+		t.Fatal("expected error for path traversal attack, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected 'invalid' in error message, got: %v", err)
+	}
+}
+
+func TestProxyService_SearchProviders(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"data": [{"attributes": {"namespace": "acme", "name": "aws", "tier": "partner"}}],
+			"meta": {"pagination": {"next-page": 3}}
+		}`))
+	}))
+	defer server.Close()
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	result, err := ps.SearchProviders(context.Background(), SearchOptions{Query: "aws", Limit: 5, Page: 2, Tier: "partner"})
 	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
+		t.Fatalf("SearchProviders() error = %v", err)
 	}
-	defer func() { _ = os.RemoveAll(tempDir) }()
 
-	ps := NewProxyService(tempDir, "")
+	// Page 2 with a non-official tier skips the hashicorp exact-match lookup.
+	if len(queries) != 1 {
+		t.Fatalf("upstream requests = %d, want 1", len(queries))
+	}
+	q := queries[0]
+	if q.Get("filter[tier]") != "partner" || q.Get("page[number]") != "2" || q.Get("page[size]") != "5" {
+		t.Errorf("unexpected upstream query: %v", q)
+	}
+	if !result.HasMore {
+		t.Error("HasMore = false, want true")
+	}
+	if len(result.Providers) != 1 || result.Providers[0].Tier != "partner" {
+		t.Errorf("unexpected providers: %+v", result.Providers)
+	}
+}
+
+func TestProxyService_GetProviderPublishDates(t *testing.T) {
+	var gotPath, gotInclude string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotInclude = r.URL.Path, r.URL.Query().Get("include")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"data": {"type": "providers", "attributes": {"name": "aws"}},
+			"included": [
+				{"type": "provider-versions", "attributes": {"version": "5.0.0", "published-at": "2023-05-25T18:40:36Z"}},
+				{"type": "provider-versions", "attributes": {"version": "4.0.0", "published-at": "not-a-date"}},
+				{"type": "provider-platforms", "attributes": {"version": "3.0.0", "published-at": "2020-01-01T00:00:00Z"}}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	dates, err := ps.GetProviderPublishDates(context.Background(), "hashicorp", "aws")
+	if err != nil {
+		t.Fatalf("GetProviderPublishDates() error = %v", err)
+	}
+	if gotPath != "/v2/providers/hashicorp/aws" || gotInclude != "provider-versions" {
+		t.Errorf("requested %s?include=%s", gotPath, gotInclude)
+	}
+	want := time.Date(2023, 5, 25, 18, 40, 36, 0, time.UTC)
+	if len(dates) != 1 || !dates["5.0.0"].Equal(want) {
+		t.Errorf("dates = %v, want only 5.0.0 at %v", dates, want)
+	}
+}
+
+func TestProxyService_GetProviderDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/providers/cloudflare/cloudflare" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"type": "providers", "attributes": {
+			"name": "cloudflare", "description": "Cloudflare provider", "tier": "partner", "logo-url": "/images/providers/cloudflare.png"}}}`))
+	}))
+	defer server.Close()
 
-	t.Run("path traversal attack prevention", func(t *testing.T) {
-		_, _, err := ps.DownloadAndStoreProvider(
-			"../../../etc",
-			"passwd",
-			"1.0.0",
-			"linux",
-			"amd64",
-			"http://example.com/file.zip",
-		)
-		if err == nil {
-			t.Error("expected error for path traversal attack, got nil")
+	ps := NewProxyService(t.TempDir(), server.URL)
+	details, err := ps.GetProviderDetails(context.Background(), "cloudflare", "cloudflare")
+	if err != nil {
+		t.Fatalf("GetProviderDetails() error = %v", err)
+	}
+	if details.Description != "Cloudflare provider" || details.Tier != "partner" || details.LogoURL != "/images/providers/cloudflare.png" {
+		t.Errorf("details = %+v", details)
+	}
+
+	if _, err := ps.GetProviderDetails(context.Background(), "hashicorp", "missing"); err == nil {
+		t.Error("GetProviderDetails() for unknown provider: expected error")
+	}
+}
+
+func TestSetMetadataTimeout(t *testing.T) {
+	SetMetadataTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { SetMetadataTimeout(0) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Both responses take longer than the metadata timeout; only the
+		// binary, which streams, is allowed to.
+		if strings.HasSuffix(r.URL.Path, ".zip") {
+			_, _ = w.Write([]byte("provider-"))
+			w.(http.Flusher).Flush()
 		}
-		if !strings.Contains(err.Error(), "invalid") {
-			t.Errorf("expected 'invalid' in error message, got: %v", err)
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
 		}
-	})
+		if strings.HasSuffix(r.URL.Path, ".zip") {
+			_, _ = w.Write([]byte("binary"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"versions": []}`))
+	}))
+	defer server.Close()
+	ps := NewProxyService(t.TempDir(), server.URL)
+
+	if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetProviderVersions() error = %v, want a deadline exceeded", err)
+	}
+
+	resp, err := ps.getBinary(context.Background(), server.URL+"/terraform-provider-aws_5.0.0_linux_amd64.zip")
+	if err != nil {
+		t.Fatalf("getBinary() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "provider-binary" {
+		t.Errorf("binary = %q, %v; want the whole body", body, err)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	p := NewProxyService(t.TempDir(), "http://127.0.0.1:1")
+	_, networkErr := p.GetProviderVersions(context.Background(), "hashicorp", "aws")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"server error", &StatusError{Op: "upstream", StatusCode: http.StatusBadGateway}, true},
+		{"rate limited", &StatusError{Op: "download", StatusCode: http.StatusTooManyRequests}, true},
+		{"not found", &StatusError{Op: "upstream", StatusCode: http.StatusNotFound}, false},
+		{"wrapped server error", fmt.Errorf("failed to get versions: %w", &StatusError{Op: "upstream", StatusCode: 503}), true},
+		{"connection refused", networkErr, true},
+		{"plain error", errors.New("no matching platforms found"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyService_ResponseSizeLimit(t *testing.T) {
+	// A versions document padded well past the limit.
+	padding := strings.Repeat(`{"version": "1.0.0", "protocols": ["5.0"], "platforms": []},`, 200)
+	body := `{"versions": [` + padding + `{"version": "2.0.0"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	SetMaxResponseSize(1024)
+	defer SetMaxResponseSize(0)
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	_, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws")
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("GetProviderVersions() error = %v, want ErrResponseTooLarge", err)
+	}
+
+	SetMaxResponseSize(int64(len(body)))
+	versions, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws")
+	if err != nil {
+		t.Fatalf("GetProviderVersions() at the limit error = %v", err)
+	}
+	if len(versions.Versions) != 201 {
+		t.Errorf("len(Versions) = %d, want 201", len(versions.Versions))
+	}
+}
+
+func TestHashZipH1(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "provider.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	// Entries are written out of order; the hash sorts them by name.
+	for _, entry := range []struct{ name, body string }{
+		{"terraform-provider-test_v1.0.0", "binary-contents"},
+		{"README.md", "readme"},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(entry.body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	got, err := HashZipH1(zipPath)
+	if err != nil {
+		t.Fatalf("HashZipH1() error = %v", err)
+	}
+	if want := "h1:AdrjevDk9zsJsJuTMcmTtSye41a3pz7nmg5tPLECdMo="; got != want {
+		t.Errorf("HashZipH1() = %q, want %q", got, want)
+	}
+
+	if _, err := HashZipH1(filepath.Join(t.TempDir(), "missing.zip")); err == nil {
+		t.Error("HashZipH1() on a missing file: expected error")
+	}
+}
+
+func TestParseSHA256Sums(t *testing.T) {
+	amd64 := strings.Repeat("a", 64)
+	arm64 := strings.Repeat("b", 64)
+	data := amd64 + "  terraform-provider-aws_5.0.0_linux_amd64.zip\n" +
+		arm64 + " *terraform-provider-aws_5.0.0_darwin_arm64.zip\n" +
+		"not-a-hash  terraform-provider-aws_5.0.0_windows_amd64.zip\n\n"
+
+	got := parseSHA256Sums(data)
+	want := map[string]string{
+		"terraform-provider-aws_5.0.0_linux_amd64.zip":  amd64,
+		"terraform-provider-aws_5.0.0_darwin_arm64.zip": arm64,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseSHA256Sums() = %v, want %v", got, want)
+	}
+	for name, sum := range want {
+		if got[name] != sum {
+			t.Errorf("%s = %q, want %q", name, got[name], sum)
+		}
+	}
+}
+
+func TestProxyService_VerifySignatures(t *testing.T) {
+	newKey := func(t *testing.T) (*openpgp.Entity, string) {
+		t.Helper()
+		entity, err := openpgp.NewEntity("Test Signer", "", "signer@example.com", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := entity.Serialize(w); err != nil {
+			t.Fatal(err)
+		}
+		_ = w.Close()
+		return entity, buf.String()
+	}
+	signer, signerArmor := newKey(t)
+	_, otherArmor := newKey(t)
+
+	binary := []byte("provider-binary")
+	digest := sha256.Sum256(binary)
+	binarySum := hex.EncodeToString(digest[:])
+	const filename = "terraform-provider-aws_5.0.0_linux_amd64.zip"
+
+	tests := []struct {
+		name      string
+		listedSum string // checksum the signed SHA256SUMS lists for the binary
+		keyArmor  string // key advertised in the download info
+		wantErr   bool
+	}{
+		{"valid signature", binarySum, signerArmor, false},
+		{"untrusted key", binarySum, otherArmor, true},
+		{"sums mismatch", strings.Repeat("0", 64), signerArmor, true},
+		{"no signing keys", binarySum, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sums := []byte(tt.listedSum + "  " + filename + "\n")
+			var sig bytes.Buffer
+			if err := openpgp.DetachSign(&sig, signer, bytes.NewReader(sums), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+					info := DownloadInfo{
+						Filename:            filename,
+						DownloadURL:         server.URL + "/binary",
+						SHA256Sum:           binarySum,
+						SHA256SumsURL:       server.URL + "/SHA256SUMS",
+						SHA256SumsSignature: server.URL + "/SHA256SUMS.sig",
+					}
+					if tt.keyArmor != "" {
+						info.SigningKeys.GPGPublicKeys = []GPGPublicKey{{ASCIIArmor: tt.keyArmor}}
+					}
+					_ = json.NewEncoder(w).Encode(info)
+				case "/SHA256SUMS":
+					_, _ = w.Write(sums)
+				case "/SHA256SUMS.sig":
+					_, _ = w.Write(sig.Bytes())
+				case "/binary":
+					_, _ = w.Write(binary)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			storagePath := t.TempDir()
+			ps := NewProxyService(storagePath, server.URL)
+			ps.SetVerifySignatures(true)
+
+			filePath, _, err := ps.DownloadAndCacheProvider(context.Background(), "hashicorp", "aws", "5.0.0", "linux", "amd64")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("DownloadAndCacheProvider() error = %v", err)
+				}
+				if _, err := os.Stat(filePath); err != nil {
+					t.Errorf("cached file missing: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSignatureInvalid) {
+				t.Fatalf("DownloadAndCacheProvider() error = %v, want ErrSignatureInvalid", err)
+			}
+			_ = filepath.WalkDir(storagePath, func(path string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					t.Errorf("rejected download left %s behind", path)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestProxyService_DownloadAndCacheProvider_Concurrent(t *testing.T) {
+	binary := []byte("provider-binary")
+	digest := sha256.Sum256(binary)
+	release := make(chan struct{})
+	var downloads atomic.Int32
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/providers/hashicorp/aws/5.0.0/download/linux/amd64":
+			_ = json.NewEncoder(w).Encode(DownloadInfo{
+				Filename:    "terraform-provider-aws_5.0.0_linux_amd64.zip",
+				DownloadURL: server.URL + "/binary",
+				SHA256Sum:   hex.EncodeToString(digest[:]),
+			})
+		case "/binary":
+			downloads.Add(1)
+			<-release
+			_, _ = w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	storagePath := t.TempDir()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ps := NewProxyService(storagePath, server.URL)
+			filePath, _, err := ps.DownloadAndCacheProvider(context.Background(), "hashicorp", "aws", "5.0.0", "linux", "amd64")
+			if err == nil && filepath.Base(filePath) != "terraform-provider-aws_5.0.0_linux_amd64.zip" {
+				err = fmt.Errorf("filePath = %s", filePath)
+			}
+			errs <- err
+		}()
+	}
+	// Give every caller time to join the download before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("DownloadAndCacheProvider() error = %v", err)
+		}
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("binary downloaded %d times, want once", n)
+	}
+}
+
+func TestProxyService_DownloadAndCacheProvider_Cancel(t *testing.T) {
+	binary := []byte("provider-binary")
+	digest := sha256.Sum256(binary)
+	started := make(chan struct{}, 2)
+	release, aborted := make(chan struct{}), make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/download/linux/amd64"):
+			_ = json.NewEncoder(w).Encode(DownloadInfo{
+				Filename:    "terraform-provider-aws_5.0.0_linux_amd64.zip",
+				DownloadURL: server.URL + "/binary",
+				SHA256Sum:   hex.EncodeToString(digest[:]),
+			})
+		case r.URL.Path == "/binary":
+			started <- struct{}{}
+			select {
+			case <-release:
+				_, _ = w.Write(binary)
+			case <-r.Context().Done():
+				close(aborted)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ps := NewProxyService(t.TempDir(), server.URL)
+
+	// A caller giving up leaves the download running for the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, _, err := ps.DownloadAndCacheProvider(ctx, "hashicorp", "aws", "5.0.0", "linux", "amd64")
+		cancelled <- err
+	}()
+	<-started
+	waited := make(chan error, 1)
+	go func() {
+		_, _, err := ps.DownloadAndCacheProvider(context.Background(), "hashicorp", "aws", "5.0.0", "linux", "amd64")
+		waited <- err
+	}()
+	time.Sleep(20 * time.Millisecond) // let the second caller join
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-waited; err != nil {
+		t.Errorf("remaining caller error = %v", err)
+	}
+
+	// Once nobody waits for it, the upstream request is aborted.
+	ps = NewProxyService(t.TempDir(), server.URL)
+	release = make(chan struct{})
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		_, _, err := ps.DownloadAndCacheProvider(ctx, "hashicorp", "aws", "5.0.0", "linux", "amd64")
+		cancelled <- err
+	}()
+	<-started
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream download not aborted")
+	}
+	<-cancelled
+}
+
+func TestProxyService_StoreChecksumFiles(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			_, _ = w.Write([]byte("sums"))
+		case "/SHA256SUMS.sig":
+			_, _ = w.Write([]byte("signature"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	sumsPath, sigPath, err := ps.ChecksumFilePaths("hashicorp", "aws", "5.0.0")
+	if err != nil {
+		t.Fatalf("ChecksumFilePaths() error = %v", err)
+	}
+
+	// Providers that publish no signature still get their SHA256SUMS stored.
+	unsigned := &DownloadInfo{SHA256SumsURL: server.URL + "/SHA256SUMS"}
+	if err := ps.StoreChecksumFiles(context.Background(), "hashicorp", "aws", "5.0.0", unsigned); err != nil {
+		t.Fatalf("StoreChecksumFiles() unsigned error = %v", err)
+	}
+	if !ps.FileExists(sumsPath) || ps.FileExists(sigPath) {
+		t.Fatalf("stored sums = %v, signature = %v; want only the sums", ps.FileExists(sumsPath), ps.FileExists(sigPath))
+	}
+
+	signed := &DownloadInfo{SHA256SumsURL: server.URL + "/SHA256SUMS", SHA256SumsSignature: server.URL + "/SHA256SUMS.sig"}
+	fetches.Store(0)
+	if err := ps.StoreChecksumFiles(context.Background(), "hashicorp", "aws", "5.0.0", signed); err != nil {
+		t.Fatalf("StoreChecksumFiles() signed error = %v", err)
+	}
+	if data, _ := os.ReadFile(sigPath); string(data) != "signature" || fetches.Load() != 1 {
+		t.Errorf("signature = %q after %d fetches, want it fetched alone", data, fetches.Load())
+	}
+
+	// Download info without checksum files stores nothing.
+	if err := ps.StoreChecksumFiles(context.Background(), "hashicorp", "aws", "6.0.0", &DownloadInfo{}); err != nil {
+		t.Errorf("StoreChecksumFiles() without files error = %v", err)
+	}
+
+	ps.RemoveChecksumFiles("hashicorp", "aws", "5.0.0")
+	if ps.FileExists(sumsPath) || ps.FileExists(sigPath) {
+		t.Error("checksum files left after RemoveChecksumFiles")
+	}
+}
+func TestNewestVersions(t *testing.T) {
+	upstream := []Version{{Version: "1.9.0"}, {Version: "1.10.0"}, {Version: "0.1.0"}, {Version: "2.0.0"},
+		{Version: "2.1.0-beta1"}, {Version: "2.0.0-rc1"}}
+
+	tests := []struct {
+		name        string
+		limit       int
+		prereleases bool
+		want        []string
+	}{
+		{"unlimited", 0, false, []string{"2.0.0", "1.10.0", "1.9.0", "0.1.0"}},
+		{"capped", 2, false, []string{"2.0.0", "1.10.0"}},
+		{"limit above count", 10, false, []string{"2.0.0", "1.10.0", "1.9.0", "0.1.0"}},
+		{"with prereleases", 0, true, []string{"2.1.0-beta1", "2.0.0", "2.0.0-rc1", "1.10.0", "1.9.0", "0.1.0"}},
+		{"capped with prereleases", 2, true, []string{"2.1.0-beta1", "2.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewestVersions(upstream, tt.limit, tt.prereleases); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewestVersions(limit=%d, prereleases=%v) = %v, want %v", tt.limit, tt.prereleases, got, tt.want)
+			}
+		})
+	}
 }
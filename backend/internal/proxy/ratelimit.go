@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultUpstreamRateBurst is the burst allowed when an upstream rate limit is
+// set without one.
+const DefaultUpstreamRateBurst = 10
+
+// upstreamLimiter paces requests to upstream registries. It is shared by every
+// ProxyService, since handlers create one per request, and is unlimited until
+// SetUpstreamRateLimit is called.
+var upstreamLimiter = rate.NewLimiter(rate.Inf, DefaultUpstreamRateBurst)
+
+// SetUpstreamRateLimit limits requests to upstream registries to perSecond on
+// average, with bursts of up to burst requests. A non-positive perSecond
+// removes the limit; a non-positive burst selects DefaultUpstreamRateBurst.
+func SetUpstreamRateLimit(perSecond float64, burst int) {
+	if burst <= 0 {
+		burst = DefaultUpstreamRateBurst
+	}
+	limit := rate.Inf
+	if perSecond > 0 {
+		limit = rate.Limit(perSecond)
+	}
+	upstreamLimiter.SetBurst(burst)
+	upstreamLimiter.SetLimit(limit)
+}
+
+// Throttled upstream responses are retried up to upstreamRetries times. The
+// wait before a retry is the response's Retry-After, or upstreamRetryBackoff
+// doubled on each attempt without one. A Retry-After above maxRetryAfter is not
+// waited for; the throttled response is returned instead.
+const (
+	upstreamRetries = 3
+	maxRetryAfter   = 30 * time.Second
+)
+
+// upstreamRetryBackoff is a variable so that tests can shorten it.
+var upstreamRetryBackoff = time.Second
+
+// throttledTransport waits for upstreamLimiter before each request and retries
+// requests that upstream answers with 429 Too Many Requests or 503 Service
+// Unavailable.
+type throttledTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := upstreamRetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err := upstreamLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || attempt == upstreamRetries || req.Body != nil ||
+			(resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+			return resp, err
+		}
+
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = backoff
+			backoff *= 2
+		}
+		if wait > maxRetryAfter {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date, into the time to wait from now.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThrottledTransport_RetriesThrottledResponses(t *testing.T) {
+	upstreamRetryBackoff = time.Millisecond
+	t.Cleanup(func() { upstreamRetryBackoff = time.Second })
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/versions") {
+			http.NotFound(w, r)
+			return
+		}
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"versions": [{"version": "1.0.0"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	ps := NewProxyService(t.TempDir(), server.URL)
+	versions, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws")
+	if err != nil {
+		t.Fatalf("GetProviderVersions() error = %v", err)
+	}
+	if len(versions.Versions) != 1 || requests.Load() != 3 {
+		t.Errorf("versions = %+v after %d requests, want one version after 3", versions.Versions, requests.Load())
+	}
+
+	// Upstream asking for a longer wait than we are willing to hold a client
+	// for gets its throttled response passed on.
+	var banned atomic.Int32
+	bannedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/versions") {
+			banned.Add(1)
+		}
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer bannedServer.Close()
+	ps = NewProxyService(t.TempDir(), bannedServer.URL)
+	if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err == nil {
+		t.Fatal("GetProviderVersions() succeeded against a throttled upstream")
+	}
+	if banned.Load() != 1 {
+		t.Errorf("requests = %d, want 1", banned.Load())
+	}
+}
+
+func TestSetUpstreamRateLimit(t *testing.T) {
+	t.Cleanup(func() { SetUpstreamRateLimit(0, 0) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions": []}`))
+	}))
+	defer server.Close()
+	ps := NewProxyService(t.TempDir(), server.URL)
+
+	// Two requests fit in the burst; the third waits for a token at 20/s.
+	SetUpstreamRateLimit(20, 2)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := ps.GetProviderVersions(context.Background(), "hashicorp", "aws"); err != nil {
+			t.Fatalf("GetProviderVersions() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("three requests took %s, want the third delayed by the limit", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, true},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %v; want %s, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
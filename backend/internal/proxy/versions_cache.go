@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultVersionsCacheTTL is how long upstream version lists are reused by
+// default; see GetProviderVersionsCached.
+const DefaultVersionsCacheTTL = 5 * time.Minute
+
+type versionsCacheKey struct {
+	upstreamURL, namespace, name string
+}
+
+type cachedVersions struct {
+	versions *VersionsResponse
+	expires  time.Time
+}
+
+// The versions cache is shared by every ProxyService, since handlers create
+// one per request.
+var (
+	versionsCacheMu  sync.Mutex
+	versionsCache    = make(map[versionsCacheKey]cachedVersions)
+	versionsCacheTTL = DefaultVersionsCacheTTL
+)
+
+// SetVersionsCacheTTL sets how long GetProviderVersionsCached reuses an
+// upstream version list. A non-positive ttl disables the cache.
+func SetVersionsCacheTTL(ttl time.Duration) {
+	versionsCacheMu.Lock()
+	defer versionsCacheMu.Unlock()
+	versionsCacheTTL = max(ttl, 0)
+	if versionsCacheTTL == 0 {
+		clear(versionsCache)
+	}
+}
+
+// GetProviderVersionsCached is GetProviderVersions answered, while fresh, from
+// the last response for the same upstream and provider. With refresh set the
+// upstream is queried regardless and the cache updated. Failures are not
+// cached. The response is shared and must not be modified.
+func (p *ProxyService) GetProviderVersionsCached(ctx context.Context, namespace, name string, refresh bool) (*VersionsResponse, error) {
+	key := versionsCacheKey{upstreamURL: p.upstreamURL, namespace: namespace, name: name}
+	versionsCacheMu.Lock()
+	cached, ok := versionsCache[key]
+	ttl := versionsCacheTTL
+	versionsCacheMu.Unlock()
+	if ok && !refresh && time.Now().Before(cached.expires) {
+		return cached.versions, nil
+	}
+
+	versions, err := p.GetProviderVersions(ctx, namespace, name)
+	if err != nil || ttl == 0 {
+		return versions, err
+	}
+	versionsCacheMu.Lock()
+	versionsCache[key] = cachedVersions{versions: versions, expires: time.Now().Add(ttl)}
+	versionsCacheMu.Unlock()
+	return versions, nil
+}
+
+// InvalidateProviderVersions drops the cached version lists of a provider for
+// every upstream, so that the next lookup reflects a mirror or sync that just
+// completed.
+func InvalidateProviderVersions(namespace, name string) {
+	versionsCacheMu.Lock()
+	defer versionsCacheMu.Unlock()
+	for key := range versionsCache {
+		if key.namespace == namespace && key.name == name {
+			delete(versionsCache, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetProviderVersionsCached(t *testing.T) {
+	t.Cleanup(func() { SetVersionsCacheTTL(DefaultVersionsCacheTTL) })
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/versions") {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"versions": [{"version": "1.0.0"}]}`))
+	}))
+	defer server.Close()
+
+	lookup := func(refresh bool) {
+		t.Helper()
+		// A new service per lookup, as handlers create, still shares the cache.
+		ps := NewProxyService(t.TempDir(), server.URL)
+		versions, err := ps.GetProviderVersionsCached(context.Background(), "hashicorp", "aws", refresh)
+		if err != nil || len(versions.Versions) != 1 {
+			t.Fatalf("GetProviderVersionsCached() = %+v, %v", versions, err)
+		}
+	}
+	steps := []struct {
+		name    string
+		prepare func()
+		refresh bool
+		want    int32
+	}{
+		{"first lookup", func() {}, false, 1},
+		{"cached", func() {}, false, 1},
+		{"refresh", func() {}, true, 2},
+		{"cached after refresh", func() {}, false, 2},
+		{"invalidated", func() { InvalidateProviderVersions("hashicorp", "aws") }, false, 3},
+		{"other provider invalidated", func() { InvalidateProviderVersions("hashicorp", "google") }, false, 3},
+		{"cache disabled", func() { SetVersionsCacheTTL(0) }, false, 4},
+		{"still disabled", func() {}, false, 5},
+	}
+	for _, step := range steps {
+		step.prepare()
+		lookup(step.refresh)
+		if got := requests.Load(); got != step.want {
+			t.Errorf("%s: upstream requests = %d, want %d", step.name, got, step.want)
+		}
+	}
+}
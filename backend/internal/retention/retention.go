@@ -0,0 +1,75 @@
+// Package retention removes old provider versions to bound disk usage.
+package retention
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"gorm.io/gorm"
+)
+
+// PrunedVersion describes a provider version removed by Prune.
+type PrunedVersion struct {
+	ID         uint   `json:"id"`
+	Version    string `json:"version"`
+	FreedBytes int64  `json:"freed_bytes"`
+}
+
+// FileRemover deletes stored provider files by their FilePath, and the
+// checksum files of a version; *proxy.ProxyService implements it for the
+// configured storage backend.
+type FileRemover interface {
+	RemoveFile(filePath string) error
+	RemoveChecksumFiles(namespace, name, version string)
+}
+
+// DeleteVersion removes a provider version, its platform rows, signing keys
+// and files, and returns the bytes the platform files took up.
+func DeleteVersion(db *gorm.DB, files FileRemover, provider *models.Provider) int64 {
+	var platforms []models.ProviderPlatform
+	db.Where("provider_id = ?", provider.ID).Find(&platforms)
+
+	var freed int64
+	for _, p := range platforms {
+		if p.FilePath != "" {
+			_ = files.RemoveFile(p.FilePath) // #nosec G104 - best effort cleanup
+		}
+		freed += p.FileSize
+	}
+
+	files.RemoveChecksumFiles(provider.Namespace, provider.Name, provider.Version)
+
+	// Hard deletes: a soft-deleted provider row would still hold its
+	// namespace, name and version in the unique index, and mirroring the
+	// version again would fail.
+	db.Unscoped().Where("provider_id = ?", provider.ID).Delete(&models.ProviderPlatform{})
+	db.Where("provider_id = ?", provider.ID).Delete(&models.ProviderSigningKey{})
+	db.Unscoped().Delete(provider)
+	return freed
+}
+
+// Prune keeps the keep newest versions of a provider, ordered by semver
+// precedence, and deletes the rest with DeleteVersion. Versions that do not
+// parse as semver count as the oldest.
+func Prune(db *gorm.DB, files FileRemover, namespace, name string, keep int) ([]PrunedVersion, error) {
+	if keep < 1 {
+		return nil, fmt.Errorf("keep must be at least 1")
+	}
+
+	var providers []models.Provider
+	if err := db.Where("namespace = ? AND name = ?", namespace, name).Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(providers, func(i, j int) bool {
+		return semver.Compare(providers[i].Version, providers[j].Version) > 0
+	})
+
+	pruned := make([]PrunedVersion, 0)
+	for i := keep; i < len(providers); i++ {
+		freed := DeleteVersion(db, files, &providers[i])
+		pruned = append(pruned, PrunedVersion{ID: providers[i].ID, Version: providers[i].Version, FreedBytes: freed})
+	}
+	return pruned, nil
+}
@@ -0,0 +1,75 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Provider{}, &models.ProviderPlatform{}, &models.ProviderSigningKey{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// localFiles removes provider files from the local filesystem.
+type localFiles struct{}
+
+func (localFiles) RemoveFile(filePath string) error { return os.Remove(filePath) }
+
+func (localFiles) RemoveChecksumFiles(namespace, name, version string) {}
+
+func TestPrune(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	// String order would put 9.0.0 first and prune 10.0.0.
+	for _, v := range []string{"9.0.0", "10.0.0", "1.0.0", "10.0.0-beta1"} {
+		provider := models.Provider{Namespace: "hashicorp", Name: "aws", Version: v}
+		db.Create(&provider)
+		filePath := filepath.Join(dir, v+".zip")
+		if err := os.WriteFile(filePath, []byte("binary"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&models.ProviderPlatform{ProviderID: provider.ID, OS: "linux", Arch: "amd64", FilePath: filePath, FileSize: 6})
+	}
+	db.Create(&models.Provider{Namespace: "hashicorp", Name: "google", Version: "1.0.0"})
+
+	if _, err := Prune(db, localFiles{}, "hashicorp", "aws", 0); err == nil {
+		t.Error("Prune with keep 0 should fail")
+	}
+
+	pruned, err := Prune(db, localFiles{}, "hashicorp", "aws", 2)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if len(pruned) != 2 || pruned[0].Version != "9.0.0" || pruned[1].Version != "1.0.0" || pruned[0].FreedBytes != 6 {
+		t.Fatalf("pruned = %+v, want 9.0.0 and 1.0.0", pruned)
+	}
+
+	var kept []string
+	db.Model(&models.Provider{}).Where("name = ?", "aws").Order("id").Pluck("version", &kept)
+	if len(kept) != 2 || kept[0] != "10.0.0" || kept[1] != "10.0.0-beta1" {
+		t.Errorf("kept = %v, want [10.0.0 10.0.0-beta1]", kept)
+	}
+	for _, v := range []string{"9.0.0", "1.0.0"} {
+		if _, err := os.Stat(filepath.Join(dir, v+".zip")); !os.IsNotExist(err) {
+			t.Errorf("file of pruned version %s still exists", v)
+		}
+	}
+	var platforms, others int64
+	db.Model(&models.ProviderPlatform{}).Count(&platforms)
+	db.Model(&models.Provider{}).Where("name = ?", "google").Count(&others)
+	if platforms != 2 || others != 1 {
+		t.Errorf("platforms = %d, other providers = %d; want 2, 1", platforms, others)
+	}
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
 	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/retention"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/semver"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/webhook"
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 )
@@ -20,28 +23,156 @@ import (
 type Scheduler struct {
 	db          *gorm.DB
 	storagePath string
+	retry       RetryPolicy
 	cron        *cron.Cron
 	jobs        map[uint]cron.EntryID
-	mu          sync.RWMutex
+	running     map[uint]bool // schedules with a sync in progress
+	mu          sync.RWMutex  // guards jobs and running
 	ctx         context.Context
 	cancel      context.CancelFunc
 }
 
+// RetryPolicy controls how a failing sync is retried.
+type RetryPolicy struct {
+	// Retries is the number of extra attempts made within a run after a transient error.
+	Retries int
+	// Backoff is the delay before the first retry; it doubles for each further attempt.
+	Backoff time.Duration
+	// FailureRetryDelay schedules one follow-up run this long after a failed run,
+	// independent of the cron expression. Zero disables the follow-up.
+	FailureRetryDelay time.Duration
+}
+
+// maxRunsPerSchedule is how many SyncRun records are kept per schedule; older
+// ones are deleted as new runs are recorded.
+const maxRunsPerSchedule = 100
+
+// runStats counts what a sync attempt added to the cache.
+type runStats struct {
+	platforms int
+	bytes     int64
+}
+
+func (r *runStats) add(o runStats) {
+	r.platforms += o.platforms
+	r.bytes += o.bytes
+}
+
+// cronParser parses the cron expressions of sync schedules: five fields
+// (minute, hour, day of month, month, day of week) or a descriptor such as
+// @daily or @every 6h. There is no seconds field.
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// StatusInvalid is the LastStatus of a schedule whose cron expression cannot
+// be parsed; LastError then holds the parse error.
+const StatusInvalid = "invalid"
+
+// StatusRunning is the LastStatus of a schedule while a sync of it runs.
+const StatusRunning = "running"
+
+// ParseCron parses the cron expression of a sync schedule. The API validates
+// expressions and stores NextRunAt with it and the scheduler runs them with it,
+// so both agree on which expressions are valid and when a schedule fires.
+func ParseCron(expr string) (cron.Schedule, error) {
+	return cronParser.Parse(expr)
+}
+
+// NextRun returns when the cron expression expr next fires after from.
+func NextRun(expr string, from time.Time) (time.Time, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(from), nil
+}
+
+// AnyName is the MirrorConfig name that applies to every provider of a namespace.
+const AnyName = "*"
+
+// UpstreamFor returns the registry base URL namespace/name is mirrored from:
+// its MirrorConfig, else the MirrorConfig of its whole namespace, else
+// Settings.DefaultUpstreamURL. It returns "" when that is registry.terraform.io
+// or unset, meaning the caller's default upstream. Scheduled syncs and
+// the API's mirror operations both resolve the upstream with it.
+func UpstreamFor(db *gorm.DB, namespace, name string) string {
+	var configs []models.MirrorConfig
+	db.Where("namespace = ? AND name IN ?", namespace, []string{name, AnyName}).Find(&configs)
+	upstream := ""
+	for _, cfg := range configs {
+		if cfg.UpstreamURL != "" && (cfg.Name == name || upstream == "") {
+			upstream = cfg.UpstreamURL
+		}
+	}
+	if upstream != "" {
+		return upstream
+	}
+	var settings models.Settings
+	if db.First(&settings).Error == nil && settings.DefaultUpstreamURL != proxy.UpstreamRegistry {
+		return settings.DefaultUpstreamURL
+	}
+	return ""
+}
+
+// RecordSigningKeys replaces the signing keys stored for a provider version
+// with the ones upstream lists in its download info. An empty list keeps the
+// stored keys. Scheduled syncs and the API's mirror operations both record
+// keys with it.
+func RecordSigningKeys(db *gorm.DB, providerID uint, keys proxy.SigningKeys) {
+	if len(keys.GPGPublicKeys) == 0 {
+		return
+	}
+	rows := make([]models.ProviderSigningKey, 0, len(keys.GPGPublicKeys))
+	for _, k := range keys.GPGPublicKeys {
+		rows = append(rows, models.ProviderSigningKey{
+			ProviderID:     providerID,
+			KeyID:          k.KeyID,
+			ASCIIArmor:     k.ASCIIArmor,
+			TrustSignature: k.TrustSignature,
+			Source:         k.Source,
+			SourceURL:      k.SourceURL,
+		})
+	}
+	// The keys are advisory; download info is served without them on failure.
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", providerID).Delete(&models.ProviderSigningKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// NextRunOf reports when the running cron next fires a schedule, and false if
+// the schedule has no job.
+func (s *Scheduler) NextRunOf(scheduleID uint) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entryID, exists := s.jobs[scheduleID]
+	if !exists {
+		return time.Time{}, false
+	}
+	return s.cron.Entry(entryID).Next, true
+}
+
 // New creates a new Scheduler.
-func New(db *gorm.DB, storagePath string) *Scheduler {
+func New(db *gorm.DB, storagePath string, retry RetryPolicy) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		db:          db,
 		storagePath: storagePath,
-		cron:        cron.New(),
+		retry:       retry,
+		cron:        cron.New(cron.WithParser(cronParser)),
 		jobs:        make(map[uint]cron.EntryID),
+		running:     make(map[uint]bool),
 		ctx:         ctx,
 		cancel:      cancel,
 	}
 }
 
-// Start begins the scheduler.
+// Start begins the scheduler. Schedules a previous process left running were
+// interrupted by its exit and are marked failed.
 func (s *Scheduler) Start() error {
+	s.db.Model(&models.SyncSchedule{}).Where("last_status = ?", StatusRunning).
+		Updates(map[string]interface{}{"last_status": "failed", "last_error": "interrupted by a restart"})
 	if err := s.loadSchedules(); err != nil {
 		return err
 	}
@@ -66,12 +197,28 @@ func (s *Scheduler) loadSchedules() error {
 	}
 	for _, schedule := range schedules {
 		if err := s.addJob(schedule); err != nil {
-			log.Printf("Failed to add schedule %d: %v", schedule.ID, err)
+			s.markInvalid(schedule, err)
 		}
 	}
 	return nil
 }
 
+// markInvalid records on a schedule that its cron expression does not parse,
+// so a schedule that never runs is visible through the API. The error is
+// logged and saved once, not on every refresh.
+func (s *Scheduler) markInvalid(schedule models.SyncSchedule, err error) {
+	message := fmt.Sprintf("invalid cron expression %q: %v", schedule.CronExpr, err)
+	if schedule.LastStatus == StatusInvalid && schedule.LastError == message {
+		return
+	}
+	log.Printf("Failed to add schedule %d: %s", schedule.ID, logsafe.Clean(message))
+	s.db.Model(&models.SyncSchedule{}).Where("id = ?", schedule.ID).Updates(map[string]interface{}{
+		"last_status": StatusInvalid,
+		"last_error":  message,
+		"next_run_at": nil,
+	})
+}
+
 func (s *Scheduler) addJob(schedule models.SyncSchedule) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -80,18 +227,24 @@ func (s *Scheduler) addJob(schedule models.SyncSchedule) error {
 		s.cron.Remove(entryID)
 	}
 
-	scheduleID := schedule.ID
-	entryID, err := s.cron.AddFunc(schedule.CronExpr, func() {
-		s.runSync(scheduleID)
-	})
+	cronSchedule, err := ParseCron(schedule.CronExpr)
 	if err != nil {
 		return err
 	}
+	scheduleID := schedule.ID
+	entryID := s.cron.Schedule(cronSchedule, cron.FuncJob(func() {
+		s.runSync(scheduleID)
+	}))
 
 	s.jobs[schedule.ID] = entryID
-	entry := s.cron.Entry(entryID)
-	nextRun := entry.Next
-	s.db.Model(&models.SyncSchedule{}).Where("id = ?", schedule.ID).Update("next_run_at", nextRun)
+	// Entry.Next stays zero until the cron is started, so compute it the same
+	// way the cron will rather than reading it back.
+	updates := map[string]interface{}{"next_run_at": cronSchedule.Next(time.Now())}
+	if schedule.LastStatus == StatusInvalid {
+		updates["last_status"] = ""
+		updates["last_error"] = ""
+	}
+	s.db.Model(&models.SyncSchedule{}).Where("id = ?", schedule.ID).Updates(updates)
 	return nil
 }
 
@@ -105,23 +258,91 @@ func (s *Scheduler) removeJob(scheduleID uint) {
 	}
 }
 
+// beginRun marks a schedule as syncing and reports false if it already was.
+func (s *Scheduler) beginRun(scheduleID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[scheduleID] {
+		return false
+	}
+	s.running[scheduleID] = true
+	return true
+}
+
+// endRun clears the mark set by beginRun.
+func (s *Scheduler) endRun(scheduleID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, scheduleID)
+}
+
 func (s *Scheduler) runSync(scheduleID uint) {
+	s.sync(scheduleID, false)
+}
+
+// sync runs a schedule, retrying transient failures according to the retry policy.
+// followUp marks the extra run scheduled after a failure; it does not schedule another.
+// A schedule that is already syncing, because a run outlasted the cron interval
+// or was triggered manually meanwhile, is not run a second time concurrently.
+func (s *Scheduler) sync(scheduleID uint, followUp bool) {
+	if !s.beginRun(scheduleID) {
+		log.Printf("Sync of schedule %d is still running; skipping this trigger", scheduleID)
+		return
+	}
+	defer s.endRun(scheduleID)
+
 	var schedule models.SyncSchedule
 	if err := s.db.First(&schedule, scheduleID).Error; err != nil {
 		log.Printf("Schedule %d not found: %v", scheduleID, err)
 		return
 	}
+	if followUp && !schedule.Enabled {
+		return
+	}
+
+	var settings models.Settings
+	if err := s.db.First(&settings).Error; err == nil && settings.Frozen {
+		log.Printf("Registry is frozen; skipping sync for %s/%s", logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name))
+		return
+	}
 
-	log.Printf("Running sync for %s/%s", logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name))
+	log.Printf("Running sync for %s/%s (schedule %d, os=%s, arch=%s)", logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name), scheduleID, logsafe.Clean(schedule.SyncOS), logsafe.Clean(schedule.SyncArch))
 
 	now := time.Now()
 	schedule.LastRunAt = &now
-	schedule.LastStatus = "running"
+	schedule.LastStatus = StatusRunning
 	s.db.Save(&schedule)
 
-	proxyService := proxy.NewProxyService(s.storagePath, "")
-	err := s.mirrorProvider(proxyService, schedule.Namespace, schedule.Name, "", schedule.SyncOS, schedule.SyncArch)
+	proxyService := proxy.NewProxyService(s.storagePath, UpstreamFor(s.db, schedule.Namespace, schedule.Name))
+	proxyService.SetAuthenticatedProxy(settings.ProxyEnabled, settings.ProxyURL, settings.ProxyType, settings.ProxyUsername, settings.ProxyPassword)
+	proxyService.SetVerifySignatures(settings.VerifySignatures)
+	var total runStats
+	err := withRetries(s.ctx, s.retry, func(attempt int) error {
+		run := models.SyncRun{ScheduleID: scheduleID, Attempt: attempt, Retry: followUp, StartedAt: time.Now()}
+		var stats runStats
+		err := proxyService.ProxyError()
+		if err != nil {
+			err = fmt.Errorf("upstream proxy cannot be used: %w", err)
+		} else if schedule.Mode == models.SyncModeLatestN {
+			stats, err = s.warmProvider(proxyService, schedule)
+		} else {
+			stats, err = s.mirrorProvider(proxyService, schedule.Namespace, schedule.Name, "", schedule.SyncOS, schedule.SyncArch, schedule.PublishedAfter, false)
+		}
+		run.FinishedAt = time.Now()
+		run.Status = "success"
+		run.PlatformsDownloaded = stats.platforms
+		run.Bytes = stats.bytes
+		total.add(stats)
+		if err != nil {
+			run.Status = "failed"
+			run.Error = err.Error()
+			log.Printf("Sync attempt %d failed for %s/%s: %s", attempt, logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name), logsafe.CleanErr(err))
+		}
+		s.recordRun(&run)
+		return err
+	})
 	finishTime := time.Now()
+	proxy.InvalidateProviderVersions(schedule.Namespace, schedule.Name)
 
 	if err != nil {
 		schedule.LastStatus = "failed"
@@ -131,6 +352,9 @@ func (s *Scheduler) runSync(scheduleID uint) {
 		schedule.LastStatus = "success"
 		schedule.LastError = ""
 		log.Printf("Sync completed for %s/%s", logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name))
+		if settings.RetainVersions > 0 {
+			s.pruneVersions(schedule.Namespace, schedule.Name, settings.RetainVersions)
+		}
 	}
 
 	schedule.LastRunAt = &finishTime
@@ -142,39 +366,244 @@ func (s *Scheduler) runSync(scheduleID uint) {
 	}
 	s.mu.RUnlock()
 
+	if err != nil && !followUp && s.retry.FailureRetryDelay > 0 {
+		retryAt := finishTime.Add(s.retry.FailureRetryDelay)
+		if schedule.NextRunAt == nil || retryAt.Before(*schedule.NextRunAt) {
+			schedule.NextRunAt = &retryAt
+		}
+		s.scheduleFollowUp(scheduleID)
+	}
+
 	s.db.Save(&schedule)
+
+	event := webhook.EventSyncSucceeded
+	if err != nil {
+		event = webhook.EventSyncFailed
+	}
+	webhook.Dispatch(s.db, event, SyncEvent{
+		ScheduleID:          scheduleID,
+		Namespace:           schedule.Namespace,
+		Name:                schedule.Name,
+		Status:              schedule.LastStatus,
+		Error:               schedule.LastError,
+		Retry:               followUp,
+		PlatformsDownloaded: total.platforms,
+		Bytes:               total.bytes,
+		StartedAt:           now,
+		FinishedAt:          finishTime,
+	})
+}
+
+// SyncEvent is the data of the sync webhook events sent after each run. The
+// counts cover every attempt of the run.
+type SyncEvent struct {
+	ScheduleID          uint      `json:"schedule_id"`
+	Namespace           string    `json:"namespace"`
+	Name                string    `json:"name"`
+	Status              string    `json:"status"` // success, failed
+	Error               string    `json:"error,omitempty"`
+	Retry               bool      `json:"retry"` // The follow-up run scheduled after a failure
+	PlatformsDownloaded int       `json:"platforms_downloaded"`
+	Bytes               int64     `json:"bytes"`
+	StartedAt           time.Time `json:"started_at"`
+	FinishedAt          time.Time `json:"finished_at"`
+}
+
+// recordRun saves a sync attempt and deletes the schedule's runs beyond the
+// newest maxRunsPerSchedule.
+func (s *Scheduler) recordRun(run *models.SyncRun) {
+	if err := s.db.Create(run).Error; err != nil {
+		log.Printf("Failed to record sync run for schedule %d: %v", run.ScheduleID, err)
+		return
+	}
+	keep := s.db.Model(&models.SyncRun{}).Select("id").Where("schedule_id = ?", run.ScheduleID).
+		Order("id DESC").Limit(maxRunsPerSchedule)
+	s.db.Where("schedule_id = ? AND id NOT IN (?)", run.ScheduleID, keep).Delete(&models.SyncRun{})
 }
 
-func (s *Scheduler) mirrorProvider(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string) error {
-	platforms, resolvedVersion, err := s.getPlatformsToMirror(proxyService, namespace, name, version, osType, arch)
+// pruneVersions enforces the global retention policy on a synced provider.
+func (s *Scheduler) pruneVersions(namespace, name string, keep int) {
+	pruned, err := retention.Prune(s.db, proxy.NewProxyService(s.storagePath, ""), namespace, name, keep)
 	if err != nil {
-		return err
+		log.Printf("Failed to prune %s/%s: %s", logsafe.Clean(namespace), logsafe.Clean(name), logsafe.CleanErr(err))
+		return
+	}
+	if len(pruned) > 0 {
+		log.Printf("Pruned %d old versions of %s/%s, keeping the newest %d", len(pruned), logsafe.Clean(namespace), logsafe.Clean(name), keep)
+	}
+}
+
+// withRetries calls fn until it succeeds, fails with a non-transient error, or
+// the policy's retries are used up, backing off exponentially between attempts.
+// It returns the last error, or early if ctx is cancelled while waiting.
+func withRetries(ctx context.Context, policy RetryPolicy, fn func(attempt int) error) error {
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || attempt > policy.Retries || !proxy.IsTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// scheduleFollowUp runs a schedule once more after FailureRetryDelay unless the
+// scheduler is stopped first.
+func (s *Scheduler) scheduleFollowUp(scheduleID uint) {
+	go func() {
+		select {
+		case <-s.ctx.Done():
+		case <-time.After(s.retry.FailureRetryDelay):
+			s.sync(scheduleID, true)
+		}
+	}()
+}
+
+// warmProvider keeps the newest VersionCount upstream releases of a scheduled
+// provider cached, so new releases are downloaded before anyone asks for them.
+// Versions whose matching platforms are all cached make no downloads.
+// Prereleases are not counted.
+func (s *Scheduler) warmProvider(proxyService *proxy.ProxyService, schedule models.SyncSchedule) (runStats, error) {
+	var stats runStats
+	versions, err := proxyService.GetProviderVersions(s.ctx, schedule.Namespace, schedule.Name)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get versions: %w", err)
+	}
+	platforms := make(map[string][]proxy.Platform)
+	var releases []string
+	for _, v := range versions.Versions {
+		if parsed, ok := semver.Parse(v.Version); ok && parsed.Prerelease == "" {
+			releases = append(releases, v.Version)
+			platforms[v.Version] = v.Platforms
+		}
+	}
+	semver.SortDescending(releases)
+	if len(releases) > schedule.VersionCount {
+		releases = releases[:schedule.VersionCount]
 	}
 
+	var lastErr error
+	for _, version := range releases {
+		if s.versionCached(schedule, version, platforms[version]) {
+			continue
+		}
+		versionStats, err := s.mirrorProvider(proxyService, schedule.Namespace, schedule.Name, version, schedule.SyncOS, schedule.SyncArch, schedule.PublishedAfter, true)
+		stats.add(versionStats)
+		if err != nil {
+			log.Printf("Failed to warm %s/%s %s: %s", logsafe.Clean(schedule.Namespace), logsafe.Clean(schedule.Name), logsafe.Clean(version), logsafe.CleanErr(err))
+			lastErr = err
+		}
+	}
+	return stats, lastErr
+}
+
+// versionCached reports whether every platform of version that the schedule
+// syncs is already stored.
+func (s *Scheduler) versionCached(schedule models.SyncSchedule, version string, platforms []proxy.Platform) bool {
+	for _, p := range platforms {
+		if (schedule.SyncOS == "all" || schedule.SyncOS == p.OS) && (schedule.SyncArch == "all" || schedule.SyncArch == p.Arch) &&
+			!s.platformExists(schedule.Namespace, schedule.Name, version, p.OS, p.Arch) {
+			return false
+		}
+	}
+	return true
+}
+
+// mirrorProvider downloads the platforms of a provider version. A version published
+// before publishedAfter is skipped without error; there is nothing new to sync.
+// With onlyMissing set, platforms that are already stored are not downloaded again.
+func (s *Scheduler) mirrorProvider(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string, publishedAfter *time.Time, onlyMissing bool) (runStats, error) {
+	var stats runStats
+	platforms, resolvedVersion, protocols, err := s.getPlatformsToMirror(proxyService, namespace, name, version, osType, arch)
+	if err != nil {
+		return stats, err
+	}
+
+	var published time.Time
+	dates, err := proxyService.GetProviderPublishDates(s.ctx, namespace, name)
+	if err == nil {
+		published = dates[resolvedVersion]
+	}
+	if publishedAfter != nil {
+		if err != nil {
+			return stats, fmt.Errorf("failed to get publish dates: %w", err)
+		}
+		if published.IsZero() || published.Before(*publishedAfter) {
+			log.Printf("Skipping %s/%s %s: not published after %s", logsafe.Clean(namespace), logsafe.Clean(name),
+				logsafe.Clean(resolvedVersion), publishedAfter.Format(time.RFC3339))
+			return stats, nil
+		}
+	}
+
+	// Immutable versions keep their published platforms; only new ones are fetched.
+	var settings models.Settings
+	immutable := s.db.First(&settings).Error == nil && settings.ImmutableVersions
 	for _, platform := range platforms {
-		s.downloadAndSavePlatform(proxyService, namespace, name, resolvedVersion, platform.OS, platform.Arch)
+		if (immutable || onlyMissing) && s.platformExists(namespace, name, resolvedVersion, platform.OS, platform.Arch) {
+			continue
+		}
+		if size, added := s.downloadAndSavePlatform(proxyService, namespace, name, resolvedVersion, platform.OS, platform.Arch, protocols, published); added {
+			stats.add(runStats{platforms: 1, bytes: size})
+		}
 	}
 
-	return nil
+	// Keep the upstream description, tier and logo current for local search results.
+	RecordUpstreamDetails(s.ctx, s.db, proxyService, namespace, name)
+
+	return stats, nil
 }
 
-// getPlatformsToMirror fetches version info and returns matching platforms.
-func (s *Scheduler) getPlatformsToMirror(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string) ([]struct{ OS, Arch string }, string, error) {
-	versions, err := proxyService.GetProviderVersions(namespace, name)
+// RecordUpstreamDetails stores the upstream tier and logo on every version of a
+// provider. Failures are ignored; local search then falls back to guessing the tier.
+// The upstream description is provider-level, so it is shared by every mirrored
+// version and fills in uploaded versions that have none of their own. Scheduled
+// syncs and the API's mirror operations both record details with it.
+func RecordUpstreamDetails(ctx context.Context, db *gorm.DB, proxyService *proxy.ProxyService, namespace, name string) {
+	details, err := proxyService.GetProviderDetails(ctx, namespace, name)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get versions: %w", err)
+		return
+	}
+	db.Model(&models.Provider{}).Where("namespace = ? AND name = ?", namespace, name).
+		Updates(models.Provider{Tier: details.Tier, LogoURL: details.LogoURL})
+	if details.Description != "" {
+		db.Model(&models.Provider{}).
+			Where("namespace = ? AND name = ? AND (source_type = ? OR description = '')", namespace, name, models.SourceMirror).
+			Update("description", details.Description)
+	}
+}
+
+// getPlatformsToMirror fetches version info and returns matching platforms,
+// the resolved version and the plugin protocols upstream lists for it. Without a
+// version the newest one by semver is taken, skipping prereleases unless enabled.
+func (s *Scheduler) getPlatformsToMirror(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string) ([]struct{ OS, Arch string }, string, []string, error) {
+	versions, err := proxyService.GetProviderVersions(s.ctx, namespace, name)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to get versions: %w", err)
 	}
 	if len(versions.Versions) == 0 {
-		return nil, "", fmt.Errorf("no versions available")
+		return nil, "", nil, fmt.Errorf("no versions available")
 	}
 
 	if version == "" {
-		version = versions.Versions[0].Version
+		var settings models.Settings
+		prereleases := s.db.First(&settings).Error == nil && settings.IncludePrereleases
+		newest := proxy.NewestVersions(versions.Versions, 1, prereleases)
+		if len(newest) == 0 {
+			return nil, "", nil, fmt.Errorf("no stable versions available")
+		}
+		version = newest[0]
 	}
 
 	var platforms []struct{ OS, Arch string }
+	var protocols []string
 	for _, v := range versions.Versions {
 		if v.Version == version {
+			protocols = v.Protocols
 			for _, p := range v.Platforms {
 				if (osType == "all" || osType == p.OS) && (arch == "all" || arch == p.Arch) {
 					platforms = append(platforms, struct{ OS, Arch string }{p.OS, p.Arch})
@@ -185,45 +614,73 @@ func (s *Scheduler) getPlatformsToMirror(proxyService *proxy.ProxyService, names
 	}
 
 	if len(platforms) == 0 {
-		return nil, "", fmt.Errorf("no matching platforms found")
+		return nil, "", nil, fmt.Errorf("no matching platforms found")
 	}
 
-	return platforms, version, nil
+	return platforms, version, protocols, nil
+}
+
+// platformExists reports whether a platform of a provider version is already stored.
+func (s *Scheduler) platformExists(namespace, name, version, osType, arch string) bool {
+	var count int64
+	s.db.Model(&models.ProviderPlatform{}).
+		Joins("JOIN providers ON providers.id = provider_platforms.provider_id AND providers.deleted_at IS NULL").
+		Where("providers.namespace = ? AND providers.name = ? AND providers.version = ? AND provider_platforms.os = ? AND provider_platforms.arch = ?",
+			namespace, name, version, osType, arch).
+		Count(&count)
+	return count > 0
 }
 
 // downloadAndSavePlatform downloads a platform and saves it to the database.
-func (s *Scheduler) downloadAndSavePlatform(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string) {
-	filePath, sha256sum, err := proxyService.DownloadAndCacheProvider(namespace, name, version, osType, arch)
+// protocols and published are the upstream plugin protocols and publish time
+// recorded on the provider; the publish time only on a newly created one.
+// It returns the file size and whether the platform was newly recorded.
+func (s *Scheduler) downloadAndSavePlatform(proxyService *proxy.ProxyService, namespace, name, version, osType, arch string, protocols []string, published time.Time) (int64, bool) {
+	filePath, sha256sum, info, err := proxyService.DownloadAndCacheProviderInfo(s.ctx, namespace, name, version, osType, arch)
 	if err != nil {
 		log.Printf("Failed to download %s_%s: %s", logsafe.Clean(osType), logsafe.Clean(arch), logsafe.CleanErr(err))
-		return
+		return 0, false
 	}
 
 	var provider models.Provider
 	result := s.db.Where("namespace = ? AND name = ? AND version = ?", namespace, name, version).First(&provider)
 	if result.Error == gorm.ErrRecordNotFound {
+		if published.IsZero() {
+			published = time.Now()
+		}
 		provider = models.Provider{
 			Namespace:  namespace,
 			Name:       name,
 			Version:    version,
 			SourceType: models.SourceMirror,
-			Protocols:  `["5.0"]`,
+			Published:  published,
+			Protocols:  proxy.EncodeProtocols(protocols),
 		}
-		s.db.Create(&provider)
+		if s.db.Create(&provider).Error == nil {
+			RecordSigningKeys(s.db, provider.ID, info.SigningKeys)
+		}
+	} else if encoded := proxy.EncodeProtocols(protocols); len(protocols) > 0 && provider.Protocols != encoded {
+		s.db.Model(&provider).UpdateColumn("protocols", encoded)
 	}
 
 	var existingPlatform models.ProviderPlatform
-	if err := s.db.Where("provider_id = ? AND os = ? AND arch = ?", provider.ID, osType, arch).First(&existingPlatform).Error; err != nil {
-		platformModel := models.ProviderPlatform{
-			ProviderID: provider.ID,
-			OS:         osType,
-			Arch:       arch,
-			Filename:   filepath.Base(filePath),
-			FilePath:   filePath,
-			SHA256Sum:  sha256sum,
-		}
-		s.db.Create(&platformModel)
+	if err := s.db.Where("provider_id = ? AND os = ? AND arch = ?", provider.ID, osType, arch).First(&existingPlatform).Error; err == nil {
+		return 0, false
 	}
+	size, _ := proxyService.FileSize(filePath)
+	platformModel := models.ProviderPlatform{
+		ProviderID: provider.ID,
+		OS:         osType,
+		Arch:       arch,
+		Filename:   filepath.Base(filePath),
+		FilePath:   filePath,
+		SHA256Sum:  sha256sum,
+		FileSize:   size,
+	}
+	if err := s.db.Create(&platformModel).Error; err != nil {
+		return 0, false
+	}
+	return size, true
 }
 
 func (s *Scheduler) watchForChanges() {
@@ -240,6 +697,12 @@ func (s *Scheduler) watchForChanges() {
 	}
 }
 
+// Reload applies schedule changes from the database right away instead of
+// waiting for the next periodic refresh.
+func (s *Scheduler) Reload() {
+	s.refreshSchedules()
+}
+
 func (s *Scheduler) refreshSchedules() {
 	var schedules []models.SyncSchedule
 	if err := s.db.Find(&schedules).Error; err != nil {
@@ -256,7 +719,7 @@ func (s *Scheduler) refreshSchedules() {
 			s.mu.RUnlock()
 			if !exists {
 				if err := s.addJob(schedule); err != nil {
-					log.Printf("Failed to add schedule %d: %v", schedule.ID, err)
+					s.markInvalid(schedule, err)
 				}
 			}
 		} else {
@@ -1,16 +1,29 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/proxy"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/webhook"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestSchedulerNew(t *testing.T) {
 	t.Run("create new scheduler with nil db", func(t *testing.T) {
 		tempDir := t.TempDir()
 
-		scheduler := New(nil, tempDir)
+		scheduler := New(nil, tempDir, RetryPolicy{})
 		if scheduler == nil {
 			t.Fatal("New returned nil scheduler")
 		}
@@ -261,7 +274,7 @@ func TestStoragePathCreation(t *testing.T) {
 			t.Fatalf("failed to create test directory: %v", err)
 		}
 
-		scheduler := New(nil, subDir)
+		scheduler := New(nil, subDir, RetryPolicy{})
 		if scheduler.storagePath != subDir {
 			t.Errorf("storagePath = %q, want %q", scheduler.storagePath, subDir)
 		}
@@ -271,7 +284,7 @@ func TestStoragePathCreation(t *testing.T) {
 func TestJobManagement(t *testing.T) {
 	t.Run("jobs map is initialized", func(t *testing.T) {
 		tempDir := t.TempDir()
-		scheduler := New(nil, tempDir)
+		scheduler := New(nil, tempDir, RetryPolicy{})
 
 		if scheduler.jobs == nil {
 			t.Fatal("jobs map should be initialized")
@@ -282,3 +295,352 @@ func TestJobManagement(t *testing.T) {
 		}
 	})
 }
+
+func TestWithRetries(t *testing.T) {
+	policy := RetryPolicy{Retries: 2, Backoff: time.Millisecond}
+	transient := &proxy.StatusError{Op: "upstream", StatusCode: http.StatusServiceUnavailable}
+	permanent := &proxy.StatusError{Op: "upstream", StatusCode: http.StatusNotFound}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"succeeds first time", []error{nil}, 1, nil},
+		{"recovers from transient error", []error{transient, nil}, 2, nil},
+		{"gives up after retries", []error{transient, transient, transient, nil}, 3, transient},
+		{"does not retry permanent error", []error{permanent, nil}, 1, permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetries(context.Background(), policy, func(attempt int) error {
+				if attempt != attempts+1 {
+					t.Errorf("attempt = %d, want %d", attempt, attempts+1)
+				}
+				attempts++
+				return tt.errs[attempt-1]
+			})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		attempts := 0
+		err := withRetries(ctx, RetryPolicy{Retries: 5, Backoff: time.Hour}, func(int) error {
+			attempts++
+			return transient
+		})
+		if attempts != 1 || err == nil {
+			t.Errorf("attempts = %d, err = %v; want 1 attempt and an error", attempts, err)
+		}
+	})
+}
+
+func TestNextRun(t *testing.T) {
+	from := time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC)
+	got, err := NextRun("0 2 * * *", from)
+	if err != nil {
+		t.Fatalf("NextRun() error = %v", err)
+	}
+	if want := time.Date(2024, 3, 10, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextRun() = %v, want %v", got, want)
+	}
+
+	for expr, want := range map[string]time.Time{
+		"@daily":     time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		"@every 15m": from.Add(15 * time.Minute),
+	} {
+		if got, err := NextRun(expr, from); err != nil || !got.Equal(want) {
+			t.Errorf("NextRun(%q) = %v, %v; want %v", expr, got, err, want)
+		}
+	}
+
+	for _, expr := range []string{"", "not cron", "0 0 2 * * *", "61 * * * *", "@fortnightly"} {
+		if _, err := NextRun(expr, from); err == nil {
+			t.Errorf("NextRun(%q) expected error", expr)
+		}
+	}
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Provider{}, &models.ProviderPlatform{}, &models.ProviderSigningKey{}, &models.Settings{}, &models.SyncRun{}, &models.SyncSchedule{}, &models.Webhook{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestRecordRun_KeepsNewestRuns(t *testing.T) {
+	db := newTestDB(t)
+	s := New(db, t.TempDir(), RetryPolicy{})
+	for i := 0; i < maxRunsPerSchedule+5; i++ {
+		s.recordRun(&models.SyncRun{ScheduleID: 1, Attempt: i + 1, Status: "success"})
+	}
+	s.recordRun(&models.SyncRun{ScheduleID: 2, Attempt: 1, Status: "failed"})
+
+	var runs []models.SyncRun
+	db.Where("schedule_id = ?", 1).Order("id").Find(&runs)
+	if len(runs) != maxRunsPerSchedule || runs[0].Attempt != 6 {
+		t.Errorf("schedule 1 kept %d runs starting at attempt %d, want %d starting at 6", len(runs), runs[0].Attempt, maxRunsPerSchedule)
+	}
+	var other int64
+	db.Model(&models.SyncRun{}).Where("schedule_id = ?", 2).Count(&other)
+	if other != 1 {
+		t.Errorf("schedule 2 runs = %d, want 1", other)
+	}
+}
+
+func TestWarmProvider(t *testing.T) {
+	db := newTestDB(t)
+
+	const binary = "provider-binary"
+	const sum = "896a025bc231d713500d62dd96bc4026e62165619602cc586a6bcef7dd4e6db9" // sha256 of binary
+	var mu sync.Mutex
+	downloads := map[string]int{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/providers/hashicorp/aws/versions" {
+			_, _ = w.Write([]byte(`{"versions":[` +
+				`{"version":"1.0.0","platforms":[{"os":"linux","arch":"amd64"}]},` +
+				`{"version":"2.0.0","platforms":[{"os":"linux","arch":"amd64"}]},` +
+				`{"version":"2.1.0-beta1","platforms":[{"os":"linux","arch":"amd64"}]},` +
+				`{"version":"1.1.0","platforms":[{"os":"linux","arch":"amd64"},{"os":"darwin","arch":"arm64"}]}]}`))
+			return
+		}
+		if version, ok := strings.CutPrefix(r.URL.Path, "/v1/providers/hashicorp/aws/"); ok && strings.HasSuffix(version, "/download/linux/amd64") {
+			version = strings.TrimSuffix(version, "/download/linux/amd64")
+			mu.Lock()
+			downloads[version]++
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_` + version + `_linux_amd64.zip",` +
+				`"download_url":"` + server.URL + `/binary","shasum":"` + sum + `"}`))
+			return
+		}
+		if r.URL.Path == "/binary" {
+			_, _ = w.Write([]byte(binary))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// 2.0.0 is already cached, so only 1.1.0 is missing from the newest two releases.
+	cached := models.Provider{Namespace: "hashicorp", Name: "aws", Version: "2.0.0"}
+	db.Create(&cached)
+	db.Create(&models.ProviderPlatform{ProviderID: cached.ID, OS: "linux", Arch: "amd64"})
+
+	storagePath := t.TempDir()
+	s := New(db, storagePath, RetryPolicy{})
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", SyncOS: "linux", SyncArch: "all",
+		Mode: models.SyncModeLatestN, VersionCount: 2}
+	stats, err := s.warmProvider(proxy.NewProxyService(storagePath, server.URL), schedule)
+	if err != nil {
+		t.Fatalf("warmProvider() error = %v", err)
+	}
+	if stats.platforms != 1 || stats.bytes != int64(len(binary)) {
+		t.Errorf("stats = %+v, want one platform of %d bytes", stats, len(binary))
+	}
+
+	if len(downloads) != 1 || downloads["1.1.0"] != 1 {
+		t.Errorf("download requests = %v, want only 1.1.0", downloads)
+	}
+	if !s.platformExists("hashicorp", "aws", "1.1.0", "linux", "amd64") {
+		t.Error("1.1.0 linux/amd64 was not cached")
+	}
+	if s.platformExists("hashicorp", "aws", "1.1.0", "darwin", "arm64") {
+		t.Error("platform outside the schedule's selection was cached")
+	}
+}
+
+func TestGetPlatformsToMirror_LatestBySemver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":[` +
+			`{"version":"1.9.0","platforms":[{"os":"linux","arch":"amd64"}]},` +
+			`{"version":"2.1.0-beta1","platforms":[{"os":"linux","arch":"amd64"}]},` +
+			`{"version":"1.10.0","platforms":[{"os":"linux","arch":"amd64"}]}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	db := newTestDB(t)
+	db.Create(&models.Settings{})
+	storagePath := t.TempDir()
+	s := New(db, storagePath, RetryPolicy{})
+	proxyService := proxy.NewProxyService(storagePath, server.URL)
+
+	_, version, _, err := s.getPlatformsToMirror(proxyService, "hashicorp", "aws", "", "all", "all")
+	if err != nil || version != "1.10.0" {
+		t.Errorf("latest version = %q, %v, want 1.10.0", version, err)
+	}
+
+	db.Model(&models.Settings{}).Where("1 = 1").Update("include_prereleases", true)
+	_, version, _, err = s.getPlatformsToMirror(proxyService, "hashicorp", "aws", "", "all", "all")
+	if err != nil || version != "2.1.0-beta1" {
+		t.Errorf("latest version with prereleases = %q, %v, want 2.1.0-beta1", version, err)
+	}
+}
+
+func TestLoadSchedules_MarksInvalidCron(t *testing.T) {
+	db := newTestDB(t)
+	valid := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "@every 6h", Enabled: true}
+	invalid := models.SyncSchedule{Namespace: "hashicorp", Name: "google", CronExpr: "0 0 2 * * *", Enabled: true}
+	db.Create(&valid)
+	db.Create(&invalid)
+
+	s := New(db, t.TempDir(), RetryPolicy{})
+	if err := s.loadSchedules(); err != nil {
+		t.Fatalf("loadSchedules() error = %v", err)
+	}
+	if _, ok := s.NextRunOf(valid.ID); !ok {
+		t.Error("descriptor schedule was not loaded")
+	}
+
+	db.First(&invalid, invalid.ID)
+	if invalid.LastStatus != StatusInvalid || !strings.Contains(invalid.LastError, "0 0 2 * * *") || invalid.NextRunAt != nil {
+		t.Errorf("invalid schedule = status %q, error %q, next run %v", invalid.LastStatus, invalid.LastError, invalid.NextRunAt)
+	}
+
+	// Once fixed, the next refresh schedules it and clears the error.
+	db.Model(&invalid).Update("cron_expr", "0 2 * * *")
+	s.refreshSchedules()
+	db.First(&invalid, invalid.ID)
+	if invalid.LastStatus != "" || invalid.LastError != "" || invalid.NextRunAt == nil {
+		t.Errorf("fixed schedule = status %q, error %q, next run %v", invalid.LastStatus, invalid.LastError, invalid.NextRunAt)
+	}
+}
+
+func TestSync_SkipsOverlappingRun(t *testing.T) {
+	db := newTestDB(t)
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/versions") {
+			entered <- struct{}{}
+			<-release
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	db.Create(&models.Settings{DefaultUpstreamURL: server.URL})
+
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "@every 6h", SyncOS: "all", SyncArch: "all", Enabled: true}
+	db.Create(&schedule)
+	s := New(db, t.TempDir(), RetryPolicy{})
+
+	done := make(chan struct{})
+	go func() {
+		s.runSync(schedule.ID)
+		close(done)
+	}()
+	<-entered
+
+	// A trigger while the first run is in progress returns without syncing.
+	s.runSync(schedule.ID)
+	var got models.SyncSchedule
+	db.First(&got, schedule.ID)
+	if got.LastStatus != StatusRunning {
+		t.Errorf("status during the run = %q, want %q", got.LastStatus, StatusRunning)
+	}
+	close(release)
+	<-done
+	if len(entered) != 0 {
+		t.Error("overlapping trigger synced the schedule a second time")
+	}
+
+	// Once the run is over the schedule can be synced again.
+	if !s.beginRun(schedule.ID) {
+		t.Error("schedule still marked as running after its sync finished")
+	}
+}
+
+func TestStart_ClearsInterruptedRuns(t *testing.T) {
+	db := newTestDB(t)
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "@every 6h", Enabled: true, LastStatus: StatusRunning}
+	db.Create(&schedule)
+
+	s := New(db, t.TempDir(), RetryPolicy{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(s.Stop)
+
+	var got models.SyncSchedule
+	db.First(&got, schedule.ID)
+	if got.LastStatus != "failed" || got.LastError == "" {
+		t.Errorf("status = %q, error = %q; want the interrupted run marked failed", got.LastStatus, got.LastError)
+	}
+}
+
+func TestSync_NotifiesWebhooks(t *testing.T) {
+	db := newTestDB(t)
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(upstream.Close)
+	var mu sync.Mutex
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("X-Webhook-Event"))
+		mu.Unlock()
+	}))
+	t.Cleanup(receiver.Close)
+	db.Create(&models.Settings{DefaultUpstreamURL: upstream.URL})
+	db.Create(&models.Webhook{URL: receiver.URL, Secret: "s", Events: webhook.EventSyncFailed, Enabled: true})
+
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "@every 6h", SyncOS: "all", SyncArch: "all", Enabled: true}
+	db.Create(&schedule)
+	New(db, t.TempDir(), RetryPolicy{}).runSync(schedule.ID)
+	webhook.Default.Wait(context.Background())
+
+	if len(received) != 1 || received[0] != webhook.EventSyncFailed {
+		t.Errorf("received events = %v, want one sync.failed", received)
+	}
+}
+
+func TestSync_AppliesProxySettings(t *testing.T) {
+	var proxied []string
+	var mu sync.Mutex
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(proxyServer.Close)
+
+	db := newTestDB(t)
+	settings := models.Settings{DefaultUpstreamURL: "http://upstream.invalid", ProxyEnabled: true, ProxyURL: proxyServer.URL, ProxyType: "http"}
+	db.Create(&settings)
+	schedule := models.SyncSchedule{Namespace: "hashicorp", Name: "aws", CronExpr: "@every 6h", SyncOS: "all", SyncArch: "all", Enabled: true}
+	db.Create(&schedule)
+	s := New(db, t.TempDir(), RetryPolicy{})
+
+	s.runSync(schedule.ID)
+	mu.Lock()
+	if len(proxied) == 0 || !strings.HasPrefix(proxied[0], "http://upstream.invalid/") {
+		t.Errorf("proxied requests = %v, want the upstream requests to go through the proxy", proxied)
+	}
+	mu.Unlock()
+
+	// A proxy that cannot be set up fails the run rather than connecting directly.
+	db.Model(&settings).Updates(map[string]interface{}{"proxy_url": "socks5://", "proxy_type": "socks5"})
+	s.runSync(schedule.ID)
+	var got models.SyncSchedule
+	db.First(&got, schedule.ID)
+	if got.LastStatus != "failed" || !strings.Contains(got.LastError, "proxy") {
+		t.Errorf("status = %q, error = %q; want a failed run naming the proxy", got.LastStatus, got.LastError)
+	}
+}
@@ -0,0 +1,118 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraints is a parsed Terraform version constraint string such as
+// ">= 5.0, < 6.0" or "~> 5.1". A version must satisfy every constraint.
+type Constraints []constraint
+
+type constraint struct {
+	op      string
+	version Version
+	parts   int // number of version components written, for "~>"
+}
+
+// constraintOps lists the supported operators, longest first so that prefixes
+// such as ">" do not shadow ">=".
+var constraintOps = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraints parses a comma-separated list of Terraform version
+// constraints. Versions may omit trailing components ("5", "5.1").
+func ParseConstraints(s string) (Constraints, error) {
+	var cs Constraints
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil, fmt.Errorf("empty version constraint in %q", s)
+		}
+		op := "="
+		for _, candidate := range constraintOps {
+			if rest, ok := strings.CutPrefix(raw, candidate); ok {
+				op, raw = candidate, strings.TrimSpace(rest)
+				break
+			}
+		}
+		v, parts, ok := parsePartial(raw)
+		if !ok {
+			return nil, fmt.Errorf("invalid version %q in constraint %q", raw, s)
+		}
+		cs = append(cs, constraint{op: op, version: v, parts: parts})
+	}
+	return cs, nil
+}
+
+// parsePartial parses a version with one to three numeric components and an
+// optional prerelease, reporting how many components were given.
+func parsePartial(s string) (Version, int, bool) {
+	if v, ok := Parse(s); ok {
+		return v, 3, true
+	}
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) > 2 {
+		return Version{}, 0, false
+	}
+	nums := make([]uint64, 3)
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return Version{}, 0, false
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, len(parts), true
+}
+
+// Check reports whether v satisfies all constraints. As in Terraform, a
+// prerelease version only matches a constraint that names it exactly.
+func (cs Constraints) Check(v Version) bool {
+	if v.Prerelease != "" {
+		exact := false
+		for _, c := range cs {
+			if c.op == "=" && c.version == v {
+				exact = true
+			}
+		}
+		if !exact {
+			return false
+		}
+	}
+	for _, c := range cs {
+		if !c.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c constraint) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "~>":
+		// Only the rightmost written component may increase: "~> 5.1" allows
+		// 5.x from 5.1, "~> 5.1.2" allows 5.1.x from 5.1.2.
+		if cmp < 0 {
+			return false
+		}
+		if c.parts <= 2 {
+			return v.Major == c.version.Major
+		}
+		return v.Major == c.version.Major && v.Minor == c.version.Minor
+	}
+	return false
+}
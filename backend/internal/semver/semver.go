@@ -0,0 +1,134 @@
+// Package semver compares Terraform provider version strings.
+package semver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+}
+
+// Parse parses a semantic version. A leading "v" is accepted and build
+// metadata is discarded, since it does not affect precedence.
+func Parse(s string) (Version, bool) {
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	var v Version
+	core := s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		core, v.Prerelease = s[:i], s[i+1:]
+		if v.Prerelease == "" {
+			return Version{}, false
+		}
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, false
+	}
+	nums := make([]uint64, 3)
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return Version{}, false
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as a is lower than, equal to, or higher than b,
+// following semver 2.0 precedence. Unparsable versions sort below valid ones
+// and are compared lexically among themselves.
+func Compare(a, b string) int {
+	va, okA := Parse(a)
+	vb, okB := Parse(b)
+	switch {
+	case !okA && !okB:
+		return strings.Compare(a, b)
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to, or higher than o.
+func (v Version) Compare(o Version) int {
+	if c := compareUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// IsPrerelease reports whether s parses as a version with a prerelease suffix.
+func IsPrerelease(s string) bool {
+	v, ok := Parse(s)
+	return ok && v.Prerelease != ""
+}
+
+// SortDescending sorts versions newest first.
+func SortDescending(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) > 0
+	})
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers. A version
+// without a prerelease has higher precedence than one with.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.ParseUint(pa[i], 10, 64)
+		nb, errB := strconv.ParseUint(pb[i], 10, 64)
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareUint(na, nb)
+		case errA == nil:
+			c = -1 // numeric identifiers sort below alphanumeric ones
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(pa[i], pb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(pa)), uint64(len(pb)))
+}
@@ -0,0 +1,111 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   Version
+		wantOK bool
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+		{"1.0.0-rc.1", Version{Major: 1, Prerelease: "rc.1"}, true},
+		{"1.0.0+build.5", Version{Major: 1}, true},
+		{"1.0", Version{}, false},
+		{"1.0.0-", Version{}, false},
+		{"latest", Version{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Parse(tt.input)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta", 1},
+		{"1.0.0+build", "1.0.0", 0},
+		{"garbage", "0.0.1", -1},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortDescending(t *testing.T) {
+	versions := []string{"1.9.0", "1.10.0", "1.10.0-rc.1", "0.1.0", "2.0.0"}
+	SortDescending(versions)
+
+	want := []string{"2.0.0", "1.10.0", "1.10.0-rc.1", "1.9.0", "0.1.0"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("SortDescending() = %v, want %v", versions, want)
+	}
+}
+
+func TestIsPrerelease(t *testing.T) {
+	if !IsPrerelease("1.0.0-beta") {
+		t.Error("IsPrerelease(1.0.0-beta) = false, want true")
+	}
+	if IsPrerelease("1.0.0") {
+		t.Error("IsPrerelease(1.0.0) = true, want false")
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"5.0.0", "5.0.0", true},
+		{"= 5.0.0", "5.0.1", false},
+		{">= 5.0, < 6.0", "5.9.3", true},
+		{">= 5.0, < 6.0", "6.0.0", false},
+		{"!= 5.0.1", "5.0.1", false},
+		{"~> 5.0", "5.7.0", true},
+		{"~> 5.0", "6.0.0", false},
+		{"~> 5.0.1", "5.0.9", true},
+		{"~> 5.0.1", "5.1.0", false},
+		{"~> 5.0.1", "5.0.0", false},
+		{"~> 5", "5.3.0", true},
+		{">= 5.0", "5.1.0-beta1", false},
+		{"5.1.0-beta1", "5.1.0-beta1", true},
+	}
+
+	for _, tt := range tests {
+		cs, err := ParseConstraints(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q) error = %v", tt.constraint, err)
+		}
+		v, _ := Parse(tt.version)
+		if got := cs.Check(v); got != tt.want {
+			t.Errorf("%q.Check(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", ">= ", "~> latest", "5.0,", "1.2.3.4"} {
+		if _, err := ParseConstraints(invalid); err == nil {
+			t.Errorf("ParseConstraints(%q) succeeded, want error", invalid)
+		}
+	}
+}
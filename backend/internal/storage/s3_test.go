@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves the subset of the S3 API S3Storage uses, with path-style
+// addressing, for a single bucket.
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	f := &fakeS3{bucket: bucket, objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	query := r.URL.Query()
+	uploadID := query.Get("uploadId")
+
+	switch {
+	case key == "" && query.Has("location"):
+		_, _ = io.WriteString(w, `<LocationConstraint>us-east-1</LocationConstraint>`)
+	case key == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = map[int][]byte{}
+		_, _ = fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case r.Method == http.MethodPut && uploadID != "":
+		part, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		f.uploads[uploadID][part] = data
+		w.Header().Set("ETag", `"part-`+strconv.Itoa(part)+`"`)
+	case r.Method == http.MethodPost && uploadID != "":
+		parts := f.uploads[uploadID]
+		numbers := make([]int, 0, len(parts))
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var object []byte
+		for _, n := range numbers {
+			object = append(object, parts[n]...)
+		}
+		f.objects[key] = object
+		delete(f.uploads, uploadID)
+		_, _ = fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"object"</ETag></CompleteMultipartUploadResult>`, bucket, key)
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		w.Header().Set("ETag", `"object"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default: // GET and HEAD
+		data, ok := f.objects[key]
+		if !ok {
+			s3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"object"`)
+		http.ServeContent(w, r, "", time.Unix(1700000000, 0), bytes.NewReader(data))
+	}
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func newTestS3Storage(t *testing.T, prefix string) (*S3Storage, *fakeS3) {
+	t.Helper()
+	fake, server := newFakeS3(t, "registry")
+	s, err := NewS3Storage(S3Options{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Bucket:    "registry",
+		Region:    "us-east-1",
+		AccessKey: "test",
+		SecretKey: "test-secret",
+		Prefix:    prefix,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+	return s, fake
+}
+
+// failingReader returns its data and then err instead of io.EOF.
+type failingReader struct {
+	data io.Reader
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestS3Storage(t *testing.T) {
+	s, fake := newTestS3Storage(t, "/mirror/")
+
+	if err := s.Save("hashicorp/aws/5.0.0/linux_amd64/provider.zip", strings.NewReader("provider-binary")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, ok := fake.objects["mirror/hashicorp/aws/5.0.0/linux_amd64/provider.zip"]; !ok {
+		t.Fatalf("object keys = %v, want the prefixed key", fake.objects)
+	}
+
+	r, err := s.Get("hashicorp/aws/5.0.0/linux_amd64/provider.zip")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	if string(data) != "provider-binary" {
+		t.Errorf("Get() = %q", data)
+	}
+	if _, err := r.(io.Seeker).Seek(9, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "binary" {
+		t.Errorf("read after Seek = %q, want %q", rest, "binary")
+	}
+	_ = r.Close()
+
+	if size, err := s.Size("hashicorp/aws/5.0.0/linux_amd64/provider.zip"); err != nil || size != int64(len("provider-binary")) {
+		t.Errorf("Size() = %d, %v", size, err)
+	}
+	if ok, err := s.Exists("hashicorp/aws/5.0.0/linux_amd64/provider.zip"); !ok || err != nil {
+		t.Errorf("Exists() = %v, %v", ok, err)
+	}
+
+	if err := s.Delete("hashicorp/aws/5.0.0/linux_amd64/provider.zip"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if ok, err := s.Exists("hashicorp/aws/5.0.0/linux_amd64/provider.zip"); ok || err != nil {
+		t.Errorf("Exists() after delete = %v, %v", ok, err)
+	}
+	if _, err := s.Get("hashicorp/aws/5.0.0/linux_amd64/provider.zip"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Get() missing error = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := s.Size("missing.zip"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Size() missing error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestS3Storage_SaveFailure(t *testing.T) {
+	s, fake := newTestS3Storage(t, "")
+	readErr := errors.New("checksum mismatch")
+	err := s.Save("provider.zip", &failingReader{data: strings.NewReader("partial"), err: readErr})
+	if !errors.Is(err, readErr) {
+		t.Fatalf("Save() error = %v, want the reader's error", err)
+	}
+	if len(fake.objects) != 0 || len(fake.uploads) != 0 {
+		t.Errorf("objects = %v, uploads = %v; want the upload aborted", fake.objects, fake.uploads)
+	}
+}
+
+func TestS3Storage_InvalidPath(t *testing.T) {
+	s, _ := newTestS3Storage(t, "")
+	for _, p := range []string{"", "../escape.zip", "/absolute.zip", "a/../../escape.zip"} {
+		if err := s.Save(p, strings.NewReader("x")); err == nil {
+			t.Errorf("Save(%q) succeeded, want an error", p)
+		}
+	}
+}
+
+func TestNewS3Storage_MissingBucket(t *testing.T) {
+	_, server := newFakeS3(t, "registry")
+	_, err := NewS3Storage(S3Options{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"), Bucket: "other", Region: "us-east-1",
+		AccessKey: "test", SecretKey: "test-secret",
+	})
+	if err == nil {
+		t.Fatal("NewS3Storage() with a missing bucket succeeded")
+	}
+}
+
+func TestLocalStorage_SaveFailureKeepsPrevious(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	if err := s.Save("provider.zip", strings.NewReader("good")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := s.Save("provider.zip", &failingReader{data: strings.NewReader("bad"), err: errors.New("boom")}); err == nil {
+		t.Fatal("Save() with a failing reader succeeded")
+	}
+	r, _ := s.Get("provider.zip")
+	defer func() { _ = r.Close() }()
+	if data, _ := io.ReadAll(r); string(data) != "good" {
+		t.Errorf("contents = %q, want the previous file", data)
+	}
+	if leftover, _ := filepath.Glob(filepath.Join(s.basePath, "provider.zip.*.tmp")); len(leftover) != 0 {
+		t.Errorf("temporary files left behind: %v", leftover)
+	}
+	if size, err := s.Size("provider.zip"); err != nil || size != 4 {
+		t.Errorf("Size() = %d, %v", size, err)
+	}
+}
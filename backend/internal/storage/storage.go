@@ -4,16 +4,26 @@ package storage
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
-// Storage defines the interface for file storage operations.
+// Storage defines the interface for file storage operations. Paths are
+// slash-separated and relative to the root of the store. Get and Size report a
+// missing file with an error matching fs.ErrNotExist.
+//
+// Save must not leave a partial file behind: when data returns an error, the
+// previous contents of path, if any, are kept. The readers returned by both
+// implementations also implement io.Seeker and io.ReaderAt.
 type Storage interface {
 	Save(path string, data io.Reader) error
 	Get(path string) (io.ReadCloser, error)
 	Delete(path string) error
 	Exists(path string) (bool, error)
+	Size(path string) (int64, error)
 }
 
 // LocalStorage implements Storage interface using local filesystem.
@@ -38,15 +48,27 @@ func (s *LocalStorage) Save(path string, data io.Reader) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	file, err := os.Create(fullPath) // #nosec G304 - path is validated via basePath join
+	// Write to a temporary file and rename it into place, so that readers never
+	// see a partial file. Each write gets its own, so concurrent saves of the
+	// same path do not interleave.
+	file, err := os.CreateTemp(dir, filepath.Base(fullPath)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer func() { _ = file.Close() }()
+	tempPath := file.Name()
 
-	if _, err := io.Copy(file, data); err != nil {
+	_, err = io.Copy(file, data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
 		return fmt.Errorf("failed to write data: %w", err)
 	}
+	if err := os.Rename(tempPath, fullPath); err != nil {
+		_ = os.Remove(tempPath) // #nosec G104 - best effort cleanup
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
 
 	return nil
 }
@@ -82,3 +104,53 @@ func (s *LocalStorage) Exists(path string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Size returns the size in bytes of the file at the specified path.
+func (s *LocalStorage) Size(path string) (int64, error) {
+	info, err := os.Stat(filepath.Join(s.basePath, path))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// partialSuffixes are the suffixes of files written before being renamed into
+// place: ".tmp" by Save, ".part" by module uploads.
+var partialSuffixes = []string{".tmp", ".part"}
+
+// RemoveStaleTempFiles deletes partial files that were last written more than
+// olderThan ago. They are left behind when the process is killed mid-write and
+// are never completed, since every write starts a new one. It returns the
+// number and total size of the files removed.
+func (s *LocalStorage) RemoveStaleTempFiles(olderThan time.Duration) (int, int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+	removed, size := 0, int64(0)
+	err := filepath.WalkDir(s.basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !isPartialFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed++
+		size += info.Size()
+		return nil
+	})
+	return removed, size, err
+}
+
+func isPartialFile(name string) bool {
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,7 +6,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewLocalStorage(t *testing.T) {
@@ -293,3 +297,96 @@ func TestLocalStorage_IntegrationWorkflow(t *testing.T) {
 		t.Error("file should not exist after delete")
 	}
 }
+
+func TestLocalStorage_RemoveStaleTempFiles(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	if err := s.Save("hashicorp/aws/5.0.0/linux_amd64/provider.zip", bytes.NewReader([]byte("complete"))); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A process killed mid-write leaves the partial file where Save wrote it.
+	write := func(path string, age time.Duration) string {
+		t.Helper()
+		full := filepath.Join(s.basePath, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("partial"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(full, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return full
+	}
+	interrupted := write("hashicorp/aws/5.0.0/linux_amd64/provider.zip.tmp", 2*time.Hour)
+	upload := write("_modules/vpc.tar.gz.part", 2*time.Hour)
+	inProgress := write("hashicorp/aws/5.0.0/darwin_arm64/provider.zip.tmp", time.Minute)
+	oldComplete := write("hashicorp/aws/4.0.0/linux_amd64/provider.zip", 2*time.Hour)
+
+	removed, size, err := s.RemoveStaleTempFiles(time.Hour)
+	if err != nil {
+		t.Fatalf("RemoveStaleTempFiles() error = %v", err)
+	}
+	if removed != 2 || size != int64(2*len("partial")) {
+		t.Errorf("RemoveStaleTempFiles() = %d, %d; want 2 files of %d bytes", removed, size, 2*len("partial"))
+	}
+	for _, path := range []string{interrupted, upload} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s not removed", path)
+		}
+	}
+	for _, path := range []string{inProgress, oldComplete, filepath.Join(s.basePath, "hashicorp/aws/5.0.0/linux_amd64/provider.zip")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s removed: %v", path, err)
+		}
+	}
+}
+
+func TestLocalStorage_SaveRenameFailure(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	// A non-empty directory in the way makes the final rename fail.
+	if err := os.MkdirAll(filepath.Join(s.basePath, "provider.zip", "child"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("provider.zip", bytes.NewReader([]byte("data"))); err == nil {
+		t.Fatal("Save() over a directory succeeded")
+	}
+	if leftover, _ := filepath.Glob(filepath.Join(s.basePath, "provider.zip.*.tmp")); len(leftover) != 0 {
+		t.Errorf("temporary files left behind after a failed rename: %v", leftover)
+	}
+}
+
+func TestLocalStorage_ConcurrentSave(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage() error = %v", err)
+	}
+	contents := []string{"first-binary", "second-binary-longer", "third"}
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(data string) {
+			defer wg.Done()
+			if err := s.Save("provider.zip", strings.NewReader(data)); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+		}(contents[i%len(contents)])
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(s.basePath, "provider.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(contents, string(data)) {
+		t.Errorf("contents = %q, want one of the saves whole", data)
+	}
+}
@@ -0,0 +1,201 @@
+// Package webhook notifies configured HTTP endpoints of registry events.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/logsafe"
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"gorm.io/gorm"
+)
+
+// Events a webhook can subscribe to.
+const (
+	// EventSyncSucceeded is sent when a scheduled sync run succeeds.
+	EventSyncSucceeded = "sync.succeeded"
+	// EventSyncFailed is sent when a scheduled sync run fails after its retries.
+	EventSyncFailed = "sync.failed"
+	// EventMirrorCompleted is sent when a provider version has been mirrored
+	// through the API, fully or partially.
+	EventMirrorCompleted = "mirror.completed"
+	// EventProviderDeleted is sent when a provider version is deleted through the API.
+	EventProviderDeleted = "provider.deleted"
+	// EventTest is only sent by the API's test delivery.
+	EventTest = "webhook.test"
+)
+
+// Events lists the events a webhook can subscribe to.
+var Events = []string{EventSyncSucceeded, EventSyncFailed, EventMirrorCompleted, EventProviderDeleted}
+
+// AllEvents subscribes a webhook to every event.
+const AllEvents = "*"
+
+// SignatureHeader carries the HMAC-SHA256 of the request body, keyed with the
+// webhook's secret, as "sha256=<hex>".
+const SignatureHeader = "X-Signature"
+
+// Delivery attempts made by Default before a delivery is given up.
+const (
+	DefaultAttempts = 4
+	DefaultBackoff  = 2 * time.Second
+)
+
+// requestTimeout bounds each delivery attempt.
+const requestTimeout = 10 * time.Second
+
+// Default is the process-wide dispatcher the scheduler and the API send
+// events through.
+var Default = NewDispatcher(DefaultAttempts, DefaultBackoff)
+
+// Payload is the JSON body of a delivery. ID is the same for every attempt
+// of a delivery, so that receivers can drop repeats.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to the enabled webhooks subscribed to them, in
+// the background, retrying failed attempts with exponential backoff.
+type Dispatcher struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+	wg       sync.WaitGroup
+}
+
+// NewDispatcher creates a Dispatcher that makes up to attempts attempts per
+// delivery, waiting backoff before the first retry and twice as long before
+// each further one.
+func NewDispatcher(attempts int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		client:   &http.Client{Timeout: requestTimeout},
+		attempts: max(attempts, 1),
+		backoff:  backoff,
+	}
+}
+
+// Sign returns the value of SignatureHeader for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribed reports whether hook receives event.
+func Subscribed(hook models.Webhook, event string) bool {
+	for _, e := range strings.Split(hook.Events, ",") {
+		if e = strings.TrimSpace(e); e == AllEvents || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch sends an event to every enabled webhook subscribed to it. It
+// returns once the webhooks are looked up; delivery happens in the background.
+func Dispatch(db *gorm.DB, event string, data interface{}) {
+	Default.Dispatch(db, event, data)
+}
+
+// Dispatch sends an event to every enabled webhook subscribed to it. It
+// returns once the webhooks are looked up; delivery happens in the background.
+func (d *Dispatcher) Dispatch(db *gorm.DB, event string, data interface{}) {
+	var hooks []models.Webhook
+	if err := db.Where("enabled = ?", true).Find(&hooks).Error; err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+	for _, hook := range hooks {
+		if Subscribed(hook, event) {
+			d.Send(db, hook, event, data)
+		}
+	}
+}
+
+// Send delivers an event to one webhook in the background, whether or not it
+// is subscribed, and records the outcome on the webhook.
+func (d *Dispatcher) Send(db *gorm.DB, hook models.Webhook, event string, data interface{}) {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	body, err := json.Marshal(Payload{ID: hex.EncodeToString(id), Event: event, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", event, err)
+		return
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		err := d.deliver(hook, event, body)
+		updates := map[string]interface{}{"last_delivery_at": time.Now(), "last_status": "delivered", "last_error": ""}
+		if err != nil {
+			updates["last_status"] = "failed"
+			updates["last_error"] = err.Error()
+			log.Printf("Webhook %d: giving up on %s after %d attempts: %s", hook.ID, event, d.attempts, logsafe.CleanErr(err))
+		}
+		db.Model(&models.Webhook{}).Where("id = ?", hook.ID).Updates(updates)
+	}()
+}
+
+// deliver posts body to the webhook until it answers 2xx or the attempts run out.
+func (d *Dispatcher) deliver(hook models.Webhook, event string, body []byte) error {
+	backoff := d.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = d.post(hook, event, body); err == nil || attempt >= d.attempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) post(hook models.Webhook, event string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vc-terraform-registry-webhook")
+	req.Header.Set("X-Webhook-Event", event)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Wait blocks until deliveries in progress have finished or ctx is done.
+func (d *Dispatcher) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Veritas-Calculus/vc-terraform-registry/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Webhook{}); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestDispatcher(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []Payload
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if r.Header.Get(SignatureHeader) != Sign("s3cret", body) {
+			t.Errorf("signature %q does not match the body", r.Header.Get(SignatureHeader))
+		}
+		// The first attempt fails, so the delivery is retried.
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var p Payload
+		_ = json.Unmarshal(body, &p)
+		bodies = append(bodies, p)
+	}))
+	t.Cleanup(server.Close)
+
+	db := newTestDB(t)
+	subscribed := models.Webhook{URL: server.URL, Secret: "s3cret", Events: EventSyncFailed + "," + EventMirrorCompleted, Enabled: true}
+	other := models.Webhook{URL: server.URL, Secret: "s3cret", Events: EventProviderDeleted, Enabled: true}
+	db.Create(&subscribed)
+	db.Create(&other)
+
+	d := NewDispatcher(3, time.Millisecond)
+	d.Dispatch(db, EventSyncFailed, map[string]string{"name": "aws"})
+	d.Wait(context.Background())
+
+	if requests != 2 || len(bodies) != 1 {
+		t.Fatalf("requests = %d, deliveries = %d; want one retried delivery", requests, len(bodies))
+	}
+	if bodies[0].Event != EventSyncFailed || bodies[0].ID == "" {
+		t.Errorf("payload = %+v", bodies[0])
+	}
+	db.First(&subscribed, subscribed.ID)
+	if subscribed.LastStatus != "delivered" || subscribed.LastDeliveryAt == nil {
+		t.Errorf("webhook = %+v, want delivered", subscribed)
+	}
+
+	// Every attempt failing records the failure.
+	failing := models.Webhook{URL: server.URL + "/gone", Secret: "s3cret", Events: AllEvents, Enabled: true}
+	db.Create(&failing)
+	server.Config.Handler = http.NotFoundHandler()
+	d.Send(db, failing, EventTest, nil)
+	d.Wait(context.Background())
+	db.First(&failing, failing.ID)
+	if failing.LastStatus != "failed" || failing.LastError == "" {
+		t.Errorf("webhook = %+v, want failed", failing)
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	hook := models.Webhook{Events: "sync.failed, mirror.completed"}
+	if !Subscribed(hook, EventMirrorCompleted) || Subscribed(hook, EventSyncSucceeded) {
+		t.Errorf("Subscribed(%q) is wrong", hook.Events)
+	}
+	if !Subscribed(models.Webhook{Events: AllEvents}, EventProviderDeleted) {
+		t.Error("* does not match every event")
+	}
+}
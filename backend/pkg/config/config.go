@@ -2,18 +2,27 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application.
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Storage  StorageConfig
-	Auth     AuthConfig
-	Log      LogConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Storage   StorageConfig
+	Auth      AuthConfig
+	Scheduler SchedulerConfig
+	Upstream  UpstreamConfig
+	Log       LogConfig
 }
 
 // ServerConfig contains server-related configuration.
@@ -21,6 +30,19 @@ type ServerConfig struct {
 	Port string
 	Host string
 	Mode string
+	// InstanceID identifies this process in responses; defaults to the hostname.
+	InstanceID string
+	// Frozen forces read-only mode regardless of the stored setting.
+	Frozen bool
+	// DownloadFlushInterval batches provider download counts in memory and writes
+	// them this often; 0 writes every download immediately.
+	DownloadFlushInterval time.Duration
+	// StaticMirror also serves the cache as a browsable file tree with
+	// {os}_{arch}.zip archives next to the network mirror documents.
+	StaticMirror bool
+	// ShutdownTimeout is how long active requests may run after a shutdown
+	// signal before their connections are closed.
+	ShutdownTimeout time.Duration
 }
 
 // DatabaseConfig contains database connection settings.
@@ -28,16 +50,124 @@ type DatabaseConfig struct {
 	URL string
 }
 
+// Database drivers selected by the scheme of DatabaseConfig.URL.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Driver returns the database driver for URL: DriverPostgres for postgres:// and
+// postgresql:// URLs, DriverSQLite for "sqlite:" URLs and plain file paths, and
+// "" for any other scheme.
+func (d DatabaseConfig) Driver() string {
+	switch {
+	case strings.HasPrefix(d.URL, "postgres://"), strings.HasPrefix(d.URL, "postgresql://"):
+		return DriverPostgres
+	case strings.HasPrefix(d.URL, "sqlite:"), !strings.Contains(d.URL, "://"):
+		return DriverSQLite
+	default:
+		return ""
+	}
+}
+
+// SQLitePath returns the database file path from URL, without the optional
+// "sqlite:" scheme prefix. It is empty when URL is not a SQLite database.
+func (d DatabaseConfig) SQLitePath() string {
+	if d.Driver() != DriverSQLite {
+		return ""
+	}
+	path := strings.TrimPrefix(d.URL, "sqlite:")
+	if path == "" {
+		return ""
+	}
+	return filepath.Clean(path)
+}
+
+// PathWithin reports whether path is dir or lies inside it, comparing absolute
+// forms so relative and absolute spellings of the same location match.
+func PathWithin(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
 // StorageConfig contains storage backend configuration.
 type StorageConfig struct {
 	Path string
+	// Type selects where provider files are kept: "local" under Path, or "s3"
+	// in the bucket described by S3. Module archives stay under Path either way.
 	Type string
+	S3   S3Config
+	// BackfillMetadata fills missing platform checksums and sizes from stored
+	// files, and corrects mirrored provider protocols from upstream, on startup.
+	BackfillMetadata bool
+	// MaxModuleSize caps uploaded module archives, in bytes.
+	MaxModuleSize int64
+}
+
+// S3Config describes the S3-compatible bucket provider files are kept in when
+// storage.type is "s3".
+type S3Config struct {
+	// Endpoint is the host, and optionally port, of the S3 API.
+	Endpoint string
+	Bucket   string
+	Region   string
+	// AccessKey and SecretKey are static credentials. When AccessKey is empty
+	// the AWS environment variables or the instance's IAM role are used.
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to every object key, to share a bucket.
+	Prefix string
+	UseSSL bool
 }
 
 // AuthConfig contains authentication settings.
 type AuthConfig struct {
 	Enabled   bool
 	SecretKey string
+	// SignedDownloads requires a short-lived signed token on provider binary downloads.
+	SignedDownloads  bool
+	DownloadTokenTTL time.Duration
+	// RefreshTokenTTL is how long refresh tokens issued at login stay valid.
+	RefreshTokenTTL time.Duration
+	// NamespaceOwnership restricts non-admin uploads, imports and mirrors to
+	// namespaces the user has been granted.
+	NamespaceOwnership bool
+}
+
+// SchedulerConfig contains retry settings for scheduled syncs.
+type SchedulerConfig struct {
+	// Retries is the number of extra in-run attempts after a transient failure.
+	Retries int
+	// RetryBackoff is the delay before the first retry; it doubles per attempt.
+	RetryBackoff time.Duration
+	// FailureRetryDelay schedules one extra run this long after a failed sync; 0 disables it.
+	FailureRetryDelay time.Duration
+}
+
+// UpstreamConfig contains limits applied to upstream registry requests.
+type UpstreamConfig struct {
+	// MaxResponseSize caps upstream metadata and search responses, in bytes.
+	MaxResponseSize int64
+	// MetadataTimeout bounds each upstream metadata, search and checksum
+	// request; provider binary downloads have no overall timeout.
+	MetadataTimeout time.Duration
+	// VersionsCacheTTL is how long upstream version lists are reused by the
+	// mirror protocol; 0 disables the cache.
+	VersionsCacheTTL time.Duration
+	// AllowedOverrides lists the hosts clients may select per request with the
+	// X-Upstream-Registry header on the mirror protocol; empty disables overrides.
+	AllowedOverrides []string
 }
 
 // LogConfig contains logging configuration.
@@ -45,23 +175,53 @@ type LogConfig struct {
 	Level string
 }
 
+// DefaultSecretKey is the placeholder JWT secret applied when none is configured.
+const DefaultSecretKey = "change-me-in-production"
+
 // Load reads configuration from environment variables and config files.
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./config")
+	// Map nested keys to environment variables, e.g. server.port -> SERVER_PORT.
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	// Set defaults
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.mode", "release")
+	viper.SetDefault("server.instanceid", "")
+	viper.SetDefault("server.frozen", false)
+	viper.SetDefault("server.downloadflushinterval", "10s")
+	viper.SetDefault("server.staticmirror", false)
+	viper.SetDefault("server.shutdowntimeout", "30s")
 	viper.SetDefault("database.url", "sqlite:///data/registry.db")
 	viper.SetDefault("storage.path", "/data/registry")
 	viper.SetDefault("storage.type", "local")
+	viper.SetDefault("storage.backfillmetadata", false)
+	viper.SetDefault("storage.maxmodulesize", 64<<20)
+	viper.SetDefault("storage.s3.endpoint", "s3.amazonaws.com")
+	viper.SetDefault("storage.s3.bucket", "")
+	viper.SetDefault("storage.s3.region", "")
+	viper.SetDefault("storage.s3.accesskey", "")
+	viper.SetDefault("storage.s3.secretkey", "")
+	viper.SetDefault("storage.s3.prefix", "")
+	viper.SetDefault("storage.s3.usessl", true)
 	viper.SetDefault("auth.enabled", true)
-	viper.SetDefault("auth.secretkey", "change-me-in-production")
+	viper.SetDefault("auth.secretkey", DefaultSecretKey)
+	viper.SetDefault("auth.signeddownloads", false)
+	viper.SetDefault("auth.downloadtokenttl", "15m")
+	viper.SetDefault("auth.refreshtokenttl", "720h")
+	viper.SetDefault("auth.namespaceownership", false)
+	viper.SetDefault("scheduler.retries", 2)
+	viper.SetDefault("scheduler.retrybackoff", "30s")
+	viper.SetDefault("scheduler.failureretrydelay", "15m")
+	viper.SetDefault("upstream.maxresponsesize", 32<<20)
+	viper.SetDefault("upstream.metadatatimeout", "30s")
+	viper.SetDefault("upstream.versionscachettl", "5m")
+	viper.SetDefault("upstream.allowedoverrides", []string{})
 	viper.SetDefault("log.level", "info")
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -76,5 +236,129 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Server.InstanceID == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			cfg.Server.InstanceID = hostname
+		}
+	}
+
 	return &cfg, nil
 }
+
+// Validate checks that the configuration is complete and consistent.
+// Every problem found is reported in a single joined error. Running in release
+// mode with authentication enabled and the default secret key is an error; in
+// other modes it only logs a warning.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %q must be a number between 1 and 65535", c.Server.Port))
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("server.mode %q must be one of debug, release, test", c.Server.Mode))
+	}
+
+	if c.Server.DownloadFlushInterval < 0 {
+		errs = append(errs, errors.New("server.downloadflushinterval must not be negative"))
+	}
+	if c.Server.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("server.shutdowntimeout must not be negative"))
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database.url must not be empty"))
+	} else if c.Database.Driver() == "" {
+		errs = append(errs, fmt.Errorf("database.url %q has an unsupported scheme (supported: sqlite, postgres)", c.Database.RedactedURL()))
+	}
+
+	if strings.TrimSpace(c.Storage.Path) == "" {
+		errs = append(errs, errors.New("storage.path must not be empty"))
+	} else if strings.ContainsRune(c.Storage.Path, 0) {
+		errs = append(errs, errors.New("storage.path contains invalid characters"))
+	} else if dbPath := c.Database.SQLitePath(); dbPath != "" && PathWithin(dbPath, c.Storage.Path) {
+		// Storage walks (GC, backfill, usage) would treat the database and its
+		// WAL files as provider artifacts.
+		errs = append(errs, fmt.Errorf("database.url %q is inside storage.path %q; move the database out of the storage tree", c.Database.URL, c.Storage.Path))
+	}
+	if c.Storage.MaxModuleSize <= 0 {
+		errs = append(errs, errors.New("storage.maxmodulesize must be positive"))
+	}
+	switch c.Storage.Type {
+	case "local":
+	case "s3":
+		if c.Storage.S3.Endpoint == "" {
+			errs = append(errs, errors.New("storage.s3.endpoint must not be empty when storage.type is s3"))
+		} else if strings.Contains(c.Storage.S3.Endpoint, "/") {
+			errs = append(errs, fmt.Errorf("storage.s3.endpoint %q must be a host[:port], without scheme or path", c.Storage.S3.Endpoint))
+		}
+		if c.Storage.S3.Bucket == "" {
+			errs = append(errs, errors.New("storage.s3.bucket must not be empty when storage.type is s3"))
+		}
+		if c.Storage.S3.AccessKey != "" && c.Storage.S3.SecretKey == "" {
+			errs = append(errs, errors.New("storage.s3.secretkey must be set with storage.s3.accesskey"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.type %q is not supported (supported: local, s3)", c.Storage.Type))
+	}
+
+	if c.Auth.Enabled {
+		switch {
+		case c.Auth.SecretKey == "":
+			errs = append(errs, errors.New("auth.secretkey must not be empty when auth is enabled"))
+		case c.Auth.SecretKey == DefaultSecretKey && c.Server.Mode == "release":
+			errs = append(errs, errors.New("auth.secretkey is the default value; set AUTH_SECRETKEY before running in release mode"))
+		case c.Auth.SecretKey == DefaultSecretKey:
+			log.Println("Warning: auth.secretkey is the default value; set AUTH_SECRETKEY for any shared deployment")
+		}
+	}
+
+	if c.Auth.SignedDownloads && c.Auth.DownloadTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.downloadtokenttl must be positive when signed downloads are enabled"))
+	}
+	if c.Auth.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.refreshtokenttl must be positive"))
+	}
+	if c.Auth.SignedDownloads && c.Server.StaticMirror {
+		// Static mirror archives are plain URLs with nowhere to carry a token.
+		errs = append(errs, errors.New("server.staticmirror cannot be enabled together with auth.signeddownloads"))
+	}
+
+	if c.Scheduler.Retries < 0 {
+		errs = append(errs, errors.New("scheduler.retries must not be negative"))
+	}
+	if c.Scheduler.Retries > 0 && c.Scheduler.RetryBackoff <= 0 {
+		errs = append(errs, errors.New("scheduler.retrybackoff must be positive when retries are enabled"))
+	}
+	if c.Scheduler.FailureRetryDelay < 0 {
+		errs = append(errs, errors.New("scheduler.failureretrydelay must not be negative"))
+	}
+
+	if c.Upstream.MaxResponseSize <= 0 {
+		errs = append(errs, errors.New("upstream.maxresponsesize must be positive"))
+	}
+	if c.Upstream.MetadataTimeout <= 0 {
+		errs = append(errs, errors.New("upstream.metadatatimeout must be positive"))
+	}
+	if c.Upstream.VersionsCacheTTL < 0 {
+		errs = append(errs, errors.New("upstream.versionscachettl must not be negative"))
+	}
+	for _, host := range c.Upstream.AllowedOverrides {
+		if host == "" || strings.ContainsAny(host, "/@ \t") {
+			errs = append(errs, fmt.Errorf("upstream.allowedoverrides entry %q must be a host name, optionally with a port", host))
+		}
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be one of debug, info, warn, error", c.Log.Level))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_Defaults(t *testing.T) {
@@ -28,6 +30,12 @@ func TestLoad_Defaults(t *testing.T) {
 		if cfg.Server.Mode != "release" {
 			t.Errorf("Server.Mode = %q, want %q", cfg.Server.Mode, "release")
 		}
+		if cfg.Server.StaticMirror {
+			t.Error("Server.StaticMirror = true, want false")
+		}
+		if cfg.Server.ShutdownTimeout != 30*time.Second {
+			t.Errorf("Server.ShutdownTimeout = %s, want 30s", cfg.Server.ShutdownTimeout)
+		}
 	})
 
 	t.Run("storage defaults", func(t *testing.T) {
@@ -37,6 +45,12 @@ func TestLoad_Defaults(t *testing.T) {
 		if cfg.Storage.Type != "local" {
 			t.Errorf("Storage.Type = %q, want %q", cfg.Storage.Type, "local")
 		}
+		if cfg.Storage.MaxModuleSize != 64<<20 {
+			t.Errorf("Storage.MaxModuleSize = %d, want %d", cfg.Storage.MaxModuleSize, 64<<20)
+		}
+		if cfg.Storage.S3.Endpoint != "s3.amazonaws.com" || !cfg.Storage.S3.UseSSL {
+			t.Errorf("Storage.S3 = %+v, want the AWS endpoint over TLS", cfg.Storage.S3)
+		}
 	})
 
 	t.Run("auth defaults", func(t *testing.T) {
@@ -46,6 +60,39 @@ func TestLoad_Defaults(t *testing.T) {
 		if cfg.Auth.SecretKey != "change-me-in-production" {
 			t.Errorf("Auth.SecretKey = %q, want %q", cfg.Auth.SecretKey, "change-me-in-production")
 		}
+		if cfg.Auth.SignedDownloads {
+			t.Error("Auth.SignedDownloads = true, want false")
+		}
+		if cfg.Auth.DownloadTokenTTL != 15*time.Minute {
+			t.Errorf("Auth.DownloadTokenTTL = %v, want %v", cfg.Auth.DownloadTokenTTL, 15*time.Minute)
+		}
+		if cfg.Auth.RefreshTokenTTL != 30*24*time.Hour {
+			t.Errorf("Auth.RefreshTokenTTL = %v, want %v", cfg.Auth.RefreshTokenTTL, 30*24*time.Hour)
+		}
+	})
+
+	t.Run("scheduler defaults", func(t *testing.T) {
+		if cfg.Scheduler.Retries != 2 {
+			t.Errorf("Scheduler.Retries = %d, want 2", cfg.Scheduler.Retries)
+		}
+		if cfg.Scheduler.RetryBackoff != 30*time.Second {
+			t.Errorf("Scheduler.RetryBackoff = %v, want %v", cfg.Scheduler.RetryBackoff, 30*time.Second)
+		}
+		if cfg.Scheduler.FailureRetryDelay != 15*time.Minute {
+			t.Errorf("Scheduler.FailureRetryDelay = %v, want %v", cfg.Scheduler.FailureRetryDelay, 15*time.Minute)
+		}
+	})
+
+	t.Run("upstream defaults", func(t *testing.T) {
+		if cfg.Upstream.MaxResponseSize != 32<<20 {
+			t.Errorf("Upstream.MaxResponseSize = %d, want %d", cfg.Upstream.MaxResponseSize, 32<<20)
+		}
+		if cfg.Upstream.MetadataTimeout != 30*time.Second {
+			t.Errorf("Upstream.MetadataTimeout = %v, want %v", cfg.Upstream.MetadataTimeout, 30*time.Second)
+		}
+		if cfg.Upstream.VersionsCacheTTL != 5*time.Minute {
+			t.Errorf("Upstream.VersionsCacheTTL = %v, want %v", cfg.Upstream.VersionsCacheTTL, 5*time.Minute)
+		}
 	})
 
 	t.Run("log defaults", func(t *testing.T) {
@@ -201,3 +248,179 @@ func TestConfig_Structs(t *testing.T) {
 		}
 	})
 }
+
+func TestLoad_FromEnvironment(t *testing.T) {
+	t.Setenv("SERVER_PORT", "7070")
+	t.Setenv("SERVER_INSTANCEID", "registry-eu-1")
+	t.Setenv("UPSTREAM_ALLOWEDOVERRIDES", "registry.opentofu.org,mirror.local:8443")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != "7070" {
+		t.Errorf("Server.Port = %q, want %q", cfg.Server.Port, "7070")
+	}
+	if cfg.Server.InstanceID != "registry-eu-1" {
+		t.Errorf("Server.InstanceID = %q, want %q", cfg.Server.InstanceID, "registry-eu-1")
+	}
+	if got := cfg.Upstream.AllowedOverrides; len(got) != 2 || got[1] != "mirror.local:8443" {
+		t.Errorf("Upstream.AllowedOverrides = %q, want two hosts", got)
+	}
+}
+
+func validConfig() Config {
+	return Config{
+		Server:   ServerConfig{Port: "8080", Host: "0.0.0.0", Mode: "release"},
+		Database: DatabaseConfig{URL: "sqlite:///data/registry.db"},
+		Storage:  StorageConfig{Path: "/data/registry", Type: "local", MaxModuleSize: 1 << 20},
+		Auth:     AuthConfig{Enabled: true, SecretKey: "a-real-secret", RefreshTokenTTL: time.Hour},
+		Upstream: UpstreamConfig{MaxResponseSize: 1 << 20, MetadataTimeout: 30 * time.Second},
+		Log:      LogConfig{Level: "info"},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr []string
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"non-numeric port", func(c *Config) { c.Server.Port = "http" }, []string{"server.port"}},
+		{"port out of range", func(c *Config) { c.Server.Port = "70000" }, []string{"server.port"}},
+		{"unknown mode", func(c *Config) { c.Server.Mode = "prod" }, []string{"server.mode"}},
+		{"negative download flush interval", func(c *Config) { c.Server.DownloadFlushInterval = -time.Second }, []string{"server.downloadflushinterval"}},
+		{"negative shutdown timeout", func(c *Config) { c.Server.ShutdownTimeout = -time.Second }, []string{"server.shutdowntimeout"}},
+		{"static mirror with signed downloads", func(c *Config) {
+			c.Server.StaticMirror = true
+			c.Auth.SignedDownloads = true
+		}, []string{"server.staticmirror"}},
+		{"empty storage path", func(c *Config) { c.Storage.Path = " " }, []string{"storage.path"}},
+		{"database inside storage path", func(c *Config) { c.Database.URL = "sqlite:///data/registry/registry.db" }, []string{"database.url"}},
+		{"database equals storage path", func(c *Config) { c.Database.URL = "/data/registry" }, []string{"database.url"}},
+		{"relative database inside storage path", func(c *Config) {
+			c.Storage.Path = "./data"
+			c.Database.URL = "sqlite:data/db/registry.db"
+		}, []string{"database.url"}},
+		{"database beside storage path", func(c *Config) { c.Database.URL = "sqlite:///data/registry.db" }, nil},
+		{"database in sibling with shared prefix", func(c *Config) { c.Database.URL = "sqlite:///data/registry-db/registry.db" }, nil},
+		{"postgres database", func(c *Config) { c.Database.URL = "postgresql://registry@db/registry" }, nil},
+		{"unsupported database scheme", func(c *Config) { c.Database.URL = "mysql://registry:secret@db/registry" }, []string{"database.url"}},
+		{"zero module size", func(c *Config) { c.Storage.MaxModuleSize = 0 }, []string{"storage.maxmodulesize"}},
+		{"unsupported storage type", func(c *Config) { c.Storage.Type = "ftp" }, []string{"storage.type"}},
+		{"s3 storage", func(c *Config) {
+			c.Storage.Type = "s3"
+			c.Storage.S3 = S3Config{Endpoint: "minio:9000", Bucket: "registry"}
+		}, nil},
+		{"s3 storage without bucket", func(c *Config) {
+			c.Storage.Type = "s3"
+			c.Storage.S3 = S3Config{Endpoint: "http://minio:9000", AccessKey: "registry"}
+		}, []string{"storage.s3.endpoint", "storage.s3.bucket", "storage.s3.secretkey"}},
+		{"empty secret", func(c *Config) { c.Auth.SecretKey = "" }, []string{"auth.secretkey"}},
+		{"zero refresh token ttl", func(c *Config) { c.Auth.RefreshTokenTTL = 0 }, []string{"auth.refreshtokenttl"}},
+		{"default secret in release", func(c *Config) { c.Auth.SecretKey = DefaultSecretKey }, []string{"auth.secretkey"}},
+		{"default secret in debug", func(c *Config) {
+			c.Server.Mode = "debug"
+			c.Auth.SecretKey = DefaultSecretKey
+		}, nil},
+		{"default secret with auth disabled", func(c *Config) {
+			c.Auth.Enabled = false
+			c.Auth.SecretKey = DefaultSecretKey
+		}, nil},
+		{"negative scheduler retries", func(c *Config) { c.Scheduler.Retries = -1 }, []string{"scheduler.retries"}},
+		{"retries without backoff", func(c *Config) { c.Scheduler.Retries = 2 }, []string{"scheduler.retrybackoff"}},
+		{"zero upstream response size", func(c *Config) { c.Upstream.MaxResponseSize = 0 }, []string{"upstream.maxresponsesize"}},
+		{"zero upstream metadata timeout", func(c *Config) { c.Upstream.MetadataTimeout = 0 }, []string{"upstream.metadatatimeout"}},
+		{"disabled versions cache", func(c *Config) { c.Upstream.VersionsCacheTTL = 0 }, nil},
+		{"negative versions cache ttl", func(c *Config) { c.Upstream.VersionsCacheTTL = -time.Second }, []string{"upstream.versionscachettl"}},
+		{"allowed override host", func(c *Config) { c.Upstream.AllowedOverrides = []string{"registry.opentofu.org", "mirror.local:8443"} }, nil},
+		{"allowed override with scheme", func(c *Config) { c.Upstream.AllowedOverrides = []string{"https://registry.opentofu.org"} }, []string{"upstream.allowedoverrides"}},
+		{"multiple problems", func(c *Config) {
+			c.Server.Port = ""
+			c.Database.URL = ""
+			c.Log.Level = "loud"
+		}, []string{"server.port", "database.url", "log.level"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.mutate(&cfg)
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() expected error, got nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDatabaseConfig_Driver(t *testing.T) {
+	tests := []struct {
+		url        string
+		wantDriver string
+		wantPath   string
+	}{
+		{"sqlite:///data/registry.db", DriverSQLite, "/data/registry.db"},
+		{"sqlite:data/registry.db", DriverSQLite, "data/registry.db"},
+		{"/data/registry.db", DriverSQLite, "/data/registry.db"},
+		{"postgres://registry@db/registry", DriverPostgres, ""},
+		{"postgresql://registry@db/registry?sslmode=require", DriverPostgres, ""},
+		{"mysql://registry@db/registry", "", ""},
+	}
+	for _, tt := range tests {
+		d := DatabaseConfig{URL: tt.url}
+		if got := d.Driver(); got != tt.wantDriver {
+			t.Errorf("Driver(%q) = %q, want %q", tt.url, got, tt.wantDriver)
+		}
+		if got := d.SQLitePath(); got != tt.wantPath {
+			t.Errorf("SQLitePath(%q) = %q, want %q", tt.url, got, tt.wantPath)
+		}
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.URL = "postgres://registry:s3cr3t@db:5432/registry?sslmode=require&password=other"
+	cfg.Storage.S3.AccessKey = "AKIA"
+	cfg.Storage.S3.SecretKey = "s3-secret"
+	got := cfg.Redacted()
+
+	if got.Storage.S3.AccessKey != RedactedValue || got.Storage.S3.SecretKey != RedactedValue {
+		t.Errorf("S3 credentials = %q/%q, want redacted", got.Storage.S3.AccessKey, got.Storage.S3.SecretKey)
+	}
+
+	if got.Auth.SecretKey != RedactedValue {
+		t.Errorf("SecretKey = %q, want redacted", got.Auth.SecretKey)
+	}
+	if want := "postgres://registry:[REDACTED]@db:5432/registry?sslmode=require&password=[REDACTED]"; got.Database.URL != want {
+		t.Errorf("Database.URL = %q, want %q", got.Database.URL, want)
+	}
+	if cfg.Auth.SecretKey != "a-real-secret" {
+		t.Error("Redacted modified the original config")
+	}
+
+	urls := map[string]string{
+		"sqlite:///data/registry.db":                           "sqlite:///data/registry.db",
+		"user:pw@tcp(db:3306)/registry":                        "user:[REDACTED]@tcp(db:3306)/registry",
+		"host=db user=registry password='a b' sslmode=disable": "host=db user=registry password=[REDACTED] sslmode=disable",
+		"mysql://db/registry?api_key=abc&charset=utf8":         "mysql://db/registry?api_key=[REDACTED]&charset=utf8",
+	}
+	for in, want := range urls {
+		if got := redactURL(in); got != want {
+			t.Errorf("redactURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import "regexp"
+
+// RedactedValue replaces secrets in the output of Redacted.
+const RedactedValue = "[REDACTED]"
+
+var (
+	// urlPassword matches the password in a user:password@ userinfo, with or
+	// without a scheme (e.g. "postgres://u:p@host" or "u:p@tcp(host)/db").
+	urlPassword = regexp.MustCompile(`(^|://)([^/@\s:]*):[^/@\s]*@`)
+	// dsnSecret matches secret-named parameters in a query string or a
+	// keyword/value DSN (e.g. "?password=p" or "host=db password='p'").
+	dsnSecret = regexp.MustCompile(`(?i)((?:^|[\s?&;])(?:password|passwd|pwd|pass|secret|token|sslpassword|[\w-]*key)\s*=\s*)('[^']*'|[^\s&;]*)`)
+)
+
+// redactURL masks credentials embedded in a connection URL or DSN.
+func redactURL(s string) string {
+	s = urlPassword.ReplaceAllString(s, "${1}${2}:"+RedactedValue+"@")
+	return dsnSecret.ReplaceAllString(s, "${1}"+RedactedValue)
+}
+
+// RedactedURL returns URL with embedded credentials masked, for logging.
+func (d DatabaseConfig) RedactedURL() string {
+	return redactURL(d.URL)
+}
+
+// Redacted returns a copy of c that is safe to show to operators: the JWT
+// secret and the S3 credentials are masked and credentials in the database
+// URL are removed.
+func (c Config) Redacted() Config {
+	for _, secret := range []*string{&c.Auth.SecretKey, &c.Storage.S3.AccessKey, &c.Storage.S3.SecretKey} {
+		if *secret != "" {
+			*secret = RedactedValue
+		}
+	}
+	c.Database.URL = c.Database.RedactedURL()
+	return c
+}
@@ -62,6 +62,82 @@ export default function SettingsPanel({ onMessage }) {
     }
   };
 
+  const handleToggleRedirectUncached = async () => {
+    if (!settings) return;
+
+    const newValue = !settings.redirect_uncached;
+    try {
+      setSaving(true);
+      const updated = await updateSettings({ redirect_uncached: newValue });
+      setSettings(updated);
+      onMessage({
+        type: 'success',
+        text: newValue ? 'Uncached downloads redirect to upstream' : 'Uncached downloads return not found'
+      });
+    } catch (err) {
+      onMessage({ type: 'error', text: 'Failed to update settings: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleToggleVerifySignatures = async () => {
+    if (!settings) return;
+
+    const newValue = !settings.verify_signatures;
+    try {
+      setSaving(true);
+      const updated = await updateSettings({ verify_signatures: newValue });
+      setSettings(updated);
+      onMessage({
+        type: 'success',
+        text: newValue ? 'Mirrored binaries must now carry a valid signature' : 'Signature verification disabled'
+      });
+    } catch (err) {
+      onMessage({ type: 'error', text: 'Failed to update settings: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleToggleIncludePrereleases = async () => {
+    if (!settings) return;
+
+    const newValue = !settings.include_prereleases;
+    try {
+      setSaving(true);
+      const updated = await updateSettings({ include_prereleases: newValue });
+      setSettings(updated);
+      onMessage({
+        type: 'success',
+        text: newValue ? 'Upstream prereleases are now listed' : 'Upstream prereleases are now hidden'
+      });
+    } catch (err) {
+      onMessage({ type: 'error', text: 'Failed to update settings: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const handleToggleImmutableVersions = async () => {
+    if (!settings) return;
+
+    const newValue = !settings.immutable_versions;
+    try {
+      setSaving(true);
+      const updated = await updateSettings({ immutable_versions: newValue });
+      setSettings(updated);
+      onMessage({
+        type: 'success',
+        text: newValue ? 'Published versions are now immutable' : 'Published versions can be overwritten'
+      });
+    } catch (err) {
+      onMessage({ type: 'error', text: 'Failed to update settings: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  };
+
   const handleToggleProxy = async () => {
     if (!settings) return;
     
@@ -149,6 +225,268 @@ export default function SettingsPanel({ onMessage }) {
           </button>
         </div>
 
+        {/* Redirect Uncached Toggle */}
+        <div className="p-4 flex items-center justify-between">
+          <div className="flex-1">
+            <h3 className="font-medium text-gray-900">Redirect Uncached Downloads</h3>
+            <p className="text-sm text-gray-500 mt-1">
+              When online search is disabled, send downloads of providers that are not
+              cached to the upstream URL instead of returning not found. Nothing is cached.
+            </p>
+          </div>
+          <button
+            onClick={handleToggleRedirectUncached}
+            disabled={saving}
+            className={`relative inline-flex h-6 w-11 flex-shrink-0 cursor-pointer rounded-full border-2 border-transparent transition-colors duration-200 ease-in-out focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-offset-2 ${
+              settings.redirect_uncached ? 'bg-blue-600' : 'bg-gray-200'
+            } ${saving ? 'opacity-50 cursor-not-allowed' : ''}`}
+          >
+            <span
+              className={`pointer-events-none inline-block h-5 w-5 transform rounded-full bg-white shadow ring-0 transition duration-200 ease-in-out ${
+                settings.redirect_uncached ? 'translate-x-5' : 'translate-x-0'
+              }`}
+            />
+          </button>
+        </div>
+
+        {/* Immutable Versions Toggle */}
+        <div className="p-4 flex items-center justify-between">
+          <div className="flex-1">
+            <h3 className="font-medium text-gray-900">Immutable Versions</h3>
+            <p className="text-sm text-gray-500 mt-1">
+              When enabled, the files and checksums of published provider platforms cannot be
+              replaced by uploads, imports or mirroring. New platforms can still be added.
+            </p>
+          </div>
+          <button
+            onClick={handleToggleImmutableVersions}
+            disabled={saving}
+            className={`relative inline-flex h-6 w-11 flex-shrink-0 cursor-pointer rounded-full border-2 border-transparent transition-colors duration-200 ease-in-out focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-offset-2 ${
+              settings.immutable_versions ? 'bg-blue-600' : 'bg-gray-200'
+            } ${saving ? 'opacity-50 cursor-not-allowed' : ''}`}
+          >
+            <span
+              className={`pointer-events-none inline-block h-5 w-5 transform rounded-full bg-white shadow ring-0 transition duration-200 ease-in-out ${
+                settings.immutable_versions ? 'translate-x-5' : 'translate-x-0'
+              }`}
+            />
+          </button>
+        </div>
+
+        {/* Verify Signatures Toggle */}
+        <div className="p-4 flex items-center justify-between">
+          <div className="flex-1">
+            <h3 className="font-medium text-gray-900">Verify Signatures</h3>
+            <p className="text-sm text-gray-500 mt-1">
+              When enabled, mirrored binaries are only cached if upstream's SHA256SUMS file is
+              signed by one of the provider's GPG keys and lists the binary's checksum.
+            </p>
+          </div>
+          <button
+            onClick={handleToggleVerifySignatures}
+            disabled={saving}
+            className={`relative inline-flex h-6 w-11 flex-shrink-0 cursor-pointer rounded-full border-2 border-transparent transition-colors duration-200 ease-in-out focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-offset-2 ${
+              settings.verify_signatures ? 'bg-blue-600' : 'bg-gray-200'
+            } ${saving ? 'opacity-50 cursor-not-allowed' : ''}`}
+          >
+            <span
+              className={`pointer-events-none inline-block h-5 w-5 transform rounded-full bg-white shadow ring-0 transition duration-200 ease-in-out ${
+                settings.verify_signatures ? 'translate-x-5' : 'translate-x-0'
+              }`}
+            />
+          </button>
+        </div>
+
+        {/* Include Prereleases Toggle */}
+        <div className="p-4 flex items-center justify-between">
+          <div className="flex-1">
+            <h3 className="font-medium text-gray-900">Include Prereleases</h3>
+            <p className="text-sm text-gray-500 mt-1">
+              When enabled, upstream prerelease versions such as 1.2.0-beta1 are listed to
+              Terraform. Prereleases that are already cached are always listed.
+            </p>
+          </div>
+          <button
+            onClick={handleToggleIncludePrereleases}
+            disabled={saving}
+            className={`relative inline-flex h-6 w-11 flex-shrink-0 cursor-pointer rounded-full border-2 border-transparent transition-colors duration-200 ease-in-out focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-offset-2 ${
+              settings.include_prereleases ? 'bg-blue-600' : 'bg-gray-200'
+            } ${saving ? 'opacity-50 cursor-not-allowed' : ''}`}
+          >
+            <span
+              className={`pointer-events-none inline-block h-5 w-5 transform rounded-full bg-white shadow ring-0 transition duration-200 ease-in-out ${
+                settings.include_prereleases ? 'translate-x-5' : 'translate-x-0'
+              }`}
+            />
+          </button>
+        </div>
+
+        {/* Platform subset used when a mirror or new schedule names none */}
+        <div className="p-4">
+          <h3 className="font-medium text-gray-900">Default Platforms</h3>
+          <p className="text-sm text-gray-500 mt-1">
+            OS and architecture mirrored, and synced by new schedules, when none is specified.
+            Use "all" to include every platform.
+          </p>
+          <div className="mt-2 flex gap-2">
+            <input
+              type="text"
+              value={settings.default_os ?? 'all'}
+              onChange={(e) => setSettings({ ...settings, default_os: e.target.value })}
+              placeholder="all"
+              className="w-32 px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent font-mono text-sm"
+            />
+            <input
+              type="text"
+              value={settings.default_arch ?? 'all'}
+              onChange={(e) => setSettings({ ...settings, default_arch: e.target.value })}
+              placeholder="all"
+              className="w-32 px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent font-mono text-sm"
+            />
+            <button
+              onClick={async () => {
+                try {
+                  setSaving(true);
+                  const updated = await updateSettings({
+                    default_os: settings.default_os || 'all',
+                    default_arch: settings.default_arch || 'all'
+                  });
+                  setSettings(updated);
+                  onMessage({ type: 'success', text: 'Default platforms saved' });
+                } catch (err) {
+                  onMessage({ type: 'error', text: 'Failed to save: ' + err.message });
+                } finally {
+                  setSaving(false);
+                }
+              }}
+              disabled={saving}
+              className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
+            >
+              Save
+            </button>
+          </div>
+          <p className="mt-1 text-xs text-gray-500">
+            Existing schedules keep the platforms they were created with
+          </p>
+        </div>
+
+        {/* Platforms downloaded at once while mirroring */}
+        <div className="p-4">
+          <h3 className="font-medium text-gray-900">Mirror Concurrency</h3>
+          <p className="text-sm text-gray-500 mt-1">
+            Number of platforms downloaded in parallel when mirroring a provider.
+          </p>
+          <div className="mt-2 flex gap-2">
+            <input
+              type="number"
+              min="1"
+              max="16"
+              value={settings.mirror_concurrency ?? 4}
+              onChange={(e) => setSettings({ ...settings, mirror_concurrency: e.target.value })}
+              className="w-32 px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent font-mono text-sm"
+            />
+            <button
+              onClick={async () => {
+                try {
+                  setSaving(true);
+                  const updated = await updateSettings({ mirror_concurrency: parseInt(settings.mirror_concurrency, 10) || 4 });
+                  setSettings(updated);
+                  onMessage({ type: 'success', text: 'Mirror concurrency saved' });
+                } catch (err) {
+                  onMessage({ type: 'error', text: 'Failed to save: ' + err.message });
+                } finally {
+                  setSaving(false);
+                }
+              }}
+              disabled={saving}
+              className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
+            >
+              Save
+            </button>
+          </div>
+          <p className="mt-1 text-xs text-gray-500">
+            Between 1 and 16; 1 downloads one platform at a time
+          </p>
+        </div>
+
+        {/* Versions kept per provider after a scheduled sync */}
+        <div className="p-4">
+          <h3 className="font-medium text-gray-900">Retained Versions</h3>
+          <p className="text-sm text-gray-500 mt-1">
+            Number of newest versions kept per provider after each successful scheduled sync.
+            Older versions and their files are deleted.
+          </p>
+          <div className="mt-2 flex gap-2">
+            <input
+              type="number"
+              min="0"
+              value={settings.retain_versions ?? 0}
+              onChange={(e) => setSettings({ ...settings, retain_versions: e.target.value })}
+              className="w-32 px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent font-mono text-sm"
+            />
+            <button
+              onClick={async () => {
+                try {
+                  setSaving(true);
+                  const updated = await updateSettings({ retain_versions: parseInt(settings.retain_versions, 10) || 0 });
+                  setSettings(updated);
+                  onMessage({ type: 'success', text: 'Retention policy saved' });
+                } catch (err) {
+                  onMessage({ type: 'error', text: 'Failed to save: ' + err.message });
+                } finally {
+                  setSaving(false);
+                }
+              }}
+              disabled={saving}
+              className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
+            >
+              Save
+            </button>
+          </div>
+          <p className="mt-1 text-xs text-gray-500">
+            0 keeps every version
+          </p>
+        </div>
+
+        {/* Revalidation interval for cached mirrored versions */}
+        <div className="p-4">
+          <h3 className="font-medium text-gray-900">Revalidate Cached Versions</h3>
+          <p className="text-sm text-gray-500 mt-1">
+            Hours after which a downloaded mirrored version is re-checked against upstream in the
+            background. Yanked or changed versions are flagged but still served.
+          </p>
+          <div className="mt-2 flex gap-2">
+            <input
+              type="number"
+              min="0"
+              value={settings.revalidate_hours ?? 0}
+              onChange={(e) => setSettings({ ...settings, revalidate_hours: e.target.value })}
+              className="w-32 px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent font-mono text-sm"
+            />
+            <button
+              onClick={async () => {
+                try {
+                  setSaving(true);
+                  const updated = await updateSettings({ revalidate_hours: parseInt(settings.revalidate_hours, 10) || 0 });
+                  setSettings(updated);
+                  onMessage({ type: 'success', text: 'Revalidation interval saved' });
+                } catch (err) {
+                  onMessage({ type: 'error', text: 'Failed to save: ' + err.message });
+                } finally {
+                  setSaving(false);
+                }
+              }}
+              disabled={saving}
+              className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
+            >
+              Save
+            </button>
+          </div>
+          <p className="mt-1 text-xs text-gray-500">
+            0 disables revalidation and makes no extra upstream requests
+          </p>
+        </div>
+
         {/* Default Upstream URL (read-only display) */}
         <div className="p-4">
           <h3 className="font-medium text-gray-900">Default Upstream URL</h3>
@@ -3,6 +3,7 @@ import {
   fetchSyncSchedules,
   createSyncSchedule,
   updateSyncSchedule,
+  bulkUpdateSyncSchedules,
   deleteSyncSchedule,
   runSyncScheduleNow
 } from '../services/api';
@@ -14,8 +15,9 @@ export default function SyncSchedulesPanel({ onMessage }) {
     namespace: '',
     name: '',
     cronExpr: '0 0 * * *',
-    syncOS: 'all',
-    syncArch: 'all',
+    syncOS: '',
+    syncArch: '',
+    publishedAfter: '',
     enabled: true
   });
   const [editingId, setEditingId] = useState(null);
@@ -57,8 +59,9 @@ export default function SyncSchedulesPanel({ onMessage }) {
       namespace: '',
       name: '',
       cronExpr: '0 0 * * *',
-      syncOS: 'all',
-      syncArch: 'all',
+      syncOS: '',
+      syncArch: '',
+      publishedAfter: '',
       enabled: true
     });
     setEditingId(null);
@@ -73,6 +76,7 @@ export default function SyncSchedulesPanel({ onMessage }) {
           cron_expr: form.cronExpr,
           sync_os: form.syncOS,
           sync_arch: form.syncArch,
+          published_after: form.publishedAfter,
           enabled: form.enabled
         });
         onMessage({ type: 'success', text: 'Schedule updated successfully' });
@@ -83,6 +87,7 @@ export default function SyncSchedulesPanel({ onMessage }) {
           cron_expr: form.cronExpr,
           sync_os: form.syncOS,
           sync_arch: form.syncArch,
+          published_after: form.publishedAfter,
           enabled: form.enabled
         });
         onMessage({ type: 'success', text: 'Schedule created successfully' });
@@ -101,6 +106,7 @@ export default function SyncSchedulesPanel({ onMessage }) {
       cronExpr: schedule.cron_expr,
       syncOS: schedule.sync_os || 'all',
       syncArch: schedule.sync_arch || 'all',
+      publishedAfter: schedule.published_after ? schedule.published_after.slice(0, 10) : '',
       enabled: schedule.enabled
     });
     setEditingId(schedule.ID);
@@ -142,6 +148,19 @@ export default function SyncSchedulesPanel({ onMessage }) {
     }
   };
 
+  const handleBulkEnabled = async (enabled) => {
+    try {
+      const result = await bulkUpdateSyncSchedules({ all: true, enabled });
+      onMessage({
+        type: 'success',
+        text: `${enabled ? 'Resumed' : 'Paused'} ${result.changed} schedule(s)`
+      });
+      loadSchedules();
+    } catch (err) {
+      onMessage({ type: 'error', text: 'Failed to update schedules: ' + err.message });
+    }
+  };
+
   const formatDate = (dateStr) => {
     if (!dateStr || dateStr === '0001-01-01T00:00:00Z') {
       return '-';
@@ -175,12 +194,28 @@ export default function SyncSchedulesPanel({ onMessage }) {
             Configure automatic synchronization with upstream registry
           </p>
         </div>
-        <button
-          onClick={() => setShowForm(!showForm)}
-          className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors"
-        >
-          {showForm ? 'Cancel' : 'Add Schedule'}
-        </button>
+        <div className="flex gap-2">
+          <button
+            onClick={() => handleBulkEnabled(false)}
+            disabled={!schedules.some((s) => s.enabled)}
+            className="px-4 py-2 border border-gray-300 text-gray-700 rounded-lg hover:bg-gray-50 transition-colors disabled:opacity-50"
+          >
+            Pause All
+          </button>
+          <button
+            onClick={() => handleBulkEnabled(true)}
+            disabled={!schedules.some((s) => !s.enabled)}
+            className="px-4 py-2 border border-gray-300 text-gray-700 rounded-lg hover:bg-gray-50 transition-colors disabled:opacity-50"
+          >
+            Resume All
+          </button>
+          <button
+            onClick={() => setShowForm(!showForm)}
+            className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors"
+          >
+            {showForm ? 'Cancel' : 'Add Schedule'}
+          </button>
+        </div>
       </div>
 
       {/* Form */}
@@ -262,6 +297,7 @@ export default function SyncSchedulesPanel({ onMessage }) {
                   onChange={(e) => setForm({ ...form, syncOS: e.target.value })}
                   className="w-full px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
                 >
+                  {!editingId && <option value="">Registry default</option>}
                   <option value="all">All platforms</option>
                   <option value="linux">Linux</option>
                   <option value="darwin">macOS</option>
@@ -277,6 +313,7 @@ export default function SyncSchedulesPanel({ onMessage }) {
                   onChange={(e) => setForm({ ...form, syncArch: e.target.value })}
                   className="w-full px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
                 >
+                  {!editingId && <option value="">Registry default</option>}
                   <option value="all">All architectures</option>
                   <option value="amd64">amd64</option>
                   <option value="arm64">arm64</option>
@@ -285,6 +322,21 @@ export default function SyncSchedulesPanel({ onMessage }) {
               </div>
             </div>
 
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                Published After
+              </label>
+              <input
+                type="date"
+                value={form.publishedAfter}
+                onChange={(e) => setForm({ ...form, publishedAfter: e.target.value })}
+                className="w-full px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
+              />
+              <p className="mt-1 text-xs text-gray-500">
+                Only sync versions published upstream on or after this date. Leave empty to sync any version.
+              </p>
+            </div>
+
             <div className="flex items-center">
               <input
                 type="checkbox"
@@ -380,6 +432,9 @@ export default function SyncSchedulesPanel({ onMessage }) {
                     <span className="font-mono">{schedule.cron_expr}</span>
                     <span>OS: {schedule.sync_os || 'all'}</span>
                     <span>Arch: {schedule.sync_arch || 'all'}</span>
+                    {schedule.published_after && (
+                      <span>Published after: {schedule.published_after.slice(0, 10)}</span>
+                    )}
                   </div>
                   <div className="mt-2 flex flex-wrap gap-x-6 gap-y-1 text-xs text-gray-400">
                     <span>Last run: {formatDate(schedule.last_run_at)}</span>
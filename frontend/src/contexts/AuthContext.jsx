@@ -49,8 +49,8 @@ export function AuthProvider({ children }) {
       if (response.ok) {
         const data = await response.json();
         setUser(data.user);
-      } else {
-        // Token is invalid, clear it
+      } else if (!(await refreshAccessToken())) {
+        // Token is invalid and cannot be renewed, clear it
         logout();
       }
     } catch (err) {
@@ -61,6 +61,25 @@ export function AuthProvider({ children }) {
     }
   }
 
+  // Exchanges the stored refresh token for a new access token. Setting the token
+  // re-runs the token effect, which fetches the user again.
+  async function refreshAccessToken() {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) return false;
+    const response = await fetch(`${API_BASE_URL}/api/v1/auth/refresh`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+    if (!response.ok) return false;
+    const data = await response.json();
+    localStorage.setItem('token', data.token);
+    setToken(data.token);
+    return true;
+  }
+
   async function login(username, password) {
     const response = await fetch(`${API_BASE_URL}/api/v1/auth/login`, {
       method: 'POST',
@@ -77,12 +96,47 @@ export function AuthProvider({ children }) {
     }
 
     localStorage.setItem('token', data.token);
+    localStorage.setItem('refresh_token', data.refresh_token);
     setToken(data.token);
     setUser(data.user);
     return data;
   }
 
+  // Changing the password also lifts a pending must_change_password, which
+  // blocks every other API call until it is done.
+  async function changePassword(currentPassword, newPassword) {
+    const response = await fetch(`${API_BASE_URL}/api/v1/auth/change-password`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${token}`,
+      },
+      body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+    });
+
+    const data = await response.json();
+
+    if (!response.ok) {
+      throw new Error(data.error || 'Password change failed');
+    }
+
+    setUser((current) => current && { ...current, must_change_password: false });
+    return data;
+  }
+
   function logout() {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (refreshToken) {
+      // Best effort; the token expires on its own if this fails.
+      fetch(`${API_BASE_URL}/api/v1/auth/revoke`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      }).catch(() => {});
+    }
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('token');
     setToken(null);
     setUser(null);
@@ -100,6 +154,7 @@ export function AuthProvider({ children }) {
     isAuthenticated: !!user,
     login,
     logout,
+    changePassword,
     getAuthHeaders,
   };
 
@@ -205,6 +205,7 @@ REGISTRY_HOST=your-registry-host`}</CodeBlock>
         <ul className="list-disc list-inside text-gray-600 space-y-1">
           <li>Username: <code className="bg-gray-100 px-1.5 py-0.5 rounded">admin</code></li>
           <li>Password: Configured via <code className="bg-gray-100 px-1.5 py-0.5 rounded">ADMIN_PASSWORD</code> environment variable</li>
+          <li>Without <code className="bg-gray-100 px-1.5 py-0.5 rounded">ADMIN_PASSWORD</code>, a random password is printed once in the server log and must be changed at first login</li>
         </ul>
       </SubSection>
     </div>
@@ -26,6 +26,8 @@ export default function Mirror() {
   // Provider detail view state
   const [selectedProvider, setSelectedProvider] = useState(null);
   const [providerVersions, setProviderVersions] = useState([]);
+  const [versionsPage, setVersionsPage] = useState(1);
+  const [versionsTotal, setVersionsTotal] = useState(0);
   const [loadingVersions, setLoadingVersions] = useState(false);
 
   useEffect(() => {
@@ -71,6 +73,8 @@ export default function Mirror() {
       setSelectedProvider(provider);
       const data = await fetchProviderVersionsDetail(provider.namespace, provider.name);
       setProviderVersions(data.versions || []);
+      setVersionsPage(1);
+      setVersionsTotal(data.total || 0);
     } catch (err) {
       setMessage({ type: 'error', text: `Failed to load versions: ${err.message}` });
     } finally {
@@ -78,9 +82,34 @@ export default function Mirror() {
     }
   }
 
+  async function handleLoadMoreVersions() {
+    try {
+      const nextPage = versionsPage + 1;
+      const data = await fetchProviderVersionsDetail(selectedProvider.namespace, selectedProvider.name, { page: nextPage });
+      setProviderVersions((prev) => [...prev, ...(data.versions || [])]);
+      setVersionsPage(nextPage);
+    } catch (err) {
+      setMessage({ type: 'error', text: `Failed to load versions: ${err.message}` });
+    }
+  }
+
+  async function handleShowPlatforms(version) {
+    try {
+      const data = await fetchProviderVersionsDetail(selectedProvider.namespace, selectedProvider.name, { version: version.version });
+      const detail = (data.versions || [])[0];
+      if (detail) {
+        setProviderVersions((prev) => prev.map((v) => (v.id === detail.id ? detail : v)));
+      }
+    } catch (err) {
+      setMessage({ type: 'error', text: `Failed to load platforms: ${err.message}` });
+    }
+  }
+
   function handleBackToList() {
     setSelectedProvider(null);
     setProviderVersions([]);
+    setVersionsPage(1);
+    setVersionsTotal(0);
   }
 
   async function handleMirror() {
@@ -179,7 +208,11 @@ export default function Mirror() {
       formData.append('file', importFile);
       
       const result = await importProvider(formData);
-      setMessage({ type: 'success', text: `Provider ${result.provider.Namespace}/${result.provider.Name} v${result.provider.Version} imported successfully!` });
+      const rejected = result.rejected_count ? `, ${result.rejected_count} rejected` : '';
+      setMessage({
+        type: result.rejected_count ? 'error' : 'success',
+        text: `Provider ${result.provider.namespace}/${result.provider.name} v${result.provider.version} imported: ${result.verified_count} platform(s) verified${rejected}`
+      });
       setImportFile(null);
       loadProviders();
     } catch (err) {
@@ -355,12 +388,20 @@ export default function Mirror() {
                             }`}>
                               {version.source_type === 'mirror' ? 'Mirrored' : 'Uploaded'}
                             </span>
+                            {version.upstream_status && (
+                              <span
+                                className="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-red-50 text-red-700"
+                                title={version.revalidated_at ? `Checked ${new Date(version.revalidated_at).toLocaleString()}` : undefined}
+                              >
+                                {version.upstream_status === 'yanked' ? 'Yanked upstream' : 'Changed upstream'}
+                              </span>
+                            )}
                           </div>
                           <p className="text-sm text-gray-500 mt-1">
                             {version.downloads || 0} downloads • 
                             Published {version.published ? new Date(version.published).toLocaleDateString() : 'N/A'}
                           </p>
-                          {version.platforms && version.platforms.length > 0 && (
+                          {version.platforms && version.platforms.length > 0 ? (
                             <div className="flex flex-wrap gap-2 mt-3">
                               {version.platforms.map((p, i) => (
                                 <span key={i} className="inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium bg-gray-100 text-gray-800">
@@ -368,6 +409,13 @@ export default function Mirror() {
                                 </span>
                               ))}
                             </div>
+                          ) : version.platform_count > 0 && (
+                            <button
+                              onClick={() => handleShowPlatforms(version)}
+                              className="mt-3 text-sm text-blue-600 hover:text-blue-800"
+                            >
+                              Show {version.platform_count} platform{version.platform_count !== 1 ? 's' : ''}
+                            </button>
                           )}
                         </div>
                         {isAuthenticated && (
@@ -396,6 +444,14 @@ export default function Mirror() {
                       </div>
                     </div>
                   ))}
+                  {providerVersions.length < versionsTotal && (
+                    <button
+                      onClick={handleLoadMoreVersions}
+                      className="w-full py-3 text-sm font-medium text-blue-600 bg-blue-50 rounded-xl hover:bg-blue-100 transition-colors"
+                    >
+                      Load more versions ({providerVersions.length} of {versionsTotal})
+                    </button>
+                  )}
                 </div>
               )}
             </div>
@@ -155,6 +155,13 @@ function ProviderCard({ provider }) {
     >
       <div className="flex items-start justify-between mb-3">
         <div className="flex items-center gap-2">
+          {provider.logo_url && (
+            <img
+              src={provider.logo_url.startsWith('/') ? `https://registry.terraform.io${provider.logo_url}` : provider.logo_url}
+              alt=""
+              className="w-6 h-6 object-contain"
+            />
+          )}
           <h3 className="text-lg font-semibold text-gray-900">
             {provider.namespace}/{provider.name}
           </h3>
@@ -28,7 +28,7 @@ async function fetchJSON(url, options = {}) {
   return response.json();
 }
 
-export async function fetchProviders({ page = 1, limit = 20, namespace = '', name = '' } = {}) {
+export async function fetchProviders({ page = 1, limit = 20, namespace = '', name = '', sort = '', order = '' } = {}) {
   const params = new URLSearchParams({
     page: page.toString(),
     limit: limit.toString(),
@@ -36,20 +36,32 @@ export async function fetchProviders({ page = 1, limit = 20, namespace = '', nam
 
   if (namespace) params.append('namespace', namespace);
   if (name) params.append('name', name);
+  if (sort) params.append('sort', sort);
+  if (order) params.append('order', order);
 
   return fetchJSON(`/api/v1/providers?${params}`);
 }
 
 export async function fetchProvider(namespace, name, version) {
-  return fetchJSON(`/api/v1/providers/${namespace}/${name}/${version}`);
+  return fetchJSON(`/v1/providers/${namespace}/${name}/${version}`);
 }
 
 export async function fetchProviderVersions(namespace, name) {
   return fetchJSON(`/v1/providers/${namespace}/${name}/versions`);
 }
 
-export async function fetchProviderVersionsDetail(namespace, name) {
-  return fetchJSON(`/api/v1/mirror/providers/${namespace}/${name}`);
+export async function fetchProviderVersionsDetail(namespace, name, { page = 1, limit = 50, version = '' } = {}) {
+  const params = new URLSearchParams({ page: page.toString(), limit: limit.toString() });
+  if (version) params.append('version', version);
+  return fetchJSON(`/api/v1/mirror/providers/${namespace}/${name}?${params}`);
+}
+
+export async function fetchProviderClients(namespace, name) {
+  return fetchJSON(`/api/v1/mirror/providers/${namespace}/${name}/clients`);
+}
+
+export async function fetchProviderUsage(namespace, name) {
+  return fetchJSON(`/api/v1/mirror/providers/${namespace}/${name}/usage`);
 }
 
 export async function fetchModules({ page = 1, limit = 20, namespace = '', name = '' } = {}) {
@@ -68,17 +80,28 @@ export async function fetchModule(namespace, name, provider, version) {
   return fetchJSON(`/api/v1/modules/${namespace}/${name}/${provider}/${version}`);
 }
 
-export async function searchProviders(query) {
-  return fetchJSON(`/api/v1/providers/search?q=${encodeURIComponent(query)}`);
+export async function searchProviders(query, { page = 1, limit = 20, tier = '', cached } = {}) {
+  const params = new URLSearchParams({
+    q: query,
+    page: page.toString(),
+    limit: limit.toString(),
+  });
+
+  if (tier) params.append('tier', tier);
+  if (cached !== undefined) params.append('cached', String(cached));
+
+  return fetchJSON(`/api/v1/providers/search?${params}`);
 }
 
 // Mirror API
-export async function fetchMirroredProviders({ page = 1, limit = 20, sourceType = '' } = {}) {
+export async function fetchMirroredProviders({ page = 1, limit = 20, sourceType = '', sort = '', order = '' } = {}) {
   const params = new URLSearchParams({
     page: page.toString(),
     limit: limit.toString(),
   });
   if (sourceType) params.append('source_type', sourceType);
+  if (sort) params.append('sort', sort);
+  if (order) params.append('order', order);
   return fetchJSON(`/api/v1/mirror/providers?${params}`);
 }
 
@@ -94,6 +117,60 @@ export async function mirrorProvider(namespace, name, { version, os = 'all', arc
   });
 }
 
+// List users (admin only)
+export async function listUsers({ page = 1, limit = 20 } = {}) {
+  const params = new URLSearchParams({ page, limit });
+  return fetchJSON(`/api/v1/users?${params}`);
+}
+
+// Create a user (admin only); user is { username, email, password, role?, must_change_password? }
+export async function createUser(user) {
+  return fetchJSON('/api/v1/users', {
+    method: 'POST',
+    body: JSON.stringify(user),
+  });
+}
+
+// Delete a user (admin only)
+export async function deleteUser(id) {
+  return fetchJSON(`/api/v1/users/${id}`, { method: 'DELETE' });
+}
+
+// Webhooks (admin only); events are names such as 'sync.failed', or '*'
+export async function listWebhooks() {
+  return fetchJSON('/api/v1/webhooks');
+}
+
+export async function createWebhook({ url, events, secret }) {
+  return fetchJSON('/api/v1/webhooks', {
+    method: 'POST',
+    body: JSON.stringify({ url, events, secret }),
+  });
+}
+
+export async function updateWebhook(id, changes) {
+  return fetchJSON(`/api/v1/webhooks/${id}`, {
+    method: 'PUT',
+    body: JSON.stringify(changes),
+  });
+}
+
+export async function deleteWebhook(id) {
+  return fetchJSON(`/api/v1/webhooks/${id}`, { method: 'DELETE' });
+}
+
+export async function testWebhook(id) {
+  return fetchJSON(`/api/v1/webhooks/${id}/test`, { method: 'POST' });
+}
+
+// Mirror several providers at once; items are { namespace, name, version?, os?, arch? }
+export async function mirrorBatch(items) {
+  return fetchJSON('/api/v1/mirror/batch', {
+    method: 'POST',
+    body: JSON.stringify(items),
+  });
+}
+
 // Mirror provider with SSE progress updates
 export function mirrorProviderWithProgress(namespace, name, { version, os = 'all', arch = 'all', proxyUrl = '' } = {}, onProgress) {
   return new Promise((resolve, reject) => {
@@ -198,6 +275,13 @@ export async function updateSettings(settings) {
   });
 }
 
+export async function setFreeze(frozen) {
+  return fetchJSON('/api/v1/settings/freeze', {
+    method: 'PUT',
+    body: JSON.stringify({ frozen }),
+  });
+}
+
 // Sync schedules API
 export async function fetchSyncSchedules() {
   return fetchJSON('/api/v1/sync/schedules');
@@ -217,6 +301,13 @@ export async function updateSyncSchedule(id, schedule) {
   });
 }
 
+export async function bulkUpdateSyncSchedules(selection) {
+  return fetchJSON('/api/v1/sync/schedules/bulk', {
+    method: 'POST',
+    body: JSON.stringify(selection),
+  });
+}
+
 export async function deleteSyncSchedule(id) {
   return fetchJSON(`/api/v1/sync/schedules/${id}`, {
     method: 'DELETE',
@@ -233,3 +324,27 @@ export async function runSyncScheduleNow(id) {
 export async function fetchUpstreamVersions(namespace, name) {
   return fetchJSON(`/api/v1/mirror/upstream/${namespace}/${name}`);
 }
+
+// Recent server log events (admin only). since is an event ID or RFC 3339 timestamp.
+export async function fetchAdminLogs(since = '') {
+  const params = since ? `?since=${encodeURIComponent(since)}` : '';
+  return fetchJSON(`/api/v1/admin/logs${params}`);
+}
+
+// Tail server log events over SSE (admin only). Returns a function that stops the stream.
+export function streamAdminLogs(onEvent, since = '') {
+  const params = new URLSearchParams();
+  if (since) params.append('since', since);
+  const token = getAuthToken();
+  if (token) params.append('token', token);
+
+  const eventSource = new EventSource(`${API_BASE_URL}/api/v1/admin/logs/stream?${params}`);
+  eventSource.onmessage = (event) => {
+    try {
+      onEvent(JSON.parse(event.data));
+    } catch (e) {
+      console.error('Failed to parse SSE data:', e);
+    }
+  };
+  return () => eventSource.close();
+}